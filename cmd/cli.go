@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guttosm/url-shortener/internal/service"
+)
+
+// usage describes the subcommands accepted by the binary. Without a subcommand, the HTTP server is started.
+const usage = `usage:
+  url-shortener                                   start the HTTP server
+  url-shortener import [-format csv|jsonl] [-on-conflict skip|overwrite] [file]
+  url-shortener export [-format csv|jsonl] [-owner user-id] [-o file]`
+
+// transferFactory builds the transfer service used by the subcommands and returns its cleanup function.
+type transferFactory func() (service.TransferService, func(), error)
+
+// runCommand runs an import or export subcommand.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - args ([]string): The subcommand and its flags, without the program name.
+// - stdin (io.Reader): The input read by import when no file is given or the file is "-".
+// - stdout (io.Writer): The output written by export when no file is given or the file is "-".
+// - stderr (io.Writer): Where progress and row errors are reported.
+// - newTransfer (transferFactory): Builds the transfer service once the flags are valid.
+//
+// Returns:
+// - error: An error if the arguments are invalid or the command fails.
+func runCommand(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, newTransfer transferFactory) error {
+	switch args[0] {
+	case "import":
+		return runImport(ctx, args[1:], stdin, stderr, newTransfer)
+	case "export":
+		return runExport(ctx, args[1:], stdout, stderr, newTransfer)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintln(stderr, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// runImport imports URL mappings from a file or stdin, reporting progress after every stored batch.
+func runImport(ctx context.Context, args []string, stdin io.Reader, stderr io.Writer, newTransfer transferFactory) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "input format, csv or jsonl (default from the file extension, else jsonl)")
+	onConflict := flags.String("on-conflict", service.ConflictSkip, "what to do when a short ID exists: skip or overwrite")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = formatFromPath(path)
+	}
+
+	input := stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	transfer, cleanup, err := newTransfer()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	report, err := transfer.Import(ctx, input, service.ImportOptions{
+		Format:     *format,
+		OnConflict: *onConflict,
+		Progress: func(r service.ImportReport) {
+			fmt.Fprintln(stderr, formatImportReport(r))
+		},
+	})
+	if report != nil {
+		for _, rowErr := range report.Errors {
+			fmt.Fprintf(stderr, "line %d: %s: %v\n", rowErr.Line, rowErr.ShortID, rowErr.Err)
+		}
+		if report.Failed > int64(len(report.Errors)) {
+			fmt.Fprintf(stderr, "... and %d more failed rows\n", report.Failed-int64(len(report.Errors)))
+		}
+		fmt.Fprintln(stderr, "done: "+formatImportReport(*report))
+	}
+	return err
+}
+
+// runExport exports URL mappings to a file or stdout.
+func runExport(ctx context.Context, args []string, stdout, stderr io.Writer, newTransfer transferFactory) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "output format, csv or jsonl (default from the file extension, else jsonl)")
+	owner := flags.String("owner", "", "only export the URLs of this user")
+	path := flags.String("o", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return errors.New("export takes no arguments\n" + usage)
+	}
+	if *format == "" {
+		*format = formatFromPath(*path)
+	}
+
+	transfer, cleanup, err := newTransfer()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	output := stdout
+	if *path != "" && *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	written, err := transfer.Export(ctx, output, service.ExportOptions{Format: *format, OwnerID: *owner})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "exported %d URLs\n", written)
+	return nil
+}
+
+// formatFromPath picks the transfer format from a file extension, defaulting to JSONL.
+func formatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return service.FormatCSV
+	}
+	return service.FormatJSONL
+}
+
+// formatImportReport renders the totals of an import on one line.
+func formatImportReport(r service.ImportReport) string {
+	return fmt.Sprintf("processed=%d imported=%d overwritten=%d skipped=%d failed=%d",
+		r.Processed, r.Imported, r.Overwritten, r.Skipped, r.Failed)
+}
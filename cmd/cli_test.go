@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/service"
+)
+
+// stubTransferService records the options it is called with.
+type stubTransferService struct {
+	importOpts service.ImportOptions
+	exportOpts service.ExportOptions
+	input      string
+}
+
+func (s *stubTransferService) Import(ctx context.Context, r io.Reader, opts service.ImportOptions) (*service.ImportReport, error) {
+	s.importOpts = opts
+	data, _ := io.ReadAll(r)
+	s.input = string(data)
+	report := service.ImportReport{Processed: 2, Imported: 1, Failed: 1}
+	opts.Progress(report)
+	return &report, nil
+}
+
+func (s *stubTransferService) Export(ctx context.Context, w io.Writer, opts service.ExportOptions) (int64, error) {
+	s.exportOpts = opts
+	_, err := io.WriteString(w, "exported\n")
+	return 1, err
+}
+
+func newStubFactory(stub *stubTransferService, cleaned *bool) transferFactory {
+	return func() (service.TransferService, func(), error) {
+		return stub, func() { *cleaned = true }, nil
+	}
+}
+
+// TestRunCommand_Import tests that import reads stdin and reports progress.
+func TestRunCommand_Import(t *testing.T) {
+	stub := &stubTransferService{}
+	cleaned := false
+	var stderr bytes.Buffer
+
+	err := runCommand(context.Background(), []string{"import", "-format", "csv", "-on-conflict", "overwrite"},
+		strings.NewReader("short_id,original_url\n"), io.Discard, &stderr, newStubFactory(stub, &cleaned))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stub.importOpts.Format != service.FormatCSV || stub.importOpts.OnConflict != service.ConflictOverwrite {
+		t.Errorf("Unexpected import options %+v", stub.importOpts)
+	}
+	if stub.input != "short_id,original_url\n" {
+		t.Errorf("Expected stdin to be imported, got %q", stub.input)
+	}
+	if !strings.Contains(stderr.String(), "done: processed=2 imported=1") {
+		t.Errorf("Expected a final report, got %q", stderr.String())
+	}
+	if !cleaned {
+		t.Error("Expected cleanup to be called")
+	}
+}
+
+// TestRunCommand_Export tests that export writes to stdout and infers the format from the file name.
+func TestRunCommand_Export(t *testing.T) {
+	stub := &stubTransferService{}
+	cleaned := false
+	var stdout bytes.Buffer
+
+	err := runCommand(context.Background(), []string{"export", "-owner", "user-1"},
+		nil, &stdout, io.Discard, newStubFactory(stub, &cleaned))
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stub.exportOpts.Format != service.FormatJSONL || stub.exportOpts.OwnerID != "user-1" {
+		t.Errorf("Unexpected export options %+v", stub.exportOpts)
+	}
+	if stdout.String() != "exported\n" {
+		t.Errorf("Expected the export on stdout, got %q", stdout.String())
+	}
+	if formatFromPath("links.CSV") != service.FormatCSV {
+		t.Error("Expected .CSV files to be exported as CSV")
+	}
+}
+
+// TestRunCommand_Unknown tests that unknown subcommands are rejected before connecting.
+func TestRunCommand_Unknown(t *testing.T) {
+	factory := func() (service.TransferService, func(), error) {
+		t.Fatal("Expected no connection for an unknown command")
+		return nil, nil, nil
+	}
+
+	if err := runCommand(context.Background(), []string{"migrate"}, nil, io.Discard, io.Discard, factory); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
@@ -63,6 +63,13 @@ func gracefulShutdown(server *http.Server, cleanup func()) {
 func main() {
 	config.LoadConfig()
 
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr, app.InitializeTransfer); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	router, cleanup, err := app.InitializeApp()
 	if err != nil {
 		log.Fatal("Error on start up application:", err)
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -15,26 +16,134 @@ import (
 // - RedisURI (string): The Redis connection URI.
 // - ServerPort (string): The port on which the server will run.
 // - Auth (AuthConfig): The authentication configuration.
+// - JWT (JWTConfig): The token signing configuration.
+// - ShortID (ShortIDConfig): The short ID generation configuration.
+// - Analytics (AnalyticsConfig): The click analytics queue configuration.
+// - RateLimit (RateLimitConfig): The per-client request limits of each route group.
+// - Destination (DestinationConfig): The screening of destination URLs.
+// - Dedupe (DedupeConfig): How requests for equivalent URLs share short IDs.
+// - PublicURL (PublicURLConfig): How the short URLs returned to clients are built.
+// - GeoIPDatabase (string): The path of a MaxMind DB country database, such as GeoLite2-Country.mmdb, used for the
+// country of redirect rules and clicks when no proxy header reports it. Empty disables the lookup.
 type Config struct {
-	MongoURI   string
-	MongoDB    string
-	RedisURI   string
-	ServerPort string
-	Auth       AuthConfig
+	MongoURI      string
+	MongoDB       string
+	RedisURI      string
+	ServerPort    string
+	Auth          AuthConfig
+	JWT           JWTConfig
+	ShortID       ShortIDConfig
+	Analytics     AnalyticsConfig
+	RateLimit     RateLimitConfig
+	Destination   DestinationConfig
+	Dedupe        DedupeConfig
+	PublicURL     PublicURLConfig
+	GeoIPDatabase string
 }
 
-// AuthConfig holds the authentication configuration.
+// AuthConfig holds the bootstrap account created on startup if it does not exist yet.
 //
 // Fields:
-// - UserID (string): The user ID for authentication.
-// - Username (string): The username for authentication.
-// - Password (string): The password for authentication.
+// - UserID (string): The user ID assigned to the bootstrap account.
+// - Username (string): The username of the bootstrap account.
+// - Password (string): The password of the bootstrap account.
 type AuthConfig struct {
 	UserID   string
 	Username string
 	Password string
 }
 
+// JWTConfig holds the token signing configuration.
+//
+// Fields:
+// - Algorithm (string): The signing algorithm: "HS256", "RS256" or "EdDSA".
+// - KeyID (string): The ID of the signing key, sent in the "kid" header of issued tokens.
+// - Secret (string): The HS256 secret, used when no private key file is set.
+// - PrivateKeyFile (string): The path of the PEM-encoded RSA or Ed25519 signing key.
+// - VerificationKeys ([]string): Additional "kid=path" keys accepted when verifying tokens, e.g. keys being rotated out.
+// - AccessTokenTTL (time.Duration): The lifetime of access tokens.
+// - RefreshTokenTTL (time.Duration): The lifetime of refresh tokens.
+type JWTConfig struct {
+	Algorithm        string
+	KeyID            string
+	Secret           string
+	PrivateKeyFile   string
+	VerificationKeys []string
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+}
+
+// ShortIDConfig holds the short ID generation configuration.
+//
+// Fields:
+// - Strategy (string): The generator to use: "random", "counter" or "hashids".
+// - Length (int): The (minimum) number of characters in generated IDs.
+// - Salt (string): The salt used by the "hashids" strategy.
+type ShortIDConfig struct {
+	Strategy string
+	Length   int
+	Salt     string
+}
+
+// AnalyticsConfig holds the click analytics queue configuration.
+//
+// Fields:
+// - BufferSize (int): The number of click events buffered before new ones are dropped.
+// - BatchSize (int): The maximum number of click events written per batch.
+// - FlushInterval (time.Duration): The maximum time a click event waits before being written.
+type AnalyticsConfig struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// RateLimitConfig holds the per-client request limits of each route group, written as
+// "<requests>/<window>" such as "10/1m". An empty value or "0" disables the limit.
+//
+// Fields:
+// - Auth (string): The limit per IP of the login, registration and token refresh endpoints.
+// - API (string): The limit per IP, and then per user or API key, of the authenticated API.
+// - Redirect (string): The limit per IP of short URL redirects.
+// - LinkPassword (string): The wrong passwords allowed per password-protected URL before it is locked for the window.
+type RateLimitConfig struct {
+	Auth         string
+	API          string
+	Redirect     string
+	LinkPassword string
+}
+
+// DestinationConfig holds the screening of destination URLs before they are shortened.
+//
+// Fields:
+// - ResolveHosts (bool): Whether host names are resolved so names pointing at private addresses are rejected.
+// - ReputationFile (string): The path of a list of known harmful domains and URLs. Empty disables the reputation check.
+type DestinationConfig struct {
+	ResolveHosts   bool
+	ReputationFile string
+}
+
+// DedupeConfig holds how requests for equivalent URLs share short IDs.
+//
+// Fields:
+// - DropTrackingParams (bool): Whether utm_* and click ID parameters are ignored when comparing URLs.
+// - Scope (string): The default dedupe policy of new links: "global", "owner" or "never".
+type DedupeConfig struct {
+	DropTrackingParams bool
+	Scope              string
+}
+
+// PublicURLConfig holds how the short URLs returned to clients are built.
+//
+// Fields:
+// - BaseURL (string): The public base URL of short URLs, optionally with a path prefix, e.g. "https://sho.rt" or
+// "https://example.com/s". Empty uses the scheme and host of each request.
+// - TrustedProxies ([]string): The IP addresses and CIDR ranges of the proxies whose X-Forwarded-For, X-Forwarded-Host,
+// X-Forwarded-Proto and client country headers are honored. Empty ignores them and uses the address clients connect from.
+type PublicURLConfig struct {
+	BaseURL        string
+	TrustedProxies []string
+}
+
 // AppConfig is the global instance of the application configuration.
 var AppConfig *Config
 
@@ -53,6 +162,20 @@ func LoadConfig() {
 	viper.SetConfigType("env")
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("JWT_KEY_ID", "default")
+	viper.SetDefault("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+	viper.SetDefault("JWT_REFRESH_TOKEN_TTL", 7*24*time.Hour)
+	viper.SetDefault("SHORT_ID_STRATEGY", "random")
+	viper.SetDefault("SHORT_ID_LENGTH", 6)
+	viper.SetDefault("ANALYTICS_BUFFER_SIZE", 1024)
+	viper.SetDefault("ANALYTICS_BATCH_SIZE", 100)
+	viper.SetDefault("ANALYTICS_FLUSH_INTERVAL", time.Second)
+	viper.SetDefault("RATE_LIMIT_AUTH", "10/1m")
+	viper.SetDefault("RATE_LIMIT_API", "300/1m")
+	viper.SetDefault("RATE_LIMIT_LINK_PASSWORD", "5/15m")
+	viper.SetDefault("DESTINATION_RESOLVE_HOSTS", true)
+	viper.SetDefault("DEDUPE_SCOPE", "global")
 
 	if err := viper.ReadInConfig(); err == nil {
 		log.Println("File .env loaded")
@@ -70,9 +193,58 @@ func LoadConfig() {
 			Username: viper.GetString("AUTH_USERNAME"),
 			Password: viper.GetString("AUTH_PASSWORD"),
 		},
+		JWT: JWTConfig{
+			Algorithm:        viper.GetString("JWT_ALGORITHM"),
+			KeyID:            viper.GetString("JWT_KEY_ID"),
+			Secret:           viper.GetString("JWT_SECRET"),
+			PrivateKeyFile:   viper.GetString("JWT_PRIVATE_KEY_FILE"),
+			VerificationKeys: splitList(viper.GetString("JWT_VERIFICATION_KEYS")),
+			AccessTokenTTL:   viper.GetDuration("JWT_ACCESS_TOKEN_TTL"),
+			RefreshTokenTTL:  viper.GetDuration("JWT_REFRESH_TOKEN_TTL"),
+		},
+		ShortID: ShortIDConfig{
+			Strategy: viper.GetString("SHORT_ID_STRATEGY"),
+			Length:   viper.GetInt("SHORT_ID_LENGTH"),
+			Salt:     viper.GetString("SHORT_ID_SALT"),
+		},
+		Analytics: AnalyticsConfig{
+			BufferSize:    viper.GetInt("ANALYTICS_BUFFER_SIZE"),
+			BatchSize:     viper.GetInt("ANALYTICS_BATCH_SIZE"),
+			FlushInterval: viper.GetDuration("ANALYTICS_FLUSH_INTERVAL"),
+		},
+		RateLimit: RateLimitConfig{
+			Auth:         viper.GetString("RATE_LIMIT_AUTH"),
+			API:          viper.GetString("RATE_LIMIT_API"),
+			Redirect:     viper.GetString("RATE_LIMIT_REDIRECT"),
+			LinkPassword: viper.GetString("RATE_LIMIT_LINK_PASSWORD"),
+		},
+		Destination: DestinationConfig{
+			ResolveHosts:   viper.GetBool("DESTINATION_RESOLVE_HOSTS"),
+			ReputationFile: viper.GetString("DESTINATION_REPUTATION_FILE"),
+		},
+		Dedupe: DedupeConfig{
+			DropTrackingParams: viper.GetBool("DEDUPE_DROP_TRACKING_PARAMS"),
+			Scope:              viper.GetString("DEDUPE_SCOPE"),
+		},
+		PublicURL: PublicURLConfig{
+			BaseURL:        viper.GetString("PUBLIC_BASE_URL"),
+			TrustedProxies: splitList(viper.GetString("TRUSTED_PROXIES")),
+		},
+		GeoIPDatabase: viper.GetString("GEOIP_DATABASE"),
 	}
 
 	if AppConfig.MongoURI == "" || AppConfig.ServerPort == "" {
 		log.Println("Some variables were not declared! Check file docker-compose.yml or environment variables.")
 	}
 }
+
+// splitList splits a comma-separated value, dropping blank entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
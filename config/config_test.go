@@ -72,3 +72,27 @@ func TestLoadConfig_WithoutEnvVariables(t *testing.T) {
 		t.Errorf("Expected ServerPort to be empty, got '%s'", AppConfig.ServerPort)
 	}
 }
+
+func TestLoadConfig_JWT(t *testing.T) {
+	os.Setenv("JWT_ALGORITHM", "EdDSA")
+	os.Setenv("JWT_PRIVATE_KEY_FILE", "/keys/current.pem")
+	os.Setenv("JWT_VERIFICATION_KEYS", "2024-01=/keys/previous.pem, ,2023-12=/keys/old.pem")
+	defer func() {
+		os.Unsetenv("JWT_ALGORITHM")
+		os.Unsetenv("JWT_PRIVATE_KEY_FILE")
+		os.Unsetenv("JWT_VERIFICATION_KEYS")
+	}()
+
+	viper.Reset()
+	LoadConfig()
+
+	if AppConfig.JWT.Algorithm != "EdDSA" {
+		t.Errorf("Expected JWT.Algorithm to be 'EdDSA', got '%s'", AppConfig.JWT.Algorithm)
+	}
+	if AppConfig.JWT.KeyID != "default" {
+		t.Errorf("Expected JWT.KeyID to default to 'default', got '%s'", AppConfig.JWT.KeyID)
+	}
+	if len(AppConfig.JWT.VerificationKeys) != 2 || AppConfig.JWT.VerificationKeys[1] != "2023-12=/keys/old.pem" {
+		t.Errorf("Expected two verification keys, got %v", AppConfig.JWT.VerificationKeys)
+	}
+}
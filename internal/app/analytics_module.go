@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+
+	"github.com/guttosm/url-shortener/config"
+	"github.com/guttosm/url-shortener/internal/repository"
+	mongoRepo "github.com/guttosm/url-shortener/internal/repository/mongo"
+	"github.com/guttosm/url-shortener/internal/service"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type AnalyticsModule struct {
+	Repository repository.ClickRepository
+	Service    service.AnalyticsService
+}
+
+func InitAnalyticsModule(db *mongoDriver.Database) (*AnalyticsModule, error) {
+	clickCollection := db.Collection("clicks")
+	if err := mongoRepo.EnsureClickIndexes(context.Background(), clickCollection); err != nil {
+		return nil, err
+	}
+
+	clickRepo := mongoRepo.NewClickMongoRepository(clickCollection)
+	cfg := config.AppConfig.Analytics
+	analyticsService := service.NewAnalyticsService(
+		clickRepo,
+		service.AnalyticsConfig{
+			BufferSize:    cfg.BufferSize,
+			BatchSize:     cfg.BatchSize,
+			FlushInterval: cfg.FlushInterval,
+		},
+	)
+
+	return &AnalyticsModule{
+		Repository: clickRepo,
+		Service:    analyticsService,
+	}, nil
+}
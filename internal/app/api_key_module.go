@@ -0,0 +1,26 @@
+package app
+
+import (
+	"context"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+	mongoRepo "github.com/guttosm/url-shortener/internal/repository/mongo"
+	"github.com/guttosm/url-shortener/internal/service"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type APIKeyModule struct {
+	Service service.APIKeyService
+}
+
+// InitAPIKeyModule sets up API keys for machine clients, stored hashed in the api_keys collection.
+func InitAPIKeyModule(db *mongoDriver.Database, users repository.UserRepository) (*APIKeyModule, error) {
+	apiKeyCollection := db.Collection("api_keys")
+	if err := mongoRepo.EnsureAPIKeyIndexes(context.Background(), apiKeyCollection); err != nil {
+		return nil, err
+	}
+
+	return &APIKeyModule{
+		Service: service.NewAPIKeyService(mongoRepo.NewAPIKeyMongoRepository(apiKeyCollection), users),
+	}, nil
+}
@@ -5,10 +5,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/guttosm/url-shortener/config"
-	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/geoip"
 	apphttp "github.com/guttosm/url-shortener/internal/http"
-	"github.com/guttosm/url-shortener/internal/repository/mongo"
-	redisrepo "github.com/guttosm/url-shortener/internal/repository/redis"
+	redisRepo "github.com/guttosm/url-shortener/internal/repository/redis"
 	"github.com/guttosm/url-shortener/internal/service"
 	"github.com/redis/go-redis/v9"
 	mongodriver "go.mongodb.org/mongo-driver/mongo"
@@ -23,7 +22,6 @@ func InitializeApp() (*gin.Engine, func(), error) {
 		return nil, nil, err
 	}
 	db := mongoClient.Database(config.AppConfig.MongoDB)
-	urlMongoRepo := mongo.NewURLMongoRepository(db.Collection("urls"))
 
 	// --- Redis setup
 	redisClient := redis.NewClient(&redis.Options{
@@ -32,22 +30,113 @@ func InitializeApp() (*gin.Engine, func(), error) {
 	if err := redisClient.Ping(context.Background()).Err(); err != nil {
 		return nil, nil, err
 	}
-	urlRedisRepo := redisrepo.NewURLRedisRepository(redisClient)
 
-	// --- Services
-	urlService := service.NewURLService(urlMongoRepo, urlRedisRepo)
+	// --- Modules
+	destinationModule, err := InitDestinationModule(db, config.AppConfig.Destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	urlModule, err := InitURLModule(db, redisClient, destinationModule.Policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	userModule, err := InitUserModule(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	authModule, err := InitAuthModule(config.AppConfig.JWT, redisClient, userModule.Repository)
+	if err != nil {
+		return nil, nil, err
+	}
+	analyticsModule, err := InitAnalyticsModule(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKeyModule, err := InitAPIKeyModule(db, userModule.Repository)
+	if err != nil {
+		return nil, nil, err
+	}
+	transferModule := InitTransferModule(urlModule, destinationModule.Policy)
+	rateLimitModule, err := InitRateLimitModule(config.AppConfig.RateLimit, redisClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicURL, err := apphttp.NewPublicURL(config.AppConfig.PublicURL.BaseURL, config.AppConfig.PublicURL.TrustedProxies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handlerOpts := []apphttp.HandlerOption{
+		apphttp.WithAnalytics(analyticsModule.Service),
+		apphttp.WithAPIKeys(apiKeyModule.Service),
+		apphttp.WithTransfer(transferModule.Service),
+		apphttp.WithDomainRules(destinationModule.Rules),
+		apphttp.WithPreviews(service.NewPreviewService(urlModule.Service, userModule.Repository, analyticsModule.Repository)),
+		apphttp.WithQRCodes(service.NewQRCodeService(redisRepo.NewQRCodeCacheRedisRepository(redisClient), 0)),
+		apphttp.WithCustomDomains(urlModule.CustomDomains),
+		apphttp.WithPublicURL(publicURL),
+	}
+	if path := config.AppConfig.GeoIPDatabase; path != "" {
+		countries, err := geoip.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		handlerOpts = append(handlerOpts, apphttp.WithGeoIP(countries))
+	}
 
 	// --- HTTP Handler and Router
-	authCfg := config.AppConfig.Auth
-	handler := apphttp.NewHandler(urlService, authCfg)
-	validator := &auth.JWTValidator{}
-	router := apphttp.NewRouter(handler, validator)
+	handler := apphttp.NewHandler(urlModule.Service, userModule.Service, authModule.Service, handlerOpts...)
+	routerOpts := []apphttp.RouterOption{
+		apphttp.WithAPIKeyAuth(apiKeyModule.Service),
+		apphttp.WithRateLimits(rateLimitModule.Limiter, rateLimitModule.Limits),
+	}
+	if proxies := publicURL.TrustedProxies(); len(proxies) > 0 {
+		routerOpts = append(routerOpts, apphttp.WithTrustedProxies(proxies))
+	}
+	router := apphttp.NewRouter(handler, authModule.Service, routerOpts...)
 
 	// --- Cleanup resources
 	cleanup := func() {
+		analyticsModule.Service.Close()
 		_ = mongoClient.Disconnect(context.Background())
 		_ = redisClient.Close()
 	}
 
 	return router, cleanup, nil
 }
+
+// InitializeTransfer sets up only the dependencies of the import and export commands.
+func InitializeTransfer() (service.TransferService, func(), error) {
+	mongoClient, db, err := ConnectMongo(config.AppConfig.MongoURI, config.AppConfig.MongoDB)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: config.AppConfig.RedisURI,
+	})
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		_ = mongoClient.Disconnect(context.Background())
+		return nil, nil, err
+	}
+
+	destinationModule, err := InitDestinationModule(db, config.AppConfig.Destination)
+	if err != nil {
+		_ = mongoClient.Disconnect(context.Background())
+		_ = redisClient.Close()
+		return nil, nil, err
+	}
+	urlModule, err := InitURLModule(db, redisClient, destinationModule.Policy)
+	if err != nil {
+		_ = mongoClient.Disconnect(context.Background())
+		_ = redisClient.Close()
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		_ = mongoClient.Disconnect(context.Background())
+		_ = redisClient.Close()
+	}
+	return InitTransferModule(urlModule, destinationModule.Policy).Service, cleanup, nil
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guttosm/url-shortener/config"
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/repository"
+	redisRepo "github.com/guttosm/url-shortener/internal/repository/redis"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/redis/go-redis/v9"
+)
+
+type AuthModule struct {
+	Service service.TokenService
+}
+
+// InitAuthModule loads the token signing keys configured through the JWT_* variables
+// and keeps the list of revoked tokens in Redis.
+func InitAuthModule(cfg config.JWTConfig, redisClient *redis.Client, users repository.UserRepository) (*AuthModule, error) {
+	keys, err := loadKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenService := service.NewTokenService(
+		auth.NewJWTIssuer(keys),
+		auth.NewJWTValidator(keys),
+		redisRepo.NewRevokedTokenRedisRepository(redisClient),
+		users,
+		service.TokenConfig{AccessTTL: cfg.AccessTokenTTL, RefreshTTL: cfg.RefreshTokenTTL},
+	)
+
+	return &AuthModule{
+		Service: tokenService,
+	}, nil
+}
+
+// loadKeySet builds the key set from the signing key and the "kid=path" verification keys.
+func loadKeySet(cfg config.JWTConfig) (*auth.KeySet, error) {
+	var signing *auth.Key
+	var err error
+	switch {
+	case cfg.PrivateKeyFile != "":
+		signing, err = auth.LoadKeyFile(cfg.KeyID, cfg.PrivateKeyFile)
+	case cfg.Secret != "":
+		signing, err = auth.NewHMACKey(cfg.KeyID, []byte(cfg.Secret))
+	default:
+		return nil, fmt.Errorf("JWT_SECRET or JWT_PRIVATE_KEY_FILE must be set")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading JWT signing key: %w", err)
+	}
+	if signing.Algorithm != cfg.Algorithm {
+		return nil, fmt.Errorf("JWT signing key %q is a %s key, but JWT_ALGORITHM is %q", signing.ID, signing.Algorithm, cfg.Algorithm)
+	}
+
+	verification := make([]*auth.Key, 0, len(cfg.VerificationKeys))
+	for _, entry := range cfg.VerificationKeys {
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT verification key %q: expected kid=path", entry)
+		}
+		key, err := auth.LoadKeyFile(kid, path)
+		if err != nil {
+			return nil, fmt.Errorf("loading JWT verification key %q: %w", kid, err)
+		}
+		verification = append(verification, key)
+	}
+
+	return auth.NewKeySet(signing, verification...)
+}
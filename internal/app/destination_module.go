@@ -0,0 +1,41 @@
+package app
+
+import (
+	"net"
+
+	"github.com/guttosm/url-shortener/config"
+	mongoRepo "github.com/guttosm/url-shortener/internal/repository/mongo"
+	"github.com/guttosm/url-shortener/internal/service"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type DestinationModule struct {
+	Rules  service.DomainRuleService
+	Policy service.DestinationPolicy
+}
+
+// InitDestinationModule sets up the screening of destination URLs: blocked schemes, private addresses,
+// the domain rules stored in the domain_rules collection and, if configured, a reputation list file.
+func InitDestinationModule(db *mongoDriver.Database, cfg config.DestinationConfig) (*DestinationModule, error) {
+	rules := service.NewDomainRuleService(mongoRepo.NewDomainRuleMongoRepository(db.Collection("domain_rules")), 0)
+
+	var resolver service.HostResolver
+	if cfg.ResolveHosts {
+		resolver = net.DefaultResolver
+	}
+	policy := service.DestinationPolicyChain{
+		service.SchemePolicy,
+		service.NewPrivateAddressPolicy(resolver),
+		rules,
+	}
+
+	if cfg.ReputationFile != "" {
+		checker, err := service.NewFileReputationChecker(cfg.ReputationFile)
+		if err != nil {
+			return nil, err
+		}
+		policy = append(policy, service.NewReputationPolicy(checker))
+	}
+
+	return &DestinationModule{Rules: rules, Policy: policy}, nil
+}
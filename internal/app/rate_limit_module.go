@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/guttosm/url-shortener/config"
+	apphttp "github.com/guttosm/url-shortener/internal/http"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+type RateLimitModule struct {
+	Limiter ratelimit.Limiter
+	Limits  apphttp.RateLimits
+}
+
+// InitRateLimitModule sets up rate limiting with counters in Redis, falling back to
+// in-memory counters while Redis is unavailable.
+func InitRateLimitModule(cfg config.RateLimitConfig, redisClient *redis.Client) (*RateLimitModule, error) {
+	var limits apphttp.RateLimits
+	for _, target := range []struct {
+		name  string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_AUTH", cfg.Auth, &limits.Auth},
+		{"RATE_LIMIT_API", cfg.API, &limits.API},
+		{"RATE_LIMIT_REDIRECT", cfg.Redirect, &limits.Redirect},
+	} {
+		limit, err := ratelimit.ParseLimit(target.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.name, err)
+		}
+		*target.limit = limit
+	}
+
+	return &RateLimitModule{
+		Limiter: ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(redisClient), ratelimit.NewMemoryLimiter(), 0),
+		Limits:  limits,
+	}, nil
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"github.com/guttosm/url-shortener/config"
+	"github.com/guttosm/url-shortener/internal/service"
+)
+
+type TransferModule struct {
+	Service service.TransferService
+}
+
+// InitTransferModule sets up bulk import and export of the URLs stored by the URL module.
+// Imported destinations are screened with the same policy as the URL module; a nil policy accepts every destination.
+func InitTransferModule(urlModule *URLModule, policy service.DestinationPolicy) *TransferModule {
+	return &TransferModule{
+		Service: service.NewTransferService(urlModule.Repository, urlModule.Cache,
+			service.WithImportCanonicalizer(newCanonicalizer(config.AppConfig.Dedupe)),
+			service.WithImportDestinationPolicy(policy),
+		),
+	}
+}
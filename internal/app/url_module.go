@@ -1,6 +1,12 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/guttosm/url-shortener/config"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
 	"github.com/guttosm/url-shortener/internal/repository"
 	mongoRepo "github.com/guttosm/url-shortener/internal/repository/mongo"
 	redisRepo "github.com/guttosm/url-shortener/internal/repository/redis"
@@ -10,18 +16,79 @@ import (
 )
 
 type URLModule struct {
-	Repository repository.URLRepository
-	Service    service.URLService
+	Repository    repository.URLRepository
+	Cache         repository.URLCacheRepository
+	Service       service.URLService
+	CustomDomains service.CustomDomainService
 }
 
-func InitURLModule(db *mongoDriver.Database, redisClient *redis.Client) *URLModule {
+// InitURLModule sets up URL shortening and the custom domains stored in the custom_domains collection,
+// verified with the system DNS resolver. A nil policy accepts every destination.
+func InitURLModule(db *mongoDriver.Database, redisClient *redis.Client, policy service.DestinationPolicy) (*URLModule, error) {
+	idGen, err := newIDGenerator(config.AppConfig.ShortID, redisRepo.NewCounterRedisRepository(redisClient))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.ValidateDedupe(config.AppConfig.Dedupe.Scope); err != nil {
+		return nil, fmt.Errorf("DEDUPE_SCOPE: %w", err)
+	}
+
 	urlCollection := db.Collection("urls")
-	urlRepo := mongoRepo.NewURLMongoRepository(urlCollection)
+	if err := mongoRepo.EnsureURLIndexes(context.Background(), urlCollection); err != nil {
+		return nil, err
+	}
+
+	domainCollection := db.Collection("custom_domains")
+	if err := mongoRepo.EnsureCustomDomainIndexes(context.Background(), domainCollection); err != nil {
+		return nil, err
+	}
+
+	urlRepo := mongoRepo.NewURLMongoRepository(urlCollection, idGen.Generate)
 	urlCacheRepo := redisRepo.NewURLRedisRepository(redisClient)
-	urlService := service.NewURLService(urlRepo, urlCacheRepo)
+	customDomains := service.NewCustomDomainService(
+		mongoRepo.NewCustomDomainMongoRepository(domainCollection), urlRepo, net.DefaultResolver, 0)
+	opts := []service.URLServiceOption{
+		service.WithIDGenerator(idGen),
+		service.WithDestinationPolicy(policy),
+		service.WithCanonicalizer(newCanonicalizer(config.AppConfig.Dedupe)),
+		service.WithDedupe(config.AppConfig.Dedupe.Scope),
+		service.WithCustomDomains(customDomains),
+	}
+
+	passwordLimit, err := ratelimit.ParseLimit(config.AppConfig.RateLimit.LinkPassword)
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_LINK_PASSWORD: %w", err)
+	}
+	if passwordLimit.Enabled() {
+		opts = append(opts, service.WithPasswordThrottle(
+			redisRepo.NewPasswordAttemptRedisRepository(redisClient), passwordLimit.Requests, passwordLimit.Window))
+	}
+	urlService := service.NewURLService(urlRepo, urlCacheRepo, opts...)
 
 	return &URLModule{
-		Repository: urlRepo,
-		Service:    urlService,
+		Repository:    urlRepo,
+		Cache:         urlCacheRepo,
+		Service:       urlService,
+		CustomDomains: customDomains,
+	}, nil
+}
+
+// newIDGenerator builds the short ID generator selected by the configuration.
+func newIDGenerator(cfg config.ShortIDConfig, counter repository.CounterRepository) (service.IDGenerator, error) {
+	switch cfg.Strategy {
+	case "", "random":
+		return service.NewRandomIDGenerator(cfg.Length)
+	case "counter":
+		return service.NewCounterIDGenerator(counter, cfg.Length)
+	case "hashids":
+		return service.NewHashidsIDGenerator(counter, cfg.Salt, cfg.Length)
+	default:
+		return nil, fmt.Errorf("unknown short ID strategy %q", cfg.Strategy)
 	}
 }
+
+// newCanonicalizer builds the URL canonicalizer selected by the configuration.
+func newCanonicalizer(cfg config.DedupeConfig) service.Canonicalizer {
+	return service.Canonicalizer{DropTrackingParams: cfg.DropTrackingParams}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guttosm/url-shortener/config"
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/repository"
+	mongoRepo "github.com/guttosm/url-shortener/internal/repository/mongo"
+	"github.com/guttosm/url-shortener/internal/service"
+	mongoDriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserModule struct {
+	Repository repository.UserRepository
+	Service    service.UserService
+}
+
+// InitUserModule sets up user accounts and creates the bootstrap admin account from
+// AUTH_USERNAME/AUTH_PASSWORD (with AUTH_USER_ID as its ID) when configured. It fails when the
+// username already belongs to an account with another ID.
+func InitUserModule(db *mongoDriver.Database) (*UserModule, error) {
+	userCollection := db.Collection("users")
+	if err := mongoRepo.EnsureUserIndexes(context.Background(), userCollection); err != nil {
+		return nil, err
+	}
+
+	userRepo := mongoRepo.NewUserMongoRepository(userCollection)
+	userService := service.NewUserService(userRepo)
+
+	authCfg := config.AppConfig.Auth
+	if authCfg.Username != "" && authCfg.Password != "" {
+		if err := userService.EnsureUser(context.Background(), authCfg.UserID, authCfg.Username, authCfg.Password, auth.RoleAdmin); err != nil {
+			return nil, fmt.Errorf("AUTH_USERNAME: %w", err)
+		}
+	}
+
+	return &UserModule{
+		Repository: userRepo,
+		Service:    userService,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+//
+// Fields:
+// - KeyType (string): "RSA" for RS256 keys, "OKP" for EdDSA keys.
+// - KeyID (string): The key ID matching the "kid" header of tokens.
+// - Use (string): Always "sig".
+// - Algorithm (string): The signing algorithm.
+// - N (string): The RSA modulus.
+// - E (string): The RSA public exponent.
+// - Curve (string): The curve of an OKP key ("Ed25519").
+// - X (string): The OKP public key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, published so other services can verify issued tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set.
+// HS256 keys are shared secrets and are never published.
+//
+// Returns:
+// - JWKS: The public keys, with the signing key first.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range s.order {
+		if jwk, ok := s.keys[id].jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// jwk converts the public part of the key to a JWK.
+func (k *Key) jwk() (JWK, bool) {
+	enc := base64.RawURLEncoding
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			N:         enc.EncodeToString(pub.N.Bytes()),
+			E:         enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     k.ID,
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			Curve:     "Ed25519",
+			X:         enc.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
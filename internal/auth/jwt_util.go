@@ -1,33 +1,130 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// JWTSecret is the secret key used to sign the JWT tokens.
-var JWTSecret = []byte("your-secret-key") // TODO: replace with secure env-based key in production
+// Token types, carried in the "typ" claim so refresh tokens cannot be used as access tokens.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrInvalidAPIKey is returned by an APIKeyAuthenticator when an API key is unknown or revoked.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// IssuedToken is a signed token together with the claims needed to revoke it.
+//
+// Fields:
+// - Value (string): The signed JWT.
+// - ID (string): The unique token ID ("jti" claim).
+// - ExpiresAt (time.Time): The expiry of the token ("exp" claim).
+type IssuedToken struct {
+	Value     string
+	ID        string
+	ExpiresAt time.Time
+}
 
-var ErrInvalidToken = errors.New("invalid or expired token")
+// TokenClaims describes who a token is issued to.
+//
+// Fields:
+// - UserID (string): The ID of the user ("user_id" claim).
+// - Type (string): The token type: TokenTypeAccess or TokenTypeRefresh ("typ" claim).
+// - Role (string): The role of the user ("role" claim), omitted if empty.
+// - Scopes ([]string): The scopes granted by the token ("scopes" claim), omitted if empty.
+type TokenClaims struct {
+	UserID string
+	Type   string
+	Role   string
+	Scopes []string
+}
+
+// TokenIssuer defines the interface for issuing JWT tokens and publishing the keys that verify them.
+type TokenIssuer interface {
+	IssueToken(claims TokenClaims, ttl time.Duration) (*IssuedToken, error)
+	JWKS() JWKS
+}
+
+// JWTIssuer is the concrete implementation of TokenIssuer, signing tokens with the signing key of a KeySet.
+type JWTIssuer struct {
+	keys *KeySet
+}
 
-// GenerateToken generates a JWT token for a given user ID.
+// NewJWTIssuer creates a JWTIssuer.
 //
 // Parameters:
-// - userID (string): The ID of the user.
+// - keys (*KeySet): The keys used to sign tokens.
 //
 // Returns:
-// - string: The signed JWT token.
+// - *JWTIssuer: The token issuer.
+func NewJWTIssuer(keys *KeySet) *JWTIssuer {
+	return &JWTIssuer{keys: keys}
+}
+
+// IssueToken generates a JWT token for a given user.
+// The token carries the ID of the signing key in its "kid" header and a random "jti" claim.
+//
+// Parameters:
+// - claims (TokenClaims): The user, type, role and scopes of the token.
+// - ttl (time.Duration): The lifetime of the token.
+//
+// Returns:
+// - *IssuedToken: The signed token.
 // - error: An error if token generation fails.
-func GenerateToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+func (i *JWTIssuer) IssueToken(claims TokenClaims, ttl time.Duration) (*IssuedToken, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	mapClaims := jwt.MapClaims{
+		"user_id": claims.UserID,
+		"typ":     claims.Type,
+		"jti":     id,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+	}
+	if claims.Role != "" {
+		mapClaims["role"] = claims.Role
 	}
+	if len(claims.Scopes) > 0 {
+		mapClaims["scopes"] = claims.Scopes
+	}
+
+	key := i.keys.SigningKey()
+	token := jwt.NewWithClaims(key.method(), mapClaims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedToken{Value: signed, ID: id, ExpiresAt: time.Unix(expiresAt.Unix(), 0)}, nil
+}
+
+// JWKS returns the public keys that verify issued tokens.
+func (i *JWTIssuer) JWKS() JWKS {
+	return i.keys.JWKS()
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTSecret)
+// newTokenID returns a random 128-bit token ID.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // TokenValidator defines the interface for validating JWT tokens.
@@ -35,11 +132,42 @@ type TokenValidator interface {
 	ValidateToken(tokenString string) (map[string]interface{}, error)
 }
 
-// JWTValidator is the concrete implementation of TokenValidator using HMAC signing.
-type JWTValidator struct{}
+// RevocationChecker is an extension of TokenValidator for validators that can also tell
+// whether a token was revoked before it expired (e.g. on logout).
+// AuthMiddleware rejects revoked tokens when its validator implements it.
+type RevocationChecker interface {
+	TokenValidator
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// APIKeyAuthenticator authenticates the API keys machine clients send instead of a bearer token.
+// AuthenticateAPIKey returns claims shaped like token claims, including "user_id".
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (map[string]interface{}, error)
+}
+
+// JWTValidator is the concrete implementation of TokenValidator, accepting tokens signed by any key of a KeySet.
+type JWTValidator struct {
+	keys *KeySet
+}
+
+// NewJWTValidator creates a JWTValidator.
+//
+// Parameters:
+// - keys (*KeySet): The keys accepted when verifying tokens.
+//
+// Returns:
+// - *JWTValidator: The token validator.
+func NewJWTValidator(keys *KeySet) *JWTValidator {
+	return &JWTValidator{keys: keys}
+}
 
 // ValidateToken validates a JWT token and returns its claims.
 //
+// Behavior:
+// - The verification key is selected by the "kid" header of the token.
+// - The token algorithm must match the algorithm of that key, so a public key can never be used as an HMAC secret.
+//
 // Parameters:
 // - tokenString (string): The JWT token string.
 //
@@ -48,11 +176,15 @@ type JWTValidator struct{}
 // - error: An error if validation fails.
 func (j *JWTValidator) ValidateToken(tokenString string) (map[string]interface{}, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Ensure token uses HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Lookup(kid)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return JWTSecret, nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Supported token signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	// minHMACSecretBytes is the minimum length of an HS256 secret (the size of the SHA-256 output).
+	minHMACSecretBytes = 32
+	// minRSAKeyBits is the minimum size of an RS256 key.
+	minRSAKeyBits = 2048
+)
+
+var (
+	ErrInvalidKey     = errors.New("invalid signing key")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrDuplicateKeyID = errors.New("duplicate key ID")
+)
+
+// Key is a named key used to sign or verify tokens.
+//
+// Fields:
+// - ID (string): The key ID, published in the "kid" header of tokens signed with it.
+// - Algorithm (string): The signing algorithm: AlgHS256, AlgRS256 or AlgEdDSA.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds the secret or private key needed to sign tokens.
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// method returns the JWT signing method matching the key algorithm.
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// NewHMACKey creates an HS256 key from a shared secret.
+//
+// Parameters:
+// - id (string): The key ID.
+// - secret ([]byte): The shared secret, at least 32 bytes long.
+//
+// Returns:
+// - *Key: The key, usable for both signing and verification.
+// - error: ErrInvalidKey if the ID is empty or the secret is too short.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: key ID is required", ErrInvalidKey)
+	}
+	if len(secret) < minHMACSecretBytes {
+		return nil, fmt.Errorf("%w: HS256 secret must be at least %d bytes", ErrInvalidKey, minHMACSecretBytes)
+	}
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// ParsePEMKey creates an RS256 or EdDSA key from a PEM-encoded RSA or Ed25519 key.
+//
+// Behavior:
+// - Private keys (PKCS#1 or PKCS#8) can sign and verify tokens.
+// - Public keys (PKIX or PKCS#1) can only verify tokens, e.g. keys retired during a rotation.
+// - The algorithm is derived from the key type.
+//
+// Parameters:
+// - id (string): The key ID.
+// - data ([]byte): The PEM-encoded key.
+//
+// Returns:
+// - *Key: The parsed key.
+// - error: ErrInvalidKey if the data is not a supported key.
+func ParsePEMKey(id string, data []byte) (*Key, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: key ID is required", ErrInvalidKey)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM block %q", ErrInvalidKey, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA keys must be at least %d bits", ErrInvalidKey, minRSAKeyBits)
+		}
+		return &Key{ID: id, Algorithm: AlgRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA keys must be at least %d bits", ErrInvalidKey, minRSAKeyBits)
+		}
+		return &Key{ID: id, Algorithm: AlgRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: AlgEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Algorithm: AlgEdDSA, verifyKey: k}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, parsed)
+	}
+}
+
+// LoadKeyFile reads a key from a file.
+// Files containing a PEM block are parsed with ParsePEMKey; any other content is used as an HS256 secret.
+//
+// Parameters:
+// - id (string): The key ID.
+// - path (string): The path of the key file.
+//
+// Returns:
+// - *Key: The loaded key.
+// - error: An error if the file cannot be read or does not hold a valid key.
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte("-----BEGIN ")) {
+		return ParsePEMKey(id, data)
+	}
+	return NewHMACKey(id, bytes.TrimSpace(data))
+}
+
+// KeySet holds the key used to sign new tokens and every key accepted when verifying them.
+// Keeping the previous keys in the set lets tokens issued before a rotation stay valid until they expire.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string
+}
+
+// NewKeySet creates a key set.
+//
+// Parameters:
+// - signing (*Key): The key used to sign new tokens; it must hold a secret or private key.
+// - verification (...*Key): Additional keys accepted when verifying tokens.
+//
+// Returns:
+// - *KeySet: The key set.
+// - error: ErrInvalidKey if the signing key cannot sign, or ErrDuplicateKeyID if two keys share an ID.
+func NewKeySet(signing *Key, verification ...*Key) (*KeySet, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, fmt.Errorf("%w: the signing key must hold a secret or private key", ErrInvalidKey)
+	}
+
+	set := &KeySet{signing: signing, keys: make(map[string]*Key, len(verification)+1)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKeyID, key.ID)
+		}
+		set.keys[key.ID] = key
+		set.order = append(set.order, key.ID)
+	}
+	return set, nil
+}
+
+// SigningKey returns the key used to sign new tokens.
+func (s *KeySet) SigningKey() *Key {
+	return s.signing
+}
+
+// Lookup returns the key with the given ID.
+//
+// Parameters:
+// - id (string): The key ID.
+//
+// Returns:
+// - *Key: The key, or nil if the set holds no key with that ID.
+// - bool: Whether the key was found.
+func (s *KeySet) Lookup(id string) (*Key, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
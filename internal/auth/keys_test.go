@@ -0,0 +1,180 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func rsaPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ed25519PEM(t *testing.T) (private, public []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func TestJWT_RoundTripPerAlgorithm(t *testing.T) {
+	hmacKey, err := auth.NewHMACKey("hmac-1", []byte(testSecret))
+	require.NoError(t, err)
+	rsaKey, err := auth.ParsePEMKey("rsa-1", rsaPEM(t))
+	require.NoError(t, err)
+	edPrivate, _ := ed25519PEM(t)
+	edKey, err := auth.ParsePEMKey("ed-1", edPrivate)
+	require.NoError(t, err)
+
+	for _, key := range []*auth.Key{hmacKey, rsaKey, edKey} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			keys, err := auth.NewKeySet(key)
+			require.NoError(t, err)
+
+			token, err := auth.NewJWTIssuer(keys).IssueToken(auth.TokenClaims{UserID: "user-1", Type: auth.TokenTypeAccess}, time.Hour)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token.Value, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+			assert.Equal(t, key.Algorithm, parsed.Header["alg"])
+
+			claims, err := auth.NewJWTValidator(keys).ValidateToken(token.Value)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims["user_id"])
+			assert.Equal(t, auth.TokenTypeAccess, claims["typ"])
+			assert.Equal(t, token.ID, claims["jti"])
+		})
+	}
+}
+
+func TestJWT_KeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := ed25519PEM(t)
+	newPrivate, _ := ed25519PEM(t)
+
+	oldKey, err := auth.ParsePEMKey("2024-01", oldPrivate)
+	require.NoError(t, err)
+	oldKeys, err := auth.NewKeySet(oldKey)
+	require.NoError(t, err)
+	oldToken, err := auth.NewJWTIssuer(oldKeys).IssueToken(auth.TokenClaims{UserID: "user-1", Type: auth.TokenTypeAccess}, time.Hour)
+	require.NoError(t, err)
+
+	// After the rotation, the old key is only kept (as a public key) for verification.
+	newKey, err := auth.ParsePEMKey("2024-02", newPrivate)
+	require.NoError(t, err)
+	retiredKey, err := auth.ParsePEMKey("2024-01", oldPublic)
+	require.NoError(t, err)
+	assert.False(t, retiredKey.CanSign())
+
+	keys, err := auth.NewKeySet(newKey, retiredKey)
+	require.NoError(t, err)
+	validator := auth.NewJWTValidator(keys)
+
+	_, err = validator.ValidateToken(oldToken.Value)
+	assert.NoError(t, err)
+
+	newToken, err := auth.NewJWTIssuer(keys).IssueToken(auth.TokenClaims{UserID: "user-1", Type: auth.TokenTypeAccess}, time.Hour)
+	require.NoError(t, err)
+	_, err = validator.ValidateToken(newToken.Value)
+	assert.NoError(t, err)
+
+	// Once the old key is removed, its tokens are rejected.
+	currentOnly, err := auth.NewKeySet(newKey)
+	require.NoError(t, err)
+	_, err = auth.NewJWTValidator(currentOnly).ValidateToken(oldToken.Value)
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+}
+
+func TestJWT_RejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, err := auth.ParsePEMKey("shared", rsaPEM(t))
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(rsaKey)
+	require.NoError(t, err)
+
+	// A token claiming HS256 under the RSA key ID must not be verified with the key bytes as a secret.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "attacker"})
+	forged.Header["kid"] = "shared"
+	tokenString, err := forged.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	_, err = auth.NewJWTValidator(keys).ValidateToken(tokenString)
+	assert.Error(t, err)
+}
+
+func TestNewKeySet_Validation(t *testing.T) {
+	_, edPublic := ed25519PEM(t)
+	publicOnly, err := auth.ParsePEMKey("pub", edPublic)
+	require.NoError(t, err)
+
+	_, err = auth.NewKeySet(publicOnly)
+	assert.ErrorIs(t, err, auth.ErrInvalidKey)
+
+	hmacKey, err := auth.NewHMACKey("dup", []byte(testSecret))
+	require.NoError(t, err)
+	otherKey, err := auth.NewHMACKey("dup", []byte(testSecret+"x"))
+	require.NoError(t, err)
+	_, err = auth.NewKeySet(hmacKey, otherKey)
+	assert.ErrorIs(t, err, auth.ErrDuplicateKeyID)
+
+	_, err = auth.NewHMACKey("short", []byte("too-short"))
+	assert.ErrorIs(t, err, auth.ErrInvalidKey)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	edPrivate, _ := ed25519PEM(t)
+	edKey, err := auth.ParsePEMKey("ed-1", edPrivate)
+	require.NoError(t, err)
+	rsaKey, err := auth.ParsePEMKey("rsa-1", rsaPEM(t))
+	require.NoError(t, err)
+	hmacKey, err := auth.NewHMACKey("hmac-1", []byte(testSecret))
+	require.NoError(t, err)
+
+	keys, err := auth.NewKeySet(edKey, rsaKey, hmacKey)
+	require.NoError(t, err)
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 2, "HMAC secrets must never be published")
+	assert.Equal(t, auth.JWK{KeyType: "OKP", KeyID: "ed-1", Use: "sig", Algorithm: auth.AlgEdDSA, Curve: "Ed25519", X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.NotEmpty(t, jwks.Keys[0].X)
+	assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	assert.NotEmpty(t, jwks.Keys[1].N)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	secretPath := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secretPath, []byte(testSecret+"\n"), 0o600))
+	key, err := auth.LoadKeyFile("hmac-1", secretPath)
+	require.NoError(t, err)
+	assert.Equal(t, auth.AlgHS256, key.Algorithm)
+
+	pemPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(pemPath, rsaPEM(t), 0o600))
+	key, err = auth.LoadKeyFile("rsa-1", pemPath)
+	require.NoError(t, err)
+	assert.Equal(t, auth.AlgRS256, key.Algorithm)
+	assert.True(t, key.CanSign())
+}
@@ -0,0 +1,74 @@
+package auth
+
+// Scopes grant access to groups of endpoints. They are carried in the "scopes" claim of
+// access tokens and stored on API keys.
+const (
+	ScopeLinksWrite = "links:write"
+	ScopeLinksRead  = "links:read"
+	ScopeStatsRead  = "stats:read"
+	// ScopeAdmin grants access to the admin endpoints and implies every other scope.
+	ScopeAdmin = "admin"
+)
+
+// Roles are assigned to users and map to a fixed set of scopes.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// DefaultRole is the role of newly registered users and of users stored before roles existed.
+const DefaultRole = RoleEditor
+
+// roleScopes maps each role to the scopes it grants.
+var roleScopes = map[string][]string{
+	RoleAdmin:  {ScopeAdmin, ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead},
+	RoleEditor: {ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead},
+	RoleViewer: {ScopeLinksRead, ScopeStatsRead},
+}
+
+// IsValidRole reports whether role is a known role.
+func IsValidRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// ScopesForRole returns the scopes granted by a role. An empty role is treated as DefaultRole.
+//
+// Parameters:
+// - role (string): The role.
+//
+// Returns:
+// - []string: A copy of the scopes granted by the role, or nil for an unknown role.
+func ScopesForRole(role string) []string {
+	if role == "" {
+		role = DefaultRole
+	}
+	return append([]string(nil), roleScopes[role]...)
+}
+
+// IsValidScope reports whether scope is a known scope.
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeLinksWrite, ScopeLinksRead, ScopeStatsRead, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// HasScope reports whether the granted scopes include required. ScopeAdmin satisfies any scope.
+//
+// Parameters:
+// - granted ([]string): The scopes of the caller.
+// - required (string): The scope needed.
+//
+// Returns:
+// - bool: True if access is granted.
+func HasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required || scope == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
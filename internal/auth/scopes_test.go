@@ -0,0 +1,27 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopesForRole(t *testing.T) {
+	assert.ElementsMatch(t, []string{auth.ScopeLinksRead, auth.ScopeStatsRead}, auth.ScopesForRole(auth.RoleViewer))
+	assert.ElementsMatch(t, auth.ScopesForRole(auth.DefaultRole), auth.ScopesForRole(""))
+	assert.Contains(t, auth.ScopesForRole(auth.RoleAdmin), auth.ScopeAdmin)
+	assert.Nil(t, auth.ScopesForRole("superuser"))
+
+	// Callers may modify the returned slice without affecting the role.
+	scopes := auth.ScopesForRole(auth.RoleEditor)
+	scopes[0] = auth.ScopeAdmin
+	assert.NotContains(t, auth.ScopesForRole(auth.RoleEditor), auth.ScopeAdmin)
+}
+
+func TestHasScope(t *testing.T) {
+	assert.True(t, auth.HasScope([]string{auth.ScopeLinksRead}, auth.ScopeLinksRead))
+	assert.False(t, auth.HasScope([]string{auth.ScopeLinksRead}, auth.ScopeLinksWrite))
+	assert.True(t, auth.HasScope([]string{auth.ScopeAdmin}, auth.ScopeLinksWrite))
+	assert.False(t, auth.HasScope(nil, auth.ScopeLinksRead))
+}
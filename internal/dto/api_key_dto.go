@@ -0,0 +1,42 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest represents the request body for creating an API key.
+//
+// Fields:
+// - Name (string): A label for the key, e.g. "ci" (required).
+// - Scopes ([]string): The scopes granted to the key (optional).
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse represents an API key in API responses. The key itself is never included.
+//
+// Fields:
+// - ID (string): The unique identifier of the key.
+// - Name (string): The label of the key.
+// - Prefix (string): The first characters of the key.
+// - Scopes ([]string): The scopes granted to the key.
+// - CreatedAt (time.Time): The timestamp when the key was created.
+// - LastUsedAt (*time.Time): The last time the key was used, if ever.
+// - RevokedAt (*time.Time): The time the key was revoked, if it was.
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreatedAPIKeyResponse represents a newly created API key, the only response that includes the key.
+//
+// Fields:
+// - Key (string): The API key, sent as "Authorization: ApiKey <key>" or in the X-API-Key header.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
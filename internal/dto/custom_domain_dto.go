@@ -0,0 +1,39 @@
+package dto
+
+import "time"
+
+// CustomDomainRequest represents the request body for registering a custom domain.
+//
+// Fields:
+// - Domain (string): The host name, e.g. "go.acme.com" (required).
+type CustomDomainRequest struct {
+	Domain string `json:"domain" binding:"required,max=253"`
+}
+
+// CustomDomainResponse represents a custom domain in API responses.
+//
+// Fields:
+// - Domain (string): The host name of the domain.
+// - Verified (bool): Whether the domain serves short URLs.
+// - VerificationRecord (DomainVerificationRecord): The DNS TXT record to publish to verify the domain.
+// - CreatedAt (time.Time): The timestamp when the domain was registered.
+// - VerifiedAt (*time.Time): The time the domain was verified, if it was.
+type CustomDomainResponse struct {
+	Domain             string                   `json:"domain"`
+	Verified           bool                     `json:"verified"`
+	VerificationRecord DomainVerificationRecord `json:"verification_record"`
+	CreatedAt          time.Time                `json:"created_at"`
+	VerifiedAt         *time.Time               `json:"verified_at,omitempty"`
+}
+
+// DomainVerificationRecord represents the DNS TXT record proving control of a custom domain.
+//
+// Fields:
+// - Type (string): Always "TXT".
+// - Name (string): The DNS name of the record, e.g. "_url-shortener-challenge.go.acme.com".
+// - Value (string): The value of the record.
+type DomainVerificationRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// DomainRuleRequest represents the request body for allowing or denying a domain.
+//
+// Fields:
+// - Action (string): "allow" or "deny" (required).
+// - Reason (string): Why the rule is added (optional).
+type DomainRuleRequest struct {
+	Action string `json:"action" binding:"required,oneof=allow deny"`
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// DomainRuleResponse represents a domain allow or deny rule in API responses.
+//
+// Fields:
+// - Domain (string): The domain the rule applies to, along with its subdomains.
+// - Action (string): "allow" or "deny".
+// - Reason (string): Why the rule was added.
+// - CreatedBy (string): The ID of the admin who added the rule.
+// - CreatedAt (time.Time): The timestamp when the rule was added or last changed.
+type DomainRuleResponse struct {
+	Domain    string    `json:"domain"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
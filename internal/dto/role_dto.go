@@ -0,0 +1,9 @@
+package dto
+
+// SetRoleRequest represents the request body for assigning a role to a user.
+//
+// Fields:
+// - Role (string): The role to assign: "admin", "editor" or "viewer" (required).
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin editor viewer"`
+}
@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// StatsResponse represents the response body for the link statistics endpoint.
+//
+// Fields:
+// - ShortID (string): The shortened ID the statistics belong to.
+// - Bucket (string): The time bucket unit of the timeline, "hour" or "day".
+// - From (time.Time): The inclusive start of the period.
+// - To (time.Time): The exclusive end of the period.
+// - TotalClicks (int64): The number of clicks in the period.
+// - UniqueVisitors (int64): The number of distinct visitors in the period.
+// - Timeline ([]TimeBucketCount): Click counts per time bucket, oldest first.
+// - Referrers ([]KeyCount): Click counts per referrer, most frequent first.
+// - UserAgents ([]KeyCount): Click counts per user agent family, most frequent first.
+// - Countries ([]KeyCount): Click counts per country, most frequent first.
+// - Campaigns ([]KeyCount): Click counts per utm_campaign, most frequent first. Clicks without a campaign have an empty key.
+// - Variants ([]KeyCount): Click counts per A/B variant, most frequent first. Clicks without a variant have an empty key.
+type StatsResponse struct {
+	ShortID        string            `json:"short_id"`
+	Bucket         string            `json:"bucket"`
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	TotalClicks    int64             `json:"total_clicks"`
+	UniqueVisitors int64             `json:"unique_visitors"`
+	Timeline       []TimeBucketCount `json:"timeline"`
+	Referrers      []KeyCount        `json:"referrers"`
+	UserAgents     []KeyCount        `json:"user_agents"`
+	Countries      []KeyCount        `json:"countries"`
+	Campaigns      []KeyCount        `json:"campaigns"`
+	Variants       []KeyCount        `json:"variants"`
+}
+
+// TimeBucketCount represents the number of clicks in a time bucket.
+//
+// Fields:
+// - Time (time.Time): The start of the bucket.
+// - Count (int64): The number of clicks in the bucket.
+type TimeBucketCount struct {
+	Time  time.Time `json:"time"`
+	Count int64     `json:"count"`
+}
+
+// KeyCount represents the number of clicks sharing a value.
+//
+// Fields:
+// - Key (string): The grouped value.
+// - Count (int64): The number of clicks with that value.
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// NewStatsResponse builds a StatsResponse from aggregated click statistics.
+//
+// Parameters:
+// - shortID (string): The shortened ID the statistics belong to.
+// - bucket (string): The time bucket unit of the timeline.
+// - from (time.Time): The inclusive start of the period.
+// - to (time.Time): The exclusive end of the period.
+// - stats (*entity.ClickStats): The aggregated statistics.
+//
+// Returns:
+// - StatsResponse: The response body, with empty lists instead of nulls.
+func NewStatsResponse(shortID, bucket string, from, to time.Time, stats *entity.ClickStats) StatsResponse {
+	resp := StatsResponse{
+		ShortID:        shortID,
+		Bucket:         bucket,
+		From:           from,
+		To:             to,
+		TotalClicks:    stats.TotalClicks,
+		UniqueVisitors: stats.UniqueVisitors,
+		Timeline:       make([]TimeBucketCount, 0, len(stats.Timeline)),
+		Referrers:      newKeyCounts(stats.Referrers),
+		UserAgents:     newKeyCounts(stats.UserAgents),
+		Countries:      newKeyCounts(stats.Countries),
+		Campaigns:      newKeyCounts(stats.Campaigns),
+		Variants:       newKeyCounts(stats.Variants),
+	}
+	for _, b := range stats.Timeline {
+		resp.Timeline = append(resp.Timeline, TimeBucketCount{Time: b.Time, Count: b.Count})
+	}
+	return resp
+}
+
+// newKeyCounts converts grouped click counts to their response representation.
+func newKeyCounts(counts []entity.KeyCount) []KeyCount {
+	out := make([]KeyCount, 0, len(counts))
+	for _, c := range counts {
+		out = append(out, KeyCount{Key: c.Key, Count: c.Count})
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// TokenResponse represents the tokens returned on login and refresh.
+//
+// Fields:
+// - Token (string): The access token (kept for clients of the original login response).
+// - AccessToken (string): The short-lived token sent as "Authorization: Bearer <token>".
+// - RefreshToken (string): The single-use token exchanged for new tokens at /api/token/refresh.
+// - TokenType (string): Always "Bearer".
+// - ExpiresIn (int64): The number of seconds until the access token expires.
+// - RefreshExpiresAt (time.Time): The expiry of the refresh token.
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshRequest represents the request body for the token refresh endpoint.
+//
+// Fields:
+// - RefreshToken (string): The refresh token to exchange (required).
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents the optional request body for the logout endpoint.
+//
+// Fields:
+// - RefreshToken (string): The refresh token to revoke along with the access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
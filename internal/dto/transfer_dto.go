@@ -0,0 +1,36 @@
+package dto
+
+// ImportProgressResponse represents one line of the newline-delimited JSON stream returned by an import.
+// A line is written after every stored batch; the last line has Done set and lists the failed rows.
+//
+// Fields:
+// - Processed (int64): The number of rows read so far.
+// - Imported (int64): The number of rows stored under a new short ID.
+// - Overwritten (int64): The number of rows that replaced an existing URL.
+// - Skipped (int64): The number of rows skipped because their short ID was taken.
+// - Failed (int64): The number of invalid or unwritable rows.
+// - Done (bool): Whether the import has finished.
+// - Errors ([]ImportRowErrorResponse): The first failed rows. Only set on the last line.
+// - Error (string): The error that stopped the import early, if any. Only set on the last line.
+type ImportProgressResponse struct {
+	Processed   int64                    `json:"processed"`
+	Imported    int64                    `json:"imported"`
+	Overwritten int64                    `json:"overwritten"`
+	Skipped     int64                    `json:"skipped"`
+	Failed      int64                    `json:"failed"`
+	Done        bool                     `json:"done"`
+	Errors      []ImportRowErrorResponse `json:"errors,omitempty"`
+	Error       string                   `json:"error,omitempty"`
+}
+
+// ImportRowErrorResponse represents a row that could not be imported.
+//
+// Fields:
+// - Line (int): The 1-based line of the row in the uploaded file.
+// - ShortID (string): The short ID of the row, if it could be read.
+// - Error (string): The reason the row was rejected.
+type ImportRowErrorResponse struct {
+	Line    int    `json:"line"`
+	ShortID string `json:"short_id,omitempty"`
+	Error   string `json:"error"`
+}
@@ -1,20 +1,269 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
 // ShortenRequest represents the request body for shortening a URL.
 //
 // Fields:
 //   - URL (string): The original long URL provided by the user.
 //     It must be a valid URL and is required in the request body.
+//   - Alias (string): A custom short ID such as "spring-sale". Optional; generated when empty.
+//   - Domain (string): A verified custom domain of the user, such as "go.acme.com", to serve the link on.
+//     Optional; defaults to the domain of the service.
+//   - RedirectType (int): The HTTP status used when redirecting (301, 302, 307 or 308).
+//     Optional; defaults to 302.
+//   - ExpiresAt (*time.Time): The RFC 3339 time after which the link stops redirecting. Optional.
+//   - MaxClicks (int64): The number of redirects allowed before the link expires. Optional.
+//   - Dedupe (string): Who may share the short ID of an equivalent URL: "global", "owner" or "never".
+//     Optional; defaults to the server policy.
+//   - Password (string): A password visitors must enter before being redirected. Optional; stored hashed.
+//   - Interstitial (bool): Whether visitors are shown a preview of the destination and must confirm it. Optional.
+//   - ForwardQuery (bool): Whether the query string of the short URL is passed on to the destination when redirecting. Optional.
+//   - UTMSource, UTMMedium, UTMCampaign, UTMTerm, UTMContent (string): Campaign parameters added to the URL,
+//     replacing any it already carries. Optional.
+//   - RedirectRules ([]RedirectRule): Rules sending matching visitors to other destinations, evaluated in order. Optional.
+//   - Variants ([]Variant): Weighted destinations splitting the visitors no redirect rule matches, for A/B tests. Optional.
 type ShortenRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL           string         `json:"url" binding:"required,url"`
+	Alias         string         `json:"alias,omitempty"`
+	Domain        string         `json:"domain,omitempty" binding:"max=253"`
+	RedirectType  int            `json:"redirect_type,omitempty" binding:"omitempty,oneof=301 302 307 308"`
+	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
+	MaxClicks     int64          `json:"max_clicks,omitempty" binding:"omitempty,min=1"`
+	Dedupe        string         `json:"dedupe,omitempty" binding:"omitempty,oneof=global owner never"`
+	Password      string         `json:"password,omitempty" binding:"omitempty,max=72"`
+	Interstitial  bool           `json:"interstitial,omitempty"`
+	ForwardQuery  bool           `json:"forward_query,omitempty"`
+	UTMSource     string         `json:"utm_source,omitempty" binding:"max=255"`
+	UTMMedium     string         `json:"utm_medium,omitempty" binding:"max=255"`
+	UTMCampaign   string         `json:"utm_campaign,omitempty" binding:"max=255"`
+	UTMTerm       string         `json:"utm_term,omitempty" binding:"max=255"`
+	UTMContent    string         `json:"utm_content,omitempty" binding:"max=255"`
+	RedirectRules []RedirectRule `json:"redirect_rules,omitempty" binding:"omitempty,max=20,dive"`
+	Variants      []Variant      `json:"variants,omitempty" binding:"omitempty,min=2,max=10,dive"`
+}
+
+// RedirectRule represents a conditional destination of a shortened URL.
+// A rule matches when every condition it sets matches; the first matching rule wins.
+//
+// Fields:
+//   - Destination (string): The URL matching visitors are redirected to. Required.
+//   - OS ([]string): Operating systems: "ios", "android", "windows", "macos", "linux" or "chromeos".
+//   - Devices ([]string): Device types: "mobile", "tablet", "desktop" or "bot".
+//   - Languages ([]string): Language tags such as "pt" or "pt-BR", matched against the visitor's preferred language.
+//   - Countries ([]string): ISO 3166-1 alpha-2 country codes such as "BR".
+//   - StartsAt (*time.Time): The RFC 3339 time from which the rule applies. Optional.
+//   - EndsAt (*time.Time): The RFC 3339 time from which the rule no longer applies. Optional.
+type RedirectRule struct {
+	Destination string     `json:"destination" binding:"required,url"`
+	OS          []string   `json:"os,omitempty"`
+	Devices     []string   `json:"devices,omitempty"`
+	Languages   []string   `json:"languages,omitempty"`
+	Countries   []string   `json:"countries,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+// RedirectRulesToEntities converts requested redirect rules to entities.
+//
+// Parameters:
+// - rules ([]RedirectRule): The rules of a request.
+//
+// Returns:
+// - []entity.RedirectRule: The rules, in the same order.
+func RedirectRulesToEntities(rules []RedirectRule) []entity.RedirectRule {
+	if rules == nil {
+		return nil
+	}
+	out := make([]entity.RedirectRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, entity.RedirectRule(r))
+	}
+	return out
+}
+
+// NewRedirectRules converts redirect rule entities to their response representation.
+//
+// Parameters:
+// - rules ([]entity.RedirectRule): The rules of a URL.
+//
+// Returns:
+// - []RedirectRule: The rules, in the same order, or nil if there are none.
+func NewRedirectRules(rules []entity.RedirectRule) []RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]RedirectRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, RedirectRule(r))
+	}
+	return out
+}
+
+// Variant represents one of the weighted destinations of a shortened URL splitting its traffic.
+// Visitors keep the variant they are assigned to on later visits.
+//
+// Fields:
+//   - Name (string): A name such as "a" or "new-landing", reported in the statistics. Required.
+//   - Destination (string): The URL visitors assigned to the variant are redirected to. Required.
+//   - Weight (int): The share of visitors assigned to the variant, relative to the others, from 0 to 1000.
+//     Zero pauses the variant.
+type Variant struct {
+	Name        string `json:"name" binding:"required,max=32"`
+	Destination string `json:"destination" binding:"required,url"`
+	Weight      int    `json:"weight" binding:"min=0,max=1000"`
+}
+
+// VariantsToEntities converts requested variants to entities.
+//
+// Parameters:
+// - variants ([]Variant): The variants of a request.
+//
+// Returns:
+// - []entity.Variant: The variants, in the same order.
+func VariantsToEntities(variants []Variant) []entity.Variant {
+	if variants == nil {
+		return nil
+	}
+	out := make([]entity.Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, entity.Variant(v))
+	}
+	return out
+}
+
+// NewVariants converts variant entities to their response representation.
+//
+// Parameters:
+// - variants ([]entity.Variant): The variants of a URL.
+//
+// Returns:
+// - []Variant: The variants, in the same order, or nil if there are none.
+func NewVariants(variants []entity.Variant) []Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	out := make([]Variant, 0, len(variants))
+	for _, v := range variants {
+		out = append(out, Variant(v))
+	}
+	return out
 }
 
 // ShortenResponse represents the response body for a shortened URL.
 //
 // Fields:
 // - ShortID (string): The unique identifier for the shortened URL.
-// - ShortURL (string): The full shortened URL.
+// - ShortURL (string): The absolute short URL, e.g. "https://sho.rt/abc123".
 type ShortenResponse struct {
 	ShortID  string `json:"short_id"`
 	ShortURL string `json:"short_url"`
 }
+
+// BatchShortenResult represents the outcome of one item of a batch shorten request.
+//
+// Fields:
+// - Index (int): The position of the item in the request array.
+// - ShortID (string): The unique identifier for the shortened URL. Empty if the item failed.
+// - ShortURL (string): The absolute short URL. Empty if the item failed.
+// - Status (int): The HTTP status the item would have received from POST /api/shorten.
+// - Message (string): A human-readable error message. Empty if the item succeeded.
+// - Error (string): The technical error details, if any.
+type BatchShortenResult struct {
+	Index    int    `json:"index"`
+	ShortID  string `json:"short_id,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Status   int    `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchShortenResponse represents the response body for a batch shorten request.
+//
+// Fields:
+// - Results ([]BatchShortenResult): One result per requested item, in request order.
+// - Succeeded (int): The number of items that were shortened.
+// - Failed (int): The number of items that failed.
+type BatchShortenResponse struct {
+	Results   []BatchShortenResult `json:"results"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+}
+
+// UpdateURLRequest represents the request body for changing an existing shortened URL.
+//
+// Fields:
+//   - URL (*string): The new destination URL. Optional; must be a valid URL when set.
+//   - ExpiresAt (*time.Time): The new RFC 3339 expiry time. Optional.
+//   - RemoveExpiry (bool): Whether to remove the expiry time. Takes precedence over ExpiresAt.
+//   - Interstitial (*bool): Whether visitors must confirm the destination on a preview page. Optional.
+//   - ForwardQuery (*bool): Whether the query string of the short URL is passed on to the destination. Optional.
+//   - RedirectRules (*[]RedirectRule): The new redirect rules, replacing the current ones; an empty list removes them. Optional.
+//   - Variants (*[]Variant): The new variants, replacing the current ones; an empty list removes them. Optional.
+type UpdateURLRequest struct {
+	URL           *string         `json:"url,omitempty" binding:"omitempty,url"`
+	ExpiresAt     *time.Time      `json:"expires_at,omitempty"`
+	RemoveExpiry  bool            `json:"remove_expiry,omitempty"`
+	Interstitial  *bool           `json:"interstitial,omitempty"`
+	ForwardQuery  *bool           `json:"forward_query,omitempty"`
+	RedirectRules *[]RedirectRule `json:"redirect_rules,omitempty" binding:"omitempty,max=20,dive"`
+	Variants      *[]Variant      `json:"variants,omitempty" binding:"omitempty,max=10,dive"`
+}
+
+// URLResponse represents a shortened URL in management API responses.
+//
+// Fields:
+// - ShortID (string): The unique identifier for the shortened URL.
+// - ShortURL (string): The absolute short URL, e.g. "https://sho.rt/abc123".
+// - Domain (string): The custom domain the URL is served on, if any.
+// - OriginalURL (string): The destination URL.
+// - CanonicalURL (string): The canonical form of the destination URL, shared by equivalent URLs.
+// - Alias (bool): Whether ShortID is a custom alias.
+// - PasswordProtected (bool): Whether visitors must enter a password before being redirected.
+// - Interstitial (bool): Whether visitors must confirm the destination on a preview page.
+// - ForwardQuery (bool): Whether the query string of the short URL is passed on to the destination.
+// - RedirectRules ([]RedirectRule): The conditional destinations, in evaluation order.
+// - Variants ([]Variant): The weighted destinations of an A/B test.
+// - RedirectType (int): The HTTP status used when redirecting.
+// - ExpiresAt (*time.Time): The expiry time, if any.
+// - MaxClicks (int64): The click limit, if any.
+// - Clicks (int64): The number of recorded redirects, or the number counted against MaxClicks when analytics is disabled.
+// - CreatedAt (time.Time): The timestamp when the URL was created.
+// - UpdatedAt (time.Time): The timestamp when the URL was last changed.
+type URLResponse struct {
+	ShortID           string         `json:"short_id"`
+	ShortURL          string         `json:"short_url"`
+	Domain            string         `json:"domain,omitempty"`
+	OriginalURL       string         `json:"original_url"`
+	CanonicalURL      string         `json:"canonical_url,omitempty"`
+	Alias             bool           `json:"alias"`
+	PasswordProtected bool           `json:"password_protected,omitempty"`
+	Interstitial      bool           `json:"interstitial,omitempty"`
+	ForwardQuery      bool           `json:"forward_query,omitempty"`
+	RedirectRules     []RedirectRule `json:"redirect_rules,omitempty"`
+	Variants          []Variant      `json:"variants,omitempty"`
+	RedirectType      int            `json:"redirect_type"`
+	ExpiresAt         *time.Time     `json:"expires_at,omitempty"`
+	MaxClicks         int64          `json:"max_clicks,omitempty"`
+	Clicks            int64          `json:"clicks"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// URLListResponse represents one page of shortened URLs.
+//
+// Fields:
+// - Items ([]URLResponse): The URLs in the page.
+// - Page (int): The 1-based page number.
+// - PageSize (int): The number of URLs per page.
+// - Total (int64): The total number of URLs matching the listing.
+type URLListResponse struct {
+	Items    []URLResponse `json:"items"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+	Total    int64         `json:"total"`
+}
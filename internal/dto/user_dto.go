@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// RegisterRequest represents the request body for the registration endpoint.
+//
+// Fields:
+// - Username (string): The requested username, 3 to 64 characters (required).
+// - Password (string): The password, 8 to 72 characters (required).
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=64"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// UserResponse represents a user account in API responses.
+//
+// Fields:
+// - ID (string): The unique identifier of the user.
+// - Username (string): The username.
+// - Role (string): The role of the user.
+// - CreatedAt (time.Time): The timestamp when the user was created.
+type UserResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
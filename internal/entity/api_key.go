@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// APIKey represents a long-lived credential used by machine clients instead of a login.
+// Only a hash of the key is stored; the key itself is shown once, when it is created.
+//
+// Fields:
+// - ID (string): The unique identifier for the API key.
+// - UserID (string): The ID of the user the key acts as.
+// - Name (string): A label chosen by the user, e.g. "ci".
+// - Prefix (string): The first characters of the key, shown so users can recognize it.
+// - KeyHash (string): The hex-encoded SHA-256 hash of the key.
+// - Scopes ([]string): The scopes granted to the key.
+// - CreatedAt (time.Time): The timestamp when the key was created.
+// - LastUsedAt (*time.Time): The last time the key authenticated a request, or nil if never.
+// - RevokedAt (*time.Time): The time the key was revoked, or nil if it is active.
+type APIKey struct {
+	ID         string     `bson:"_id"`
+	UserID     string     `bson:"user_id"`
+	Name       string     `bson:"name"`
+	Prefix     string     `bson:"prefix"`
+	KeyHash    string     `bson:"key_hash"`
+	Scopes     []string   `bson:"scopes"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty"`
+}
+
+// IsRevoked reports whether the key was revoked.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,75 @@
+package entity
+
+import "time"
+
+// Click represents a single redirect event recorded for analytics.
+//
+// Fields:
+// - ID (string): The unique identifier for the click document in the database.
+// - ShortID (string): The shortened ID that was visited.
+// - Domain (string): The custom domain of the visited URL. Empty means the default domain.
+// - Timestamp (time.Time): The time of the redirect.
+// - Referrer (string): The Referer header sent by the client, if any.
+// - UserAgent (string): The raw User-Agent header sent by the client.
+// - UAFamily (string): The browser or client family derived from the user agent.
+// - IP (string): The client IP truncated to a /24 (IPv4) or /48 (IPv6) network.
+// - Country (string): The ISO 3166-1 alpha-2 country code of the client, if known.
+// - Campaign (string): The utm_campaign parameter of the URL the client was redirected to, if any.
+// - Variant (string): The name of the variant the client was assigned to, if the URL splits its traffic.
+// - VisitorID (string): A one-way hash of the client IP and user agent, used to count unique visitors.
+type Click struct {
+	ID        string    `bson:"_id,omitempty"`
+	ShortID   string    `bson:"short_id"`
+	Domain    string    `bson:"domain,omitempty"`
+	Timestamp time.Time `bson:"timestamp"`
+	Referrer  string    `bson:"referrer,omitempty"`
+	UserAgent string    `bson:"user_agent,omitempty"`
+	UAFamily  string    `bson:"ua_family"`
+	IP        string    `bson:"ip,omitempty"`
+	Country   string    `bson:"country,omitempty"`
+	Campaign  string    `bson:"campaign,omitempty"`
+	Variant   string    `bson:"variant,omitempty"`
+	VisitorID string    `bson:"visitor_id"`
+}
+
+// ClickStats holds aggregated click statistics for a shortened URL.
+//
+// Fields:
+// - TotalClicks (int64): The number of recorded clicks.
+// - UniqueVisitors (int64): The number of distinct visitors.
+// - Timeline ([]TimeBucketCount): Click counts grouped by time bucket, oldest first.
+// - Referrers ([]KeyCount): Click counts grouped by referrer, most frequent first.
+// - UserAgents ([]KeyCount): Click counts grouped by user agent family, most frequent first.
+// - Countries ([]KeyCount): Click counts grouped by country, most frequent first.
+// - Campaigns ([]KeyCount): Click counts grouped by campaign, most frequent first.
+// - Variants ([]KeyCount): Click counts grouped by variant, most frequent first.
+type ClickStats struct {
+	TotalClicks    int64
+	UniqueVisitors int64
+	Timeline       []TimeBucketCount
+	Referrers      []KeyCount
+	UserAgents     []KeyCount
+	Countries      []KeyCount
+	Campaigns      []KeyCount
+	Variants       []KeyCount
+}
+
+// TimeBucketCount holds the number of clicks in a time bucket.
+//
+// Fields:
+// - Time (time.Time): The start of the bucket.
+// - Count (int64): The number of clicks in the bucket.
+type TimeBucketCount struct {
+	Time  time.Time `bson:"_id"`
+	Count int64     `bson:"count"`
+}
+
+// KeyCount holds the number of clicks sharing a value.
+//
+// Fields:
+// - Key (string): The grouped value.
+// - Count (int64): The number of clicks with that value.
+type KeyCount struct {
+	Key   string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
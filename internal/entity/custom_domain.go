@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// CustomDomain is a user's claim on a branded domain, such as go.acme.com, to serve short URLs on.
+// Short IDs are unique per domain, so the same ID may exist on the default domain and on every custom domain.
+// Several users may claim the same host, each with their own token, but only one claim per host can be verified.
+//
+// Fields:
+// - Host (string): The lowercase host name of the domain.
+// - OwnerID (string): The ID of the user who registered the domain.
+// - Token (string): The random token the owner publishes in a DNS TXT record to prove they control the domain.
+// - CreatedAt (time.Time): The timestamp when the domain was registered.
+// - VerifiedAt (*time.Time): The time the TXT record was found, or nil while the domain is unverified.
+type CustomDomain struct {
+	Host       string     `bson:"host"`
+	OwnerID    string     `bson:"owner_id"`
+	Token      string     `bson:"token"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	VerifiedAt *time.Time `bson:"verified_at,omitempty"`
+}
+
+// IsVerified reports whether the owner proved control of the domain. Only verified domains serve short URLs.
+func (d *CustomDomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
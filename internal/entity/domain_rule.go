@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+const (
+	// DomainActionAllow puts a domain on the allow list. Once any domain is allowed,
+	// only allowed domains can be shortened.
+	DomainActionAllow = "allow"
+	// DomainActionDeny puts a domain on the deny list.
+	DomainActionDeny = "deny"
+)
+
+// DomainRule allows or denies shortening URLs of a domain and its subdomains.
+//
+// Fields:
+// - Domain (string): The lowercase domain the rule applies to, e.g. "example.com".
+// - Action (string): DomainActionAllow or DomainActionDeny.
+// - Reason (string): Why the rule was added, shown to administrators.
+// - CreatedBy (string): The ID of the user who added the rule.
+// - CreatedAt (time.Time): The timestamp when the rule was added or last changed.
+type DomainRule struct {
+	Domain    string    `bson:"_id"`
+	Action    string    `bson:"action"`
+	Reason    string    `bson:"reason,omitempty"`
+	CreatedBy string    `bson:"created_by,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+}
@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+// Operating systems matched by RedirectRule.OS.
+const (
+	OSiOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+)
+
+// Device types matched by RedirectRule.Devices.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// RedirectRule sends the visitors of a URL it matches to its own destination instead of the original URL.
+// A rule matches when every condition it sets matches; conditions left empty match every visitor.
+//
+// Fields:
+// - Destination (string): The URL matching visitors are redirected to.
+// - OS ([]string): The operating systems of the visitor's user agent, e.g. OSiOS or OSAndroid.
+// - Devices ([]string): The device types of the visitor's user agent, e.g. DeviceMobile.
+// - Languages ([]string): Lowercase language tags matched against the visitor's preferred language,
+// where "pt" matches "pt" and "pt-br" and "pt-br" only matches "pt-br".
+// - Countries ([]string): Uppercase ISO 3166-1 alpha-2 codes of the visitor's country.
+// - StartsAt (*time.Time): The time from which the rule applies. Nil means it always applied.
+// - EndsAt (*time.Time): The time from which the rule no longer applies. Nil means it never stops.
+type RedirectRule struct {
+	Destination string     `bson:"destination"`
+	OS          []string   `bson:"os,omitempty"`
+	Devices     []string   `bson:"devices,omitempty"`
+	Languages   []string   `bson:"languages,omitempty"`
+	Countries   []string   `bson:"countries,omitempty"`
+	StartsAt    *time.Time `bson:"starts_at,omitempty"`
+	EndsAt      *time.Time `bson:"ends_at,omitempty"`
+}
+
+// Active reports whether the time window of the rule contains a time.
+//
+// Parameters:
+// - now (time.Time): The reference time.
+//
+// Returns:
+// - bool: True if now is not before StartsAt and before EndsAt.
+func (r *RedirectRule) Active(now time.Time) bool {
+	return (r.StartsAt == nil || !now.Before(*r.StartsAt)) && (r.EndsAt == nil || now.Before(*r.EndsAt))
+}
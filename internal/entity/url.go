@@ -1,17 +1,172 @@
 package entity
 
-import "time"
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultRedirectStatus is the HTTP status used when a URL does not specify a redirect type.
+const DefaultRedirectStatus = http.StatusFound
+
+const (
+	// DedupeGlobal shares a mapping with every user shortening an equivalent URL.
+	DedupeGlobal = "global"
+	// DedupeOwner shares a mapping only with later requests of the same owner.
+	DedupeOwner = "owner"
+	// DedupeNever never shares a mapping; every request gets a new short ID.
+	DedupeNever = "never"
+)
 
 // URL represents a URL entity stored in the database.
 //
 // Fields:
 // - ID (string): The unique identifier for the URL document in the database.
-// - ShortID (string): The shortened identifier for the URL, used for redirection.
-// - Original (string): The original long URL provided by the user.
+// - ShortID (string): The shortened identifier for the URL, used for redirection. Unique within its domain.
+// - Domain (string): The custom domain the URL is served on. Empty means the default domain.
+// - Original (string): The original long URL provided by the user, used when redirecting.
+// - Canonical (string): The canonical form of Original, used to find existing mappings of equivalent URLs.
+// - OwnerID (string): The ID of the user who created the URL. Empty for URLs created before accounts existed.
+// - Dedupe (string): The dedupe scope the URL was created with, DedupeOwner or DedupeNever. Empty means DedupeGlobal.
+// - PasswordHash (string): The bcrypt hash of the password visitors must enter before being redirected. Empty means the URL is public.
+// - Interstitial (bool): Whether visitors are shown a preview of the destination and must confirm before being redirected.
+// - ForwardQuery (bool): Whether the query string of the short URL is merged into the destination when redirecting.
+// - RedirectRules ([]RedirectRule): Rules sending matching visitors elsewhere, evaluated in order. The first match wins; without one, visitors go to Original.
+// - Variants ([]Variant): Weighted destinations splitting the visitors no redirect rule matches. Empty means they go to Original.
+// - Alias (bool): Whether ShortID is a custom alias chosen by the user rather than a generated ID.
+// - RedirectType (int): The HTTP status used when redirecting (301, 302, 307 or 308). Zero means DefaultRedirectStatus.
+// - ExpiresAt (*time.Time): The time after which the URL stops redirecting. Nil means it never expires.
+// - MaxClicks (int64): The number of redirects allowed before the URL expires. Zero means unlimited.
+// - Clicks (int64): The number of redirects counted against MaxClicks.
 // - CreatedAt (time.Time): The timestamp when the URL was created.
+// - UpdatedAt (time.Time): The timestamp when the URL was last changed.
 type URL struct {
-	ID        string    `bson:"_id,omitempty"`
-	ShortID   string    `bson:"short_id"`
-	Original  string    `bson:"original_url"`
-	CreatedAt time.Time `bson:"created_at"`
+	ID            string         `bson:"_id,omitempty"`
+	ShortID       string         `bson:"short_id"`
+	Domain        string         `bson:"domain,omitempty"`
+	Original      string         `bson:"original_url"`
+	Canonical     string         `bson:"canonical_url,omitempty"`
+	OwnerID       string         `bson:"owner_id,omitempty"`
+	Dedupe        string         `bson:"dedupe,omitempty"`
+	PasswordHash  string         `bson:"password_hash,omitempty"`
+	Interstitial  bool           `bson:"interstitial,omitempty"`
+	ForwardQuery  bool           `bson:"forward_query,omitempty"`
+	RedirectRules []RedirectRule `bson:"redirect_rules,omitempty"`
+	Variants      []Variant      `bson:"variants,omitempty"`
+	Alias         bool           `bson:"alias,omitempty"`
+	RedirectType  int            `bson:"redirect_type,omitempty"`
+	ExpiresAt     *time.Time     `bson:"expires_at,omitempty"`
+	MaxClicks     int64          `bson:"max_clicks,omitempty"`
+	Clicks        int64          `bson:"clicks"`
+	CreatedAt     time.Time      `bson:"created_at"`
+	UpdatedAt     time.Time      `bson:"updated_at"`
+}
+
+// RedirectStatus returns the HTTP status code to use when redirecting to the original URL.
+//
+// Returns:
+// - int: The configured redirect type, or DefaultRedirectStatus if none is set.
+func (u *URL) RedirectStatus() int {
+	if u.RedirectType == 0 {
+		return DefaultRedirectStatus
+	}
+	return u.RedirectType
+}
+
+// CanonicalURL returns the form of the original URL that identifies equivalent mappings.
+//
+// Returns:
+// - string: Canonical, or Original for URLs stored before canonical forms were recorded.
+func (u *URL) CanonicalURL() string {
+	if u.Canonical != "" {
+		return u.Canonical
+	}
+	return u.Original
+}
+
+// DedupeScope returns the dedupe scope the URL was created with.
+//
+// Returns:
+// - string: DedupeGlobal, DedupeOwner or DedupeNever.
+func (u *URL) DedupeScope() string {
+	if u.Dedupe == "" {
+		return DedupeGlobal
+	}
+	return u.Dedupe
+}
+
+// Reusable reports whether the URL may be returned for later requests shortening an equivalent URL.
+// Custom aliases, URLs on custom domains, URLs with limits, password-protected URLs, interstitial URLs,
+// URLs forwarding their query string, URLs with redirect rules or variants and URLs created with DedupeNever are never reused.
+func (u *URL) Reusable() bool {
+	return !u.Alias && u.Domain == "" && !u.HasLimits() && !u.IsProtected() && !u.Interstitial && !u.ForwardQuery &&
+		len(u.RedirectRules) == 0 && len(u.Variants) == 0 && u.DedupeScope() != DedupeNever
+}
+
+// Key returns the identifier of the URL across domains, see LinkKey.
+func (u *URL) Key() string {
+	return LinkKey(u.Domain, u.ShortID)
+}
+
+// LinkKey builds the identifier of a short ID on a domain, used to key caches and counters shared by all domains.
+//
+// Parameters:
+// - domain (string): The custom domain, or an empty string for the default domain.
+// - shortID (string): The short ID.
+//
+// Returns:
+// - string: The short ID on the default domain, or "<domain>/<short ID>" on a custom domain.
+func LinkKey(domain, shortID string) string {
+	if domain == "" {
+		return shortID
+	}
+	return domain + "/" + shortID
+}
+
+// IsProtected reports whether visitors must enter a password before being redirected.
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// DedupeKey returns the key under which the URL is found by later requests, see DedupeKeyFor.
+func (u *URL) DedupeKey() string {
+	return DedupeKeyFor(u.DedupeScope(), u.OwnerID, u.CanonicalURL())
+}
+
+// DedupeKeyFor builds the key identifying the reusable mapping of a URL within a dedupe scope.
+//
+// Parameters:
+// - scope (string): DedupeGlobal, DedupeOwner or DedupeNever.
+// - ownerID (string): The owner of the mapping, used by DedupeOwner.
+// - canonicalURL (string): The canonical URL.
+//
+// Returns:
+// - string: "global:<url>" or "owner:<owner>:<url>", or an empty string for DedupeNever.
+func DedupeKeyFor(scope, ownerID, canonicalURL string) string {
+	switch scope {
+	case DedupeGlobal:
+		return "global:" + canonicalURL
+	case DedupeOwner:
+		return "owner:" + ownerID + ":" + canonicalURL
+	default:
+		return ""
+	}
+}
+
+// IsExpired reports whether the URL's expiry time has passed.
+//
+// Parameters:
+// - now (time.Time): The reference time.
+//
+// Returns:
+// - bool: True if ExpiresAt is set and not after now.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(now)
+}
+
+// HasLimits reports whether the URL has an expiry time or a click limit.
+//
+// Returns:
+// - bool: True if ExpiresAt or MaxClicks is set.
+func (u *URL) HasLimits() bool {
+	return u.ExpiresAt != nil || u.MaxClicks > 0
 }
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// User represents an account that can authenticate and own links.
+//
+// Fields:
+// - ID (string): The unique identifier for the user, used as the user_id token claim.
+// - Username (string): The unique login name of the user.
+// - PasswordHash (string): The bcrypt hash of the user's password.
+// - Role (string): The role of the user ("admin", "editor" or "viewer"); empty for users stored before roles existed.
+// - CreatedAt (time.Time): The timestamp when the user was created.
+type User struct {
+	ID           string    `bson:"_id"`
+	Username     string    `bson:"username"`
+	PasswordHash string    `bson:"password_hash"`
+	Role         string    `bson:"role,omitempty"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
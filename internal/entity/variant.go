@@ -0,0 +1,13 @@
+package entity
+
+// Variant is one of the weighted destinations a URL splits its traffic across.
+//
+// Fields:
+// - Name (string): The lowercase name identifying the variant in the sticky cookie and in click statistics.
+// - Destination (string): The URL visitors assigned to the variant are redirected to.
+// - Weight (int): The share of visitors assigned to the variant, relative to the weights of the others. Zero pauses it.
+type Variant struct {
+	Name        string `bson:"name"`
+	Destination string `bson:"destination"`
+	Weight      int    `bson:"weight"`
+}
@@ -0,0 +1,370 @@
+// Package geoip looks up the country of IP addresses in a local MaxMind DB file,
+// such as GeoLite2-Country.mmdb or an equivalent database in the same format.
+//
+// Only the parts of the format needed for country lookups are implemented: the binary search tree
+// and the data section decoder. The whole file is read into memory.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the number of zero bytes between the search tree and the data section.
+const dataSectionSeparator = 16
+
+// maxPointerDepth bounds nested decoding, so a corrupt file cannot recurse forever.
+const maxPointerDepth = 32
+
+// ErrInvalidDatabase is returned when a file is not a MaxMind DB file or is corrupt.
+var ErrInvalidDatabase = errors.New("invalid MaxMind DB file")
+
+// Reader looks up IP addresses in a MaxMind DB file held in memory. It is safe for concurrent use.
+type Reader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint32
+	recordSize uint32
+	ipVersion  uint32
+	ipv4Start  uint32
+}
+
+// Open reads a MaxMind DB file.
+//
+// Parameters:
+// - path (string): The path of the .mmdb file.
+//
+// Returns:
+// - *Reader: The reader.
+// - error: An error if the file cannot be read, or ErrInvalidDatabase if it is malformed.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buf)
+}
+
+// FromBytes reads a MaxMind DB from memory.
+//
+// Parameters:
+// - buf ([]byte): The content of a .mmdb file. It must not be modified afterwards.
+//
+// Returns:
+// - *Reader: The reader.
+// - error: ErrInvalidDatabase if the content is malformed.
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	metaBuf := buf[start+len(metadataMarker):]
+	metadata, _, err := (&decoder{buf: metaBuf}).decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := metadata.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &Reader{
+		nodeCount:  uint32(asUint(meta["node_count"])),
+		recordSize: uint32(asUint(meta["record_size"])),
+		ipVersion:  uint32(asUint(meta["ip_version"])),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported IP version %d", ErrInvalidDatabase, r.ipVersion)
+	}
+	treeSize := uint64(r.nodeCount) * uint64(r.recordSize) / 4
+	if treeSize+dataSectionSeparator > uint64(start) {
+		return nil, fmt.Errorf("%w: search tree exceeds the file", ErrInvalidDatabase)
+	}
+	r.buf = buf[:treeSize]
+	r.data = buf[treeSize+dataSectionSeparator : start]
+
+	if r.ipVersion == 6 {
+		node := uint32(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country of an IP address.
+//
+// Parameters:
+// - ip (net.IP): The IPv4 or IPv6 address.
+//
+// Behavior:
+// - Reads the "country" record, falling back to "registered_country" for addresses only assigned to a country.
+//
+// Returns:
+// - string: The country code, or an empty string if the address is not in the database.
+// - error: ErrInvalidDatabase if the record cannot be decoded.
+func (r *Reader) Country(ip net.IP) (string, error) {
+	record, err := r.Lookup(ip)
+	if err != nil || record == nil {
+		return "", err
+	}
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := record[key].(map[string]any); ok {
+			if code, ok := country["iso_code"].(string); ok && code != "" {
+				return code, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Lookup returns the record of an IP address.
+//
+// Parameters:
+// - ip (net.IP): The IPv4 or IPv6 address.
+//
+// Returns:
+// - map[string]any: The decoded record, or nil if the address is not in the database.
+// - error: ErrInvalidDatabase if the record cannot be decoded.
+func (r *Reader) Lookup(ip net.IP) (map[string]any, error) {
+	node, bits := uint32(0), ip.To16()
+	if ip4 := ip.To4(); ip4 != nil {
+		bits = ip4
+		node = r.ipv4Start
+	} else if bits == nil || r.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint32(bits[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, nil
+	}
+
+	offset := uint64(node) - uint64(r.nodeCount) - dataSectionSeparator
+	value, _, err := (&decoder{buf: r.data}).decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// record reads the left (0) or right (1) record of a search tree node.
+func (r *Reader) record(node, side uint32) uint32 {
+	b := r.buf[uint64(node)*uint64(r.recordSize)/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[side*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		if side == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		return binary.BigEndian.Uint32(b[side*4:])
+	}
+}
+
+// Data section field types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder decodes values of a data section.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at an offset, returning it and the offset following it.
+// Maps decode to map[string]any, arrays to []any, integers to uint64 or int32, and uint128 values to []byte.
+func (d *decoder) decode(offset uint64, depth int) (any, uint64, error) {
+	if depth > maxPointerDepth {
+		return nil, 0, fmt.Errorf("%w: data nested too deeply", ErrInvalidDatabase)
+	}
+	ctrl, err := d.byteAt(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+
+	kind := int(ctrl >> 5)
+	if kind == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer, depth+1)
+		return value, next, err
+	}
+	if kind == typeExtended {
+		ext, err := d.byteAt(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset++
+		kind = 7 + int(ext)
+	}
+
+	size := uint64(ctrl & 0x1f)
+	if size >= 29 {
+		n := int(size - 28)
+		raw, err := d.slice(offset, uint64(n))
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += uint64(n)
+		size = uintFromBytes(raw) + [...]uint64{29, 285, 65821}[n-1]
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]any, min(size, 64))
+		for i := uint64(0); i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", ErrInvalidDatabase)
+			}
+			value, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 64))
+		for i := uint64(0); i < size; i++ {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	raw, err := d.slice(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+	switch kind {
+	case typeString:
+		return string(raw), offset, nil
+	case typeBytes, typeUint128:
+		return raw, offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: double of %d bytes", ErrInvalidDatabase, size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: float of %d bytes", ErrInvalidDatabase, size)
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(raw)), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("%w: integer of %d bytes", ErrInvalidDatabase, size)
+		}
+		return uintFromBytes(raw), offset, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("%w: int32 of %d bytes", ErrInvalidDatabase, size)
+		}
+		return int32(uint32(uintFromBytes(raw))), offset, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown data type %d", ErrInvalidDatabase, kind)
+	}
+}
+
+// pointer reads the target of a pointer whose control byte was already read.
+func (d *decoder) pointer(ctrl byte, offset uint64) (uint64, uint64, error) {
+	n := uint64(ctrl>>3&0x3) + 1
+	raw, err := d.slice(offset, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	value := uintFromBytes(raw)
+	switch n {
+	case 1:
+		value |= uint64(ctrl&0x7) << 8
+	case 2:
+		value = value | uint64(ctrl&0x7)<<16 + 2048
+	case 3:
+		value = value | uint64(ctrl&0x7)<<24 + 526336
+	}
+	return value, offset + n, nil
+}
+
+// byteAt returns the byte at an offset.
+func (d *decoder) byteAt(offset uint64) (byte, error) {
+	if offset >= uint64(len(d.buf)) {
+		return 0, fmt.Errorf("%w: data offset out of range", ErrInvalidDatabase)
+	}
+	return d.buf[offset], nil
+}
+
+// slice returns n bytes starting at an offset.
+func (d *decoder) slice(offset, n uint64) ([]byte, error) {
+	if offset+n > uint64(len(d.buf)) || offset+n < offset {
+		return nil, fmt.Errorf("%w: data offset out of range", ErrInvalidDatabase)
+	}
+	return d.buf[offset : offset+n], nil
+}
+
+// uintFromBytes decodes a big-endian unsigned integer of up to 8 bytes.
+func uintFromBytes(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// asUint converts a decoded metadata integer, returning 0 for other values.
+func asUint(v any) uint64 {
+	n, _ := v.(uint64)
+	return n
+}
@@ -0,0 +1,216 @@
+package geoip_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/geoip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Data section field types used by the test database.
+const (
+	typePointer = 1
+	typeString  = 2
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+)
+
+func field(kind, size int, payload ...byte) []byte {
+	return append([]byte{byte(kind<<5 | size)}, payload...)
+}
+
+func str(s string) []byte {
+	return field(typeString, len(s), []byte(s)...)
+}
+
+func unsigned(kind int, v uint32) []byte {
+	var payload []byte
+	for ; v > 0; v >>= 8 {
+		payload = append([]byte{byte(v)}, payload...)
+	}
+	return field(kind, len(payload), payload...)
+}
+
+func pointer(offset int) []byte {
+	return []byte{byte(typePointer<<5 | offset>>8), byte(offset)}
+}
+
+func mapOf(pairs ...[]byte) []byte {
+	out := field(typeMap, len(pairs)/2)
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// testTree is a binary trie of network prefixes. Records are node indexes, -1 for no data,
+// or -2-i for the i-th data record.
+type testTree struct {
+	nodes [][2]int
+}
+
+func (t *testTree) insert(ip net.IP, prefix, record int) {
+	if len(t.nodes) == 0 {
+		t.nodes = append(t.nodes, [2]int{-1, -1})
+	}
+	node := 0
+	for i := 0; i < prefix; i++ {
+		bit := int(ip[i/8]>>(7-i%8)) & 1
+		if i == prefix-1 {
+			t.nodes[node][bit] = -2 - record
+			return
+		}
+		if t.nodes[node][bit] < 0 {
+			t.nodes = append(t.nodes, [2]int{-1, -1})
+			t.nodes[node][bit] = len(t.nodes) - 1
+		}
+		node = t.nodes[node][bit]
+	}
+}
+
+// network maps a network to its encoded data record.
+type network struct {
+	cidr   string
+	record []byte
+}
+
+// buildDatabase writes a MaxMind DB with one data record per network, in order.
+func buildDatabase(t *testing.T, ipVersion uint32, recordSize int, networks []network) []byte {
+	t.Helper()
+
+	var tree testTree
+	var data []byte
+	var offsets []int
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		require.NoError(t, err)
+		ip := ipNet.IP
+		ones, _ := ipNet.Mask.Size()
+		if ipVersion == 6 && ip.To4() != nil {
+			ip, ones = ip.To16(), ones+96
+			ip[10], ip[11] = 0, 0
+		}
+		tree.insert(ip, ones, len(offsets))
+		offsets = append(offsets, len(data))
+		data = append(data, n.record...)
+	}
+
+	nodeCount := len(tree.nodes)
+	value := func(r int) uint32 {
+		switch {
+		case r >= 0:
+			return uint32(r)
+		case r == -1:
+			return uint32(nodeCount)
+		default:
+			return uint32(nodeCount + 16 + offsets[-2-r])
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, node := range tree.nodes {
+		left, right := value(node[0]), value(node[1])
+		switch recordSize {
+		case 24:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(left>>20&0xf0 | right>>24&0x0f), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 32:
+			buf.Write([]byte{byte(left >> 24), byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 24), byte(right >> 16), byte(right >> 8), byte(right)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	buf.Write(mapOf(
+		str("node_count"), unsigned(typeUint32, uint32(nodeCount)),
+		str("record_size"), unsigned(typeUint16, uint32(recordSize)),
+		str("ip_version"), unsigned(typeUint16, ipVersion),
+		str("database_type"), str("Test-Country"),
+	))
+	return buf.Bytes()
+}
+
+func testNetworks() []network {
+	return []network{
+		{"81.2.69.0/24", mapOf(str("country"), mapOf(str("iso_code"), str("US")))},
+		{"2001:db8::/32", mapOf(str("country"), mapOf(str("iso_code"), str("DE"), str("geoname_id"), unsigned(typeUint32, 2921044)))},
+		{"175.16.199.0/24", mapOf(str("registered_country"), mapOf(str("iso_code"), str("CN")))},
+	}
+}
+
+func TestReader_Country(t *testing.T) {
+	for _, recordSize := range []int{24, 28, 32} {
+		db, err := geoip.FromBytes(buildDatabase(t, 6, recordSize, testNetworks()))
+		require.NoError(t, err, "record size %d", recordSize)
+
+		tests := map[string]string{
+			"81.2.69.160":      "US",
+			"2001:db8::1":      "DE",
+			"175.16.199.3":     "CN",
+			"81.2.70.1":        "",
+			"::ffff:81.2.69.1": "US",
+			"2001:db9::1":      "",
+		}
+		for ip, want := range tests {
+			got, err := db.Country(net.ParseIP(ip))
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "%s with record size %d", ip, recordSize)
+		}
+	}
+}
+
+func TestReader_IPv4Database(t *testing.T) {
+	db, err := geoip.FromBytes(buildDatabase(t, 4, 24, []network{
+		{"81.2.69.0/24", mapOf(str("country"), mapOf(str("iso_code"), str("GB")))},
+	}))
+	require.NoError(t, err)
+
+	country, err := db.Country(net.ParseIP("81.2.69.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "GB", country)
+
+	country, err = db.Country(net.ParseIP("2001:db8::1"))
+	require.NoError(t, err)
+	assert.Empty(t, country, "IPv6 addresses are not in IPv4 databases")
+}
+
+func TestReader_Pointers(t *testing.T) {
+	// The first record starts the data section: its "country" key is at offset 1 and "iso_code" at offset 10.
+	// The second record reuses both keys through pointers, as database writers do.
+	db, err := geoip.FromBytes(buildDatabase(t, 4, 24, []network{
+		{"10.0.0.0/8", mapOf(str("country"), mapOf(str("iso_code"), str("FR")))},
+		{"11.0.0.0/8", mapOf(pointer(1), mapOf(pointer(10), str("BE")))},
+	}))
+	require.NoError(t, err)
+
+	for ip, want := range map[string]string{"10.1.2.3": "FR", "11.1.2.3": "BE"} {
+		country, err := db.Country(net.ParseIP(ip))
+		require.NoError(t, err)
+		assert.Equal(t, want, country, ip)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	require.NoError(t, os.WriteFile(path, buildDatabase(t, 6, 28, testNetworks()), 0o600))
+
+	db, err := geoip.Open(path)
+	require.NoError(t, err)
+	country, err := db.Country(net.ParseIP("81.2.69.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "US", country)
+
+	_, err = geoip.Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = geoip.FromBytes([]byte("not a database"))
+	assert.ErrorIs(t, err, geoip.ErrInvalidDatabase)
+}
@@ -2,28 +2,139 @@ package http
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/guttosm/url-shortener/config"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/guttosm/url-shortener/internal/auth"
 	"github.com/guttosm/url-shortener/internal/dto"
+	"github.com/guttosm/url-shortener/internal/entity"
 	"github.com/guttosm/url-shortener/internal/middleware"
 	"github.com/guttosm/url-shortener/internal/service"
 )
 
+// defaultStatsPeriod is the period covered by statistics when no start time is given.
+const defaultStatsPeriod = 7 * 24 * time.Hour
+
+// countryHeaders lists headers set by CDNs and load balancers that carry the client country.
+// They are only read on requests from trusted proxies, since clients could otherwise pick the country they match.
+var countryHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Country-Code"}
+
+// redirectControlParams lists the query parameters read by Redirect, which are never forwarded to destinations.
+var redirectControlParams = []string{"preview", "confirm", "password"}
+
+// variantCookie names the cookie remembering the variant a visitor was assigned to. It is scoped to the path
+// of the short URL, so each URL keeps its own assignment.
+const variantCookie = "ab_variant"
+
+// variantCookieMaxAge is the number of seconds a visitor keeps the variant they were assigned to.
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
 type Handler struct {
-	urlService service.URLService
-	authConfig config.AuthConfig
+	urlService  service.URLService
+	userService service.UserService
+	tokens      service.TokenService
+	analytics   service.AnalyticsService
+	apiKeys     service.APIKeyService
+	transfer    service.TransferService
+	domainRules service.DomainRuleService
+	previews    service.PreviewService
+	qrCodes     service.QRCodeService
+	countries   service.CountryLocator
+	domains     service.CustomDomainService
+	publicURL   *PublicURL
+}
+
+// HandlerOption configures optional dependencies of the Handler.
+type HandlerOption func(*Handler)
+
+// WithAnalytics enables click recording on redirects and the statistics endpoint.
+func WithAnalytics(a service.AnalyticsService) HandlerOption {
+	return func(h *Handler) {
+		h.analytics = a
+	}
+}
+
+// WithAPIKeys enables the API key management endpoints.
+func WithAPIKeys(s service.APIKeyService) HandlerOption {
+	return func(h *Handler) {
+		h.apiKeys = s
+	}
+}
+
+// WithTransfer enables the import and export endpoints.
+func WithTransfer(s service.TransferService) HandlerOption {
+	return func(h *Handler) {
+		h.transfer = s
+	}
+}
+
+// WithDomainRules enables the domain allow and deny list endpoints.
+func WithDomainRules(s service.DomainRuleService) HandlerOption {
+	return func(h *Handler) {
+		h.domainRules = s
+	}
+}
+
+// WithPreviews enables link previews and the interstitial page of links that ask for one.
+func WithPreviews(s service.PreviewService) HandlerOption {
+	return func(h *Handler) {
+		h.previews = s
+	}
+}
+
+// WithQRCodes enables the QR code endpoint.
+func WithQRCodes(s service.QRCodeService) HandlerOption {
+	return func(h *Handler) {
+		h.qrCodes = s
+	}
+}
+
+// WithGeoIP looks up the country of clients whose proxy does not report it, for redirect rules and analytics.
+func WithGeoIP(l service.CountryLocator) HandlerOption {
+	return func(h *Handler) {
+		h.countries = l
+	}
 }
 
-func NewHandler(s service.URLService, authCfg config.AuthConfig) *Handler {
-	return &Handler{
-		urlService: s,
-		authConfig: authCfg,
+// WithCustomDomains enables the custom domain endpoints and serves the short URLs of verified custom domains
+// to requests made on them.
+func WithCustomDomains(s service.CustomDomainService) HandlerOption {
+	return func(h *Handler) {
+		h.domains = s
 	}
 }
 
+// WithPublicURL sets how the absolute short URLs returned to clients are built, and which proxies are trusted
+// to report the host, scheme and country of clients. By default short URLs use the scheme and host of the
+// request and no proxy headers are trusted.
+func WithPublicURL(p *PublicURL) HandlerOption {
+	return func(h *Handler) {
+		h.publicURL = p
+	}
+}
+
+func NewHandler(s service.URLService, users service.UserService, tokens service.TokenService, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		urlService:  s,
+		userService: users,
+		tokens:      tokens,
+		publicURL:   &PublicURL{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
 // ShortenURL handles the shortening of a URL.
 func (h *Handler) ShortenURL(c *gin.Context) {
 	var req dto.ShortenRequest
@@ -33,19 +144,671 @@ func (h *Handler) ShortenURL(c *gin.Context) {
 		return
 	}
 
-	urlEntity, err := h.urlService.Shorten(context.Background(), req.URL)
+	opts := newShortenOptions(c, req)
+
+	urlEntity, err := h.urlService.Shorten(context.Background(), req.URL, opts)
 	if err != nil {
-		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to shorten URL", err)
+		status, msg := shortenErrorStatus(err)
+		middleware.AbortWithError(c, status, msg, err)
 		return
 	}
 
 	resp := dto.ShortenResponse{
 		ShortID:  urlEntity.ShortID,
-		ShortURL: c.Request.Host + "/" + urlEntity.ShortID,
+		ShortURL: h.shortURL(c, urlEntity.Domain, urlEntity.ShortID),
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// ShortenBatch shortens an array of URLs in one request.
+//
+// Behavior:
+// - The body is a JSON array of ShortenRequest items, at most service.MaxBatchSize long.
+// - Every item is validated on its own, so an invalid item is reported in its result instead of failing the request.
+// - Responds with 200 and one result per item, carrying the status the item would have received from ShortenURL.
+func (h *Handler) ShortenBatch(c *gin.Context) {
+	var reqs []dto.ShortenRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	if len(reqs) == 0 {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", errors.New("batch is empty"))
+		return
+	}
+	if len(reqs) > service.MaxBatchSize {
+		middleware.AbortWithError(c, http.StatusRequestEntityTooLarge, "Too many URLs", service.ErrBatchTooLarge)
+		return
+	}
+
+	resp := dto.BatchShortenResponse{Results: make([]dto.BatchShortenResult, len(reqs))}
+
+	// positions maps each item sent to the service back to its index in the request.
+	var items []service.BatchItem
+	var positions []int
+	for i := range reqs {
+		resp.Results[i].Index = i
+		if err := binding.Validator.ValidateStruct(&reqs[i]); err != nil {
+			setBatchError(&resp.Results[i], http.StatusBadRequest, "Invalid request", err)
+			continue
+		}
+		items = append(items, service.BatchItem{OriginalURL: reqs[i].URL, Options: newShortenOptions(c, reqs[i])})
+		positions = append(positions, i)
+	}
+
+	if len(items) > 0 {
+		results, err := h.urlService.ShortenBatch(context.Background(), items)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to shorten URLs", err)
+			return
+		}
+		for _, r := range results {
+			result := &resp.Results[positions[r.Index]]
+			if r.Err != nil {
+				status, msg := shortenErrorStatus(r.Err)
+				setBatchError(result, status, msg, r.Err)
+				continue
+			}
+			result.ShortID = r.URL.ShortID
+			result.ShortURL = h.shortURL(c, r.URL.Domain, r.URL.ShortID)
+			result.Status = http.StatusOK
+		}
+	}
+
+	for _, r := range resp.Results {
+		if r.Status == http.StatusOK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// newShortenOptions converts a shorten request to service options owned by the current user.
+func newShortenOptions(c *gin.Context, req dto.ShortenRequest) service.ShortenOptions {
+	return service.ShortenOptions{
+		OwnerID:      currentUserID(c),
+		Domain:       req.Domain,
+		Alias:        req.Alias,
+		RedirectType: req.RedirectType,
+		ExpiresAt:    req.ExpiresAt,
+		MaxClicks:    req.MaxClicks,
+		Dedupe:       req.Dedupe,
+		Password:     req.Password,
+		Interstitial: req.Interstitial,
+		ForwardQuery: req.ForwardQuery,
+		UTM: service.UTMParams{
+			Source:   req.UTMSource,
+			Medium:   req.UTMMedium,
+			Campaign: req.UTMCampaign,
+			Term:     req.UTMTerm,
+			Content:  req.UTMContent,
+		},
+		RedirectRules: dto.RedirectRulesToEntities(req.RedirectRules),
+		Variants:      dto.VariantsToEntities(req.Variants),
+	}
+}
+
+// shortenErrorStatus maps a shortening error to an HTTP status and message.
+func shortenErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAlias), errors.Is(err, service.ErrReservedAlias):
+		return http.StatusBadRequest, "Invalid alias"
+	case errors.Is(err, service.ErrInvalidExpiry):
+		return http.StatusBadRequest, "Invalid expiry"
+	case errors.Is(err, service.ErrInvalidDedupe):
+		return http.StatusBadRequest, "Invalid dedupe policy"
+	case errors.Is(err, service.ErrInvalidLinkPassword):
+		return http.StatusBadRequest, "Invalid password"
+	case errors.Is(err, service.ErrInvalidRedirectRule):
+		return http.StatusBadRequest, "Invalid redirect rule"
+	case errors.Is(err, service.ErrInvalidVariant):
+		return http.StatusBadRequest, "Invalid variants"
+	case errors.Is(err, service.ErrCustomDomainNotFound):
+		return http.StatusBadRequest, "Unknown custom domain"
+	case errors.Is(err, service.ErrCustomDomainNotVerified):
+		return http.StatusBadRequest, "Custom domain is not verified"
+	case errors.Is(err, service.ErrAliasTaken):
+		return http.StatusConflict, "Alias already taken"
+	case errors.Is(err, service.ErrDestinationBlocked):
+		return http.StatusUnprocessableEntity, "Destination not allowed"
+	default:
+		return http.StatusInternalServerError, "Failed to shorten URL"
+	}
+}
+
+// setBatchError records a failed item in a batch result.
+func setBatchError(result *dto.BatchShortenResult, status int, msg string, err error) {
+	result.Status = status
+	result.Message = msg
+	result.Error = err.Error()
+}
+
+// Redirect resolves a shortened ID and redirects the client to the original URL.
+//
+// Behavior:
+// - Requests made on a verified custom domain resolve the IDs of that domain; any other host resolves the default ones.
+// - "/<shortID>+" and "?preview=1" show the preview page instead of redirecting.
+// - Interstitial URLs show the preview page until the visitor follows its "?confirm=1" link.
+// - Password-protected URLs are only redirected once the password is verified, see redirectProtected.
+// - Redirect rules and query forwarding decide the destination, see follow.
+func (h *Handler) Redirect(c *gin.Context) {
+	domain, err := h.requestDomain(c)
+	if err != nil {
+		abortWithResolveError(c, err)
+		return
+	}
+
+	shortID, preview := strings.CutSuffix(c.Param("shortID"), "+")
+	if preview || c.Query("preview") == "1" {
+		h.preview(c, domain, shortID)
+		return
+	}
+
+	urlEntity, err := h.urlService.Resolve(context.Background(), domain, shortID)
+	if errors.Is(err, service.ErrConfirmationRequired) {
+		if h.previews != nil && c.Query("confirm") != "1" {
+			h.preview(c, domain, shortID)
+			return
+		}
+		h.redirectProtected(c, domain, shortID)
+		return
+	}
+	if errors.Is(err, service.ErrPasswordRequired) {
+		h.redirectProtected(c, domain, shortID)
+		return
+	}
+	if err != nil {
+		abortWithResolveError(c, err)
+		return
+	}
+
+	h.follow(c, urlEntity, urlEntity.RedirectStatus())
+}
+
+// redirectProtected checks the password of a protected URL and redirects once it matches.
+// Confirmed interstitial URLs without a password are redirected right away.
+//
+// Behavior:
+// - API clients send the password in the X-Link-Password header or the "password" query parameter.
+// - Browsers are served an HTML form posting the password back to the short URL.
+// - A missing or wrong password is answered with 401, a URL locked after too many wrong passwords with 429 and Retry-After.
+// - Form posts are redirected with 303, so the browser does not post the password to the destination.
+func (h *Handler) redirectProtected(c *gin.Context, domain, shortID string) {
+	password := c.GetHeader(passwordHeader)
+	if password == "" {
+		password = c.Query("password")
+	}
+	if password == "" && c.Request.Method == http.MethodPost {
+		password = c.PostForm("password")
+	}
+
+	urlEntity, err := h.urlService.Unlock(context.Background(), domain, shortID, password)
+	if err != nil {
+		var attemptsErr *service.AttemptsError
+		switch {
+		case errors.Is(err, service.ErrPasswordRequired):
+			challengePassword(c, http.StatusUnauthorized, "Password required", "")
+		case errors.Is(err, service.ErrWrongPassword):
+			challengePassword(c, http.StatusUnauthorized, "Wrong password", "The password is not correct.")
+		case errors.As(err, &attemptsErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(attemptsErr.RetryAfter.Seconds()))))
+			challengePassword(c, http.StatusTooManyRequests, "Too many password attempts", "Too many wrong passwords. Try again later.")
+		default:
+			abortWithResolveError(c, err)
+		}
+		return
+	}
+
+	status := urlEntity.RedirectStatus()
+	if c.Request.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	h.follow(c, urlEntity, status)
+}
+
+// follow records a click and redirects to the destination of a resolved URL.
+// The first redirect rule matching the client's user agent, language, country and the current time
+// replaces the original URL. Otherwise URLs with variants send the client to the variant named by its
+// variant cookie, or assign one from a hash of its IP and user agent and set the cookie.
+// URLs forwarding their query string receive the query parameters of the visit,
+// except the preview, confirm and password parameters read by the redirect itself.
+func (h *Handler) follow(c *gin.Context, urlEntity *entity.URL, status int) {
+	query := c.Request.URL.Query()
+	for _, name := range redirectControlParams {
+		query.Del(name)
+	}
+	sticky, _ := c.Cookie(variantCookie)
+	visit := service.Visit{
+		Query:          query,
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Country:        h.clientCountry(c),
+		Time:           time.Now(),
+		IP:             c.ClientIP(),
+		Variant:        sticky,
+	}
+	destination, variant := service.RedirectURL(urlEntity, visit)
+	if variant != "" && variant != sticky {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(variantCookie, variant, variantCookieMaxAge, c.Request.URL.Path, "", c.Request.TLS != nil, true)
+	}
+
+	h.recordClick(c, urlEntity, visit.Country, destination, variant)
+	c.Redirect(status, destination)
+}
+
+// preview renders the HTML page showing where a short URL leads, without redirecting or counting a click.
+// The destination of password-protected URLs is not shown.
+func (h *Handler) preview(c *gin.Context, domain, shortID string) {
+	if h.previews == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Previews are not enabled", nil)
+		return
+	}
+
+	preview, err := h.previews.Preview(context.Background(), domain, shortID)
+	if err != nil {
+		abortWithResolveError(c, err)
+		return
+	}
+	renderPage(c, http.StatusOK, previewPage, previewData{
+		LinkPreview: preview,
+		ShortURL:    h.shortURL(c, preview.Domain, preview.ShortID),
+		ContinueURL: preview.ShortID + "?confirm=1",
+	})
+}
+
+// requestDomain returns the custom domain a redirect request was made on, or an empty string for the default domain.
+func (h *Handler) requestDomain(c *gin.Context) (string, error) {
+	if h.domains == nil {
+		return "", nil
+	}
+	host := c.Request.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return h.domains.Resolve(c.Request.Context(), host)
+}
+
+// abortWithResolveError maps an error resolving a short URL for a redirect to an HTTP status.
+func abortWithResolveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		middleware.AbortWithError(c, http.StatusNotFound, "Short URL not found", nil)
+	case errors.Is(err, service.ErrExpired):
+		middleware.AbortWithError(c, http.StatusGone, "Short URL has expired", nil)
+	default:
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to resolve URL", err)
+	}
+}
+
+// Stats returns click statistics for a shortened URL.
+//
+// Query parameters:
+// - domain: The custom domain of the URL (default the domain of the service).
+// - bucket: "hour" or "day" (default "day").
+// - from, to: RFC 3339 bounds of the period (default the last 7 days). The period never starts before the URL was
+// created, and must not be empty.
+func (h *Handler) Stats(c *gin.Context) {
+	if h.analytics == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Analytics is not enabled", nil)
+		return
+	}
+
+	shortID, domain := c.Param("shortID"), linkDomain(c)
+	url, err := h.urlService.Get(context.Background(), currentUserID(c), domain, shortID)
+	if err != nil {
+		abortWithURLError(c, err)
+		return
+	}
+	bucket := c.DefaultQuery("bucket", service.BucketDay)
+
+	to := time.Now().UTC()
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'to' parameter", err)
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultStatsPeriod)
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'from' parameter", err)
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		middleware.AbortWithError(c, http.StatusBadRequest, "'from' must be before 'to'", nil)
+		return
+	}
+	// Clicks recorded before the URL was created belong to a deleted URL that used the same short ID.
+	if from.Before(url.CreatedAt) {
+		from = url.CreatedAt
+	}
+	if !from.Before(to) {
+		middleware.AbortWithError(c, http.StatusBadRequest, "'to' must be after the URL was created", nil)
+		return
+	}
+
+	stats, err := h.analytics.Stats(context.Background(), domain, shortID, bucket, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBucket) {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'bucket' parameter", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to load statistics", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewStatsResponse(shortID, bucket, from, to, stats))
+}
+
+// QRCode returns a QR code encoding the short URL of a URL owned by the current user.
+//
+// Query parameters:
+// - domain: The custom domain of the URL (default the domain of the service).
+// - format: "png" or "svg" (default "png").
+// - size: The width and height of the image in pixels (default 256).
+// - level: The error correction level, "L", "M", "Q" or "H" (default "M").
+// - margin: The quiet zone around the code in modules (default 4).
+// - fg, bg: The hex colors of dark and light modules (default "#000000" and "#ffffff").
+func (h *Handler) QRCode(c *gin.Context) {
+	if h.qrCodes == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "QR codes are not enabled", nil)
+		return
+	}
+
+	urlEntity, err := h.urlService.Get(context.Background(), currentUserID(c), linkDomain(c), c.Param("shortID"))
+	if err != nil {
+		abortWithURLError(c, err)
+		return
+	}
+
+	size, err := queryInt(c, "size")
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'size' parameter", err)
+		return
+	}
+	opts := service.QRCodeOptions{
+		Format:     c.Query("format"),
+		Size:       size,
+		Level:      c.Query("level"),
+		Foreground: c.Query("fg"),
+		Background: c.Query("bg"),
+	}
+	if v, ok := c.GetQuery("margin"); ok {
+		margin, err := strconv.Atoi(v)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'margin' parameter", err)
+			return
+		}
+		opts.Margin = &margin
+	}
+
+	image, err := h.qrCodes.Render(context.Background(), urlEntity.Key(), h.shortURL(c, urlEntity.Domain, urlEntity.ShortID), opts)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidQRCodeOptions) {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid QR code options", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to render QR code", err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, image.ContentType, image.Data)
+}
+
+// ListURLs returns a page of the URLs owned by the current user.
+//
+// Query parameters:
+// - domain: Only URLs on this custom domain.
+// - q: Only URLs whose short ID or original URL contains this text.
+// - sort: The field to sort by, prefixed with "-" for descending order (default "-created_at").
+// - page, page_size: The 1-based page number and page size (default 1 and 20).
+func (h *Handler) ListURLs(c *gin.Context) {
+	page, err := queryInt(c, "page")
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'page' parameter", err)
+		return
+	}
+	pageSize, err := queryInt(c, "page_size")
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'page_size' parameter", err)
+		return
+	}
+
+	result, err := h.urlService.List(context.Background(), currentUserID(c), service.ListOptions{
+		Domain:   linkDomain(c),
+		Query:    c.Query("q"),
+		Sort:     c.Query("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSort) {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid 'sort' parameter", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to list URLs", err)
+		return
+	}
+
+	resp := dto.URLListResponse{
+		Items:    make([]dto.URLResponse, 0, len(result.Items)),
+		Page:     result.Page,
+		PageSize: result.PageSize,
+		Total:    result.Total,
+	}
+	for _, u := range result.Items {
+		resp.Items = append(resp.Items, h.newURLResponse(c, u))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetURL returns a URL owned by the current user. URLs on a custom domain are selected with the "domain" query parameter.
+func (h *Handler) GetURL(c *gin.Context) {
+	urlEntity, err := h.urlService.Get(context.Background(), currentUserID(c), linkDomain(c), c.Param("shortID"))
+	if err != nil {
+		abortWithURLError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.newURLResponse(c, urlEntity))
+}
+
+// UpdateURL changes the destination or expiry of a URL owned by the current user.
+// URLs on a custom domain are selected with the "domain" query parameter.
+func (h *Handler) UpdateURL(c *gin.Context) {
+	var req dto.UpdateURLRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	urlEntity, err := h.urlService.Update(context.Background(), currentUserID(c), linkDomain(c), c.Param("shortID"), service.UpdateOptions{
+		Original:      req.URL,
+		ExpiresAt:     req.ExpiresAt,
+		RemoveExpiry:  req.RemoveExpiry,
+		Interstitial:  req.Interstitial,
+		ForwardQuery:  req.ForwardQuery,
+		RedirectRules: updatedRedirectRules(req.RedirectRules),
+		Variants:      updatedVariants(req.Variants),
+	})
+	if err != nil {
+		abortWithURLError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.newURLResponse(c, urlEntity))
+}
+
+// DeleteURL removes a URL owned by the current user. URLs on a custom domain are selected with the "domain" query parameter.
+func (h *Handler) DeleteURL(c *gin.Context) {
+	if err := h.urlService.Delete(context.Background(), currentUserID(c), linkDomain(c), c.Param("shortID")); err != nil {
+		abortWithURLError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// updatedRedirectRules converts the redirect rules of an update request, keeping nil as "unchanged"
+// and an empty list as "remove the rules".
+func updatedRedirectRules(rules *[]dto.RedirectRule) *[]entity.RedirectRule {
+	if rules == nil {
+		return nil
+	}
+	converted := dto.RedirectRulesToEntities(*rules)
+	if converted == nil {
+		converted = []entity.RedirectRule{}
+	}
+	return &converted
+}
+
+// updatedVariants converts the variants of an update request, keeping nil as "unchanged"
+// and an empty list as "remove the variants".
+func updatedVariants(variants *[]dto.Variant) *[]entity.Variant {
+	if variants == nil {
+		return nil
+	}
+	converted := dto.VariantsToEntities(*variants)
+	if converted == nil {
+		converted = []entity.Variant{}
+	}
+	return &converted
+}
+
+// abortWithURLError maps URL management errors to HTTP responses.
+func abortWithURLError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		middleware.AbortWithError(c, http.StatusNotFound, "Short URL not found", nil)
+	case errors.Is(err, service.ErrForbidden):
+		middleware.AbortWithError(c, http.StatusForbidden, "Access to this short URL is denied", nil)
+	case errors.Is(err, service.ErrInvalidExpiry):
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid expiry", err)
+	case errors.Is(err, service.ErrInvalidRedirectRule):
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid redirect rule", err)
+	case errors.Is(err, service.ErrInvalidVariant):
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid variants", err)
+	case errors.Is(err, service.ErrDestinationBlocked):
+		middleware.AbortWithError(c, http.StatusUnprocessableEntity, "Destination not allowed", err)
+	default:
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to process short URL", err)
+	}
+}
+
+// newURLResponse converts a URL entity to its management API representation.
+func (h *Handler) newURLResponse(c *gin.Context, u *entity.URL) dto.URLResponse {
+	return dto.URLResponse{
+		ShortID:           u.ShortID,
+		ShortURL:          h.shortURL(c, u.Domain, u.ShortID),
+		Domain:            u.Domain,
+		OriginalURL:       u.Original,
+		CanonicalURL:      u.Canonical,
+		Alias:             u.Alias,
+		PasswordProtected: u.IsProtected(),
+		Interstitial:      u.Interstitial,
+		ForwardQuery:      u.ForwardQuery,
+		RedirectRules:     dto.NewRedirectRules(u.RedirectRules),
+		Variants:          dto.NewVariants(u.Variants),
+		RedirectType:      u.RedirectStatus(),
+		ExpiresAt:         u.ExpiresAt,
+		MaxClicks:         u.MaxClicks,
+		Clicks:            h.clickCount(c.Request.Context(), u),
+		CreatedAt:         u.CreatedAt,
+		UpdatedAt:         u.UpdatedAt,
+	}
+}
+
+// clickCount returns the number of redirects of a URL recorded by analytics since it was created, like its preview
+// page does. Without analytics, or if counting fails, it falls back to the redirects counted against MaxClicks.
+func (h *Handler) clickCount(ctx context.Context, u *entity.URL) int64 {
+	if h.analytics == nil {
+		return u.Clicks
+	}
+	clicks, err := h.analytics.Count(ctx, u.Domain, u.ShortID, u.CreatedAt)
+	if err != nil {
+		log.Printf("failed to count clicks of %s: %v", u.Key(), err)
+		return u.Clicks
+	}
+	return clicks
+}
+
+// shortURL builds the absolute public short URL for a shortened ID on a domain.
+func (h *Handler) shortURL(c *gin.Context, domain, shortID string) string {
+	return h.publicURL.ShortURL(c.Request, domain, shortID)
+}
+
+// linkDomain returns the custom domain selected by the "domain" query parameter of a management request,
+// or an empty string for the default domain.
+func linkDomain(c *gin.Context) string {
+	return strings.ToLower(strings.TrimSpace(c.Query("domain")))
+}
+
+// currentUserID returns the authenticated user's ID set by AuthMiddleware, or an empty string.
+func currentUserID(c *gin.Context) string {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(string)
+	return id
+}
+
+// queryInt parses an optional integer query parameter, returning 0 when it is absent.
+func queryInt(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// recordClick queues a click event for the visited URL if analytics is enabled,
+// attributing it to the campaign of the destination it was redirected to and to the variant it was assigned to.
+func (h *Handler) recordClick(c *gin.Context, urlEntity *entity.URL, country, destination, variant string) {
+	if h.analytics == nil {
+		return
+	}
+	h.analytics.Record(service.ClickInfo{
+		ShortID:   urlEntity.ShortID,
+		Domain:    urlEntity.Domain,
+		IP:        c.ClientIP(),
+		Referrer:  c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		Country:   country,
+		Campaign:  service.CampaignOf(destination),
+		Variant:   variant,
+	})
+}
+
+// clientCountry returns the client country code reported by a trusted proxy, or else the one
+// found in the GeoIP database, if any. Failed lookups are logged and treated as unknown.
+func (h *Handler) clientCountry(c *gin.Context) string {
+	if h.publicURL.trusted(c.Request) {
+		for _, header := range countryHeaders {
+			if v := c.GetHeader(header); v != "" {
+				return v
+			}
+		}
+	}
+	if h.countries == nil {
+		return ""
+	}
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		return ""
+	}
+	country, err := h.countries.Country(ip)
+	if err != nil {
+		log.Printf("GeoIP lookup failed for %s: %v", ip, err)
+		return ""
+	}
+	return country
+}
+
 // Login handles user login and generates a JWT token.
 func (h *Handler) Login(c *gin.Context) {
 	var req dto.LoginRequest
@@ -55,16 +818,560 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	if req.Username != h.authConfig.Username || req.Password != h.authConfig.Password {
-		middleware.AbortWithError(c, http.StatusUnauthorized, "Invalid credentials", nil)
+	user, err := h.userService.Authenticate(context.Background(), req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			middleware.AbortWithError(c, http.StatusUnauthorized, "Invalid credentials", nil)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to authenticate", err)
 		return
 	}
 
-	token, err := auth.GenerateToken(h.authConfig.UserID)
+	pair, err := h.tokens.Issue(c.Request.Context(), user)
 	if err != nil {
 		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to generate token", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, newTokenResponse(pair))
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req dto.RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	pair, err := h.tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			middleware.AbortWithError(c, http.StatusUnauthorized, "Invalid or expired refresh token", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to refresh token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTokenResponse(pair))
+}
+
+// Logout revokes the access token of the request and, if given, a refresh token.
+func (h *Handler) Logout(c *gin.Context) {
+	var req dto.LogoutRequest
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+			return
+		}
+	}
+
+	claims, _ := c.Get("token_claims")
+	accessClaims, ok := claims.(map[string]interface{})
+	if !ok {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Logout requires a bearer token", nil)
+		return
+	}
+
+	if err := h.tokens.Logout(c.Request.Context(), accessClaims, req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid refresh token", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to log out", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newTokenResponse converts a token pair to its API representation.
+func newTokenResponse(pair *service.TokenPair) dto.TokenResponse {
+	return dto.TokenResponse{
+		Token:            pair.AccessToken,
+		AccessToken:      pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(time.Until(pair.AccessExpiresAt).Seconds()),
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+	}
+}
+
+// JWKS publishes the public keys that verify issued tokens.
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.JWKS())
+}
+
+// Register creates a new user account.
+func (h *Handler) Register(c *gin.Context) {
+	var req dto.RegisterRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	user, err := h.userService.Register(context.Background(), req.Username, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUsernameTaken):
+			middleware.AbortWithError(c, http.StatusConflict, "Username already taken", err)
+		case errors.Is(err, service.ErrInvalidPassword):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid password", err)
+		default:
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to register user", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newUserResponse(user))
+}
+
+// SetUserRole assigns a role to a user. Only available to admins.
+func (h *Handler) SetUserRole(c *gin.Context) {
+	var req dto.SetRoleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	user, err := h.userService.SetRole(c.Request.Context(), c.Param("id"), req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRole):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid role", err)
+		case errors.Is(err, service.ErrUserNotFound):
+			middleware.AbortWithError(c, http.StatusNotFound, "User not found", err)
+		default:
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to assign role", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// newUserResponse converts a user entity to its API representation.
+func newUserResponse(user *entity.User) dto.UserResponse {
+	role := user.Role
+	if role == "" {
+		role = auth.DefaultRole
+	}
+	return dto.UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      role,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// ListDomainRules lists the domain allow and deny rules. Only available to admins.
+func (h *Handler) ListDomainRules(c *gin.Context) {
+	if h.domainRules == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Domain rules are not enabled", nil)
+		return
+	}
+
+	rules, err := h.domainRules.List(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to list domain rules", err)
+		return
+	}
+
+	items := make([]dto.DomainRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		items = append(items, newDomainRuleResponse(rule))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// PutDomainRule allows or denies the domain in the path and its subdomains. Only available to admins.
+func (h *Handler) PutDomainRule(c *gin.Context) {
+	if h.domainRules == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Domain rules are not enabled", nil)
+		return
+	}
+
+	var req dto.DomainRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	rule, err := h.domainRules.Put(c.Request.Context(), c.Param("domain"), req.Action, req.Reason, currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDomain), errors.Is(err, service.ErrInvalidDomainAction):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid domain rule", err)
+		default:
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to save domain rule", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newDomainRuleResponse(rule))
+}
+
+// DeleteDomainRule removes the rule of the domain in the path. Only available to admins.
+func (h *Handler) DeleteDomainRule(c *gin.Context) {
+	if h.domainRules == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Domain rules are not enabled", nil)
+		return
+	}
+
+	if err := h.domainRules.Delete(c.Request.Context(), c.Param("domain")); err != nil {
+		if errors.Is(err, service.ErrDomainRuleNotFound) {
+			middleware.AbortWithError(c, http.StatusNotFound, "Domain rule not found", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to delete domain rule", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newDomainRuleResponse converts a domain rule entity to its API representation.
+func newDomainRuleResponse(rule *entity.DomainRule) dto.DomainRuleResponse {
+	return dto.DomainRuleResponse{
+		Domain:    rule.Domain,
+		Action:    rule.Action,
+		Reason:    rule.Reason,
+		CreatedBy: rule.CreatedBy,
+		CreatedAt: rule.CreatedAt,
+	}
+}
+
+// CreateCustomDomain registers a custom domain for the authenticated user.
+//
+// Behavior:
+// - Responds with 201 and the DNS TXT record the user must publish before verifying the domain.
+// - A domain registered by any user is rejected with 409.
+func (h *Handler) CreateCustomDomain(c *gin.Context) {
+	if h.domains == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Custom domains are not enabled", nil)
+		return
+	}
+
+	var req dto.CustomDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	domain, err := h.domains.Register(c.Request.Context(), currentUserID(c), req.Domain)
+	if err != nil {
+		abortWithCustomDomainError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCustomDomainResponse(domain))
+}
+
+// ListCustomDomains lists the custom domains of the authenticated user.
+func (h *Handler) ListCustomDomains(c *gin.Context) {
+	if h.domains == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Custom domains are not enabled", nil)
+		return
+	}
+
+	domains, err := h.domains.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to list custom domains", err)
+		return
+	}
+
+	items := make([]dto.CustomDomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		items = append(items, newCustomDomainResponse(domain))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// VerifyCustomDomain checks the DNS TXT record of a custom domain of the authenticated user.
+// A missing record is answered with 422, so the user can publish it and try again.
+func (h *Handler) VerifyCustomDomain(c *gin.Context) {
+	if h.domains == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Custom domains are not enabled", nil)
+		return
+	}
+
+	domain, err := h.domains.Verify(c.Request.Context(), currentUserID(c), c.Param("domain"))
+	if err != nil {
+		abortWithCustomDomainError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCustomDomainResponse(domain))
+}
+
+// DeleteCustomDomain removes a custom domain of the authenticated user. Domains with short URLs are kept.
+func (h *Handler) DeleteCustomDomain(c *gin.Context) {
+	if h.domains == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Custom domains are not enabled", nil)
+		return
+	}
+
+	if err := h.domains.Delete(c.Request.Context(), currentUserID(c), c.Param("domain")); err != nil {
+		abortWithCustomDomainError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// abortWithCustomDomainError maps custom domain management errors to HTTP responses.
+func abortWithCustomDomainError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDomain):
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid domain", err)
+	case errors.Is(err, service.ErrCustomDomainNotFound):
+		middleware.AbortWithError(c, http.StatusNotFound, "Custom domain not found", err)
+	case errors.Is(err, service.ErrCustomDomainTaken):
+		middleware.AbortWithError(c, http.StatusConflict, "Custom domain already registered", err)
+	case errors.Is(err, service.ErrCustomDomainInUse):
+		middleware.AbortWithError(c, http.StatusConflict, "Custom domain still has short URLs", err)
+	case errors.Is(err, service.ErrDomainVerificationFailed):
+		middleware.AbortWithError(c, http.StatusUnprocessableEntity, "Verification record not found", err)
+	default:
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to process custom domain", err)
+	}
+}
+
+// newCustomDomainResponse converts a custom domain entity to its API representation.
+func newCustomDomainResponse(domain *entity.CustomDomain) dto.CustomDomainResponse {
+	challenge := service.ChallengeFor(domain)
+	return dto.CustomDomainResponse{
+		Domain:   domain.Host,
+		Verified: domain.IsVerified(),
+		VerificationRecord: dto.DomainVerificationRecord{
+			Type:  "TXT",
+			Name:  challenge.Name,
+			Value: challenge.Value,
+		},
+		CreatedAt:  domain.CreatedAt,
+		VerifiedAt: domain.VerifiedAt,
+	}
+}
+
+// CreateAPIKey creates an API key for the authenticated user. The key is only returned by this call.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	if h.apiKeys == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "API keys are not enabled", nil)
+		return
+	}
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	key, raw, err := h.apiKeys.Create(c.Request.Context(), currentUserID(c), req.Name, req.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKeyName):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid name", err)
+		case errors.Is(err, service.ErrInvalidScope):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid scope", err)
+		case errors.Is(err, service.ErrScopeNotGranted):
+			middleware.AbortWithError(c, http.StatusForbidden, "Scope exceeds your role", err)
+		case errors.Is(err, service.ErrUserNotFound):
+			middleware.AbortWithError(c, http.StatusUnauthorized, "User no longer exists", err)
+		default:
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to create API key", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CreatedAPIKeyResponse{
+		APIKeyResponse: newAPIKeyResponse(key),
+		Key:            raw,
+	})
+}
+
+// ListAPIKeys lists the API keys of the authenticated user.
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	if h.apiKeys == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "API keys are not enabled", nil)
+		return
+	}
+
+	keys, err := h.apiKeys.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to list API keys", err)
+		return
+	}
+
+	items := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, newAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// RevokeAPIKey revokes an API key of the authenticated user.
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	if h.apiKeys == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "API keys are not enabled", nil)
+		return
+	}
+
+	if err := h.apiKeys.Revoke(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			middleware.AbortWithError(c, http.StatusNotFound, "API key not found", err)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to revoke API key", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newAPIKeyResponse converts an API key entity to its API representation.
+func newAPIKeyResponse(key *entity.APIKey) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// ImportURLs imports URL mappings from a CSV or JSONL request body, keeping their short IDs.
+//
+// Query parameters:
+// - format: "csv" or "jsonl" (default derived from the Content-Type, "text/csv" meaning CSV).
+// - on_conflict: "skip" or "overwrite" (default "skip").
+//
+// Behavior:
+// - Invalid options and CSV headers are rejected with 400 before anything is imported.
+// - Otherwise responds with 200 and a newline-delimited JSON stream: one progress line per stored batch,
+// then a final line with Done set, the failed rows and, if the import stopped early, the error.
+func (h *Handler) ImportURLs(c *gin.Context) {
+	if h.transfer == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Import is not enabled", nil)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = service.FormatJSONL
+		if c.ContentType() == "text/csv" {
+			format = service.FormatCSV
+		}
+	}
+
+	started := false
+	writeLine := func(resp dto.ImportProgressResponse) {
+		if !started {
+			started = true
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+		}
+		_ = json.NewEncoder(c.Writer).Encode(resp)
+		c.Writer.Flush()
+	}
+
+	report, err := h.transfer.Import(c.Request.Context(), c.Request.Body, service.ImportOptions{
+		Format:     format,
+		OnConflict: c.DefaultQuery("on_conflict", service.ConflictSkip),
+		Progress: func(r service.ImportReport) {
+			writeLine(newImportProgressResponse(r))
+		},
+	})
+	if err != nil && !started {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedFormat),
+			errors.Is(err, service.ErrInvalidConflictMode),
+			errors.Is(err, service.ErrMissingImportColumns):
+			middleware.AbortWithError(c, http.StatusBadRequest, "Invalid import", err)
+			return
+		case report == nil:
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to import URLs", err)
+			return
+		}
+	}
+
+	resp := newImportProgressResponse(*report)
+	resp.Done = true
+	for _, rowErr := range report.Errors {
+		resp.Errors = append(resp.Errors, dto.ImportRowErrorResponse{
+			Line:    rowErr.Line,
+			ShortID: rowErr.ShortID,
+			Error:   rowErr.Err.Error(),
+		})
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeLine(resp)
+}
+
+// newImportProgressResponse converts the running totals of an import to a progress line.
+func newImportProgressResponse(r service.ImportReport) dto.ImportProgressResponse {
+	return dto.ImportProgressResponse{
+		Processed:   r.Processed,
+		Imported:    r.Imported,
+		Overwritten: r.Overwritten,
+		Skipped:     r.Skipped,
+		Failed:      r.Failed,
+	}
+}
+
+// ExportURLs streams every URL mapping as CSV or JSONL.
+//
+// Query parameters:
+// - format: "csv" or "jsonl" (default "jsonl").
+// - owner_id: If set, only the URLs of this user are exported.
+//
+// Behavior:
+// - URLs are written as they are read from the database, so the export is never held in memory.
+// - An error after the first bytes were sent cannot change the status, so it is logged and the stream is cut short.
+func (h *Handler) ExportURLs(c *gin.Context) {
+	if h.transfer == nil {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "Export is not enabled", nil)
+		return
+	}
+
+	format := c.DefaultQuery("format", service.FormatJSONL)
+	var contentType string
+	switch format {
+	case service.FormatCSV:
+		contentType = "text/csv"
+	case service.FormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		middleware.AbortWithError(c, http.StatusBadRequest, "Invalid export", service.ErrUnsupportedFormat)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="urls.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	_, err := h.transfer.Export(c.Request.Context(), c.Writer, service.ExportOptions{
+		Format:  format,
+		OwnerID: c.Query("owner_id"),
+	})
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			middleware.AbortWithError(c, http.StatusInternalServerError, "Failed to export URLs", err)
+			return
+		}
+		log.Printf("export of URLs stopped early: %v", err)
+		c.Abort()
+	}
 }
@@ -5,40 +5,89 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/guttosm/url-shortener/config"
 	"github.com/guttosm/url-shortener/internal/dto"
 	"github.com/guttosm/url-shortener/internal/entity"
 	apphttp "github.com/guttosm/url-shortener/internal/http"
+	"github.com/guttosm/url-shortener/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// Mock para URLService
+// Mock para URLService. Short IDs on a custom domain reach the funcs as their entity.LinkKey.
 type mockURLServiceHandlerTest struct {
-	shortenFunc       func(context.Context, string) (*entity.URL, error)
-	findByShortIDFunc func(context.Context, string) (*entity.URL, error)
+	shortenFunc      func(context.Context, string, service.ShortenOptions) (*entity.URL, error)
+	shortenBatchFunc func(context.Context, []service.BatchItem) ([]service.BatchResult, error)
+	resolveFunc      func(context.Context, string) (*entity.URL, error)
+	unlockFunc       func(context.Context, string, string) (*entity.URL, error)
+	peekFunc         func(context.Context, string) (*entity.URL, error)
+	getFunc          func(context.Context, string, string) (*entity.URL, error)
+	listFunc         func(context.Context, string, service.ListOptions) (*service.URLPage, error)
+	updateFunc       func(context.Context, string, string, service.UpdateOptions) (*entity.URL, error)
+	deleteFunc       func(context.Context, string, string) error
 }
 
-func (m *mockURLServiceHandlerTest) Shorten(ctx context.Context, originalURL string) (*entity.URL, error) {
-	return m.shortenFunc(ctx, originalURL)
+func (m *mockURLServiceHandlerTest) Shorten(ctx context.Context, originalURL string, opts service.ShortenOptions) (*entity.URL, error) {
+	return m.shortenFunc(ctx, originalURL, opts)
 }
 
-func (m *mockURLServiceHandlerTest) FindByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
-	return m.findByShortIDFunc(ctx, shortID)
+func (m *mockURLServiceHandlerTest) ShortenBatch(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+	return m.shortenBatchFunc(ctx, items)
+}
+
+func (m *mockURLServiceHandlerTest) Resolve(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	return m.resolveFunc(ctx, entity.LinkKey(domain, shortID))
+}
+
+func (m *mockURLServiceHandlerTest) Unlock(ctx context.Context, domain, shortID, password string) (*entity.URL, error) {
+	return m.unlockFunc(ctx, entity.LinkKey(domain, shortID), password)
+}
+
+func (m *mockURLServiceHandlerTest) Peek(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	return m.peekFunc(ctx, entity.LinkKey(domain, shortID))
+}
+
+func (m *mockURLServiceHandlerTest) Get(ctx context.Context, ownerID, domain, shortID string) (*entity.URL, error) {
+	return m.getFunc(ctx, ownerID, entity.LinkKey(domain, shortID))
+}
+
+func (m *mockURLServiceHandlerTest) List(ctx context.Context, ownerID string, opts service.ListOptions) (*service.URLPage, error) {
+	return m.listFunc(ctx, ownerID, opts)
+}
+
+func (m *mockURLServiceHandlerTest) Update(ctx context.Context, ownerID, domain, shortID string, opts service.UpdateOptions) (*entity.URL, error) {
+	return m.updateFunc(ctx, ownerID, entity.LinkKey(domain, shortID), opts)
+}
+
+func (m *mockURLServiceHandlerTest) Delete(ctx context.Context, ownerID, domain, shortID string) error {
+	return m.deleteFunc(ctx, ownerID, entity.LinkKey(domain, shortID))
+}
+
+// withUserID simulates AuthMiddleware by setting the authenticated user ID.
+func withUserID(userID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
 }
 
 func TestHandler_Login(t *testing.T) {
-	authCfg := config.AuthConfig{
-		Username: "admin",
-		Password: "secret",
-		UserID:   "user123",
+	users := &mockUserService{
+		username: "admin",
+		password: "secret",
+		userID:   "user123",
 	}
 
-	handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, authCfg)
+	handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, users, &mockTokenService{})
 
 	router := gin.Default()
 	router.POST("/login", handler.Login)
@@ -79,11 +128,69 @@ func TestHandler_Login(t *testing.T) {
 	})
 }
 
+func TestHandler_Register(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(register func(context.Context, string, string) (*entity.User, error)) *gin.Engine {
+		handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, &mockUserService{registerFunc: register}, &mockTokenService{})
+		router := gin.New()
+		router.POST("/register", handler.Register)
+		return router
+	}
+
+	t.Run("invalid request", func(t *testing.T) {
+		router := newRouter(nil)
+
+		body, _ := json.Marshal(dto.RegisterRequest{Username: "al", Password: "short"})
+		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("username taken", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, username, password string) (*entity.User, error) {
+			return nil, service.ErrUsernameTaken
+		})
+
+		body, _ := json.Marshal(dto.RegisterRequest{Username: "alice", Password: "correct-horse"})
+		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, username, password string) (*entity.User, error) {
+			assert.Equal(t, "alice", username)
+			assert.Equal(t, "correct-horse", password)
+			return &entity.User{ID: "user456", Username: username}, nil
+		})
+
+		body, _ := json.Marshal(dto.RegisterRequest{Username: "alice", Password: "correct-horse"})
+		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), "user456")
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+}
+
 func TestHandler_ShortenURL(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	t.Run("invalid JSON", func(t *testing.T) {
-		handler := apphttp.NewHandler(&mockURLService{}, config.AuthConfig{})
+		handler := apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{})
 		router := gin.Default()
 		router.POST("/shorten", handler.ShortenURL)
 
@@ -99,11 +206,11 @@ func TestHandler_ShortenURL(t *testing.T) {
 
 	t.Run("error from service", func(t *testing.T) {
 		mockService := &mockURLServiceHandlerTest{
-			shortenFunc: func(ctx context.Context, url string) (*entity.URL, error) {
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
 				return nil, errors.New("fail")
 			},
 		}
-		handler := apphttp.NewHandler(mockService, config.AuthConfig{})
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{})
 		router := gin.Default()
 		router.POST("/shorten", handler.ShortenURL)
 
@@ -118,16 +225,81 @@ func TestHandler_ShortenURL(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "Failed to shorten URL")
 	})
 
+	t.Run("alias taken", func(t *testing.T) {
+		mockService := &mockURLServiceHandlerTest{
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+				assert.Equal(t, "spring-sale", opts.Alias)
+				return nil, service.ErrAliasTaken
+			},
+		}
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{})
+		router := gin.Default()
+		router.POST("/shorten", handler.ShortenURL)
+
+		body, _ := json.Marshal(dto.ShortenRequest{URL: "https://example.com", Alias: "spring-sale"})
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "Alias already taken")
+	})
+
+	t.Run("invalid alias", func(t *testing.T) {
+		mockService := &mockURLServiceHandlerTest{
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+				return nil, service.ErrReservedAlias
+			},
+		}
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{})
+		router := gin.Default()
+		router.POST("/shorten", handler.ShortenURL)
+
+		body, _ := json.Marshal(dto.ShortenRequest{URL: "https://example.com", Alias: "swagger"})
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid alias")
+	})
+
+	t.Run("destination not allowed", func(t *testing.T) {
+		mockService := &mockURLServiceHandlerTest{
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+				return nil, &service.DestinationError{Code: service.DestinationPrivateAddress, Reason: "10.0.0.1 is a private or local address"}
+			},
+		}
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{})
+		router := gin.Default()
+		router.POST("/shorten", handler.ShortenURL)
+
+		body, _ := json.Marshal(dto.ShortenRequest{URL: "http://10.0.0.1/admin"})
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "Destination not allowed")
+		assert.Contains(t, w.Body.String(), service.DestinationPrivateAddress)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		mockService := &mockURLServiceHandlerTest{
-			shortenFunc: func(ctx context.Context, url string) (*entity.URL, error) {
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
 				return &entity.URL{
 					ShortID:  "abc123",
 					Original: url,
 				}, nil
 			},
 		}
-		handler := apphttp.NewHandler(mockService, config.AuthConfig{})
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{})
 		router := gin.Default()
 		router.POST("/shorten", handler.ShortenURL)
 
@@ -141,6 +313,1466 @@ func TestHandler_ShortenURL(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Contains(t, w.Body.String(), "abc123")
-		assert.Contains(t, w.Body.String(), "localhost:8080/abc123")
+		assert.Contains(t, w.Body.String(), "http://localhost:8080/abc123")
+	})
+
+	t.Run("public base URL", func(t *testing.T) {
+		mockService := &mockURLServiceHandlerTest{
+			shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+				return &entity.URL{ShortID: "abc123", Original: url}, nil
+			},
+		}
+		publicURL, err := apphttp.NewPublicURL("https://example.com/s/", nil)
+		require.NoError(t, err)
+		handler := apphttp.NewHandler(mockService, &mockUserService{}, &mockTokenService{}, apphttp.WithPublicURL(publicURL))
+		router := gin.New()
+		router.POST("/shorten", handler.ShortenURL)
+
+		body, _ := json.Marshal(dto.ShortenRequest{URL: "https://example.com"})
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Host = "shortener.internal:8080"
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp dto.ShortenResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "https://example.com/s/abc123", resp.ShortURL)
+	})
+}
+
+func TestPublicURL_ShortURL(t *testing.T) {
+	newRequest := func(remoteAddr string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		req.Host = "shortener.internal:8080"
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+	forwarded := map[string]string{"X-Forwarded-Host": "sho.rt, proxy.internal", "X-Forwarded-Proto": "https"}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		proxies []string
+		req     *http.Request
+		domain  string
+		want    string
+	}{
+		{"request host", "", nil, newRequest("203.0.113.7:5000", nil), "", "http://shortener.internal:8080/abc123"},
+		{"untrusted forwarded headers", "", nil, newRequest("203.0.113.7:5000", forwarded), "", "http://shortener.internal:8080/abc123"},
+		{"trusted proxy", "", []string{"10.0.0.0/8"}, newRequest("10.1.2.3:5000", forwarded), "", "https://sho.rt/abc123"},
+		{"trusted proxy IP", "", []string{"10.1.2.3"}, newRequest("10.1.2.3:5000", forwarded), "", "https://sho.rt/abc123"},
+		{"peer outside trusted proxies", "", []string{"10.0.0.0/8"}, newRequest("203.0.113.7:5000", forwarded), "", "http://shortener.internal:8080/abc123"},
+		{"invalid forwarded proto", "", []string{"10.0.0.0/8"}, newRequest("10.1.2.3:5000", map[string]string{"X-Forwarded-Proto": "javascript"}), "", "http://shortener.internal:8080/abc123"},
+		{"base URL", "https://sho.rt", nil, newRequest("203.0.113.7:5000", nil), "", "https://sho.rt/abc123"},
+		{"base URL with path prefix", "https://example.com/s", []string{"10.0.0.0/8"}, newRequest("10.1.2.3:5000", forwarded), "", "https://example.com/s/abc123"},
+		{"custom domain", "https://example.com/s", nil, newRequest("203.0.113.7:5000", nil), "go.acme.com", "https://go.acme.com/abc123"},
+		{"custom domain without base URL", "", nil, newRequest("203.0.113.7:5000", nil), "go.acme.com", "http://go.acme.com/abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicURL, err := apphttp.NewPublicURL(tt.baseURL, tt.proxies)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, publicURL.ShortURL(tt.req, tt.domain, "abc123"))
+		})
+	}
+}
+
+func TestNewPublicURL_Invalid(t *testing.T) {
+	for _, baseURL := range []string{"sho.rt", "ftp://sho.rt", "https://", "https://sho.rt/?a=1", "https://user@sho.rt"} {
+		_, err := apphttp.NewPublicURL(baseURL, nil)
+		assert.ErrorIs(t, err, apphttp.ErrInvalidBaseURL, baseURL)
+	}
+
+	_, err := apphttp.NewPublicURL("", []string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = apphttp.NewPublicURL("", []string{"proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestHandler_Redirect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(resolve func(context.Context, string) (*entity.URL, error)) *gin.Engine {
+		handler := apphttp.NewHandler(&mockURLServiceHandlerTest{resolveFunc: resolve}, &mockUserService{}, &mockTokenService{})
+		router := gin.New()
+		router.GET("/:shortID", handler.Redirect)
+		return router
+	}
+
+	t.Run("default redirect type", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{ShortID: shortID, Original: "https://example.com"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	})
+
+	t.Run("custom redirect type", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{ShortID: shortID, Original: "https://example.com", RedirectType: http.StatusPermanentRedirect}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return nil, service.ErrNotFound
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "Short URL not found")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return nil, service.ErrExpired
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("error from service", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return nil, errors.New("fail")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestHandler_RedirectProtected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return nil, service.ErrPasswordRequired
+		},
+		unlockFunc: func(ctx context.Context, shortID, password string) (*entity.URL, error) {
+			switch password {
+			case "":
+				return nil, service.ErrPasswordRequired
+			case "secret":
+				return &entity.URL{ShortID: shortID, Original: "https://example.com/internal", RedirectType: http.StatusPermanentRedirect}, nil
+			case "locked":
+				return nil, &service.AttemptsError{RetryAfter: 90 * time.Second}
+			default:
+				return nil, service.ErrWrongPassword
+			}
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+	router.POST("/:shortID", handler.Redirect)
+
+	t.Run("browser gets the password form", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), `<form method="post">`)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("API client without password", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Password required")
+	})
+
+	t.Run("password header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		req.Header.Set("X-Link-Password", "secret")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+		assert.Equal(t, "https://example.com/internal", w.Header().Get("Location"))
+	})
+
+	t.Run("password query parameter", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/docs?password=secret", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	})
+
+	t.Run("form post redirects with see other", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/docs", strings.NewReader("password=secret"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "https://example.com/internal", w.Header().Get("Location"))
+	})
+
+	t.Run("wrong password shows the form again", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/docs", strings.NewReader("password=guess"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "The password is not correct.")
+	})
+
+	t.Run("locked link", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		req.Header.Set("X-Link-Password", "locked")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "90", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), "Too many password attempts")
+	})
+}
+
+type mockPreviewService struct {
+	previewFunc func(context.Context, string) (*service.LinkPreview, error)
+}
+
+func (m *mockPreviewService) Preview(ctx context.Context, domain, shortID string) (*service.LinkPreview, error) {
+	return m.previewFunc(ctx, entity.LinkKey(domain, shortID))
+}
+
+func TestHandler_Preview(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	previews := &mockPreviewService{
+		previewFunc: func(ctx context.Context, shortID string) (*service.LinkPreview, error) {
+			if shortID == "missing" {
+				return nil, service.ErrNotFound
+			}
+			return &service.LinkPreview{
+				ShortID:      shortID,
+				Destination:  `https://example.com/?q=<script>alert(1)</script>`,
+				Interstitial: shortID == "careful",
+				CreatedAt:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+				OwnerName:    "alice",
+				Clicks:       42,
+			}, nil
+		},
+	}
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			if shortID == "careful" {
+				return nil, service.ErrConfirmationRequired
+			}
+			return &entity.URL{ShortID: shortID, Original: "https://example.com"}, nil
+		},
+		unlockFunc: func(ctx context.Context, shortID, password string) (*entity.URL, error) {
+			return &entity.URL{ShortID: shortID, Original: "https://example.com/careful"}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithPreviews(previews))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	for _, target := range []string{"/abc123+", "/abc123?preview=1"} {
+		t.Run("preview "+target, func(t *testing.T) {
+			w := serve(target)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+			body := w.Body.String()
+			assert.Contains(t, body, "https://example.com/?q=&lt;script&gt;alert(1)&lt;/script&gt;")
+			assert.NotContains(t, body, "<script>")
+			assert.Contains(t, body, "alice")
+			assert.Contains(t, body, "1 May 2024")
+			assert.Contains(t, body, "<dd>42</dd>")
+			assert.Contains(t, body, `href="abc123?confirm=1"`)
+		})
+	}
+
+	t.Run("interstitial link shows the preview", func(t *testing.T) {
+		w := serve("/careful")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "You are leaving through a short link")
+	})
+
+	t.Run("confirmed interstitial link redirects", func(t *testing.T) {
+		w := serve("/careful?confirm=1")
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/careful", w.Header().Get("Location"))
+	})
+
+	t.Run("unknown link", func(t *testing.T) {
+		w := serve("/missing+")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+type mockAnalyticsService struct {
+	recorded  []service.ClickInfo
+	statsFunc func(context.Context, string, string, time.Time, time.Time) (*entity.ClickStats, error)
+	counts    map[string]int64
+}
+
+func (m *mockAnalyticsService) Record(info service.ClickInfo) {
+	m.recorded = append(m.recorded, info)
+}
+
+func (m *mockAnalyticsService) Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+	return m.statsFunc(ctx, entity.LinkKey(domain, shortID), bucket, from, to)
+}
+
+func (m *mockAnalyticsService) Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error) {
+	return m.counts[entity.LinkKey(domain, shortID)], nil
+}
+
+func (m *mockAnalyticsService) Close() {}
+
+func TestHandler_RedirectRecordsClick(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	analytics := &mockAnalyticsService{}
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{ShortID: shortID, Original: "https://example.com"}, nil
+		},
+	}
+	publicURL, err := apphttp.NewPublicURL("", []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(analytics),
+		apphttp.WithPublicURL(publicURL), apphttp.WithGeoIP(fakeCountryLocator{"203.0.113.7": "BR"}))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	redirect := func(remoteAddr string) service.ClickInfo {
+		req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Referer", "https://news.example.org")
+		req.Header.Set("User-Agent", "curl/8.0")
+		req.Header.Set("CF-IPCountry", "DE")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		return analytics.recorded[len(analytics.recorded)-1]
+	}
+
+	click := redirect("10.1.2.3:5000")
+	assert.Equal(t, "abc123", click.ShortID)
+	assert.Equal(t, "https://news.example.org", click.Referrer)
+	assert.Equal(t, "curl/8.0", click.UserAgent)
+	assert.Equal(t, "DE", click.Country, "trusted proxies report the country")
+
+	click = redirect("203.0.113.7:5000")
+	assert.Equal(t, "BR", click.Country, "clients cannot choose their country")
+}
+
+func TestHandler_RedirectForwardsQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	analytics := &mockAnalyticsService{}
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{
+				ShortID:      shortID,
+				Original:     "https://example.com/sale?utm_campaign=spring&ref=a%2Fb#top",
+				ForwardQuery: shortID == "fwd",
+			}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(analytics))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("forwards the visit's query string", func(t *testing.T) {
+		w := serve("/fwd?utm_campaign=summer&gclid=xyz&confirm=1")
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, "https://example.com/sale?ref=a%2Fb&gclid=xyz&utm_campaign=summer#top", w.Header().Get("Location"))
+		assert.Equal(t, "summer", analytics.recorded[len(analytics.recorded)-1].Campaign)
+	})
+
+	t.Run("ignores the query string unless enabled", func(t *testing.T) {
+		w := serve("/plain?utm_campaign=summer")
+
+		assert.Equal(t, "https://example.com/sale?utm_campaign=spring&ref=a%2Fb#top", w.Header().Get("Location"))
+		assert.Equal(t, "spring", analytics.recorded[len(analytics.recorded)-1].Campaign)
+	})
+}
+
+// fakeCountryLocator maps IP addresses to countries.
+type fakeCountryLocator map[string]string
+
+func (f fakeCountryLocator) Country(ip net.IP) (string, error) {
+	return f[ip.String()], nil
+}
+
+func TestHandler_RedirectRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	analytics := &mockAnalyticsService{}
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{
+				ShortID:  shortID,
+				Original: "https://example.com/app",
+				RedirectRules: []entity.RedirectRule{
+					{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}},
+					{Destination: "https://play.google.com/store/apps/details?id=app", OS: []string{entity.OSAndroid}},
+					{Destination: "https://example.com/br", Countries: []string{"BR"}},
+				},
+			}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{},
+		apphttp.WithAnalytics(analytics), apphttp.WithGeoIP(fakeCountryLocator{"192.0.2.1": "BR"}))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	serve := func(userAgent, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/app", nil)
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		userAgent  string
+		remoteAddr string
+		want       string
+	}{
+		{name: "iOS", userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", remoteAddr: "198.51.100.7:1234", want: "https://apps.apple.com/app/id1"},
+		{name: "Android", userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile Safari/537.36", remoteAddr: "198.51.100.7:1234", want: "https://play.google.com/store/apps/details?id=app"},
+		{name: "GeoIP country", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", remoteAddr: "192.0.2.1:1234", want: "https://example.com/br"},
+		{name: "no rule matches", userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", remoteAddr: "198.51.100.7:1234", want: "https://example.com/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.userAgent, tt.remoteAddr)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+	assert.Equal(t, "BR", analytics.recorded[2].Country)
+}
+
+func TestHandler_RedirectVariants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	analytics := &mockAnalyticsService{}
+	urlService := &mockURLServiceHandlerTest{
+		resolveFunc: func(ctx context.Context, shortID string) (*entity.URL, error) {
+			return &entity.URL{
+				ShortID:  shortID,
+				Original: "https://example.com/",
+				Variants: []entity.Variant{
+					{Name: "a", Destination: "https://example.com/a", Weight: 1},
+					{Name: "b", Destination: "https://example.com/b", Weight: 1},
+				},
+			}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(analytics))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	serve := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/split", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("assigns a variant and remembers it in a cookie", func(t *testing.T) {
+		w := serve(nil)
+
+		require.Len(t, w.Result().Cookies(), 1)
+		cookie := w.Result().Cookies()[0]
+		assert.Equal(t, "ab_variant", cookie.Name)
+		assert.Equal(t, "/split", cookie.Path)
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, "https://example.com/"+cookie.Value, w.Header().Get("Location"))
+		assert.Equal(t, cookie.Value, analytics.recorded[len(analytics.recorded)-1].Variant)
+	})
+
+	t.Run("sticks to the variant of the cookie", func(t *testing.T) {
+		for _, name := range []string{"a", "b"} {
+			w := serve(&http.Cookie{Name: "ab_variant", Value: name})
+
+			assert.Equal(t, "https://example.com/"+name, w.Header().Get("Location"))
+			assert.Empty(t, w.Result().Cookies())
+			assert.Equal(t, name, analytics.recorded[len(analytics.recorded)-1].Variant)
+		}
+	})
+
+	t.Run("reassigns an unknown variant", func(t *testing.T) {
+		w := serve(&http.Cookie{Name: "ab_variant", Value: "retired"})
+
+		require.Len(t, w.Result().Cookies(), 1)
+		assert.NotEqual(t, "retired", w.Result().Cookies()[0].Value)
+	})
+}
+
+func TestHandler_ShortenURLInvalidRedirectRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+			assert.Equal(t, []string{"Symbian"}, opts.RedirectRules[0].OS)
+			return nil, service.ErrInvalidRedirectRule
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.POST("/shorten", handler.ShortenURL)
+
+	body, _ := json.Marshal(dto.ShortenRequest{
+		URL:           "https://example.com",
+		RedirectRules: []dto.RedirectRule{{Destination: "https://example.com/old", OS: []string{"Symbian"}}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid redirect rule")
+}
+
+func TestHandler_Stats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(analytics service.AnalyticsService) *gin.Engine {
+		var opts []apphttp.HandlerOption
+		if analytics != nil {
+			opts = append(opts, apphttp.WithAnalytics(analytics))
+		}
+		urlService := &mockURLServiceHandlerTest{
+			getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+				if ownerID != "user-1" {
+					return nil, service.ErrForbidden
+				}
+				return &entity.URL{ShortID: shortID, OwnerID: ownerID}, nil
+			},
+		}
+		handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, opts...)
+		router := gin.New()
+		router.Use(withUserID("user-1"))
+		router.GET("/urls/:shortID/stats", handler.Stats)
+		return router
+	}
+
+	t.Run("success", func(t *testing.T) {
+		analytics := &mockAnalyticsService{
+			statsFunc: func(ctx context.Context, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+				assert.Equal(t, "abc123", shortID)
+				assert.Equal(t, "hour", bucket)
+				assert.Equal(t, 2024, from.Year())
+				return &entity.ClickStats{
+					TotalClicks:    5,
+					UniqueVisitors: 3,
+					Referrers:      []entity.KeyCount{{Key: "https://news.example.org", Count: 4}},
+				}, nil
+			},
+		}
+		router := newRouter(analytics)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?bucket=hour&from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp dto.StatsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(5), resp.TotalClicks)
+		assert.Equal(t, int64(3), resp.UniqueVisitors)
+		assert.Len(t, resp.Referrers, 1)
+		assert.NotNil(t, resp.Timeline)
+	})
+
+	t.Run("period starts when the link was created", func(t *testing.T) {
+		createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		analytics := &mockAnalyticsService{
+			statsFunc: func(ctx context.Context, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+				assert.Equal(t, createdAt, from)
+				return &entity.ClickStats{}, nil
+			},
+		}
+		urlService := &mockURLServiceHandlerTest{
+			getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+				return &entity.URL{ShortID: shortID, OwnerID: ownerID, CreatedAt: createdAt}, nil
+			},
+		}
+		handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(analytics))
+		router := gin.New()
+		router.Use(withUserID("user-1"))
+		router.GET("/urls/:shortID/stats", handler.Stats)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?from=2024-04-01T00:00:00Z&to=2024-05-02T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp dto.StatsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, createdAt, resp.From)
+	})
+
+	t.Run("invalid bucket", func(t *testing.T) {
+		analytics := &mockAnalyticsService{
+			statsFunc: func(ctx context.Context, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+				return nil, service.ErrInvalidBucket
+			},
+		}
+		router := newRouter(analytics)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?bucket=week", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		router := newRouter(&mockAnalyticsService{})
+
+		for _, query := range []string{
+			"from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z",
+			"from=2024-05-01T00:00:00Z&to=2024-05-01T00:00:00Z",
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?"+query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("period ends before the link was created", func(t *testing.T) {
+		urlService := &mockURLServiceHandlerTest{
+			getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+				return &entity.URL{ShortID: shortID, OwnerID: ownerID, CreatedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}, nil
+			},
+		}
+		handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(&mockAnalyticsService{}))
+		router := gin.New()
+		router.Use(withUserID("user-1"))
+		router.GET("/urls/:shortID/stats", handler.Stats)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "after the URL was created")
+	})
+
+	t.Run("invalid from", func(t *testing.T) {
+		router := newRouter(&mockAnalyticsService{})
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats?from=yesterday", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("analytics disabled", func(t *testing.T) {
+		router := newRouter(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestHandler_StatsForbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+			return nil, service.ErrForbidden
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{}, apphttp.WithAnalytics(&mockAnalyticsService{}))
+	router := gin.New()
+	router.Use(withUserID("user-2"))
+	router.GET("/urls/:shortID/stats", handler.Stats)
+
+	req := httptest.NewRequest(http.MethodGet, "/urls/abc123/stats", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandler_ShortenURLSetsOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		shortenFunc: func(ctx context.Context, url string, opts service.ShortenOptions) (*entity.URL, error) {
+			assert.Equal(t, "user-1", opts.OwnerID)
+			return &entity.URL{ShortID: "abc123", Original: url, OwnerID: opts.OwnerID}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.POST("/shorten", handler.ShortenURL)
+
+	body, _ := json.Marshal(dto.ShortenRequest{URL: "https://example.com"})
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandler_ShortenBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		shortenBatchFunc: func(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+			assert.Len(t, items, 2)
+			assert.Equal(t, "user-1", items[0].Options.OwnerID)
+			assert.Equal(t, "taken", items[1].Options.Alias)
+			return []service.BatchResult{
+				{Index: 0, URL: &entity.URL{ShortID: "abc123", Original: items[0].OriginalURL}},
+				{Index: 1, Err: service.ErrAliasTaken},
+			}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.POST("/shorten/batch", handler.ShortenBatch)
+
+	t.Run("partial failure", func(t *testing.T) {
+		body, _ := json.Marshal([]dto.ShortenRequest{
+			{URL: "https://example.com/a"},
+			{URL: "not a url"},
+			{URL: "https://example.com/b", Alias: "taken"},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp dto.BatchShortenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Succeeded)
+		assert.Equal(t, 2, resp.Failed)
+		assert.Len(t, resp.Results, 3)
+		assert.Equal(t, "abc123", resp.Results[0].ShortID)
+		assert.Equal(t, http.StatusBadRequest, resp.Results[1].Status)
+		assert.Equal(t, 2, resp.Results[2].Index)
+		assert.Equal(t, http.StatusConflict, resp.Results[2].Status)
+	})
+
+	t.Run("not an array", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBufferString(`{"url":"https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("too many items", func(t *testing.T) {
+		body, _ := json.Marshal(make([]dto.ShortenRequest, service.MaxBatchSize+1))
+		req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+func TestHandler_ListURLs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(list func(context.Context, string, service.ListOptions) (*service.URLPage, error)) *gin.Engine {
+		handler := apphttp.NewHandler(&mockURLServiceHandlerTest{listFunc: list}, &mockUserService{}, &mockTokenService{})
+		router := gin.New()
+		router.Use(withUserID("user-1"))
+		router.GET("/urls", handler.ListURLs)
+		return router
+	}
+
+	t.Run("success", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, ownerID string, opts service.ListOptions) (*service.URLPage, error) {
+			assert.Equal(t, "user-1", ownerID)
+			assert.Equal(t, service.ListOptions{Query: "example", Sort: "-updated_at", Page: 2, PageSize: 10}, opts)
+			return &service.URLPage{
+				Items:    []*entity.URL{{ShortID: "abc123", Original: "https://example.com"}},
+				Total:    11,
+				Page:     2,
+				PageSize: 10,
+			}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/urls?q=example&sort=-updated_at&page=2&page_size=10", nil)
+		req.Host = "localhost:8080"
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp dto.URLListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(11), resp.Total)
+		assert.Len(t, resp.Items, 1)
+		assert.Equal(t, "http://localhost:8080/abc123", resp.Items[0].ShortURL)
+	})
+
+	t.Run("clicks are counted by analytics", func(t *testing.T) {
+		list := func(ctx context.Context, ownerID string, opts service.ListOptions) (*service.URLPage, error) {
+			return &service.URLPage{Items: []*entity.URL{
+				{ShortID: "abc123", Original: "https://example.com"},
+				{ShortID: "limited", Original: "https://example.com/offer", MaxClicks: 10, Clicks: 4},
+			}}, nil
+		}
+		analytics := &mockAnalyticsService{counts: map[string]int64{"abc123": 7, "limited": 4}}
+		handler := apphttp.NewHandler(&mockURLServiceHandlerTest{listFunc: list}, &mockUserService{}, &mockTokenService{},
+			apphttp.WithAnalytics(analytics))
+		router := gin.New()
+		router.Use(withUserID("user-1"))
+		router.GET("/urls", handler.ListURLs)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp dto.URLListResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Items, 2)
+		assert.Equal(t, int64(7), resp.Items[0].Clicks, "links without a click limit report their recorded clicks")
+		assert.Equal(t, int64(4), resp.Items[1].Clicks)
+	})
+
+	t.Run("invalid page", func(t *testing.T) {
+		router := newRouter(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/urls?page=first", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, ownerID string, opts service.ListOptions) (*service.URLPage, error) {
+			return nil, service.ErrInvalidSort
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/urls?sort=password", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_GetURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+			switch shortID {
+			case "abc123":
+				return &entity.URL{ShortID: shortID, Original: "https://example.com", OwnerID: ownerID}, nil
+			case "other":
+				return nil, service.ErrForbidden
+			default:
+				return nil, service.ErrNotFound
+			}
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.GET("/urls/:shortID", handler.GetURL)
+
+	for path, status := range map[string]int{
+		"/urls/abc123":  http.StatusOK,
+		"/urls/other":   http.StatusForbidden,
+		"/urls/missing": http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, status, w.Code, path)
+	}
+}
+
+func TestHandler_UpdateURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		updateFunc: func(ctx context.Context, ownerID, shortID string, opts service.UpdateOptions) (*entity.URL, error) {
+			assert.Equal(t, "user-1", ownerID)
+			assert.Equal(t, "https://new.example.com", *opts.Original)
+			assert.True(t, opts.RemoveExpiry)
+			return &entity.URL{ShortID: shortID, Original: *opts.Original, OwnerID: ownerID}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.PATCH("/urls/:shortID", handler.UpdateURL)
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/urls/abc123", bytes.NewBufferString(`{"url":"https://new.example.com","remove_expiry":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "https://new.example.com")
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/urls/abc123", bytes.NewBufferString(`{"url":"not a url"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_DeleteURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		deleteFunc: func(ctx context.Context, ownerID, shortID string) error {
+			if shortID == "other" {
+				return service.ErrForbidden
+			}
+			return nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.DELETE("/urls/:shortID", handler.DeleteURL)
+
+	req := httptest.NewRequest(http.MethodDelete, "/urls/abc123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/urls/other", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+type mockTransferService struct {
+	importFunc func(context.Context, io.Reader, service.ImportOptions) (*service.ImportReport, error)
+	exportFunc func(context.Context, io.Writer, service.ExportOptions) (int64, error)
+}
+
+func (m *mockTransferService) Import(ctx context.Context, r io.Reader, opts service.ImportOptions) (*service.ImportReport, error) {
+	return m.importFunc(ctx, r, opts)
+}
+
+func (m *mockTransferService) Export(ctx context.Context, w io.Writer, opts service.ExportOptions) (int64, error) {
+	return m.exportFunc(ctx, w, opts)
+}
+
+func TestHandler_ImportURLs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	transfer := &mockTransferService{
+		importFunc: func(ctx context.Context, r io.Reader, opts service.ImportOptions) (*service.ImportReport, error) {
+			if opts.Format != service.FormatCSV {
+				return nil, service.ErrUnsupportedFormat
+			}
+			assert.Equal(t, service.ConflictOverwrite, opts.OnConflict)
+			report := service.ImportReport{Processed: 1000, Imported: 1000}
+			opts.Progress(report)
+			report.Processed++
+			report.Failed++
+			report.Errors = []service.ImportRowError{{Line: 1002, ShortID: "api", Err: service.ErrReservedAlias}}
+			return &report, nil
+		},
+	}
+	handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, &mockUserService{}, &mockTokenService{}, apphttp.WithTransfer(transfer))
+	router := gin.New()
+	router.POST("/import", handler.ImportURLs)
+
+	t.Run("streams progress", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/import?on_conflict=overwrite", bytes.NewBufferString("short_id,original_url\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		assert.Len(t, lines, 2)
+
+		var last dto.ImportProgressResponse
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &last))
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(1001), last.Processed)
+		assert.Equal(t, int64(1), last.Failed)
+		assert.Equal(t, 1002, last.Errors[0].Line)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/import?format=xml", bytes.NewBufferString(""))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_ExportURLs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(export func(context.Context, io.Writer, service.ExportOptions) (int64, error)) *gin.Engine {
+		handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, &mockUserService{}, &mockTokenService{},
+			apphttp.WithTransfer(&mockTransferService{exportFunc: export}))
+		router := gin.New()
+		router.GET("/export", handler.ExportURLs)
+		return router
+	}
+
+	t.Run("streams CSV", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, w io.Writer, opts service.ExportOptions) (int64, error) {
+			assert.Equal(t, service.FormatCSV, opts.Format)
+			assert.Equal(t, "user-1", opts.OwnerID)
+			_, err := io.WriteString(w, "short_id,original_url\n")
+			return 0, err
+		})
+		req := httptest.NewRequest(http.MethodGet, "/export?format=csv&owner_id=user-1", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "urls.csv")
+		assert.Equal(t, "short_id,original_url\n", w.Body.String())
+	})
+
+	t.Run("fails before writing", func(t *testing.T) {
+		router := newRouter(func(ctx context.Context, w io.Writer, opts service.ExportOptions) (int64, error) {
+			return 0, errors.New("connection refused")
+		})
+		req := httptest.NewRequest(http.MethodGet, "/export", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		router := newRouter(nil)
+		req := httptest.NewRequest(http.MethodGet, "/export?format=xml", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// mockDomainRuleService implements service.DomainRuleService with a map of domain actions.
+type mockDomainRuleService struct {
+	rules map[string]string
+}
+
+func (m *mockDomainRuleService) Check(ctx context.Context, u *url.URL) error {
+	return nil
+}
+
+func (m *mockDomainRuleService) Put(ctx context.Context, domain, action, reason, createdBy string) (*entity.DomainRule, error) {
+	if domain == "not_a_domain" {
+		return nil, service.ErrInvalidDomain
+	}
+	m.rules[domain] = action
+	return &entity.DomainRule{Domain: domain, Action: action, Reason: reason, CreatedBy: createdBy}, nil
+}
+
+func (m *mockDomainRuleService) List(ctx context.Context) ([]*entity.DomainRule, error) {
+	var rules []*entity.DomainRule
+	for domain, action := range m.rules {
+		rules = append(rules, &entity.DomainRule{Domain: domain, Action: action})
+	}
+	return rules, nil
+}
+
+func (m *mockDomainRuleService) Delete(ctx context.Context, domain string) error {
+	if _, ok := m.rules[domain]; !ok {
+		return service.ErrDomainRuleNotFound
+	}
+	delete(m.rules, domain)
+	return nil
+}
+
+func TestHandler_DomainRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rules := &mockDomainRuleService{rules: map[string]string{}}
+	handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, &mockUserService{}, &mockTokenService{}, apphttp.WithDomainRules(rules))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "admin-1")
+		c.Next()
+	})
+	router.GET("/domains", handler.ListDomainRules)
+	router.PUT("/domains/:domain", handler.PutDomainRule)
+	router.DELETE("/domains/:domain", handler.DeleteDomainRule)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPut, "/domains/phish.example", `{"action":"deny","reason":"phishing"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"created_by":"admin-1"`)
+	assert.Equal(t, "deny", rules.rules["phish.example"])
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/domains/phish.example", `{"action":"block"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/domains/not_a_domain", `{"action":"deny"}`).Code)
+
+	w = send(http.MethodGet, "/domains", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "phish.example")
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/domains/phish.example", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/domains/phish.example", "").Code)
+}
+
+// mockCustomDomainService serves a fixed set of domains keyed by host name.
+type mockCustomDomainService struct {
+	domains map[string]*entity.CustomDomain
+	inUse   map[string]bool
+}
+
+func (m *mockCustomDomainService) Register(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error) {
+	if !strings.Contains(host, ".") {
+		return nil, service.ErrInvalidDomain
+	}
+	if _, ok := m.domains[host]; ok {
+		return nil, service.ErrCustomDomainTaken
+	}
+	domain := &entity.CustomDomain{Host: host, OwnerID: ownerID, Token: "t0k3n"}
+	m.domains[host] = domain
+	return domain, nil
+}
+
+func (m *mockCustomDomainService) Verify(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error) {
+	domain, ok := m.domains[host]
+	if !ok || domain.OwnerID != ownerID {
+		return nil, service.ErrCustomDomainNotFound
+	}
+	if host == "unpublished.acme.com" {
+		return nil, service.ErrDomainVerificationFailed
+	}
+	now := time.Now()
+	domain.VerifiedAt = &now
+	return domain, nil
+}
+
+func (m *mockCustomDomainService) List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error) {
+	var domains []*entity.CustomDomain
+	for _, domain := range m.domains {
+		if domain.OwnerID == ownerID {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
+}
+
+func (m *mockCustomDomainService) Delete(ctx context.Context, ownerID, host string) error {
+	domain, ok := m.domains[host]
+	if !ok || domain.OwnerID != ownerID {
+		return service.ErrCustomDomainNotFound
+	}
+	if m.inUse[host] {
+		return service.ErrCustomDomainInUse
+	}
+	delete(m.domains, host)
+	return nil
+}
+
+func (m *mockCustomDomainService) Authorize(ctx context.Context, ownerID, host string) (string, error) {
+	return host, nil
+}
+
+func (m *mockCustomDomainService) Resolve(ctx context.Context, host string) (string, error) {
+	if domain, ok := m.domains[host]; ok && domain.IsVerified() {
+		return host, nil
+	}
+	return "", nil
+}
+
+func TestHandler_RedirectCustomDomain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verifiedAt := time.Now()
+	domains := &mockCustomDomainService{domains: map[string]*entity.CustomDomain{
+		"go.acme.com":      {Host: "go.acme.com", OwnerID: "user-1", VerifiedAt: &verifiedAt},
+		"pending.acme.com": {Host: "pending.acme.com", OwnerID: "user-1"},
+	}}
+	urls := &mockURLServiceHandlerTest{resolveFunc: func(ctx context.Context, key string) (*entity.URL, error) {
+		switch key {
+		case "go.acme.com/sale":
+			return &entity.URL{ShortID: "sale", Domain: "go.acme.com", Original: "https://acme.com/sale"}, nil
+		case "sale":
+			return &entity.URL{ShortID: "sale", Original: "https://example.com/sale"}, nil
+		}
+		return nil, service.ErrNotFound
+	}}
+	handler := apphttp.NewHandler(urls, &mockUserService{}, &mockTokenService{}, apphttp.WithCustomDomains(domains))
+	router := gin.New()
+	router.GET("/:shortID", handler.Redirect)
+
+	redirect := func(host string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/sale", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := redirect("go.acme.com:8080")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://acme.com/sale", w.Header().Get("Location"))
+
+	w = redirect("localhost:8080")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/sale", w.Header().Get("Location"))
+
+	w = redirect("pending.acme.com")
+	assert.Equal(t, "https://example.com/sale", w.Header().Get("Location"), "unverified domains serve the default domain")
+}
+
+func TestHandler_CustomDomains(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	domains := &mockCustomDomainService{domains: map[string]*entity.CustomDomain{}, inUse: map[string]bool{"go.acme.com": true}}
+	handler := apphttp.NewHandler(&mockURLServiceHandlerTest{}, &mockUserService{}, &mockTokenService{}, apphttp.WithCustomDomains(domains))
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.POST("/domains", handler.CreateCustomDomain)
+	router.GET("/domains", handler.ListCustomDomains)
+	router.POST("/domains/:domain/verify", handler.VerifyCustomDomain)
+	router.DELETE("/domains/:domain", handler.DeleteCustomDomain)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/domains", `{"domain":"go.acme.com"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created dto.CustomDomainResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "go.acme.com", created.Domain)
+	assert.False(t, created.Verified)
+	assert.Equal(t, dto.DomainVerificationRecord{
+		Type:  "TXT",
+		Name:  "_url-shortener-challenge.go.acme.com",
+		Value: "url-shortener-verification=t0k3n",
+	}, created.VerificationRecord)
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/domains", `{"domain":"go.acme.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/domains", `{"domain":"localhost"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/domains", `{}`).Code)
+
+	w = send(http.MethodPost, "/domains/go.acme.com/verify", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"verified":true`)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/domains/other.acme.com/verify", "").Code)
+
+	require.Equal(t, http.StatusCreated, send(http.MethodPost, "/domains", `{"domain":"unpublished.acme.com"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodPost, "/domains/unpublished.acme.com/verify", "").Code)
+
+	w = send(http.MethodGet, "/domains", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go.acme.com")
+	assert.Contains(t, w.Body.String(), "unpublished.acme.com")
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, "/domains/go.acme.com", "").Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/domains/unpublished.acme.com", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/domains/unpublished.acme.com", "").Code)
+}
+
+func TestHandler_QRCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	urlService := &mockURLServiceHandlerTest{
+		getFunc: func(ctx context.Context, ownerID, shortID string) (*entity.URL, error) {
+			if shortID == "theirs" {
+				return nil, service.ErrForbidden
+			}
+			return &entity.URL{ShortID: shortID, OwnerID: ownerID}, nil
+		},
+	}
+	handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{},
+		apphttp.WithQRCodes(service.NewQRCodeService(nil, 0)))
+	router := gin.New()
+	router.Use(withUserID("user-1"))
+	router.GET("/urls/:shortID/qr", handler.QRCode)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	t.Run("png by default", func(t *testing.T) {
+		w := serve("/urls/abc123/qr")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, max-age=3600", w.Header().Get("Cache-Control"))
+		assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")))
+	})
+
+	t.Run("svg with options", func(t *testing.T) {
+		w := serve("/urls/abc123/qr?format=svg&size=512&level=H&margin=0&fg=%23ff0000&bg=%2300000000")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+		body := w.Body.String()
+		assert.Contains(t, body, `width="512"`)
+		assert.Contains(t, body, `fill="#ff0000"`)
+		assert.Contains(t, body, `fill-opacity="0.000"`)
+		assert.Contains(t, body, "M0 0h7v1h-7z", "no quiet zone")
+	})
+
+	for _, query := range []string{"format=gif", "size=big", "size=10", "margin=-1", "margin=x", "level=Z", "fg=red"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, serve("/urls/abc123/qr?"+query).Code)
+		})
+	}
+
+	t.Run("not the owner", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve("/urls/theirs/qr").Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		handler := apphttp.NewHandler(urlService, &mockUserService{}, &mockTokenService{})
+		router := gin.New()
+		router.GET("/urls/:shortID/qr", handler.QRCode)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/urls/abc123/qr", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	})
 }
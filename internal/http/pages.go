@@ -0,0 +1,98 @@
+package http
+
+import (
+	"html/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guttosm/url-shortener/internal/middleware"
+	"github.com/guttosm/url-shortener/internal/service"
+)
+
+// passwordHeader is the request header API clients send the password of a protected URL in.
+const passwordHeader = "X-Link-Password"
+
+// passwordPage is the form asking browsers for the password of a protected URL.
+// It posts back to the short URL itself.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<main>
+<h1>This link is password protected</h1>
+<form method="post">
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+{{if .}}<p role="alert">{{.}}</p>{{end}}
+</main>
+</body>
+</html>
+`))
+
+// previewPage shows where a short URL leads. The continue link goes back through the redirect,
+// so the click is counted and a password is asked for if needed.
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Link preview</title>
+</head>
+<body>
+<main>
+<h1>{{if .Interstitial}}You are leaving through a short link{{else}}Link preview{{end}}</h1>
+<dl>
+<dt>Short link</dt>
+<dd>{{.ShortURL}}</dd>
+<dt>Destination</dt>
+<dd>{{if .Protected}}Hidden until the password is entered{{else}}<code>{{.Destination}}</code>{{end}}</dd>
+<dt>Created</dt>
+<dd><time datetime="{{.CreatedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.UTC.Format "2 January 2006"}}</time></dd>
+{{if .OwnerName}}<dt>Created by</dt>
+<dd>{{.OwnerName}}</dd>
+{{end}}<dt>Clicks</dt>
+<dd>{{.Clicks}}</dd>
+</dl>
+<p><a href="{{.ContinueURL}}" rel="nofollow noreferrer">Continue to the destination</a></p>
+</main>
+</body>
+</html>
+`))
+
+// previewData is the data rendered by previewPage.
+type previewData struct {
+	*service.LinkPreview
+	ShortURL    string
+	ContinueURL string
+}
+
+// challengePassword asks the client for the password of a protected URL.
+// Browsers get the HTML form, showing notice if set; other clients get a JSON error with msg.
+func challengePassword(c *gin.Context, status int, msg, notice string) {
+	c.Header("Cache-Control", "no-store")
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		middleware.AbortWithError(c, status, msg, nil)
+		return
+	}
+	renderPage(c, status, passwordPage, notice)
+}
+
+// renderPage writes an HTML page that must not be cached or framed by other sites.
+func renderPage(c *gin.Context, status int, page *template.Template, data any) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Status(status)
+	if err := page.Execute(c.Writer, data); err != nil {
+		_ = c.Error(err)
+	}
+	c.Abort()
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidBaseURL is returned when the public base URL is not an absolute http or https URL.
+var ErrInvalidBaseURL = errors.New("public base URL must be an absolute http or https URL without query or fragment")
+
+// PublicURL builds the absolute short URLs returned to clients and knows which proxies are trusted to report
+// details of the client, such as its country.
+//
+// Behavior:
+// - With a base URL, short URLs use its scheme, host and path prefix, e.g. "https://sho.rt/s/abc123".
+// - Without one, they use the scheme and host of the request. X-Forwarded-Proto and X-Forwarded-Host are only
+// honored on requests arriving from a trusted proxy, so other clients cannot choose the links they are given.
+// - Short URLs on a custom domain use that domain as host, without the path prefix of the base URL.
+type PublicURL struct {
+	base    *url.URL
+	proxies []*net.IPNet
+}
+
+// NewPublicURL creates a PublicURL.
+//
+// Parameters:
+// - baseURL (string): The public base URL, e.g. "https://sho.rt" or "https://example.com/s". Empty derives it from requests.
+// - trustedProxies ([]string): The IP addresses and CIDR ranges of the proxies whose forwarded headers are honored.
+//
+// Returns:
+// - *PublicURL: The public URL builder.
+// - error: ErrInvalidBaseURL, or an error if a trusted proxy is neither an IP address nor a CIDR range.
+func NewPublicURL(baseURL string, trustedProxies []string) (*PublicURL, error) {
+	p := &PublicURL{}
+
+	if baseURL = strings.TrimSpace(baseURL); baseURL != "" {
+		base, err := url.Parse(baseURL)
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" ||
+			base.User != nil || base.RawQuery != "" || base.Fragment != "" {
+			return nil, ErrInvalidBaseURL
+		}
+		base.Path = strings.TrimRight(base.Path, "/")
+		base.RawPath = ""
+		p.base = base
+	}
+
+	for _, proxy := range trustedProxies {
+		network, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		p.proxies = append(p.proxies, network)
+	}
+	return p, nil
+}
+
+// TrustedProxies returns the trusted proxies as IP addresses and CIDR ranges, as accepted by gin.Engine.SetTrustedProxies.
+func (p *PublicURL) TrustedProxies() []string {
+	proxies := make([]string, 0, len(p.proxies))
+	for _, network := range p.proxies {
+		proxies = append(proxies, network.String())
+	}
+	return proxies
+}
+
+// ShortURL builds the absolute short URL of a short ID.
+//
+// Parameters:
+// - r (*http.Request): The request the short URL is returned to.
+// - domain (string): The custom domain of the short ID, or an empty string for the default domain.
+// - shortID (string): The short ID.
+//
+// Returns:
+// - string: The short URL, e.g. "https://sho.rt/abc123".
+func (p *PublicURL) ShortURL(r *http.Request, domain, shortID string) string {
+	if domain != "" {
+		return p.scheme(r) + "://" + domain + "/" + shortID
+	}
+	if p.base != nil {
+		return p.base.Scheme + "://" + p.base.Host + p.base.EscapedPath() + "/" + shortID
+	}
+	return p.scheme(r) + "://" + p.host(r) + "/" + shortID
+}
+
+// scheme returns the scheme of the base URL, or the one the client used to reach the service.
+func (p *PublicURL) scheme(r *http.Request) string {
+	if p.base != nil {
+		return p.base.Scheme
+	}
+	if p.trusted(r) {
+		switch proto := strings.ToLower(forwardedValue(r, "X-Forwarded-Proto")); proto {
+		case "http", "https":
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// host returns the host the client used to reach the service.
+func (p *PublicURL) host(r *http.Request) string {
+	if p.trusted(r) {
+		if host := forwardedValue(r, "X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+// trusted reports whether the request arrives directly from a trusted proxy.
+func (p *PublicURL) trusted(r *http.Request) bool {
+	if len(p.proxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedValue returns the first value of a forwarded header, which is the one set by the proxy closest to the client.
+func forwardedValue(r *http.Request, header string) string {
+	value, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(value)
+}
+
+// parseProxy parses a trusted proxy written as an IP address or a CIDR range.
+func parseProxy(proxy string) (*net.IPNet, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -1,40 +1,143 @@
 package http
 
 import (
+	"log"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/guttosm/url-shortener/docs"
 	"github.com/guttosm/url-shortener/internal/auth"
 	"github.com/guttosm/url-shortener/internal/middleware"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// routerConfig holds the optional dependencies of the router.
+type routerConfig struct {
+	apiKeys    auth.APIKeyAuthenticator
+	limiter    ratelimit.Limiter
+	rateLimits RateLimits
+	proxies    []string
+}
+
+// RateLimits holds the per-client request limits of each route group.
+//
+// Fields:
+// - Auth (ratelimit.Limit): The limit per IP of login, registration and token refresh.
+// - API (ratelimit.Limit): The limit per IP, and then per user or API key, of the authenticated API.
+// - Redirect (ratelimit.Limit): The limit per IP of short URL redirects.
+type RateLimits struct {
+	Auth     ratelimit.Limit
+	API      ratelimit.Limit
+	Redirect ratelimit.Limit
+}
+
+// RouterOption configures optional dependencies of the router.
+type RouterOption func(*routerConfig)
+
+// WithAPIKeyAuth lets protected routes authenticate with API keys as well as bearer tokens.
+func WithAPIKeyAuth(a auth.APIKeyAuthenticator) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.apiKeys = a
+	}
+}
+
+// WithRateLimits limits how often each client may call the routes of each group.
+func WithRateLimits(limiter ratelimit.Limiter, limits RateLimits) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.limiter = limiter
+		cfg.rateLimits = limits
+	}
+}
+
+// WithTrustedProxies sets the IP addresses and CIDR ranges of the proxies whose X-Forwarded-For header reports
+// the client IP. Without it, the header is ignored and clients are identified by the address they connect from.
+func WithTrustedProxies(proxies []string) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.proxies = proxies
+	}
+}
+
 // NewRouter sets up the HTTP routes for the application.
 //
 // Parameters:
-// - handler (*Handler): The HTTP handler containing the logic for URL shortening and login.
+// - handler (*Handler): The HTTP handler containing the logic for URL shortening and accounts.
 // - validator (auth.TokenValidator): The service used to validate JWT tokens.
+// - opts (...RouterOption): Optional dependencies, such as API key authentication and rate limits.
 //
 // Returns:
-// - *gin.Engine: The configured Gin router with public and protected endpoints.
-func NewRouter(handler *Handler, validator auth.TokenValidator) *gin.Engine {
+// - *gin.Engine: The configured Gin router with public, protected and redirect endpoints.
+func NewRouter(handler *Handler, validator auth.TokenValidator, opts ...RouterOption) *gin.Engine {
+	cfg := &routerConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	router := gin.Default()
+	// Rate limits and analytics key anonymous clients on their IP, so X-Forwarded-For is only honored from
+	// trusted proxies; otherwise each request could claim a new address.
+	if err := router.SetTrustedProxies(cfg.proxies); err != nil {
+		log.Printf("failed to set trusted proxies: %v", err)
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Token verification keys for other services
+	router.GET("/.well-known/jwks.json", handler.JWKS)
+
 	// Public routes
 	public := router.Group("/api")
+	public.Use(middleware.RateLimit(cfg.limiter, "auth", cfg.rateLimits.Auth))
 	{
 		public.POST("/login", handler.Login)
+		public.POST("/register", handler.Register)
+		public.POST("/token/refresh", handler.RefreshToken)
 	}
 
 	// Protected routes
+	// The limit is enforced per IP before authentication, so invalid tokens and API keys cannot be guessed
+	// without limit, and per user or API key after it.
 	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware(validator))
+	protected.Use(middleware.RateLimit(cfg.limiter, "api", cfg.rateLimits.API))
+	protected.Use(middleware.AuthMiddleware(validator, cfg.apiKeys))
+	protected.Use(middleware.RateLimit(cfg.limiter, "api", cfg.rateLimits.API))
+	{
+		protected.POST("/logout", handler.Logout)
+		protected.POST("/shorten", middleware.RequireScope(auth.ScopeLinksWrite), handler.ShortenURL)
+		protected.POST("/shorten/batch", middleware.RequireScope(auth.ScopeLinksWrite), handler.ShortenBatch)
+		protected.GET("/urls", middleware.RequireScope(auth.ScopeLinksRead), handler.ListURLs)
+		protected.GET("/urls/:shortID", middleware.RequireScope(auth.ScopeLinksRead), handler.GetURL)
+		protected.PATCH("/urls/:shortID", middleware.RequireScope(auth.ScopeLinksWrite), handler.UpdateURL)
+		protected.DELETE("/urls/:shortID", middleware.RequireScope(auth.ScopeLinksWrite), handler.DeleteURL)
+		protected.GET("/urls/:shortID/qr", middleware.RequireScope(auth.ScopeLinksRead), handler.QRCode)
+		protected.GET("/urls/:shortID/stats", middleware.RequireScope(auth.ScopeStatsRead), handler.Stats)
+		protected.POST("/domains", middleware.RequireScope(auth.ScopeLinksWrite), handler.CreateCustomDomain)
+		protected.GET("/domains", middleware.RequireScope(auth.ScopeLinksRead), handler.ListCustomDomains)
+		protected.POST("/domains/:domain/verify", middleware.RequireScope(auth.ScopeLinksWrite), handler.VerifyCustomDomain)
+		protected.DELETE("/domains/:domain", middleware.RequireScope(auth.ScopeLinksWrite), handler.DeleteCustomDomain)
+		protected.POST("/keys", middleware.RequireSession(), handler.CreateAPIKey)
+		protected.GET("/keys", middleware.RequireSession(), handler.ListAPIKeys)
+		protected.DELETE("/keys/:id", middleware.RequireSession(), handler.RevokeAPIKey)
+	}
+
+	// Admin routes
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireScope(auth.ScopeAdmin))
 	{
-		protected.POST("/shorten", handler.ShortenURL)
+		admin.PUT("/users/:id/role", handler.SetUserRole)
+		admin.POST("/import", handler.ImportURLs)
+		admin.GET("/export", handler.ExportURLs)
+		admin.GET("/domains", handler.ListDomainRules)
+		admin.PUT("/domains/:domain", handler.PutDomainRule)
+		admin.DELETE("/domains/:domain", handler.DeleteDomainRule)
 	}
 
+	// Public redirect
+	redirectLimit := middleware.RateLimit(cfg.limiter, "redirect", cfg.rateLimits.Redirect)
+	router.GET("/:shortID", redirectLimit, handler.Redirect)
+	router.POST("/:shortID", redirectLimit, handler.Redirect)
+
 	return router
 }
@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
-	"github.com/guttosm/url-shortener/config"
 	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/dto"
 	"github.com/guttosm/url-shortener/internal/entity"
 	apphttp "github.com/guttosm/url-shortener/internal/http"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
+	"github.com/guttosm/url-shortener/internal/service"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,42 +22,145 @@ import (
 
 type mockURLService struct{}
 
-func (m *mockURLService) Shorten(ctx context.Context, originalURL string) (*entity.URL, error) {
+func (m *mockURLService) Shorten(ctx context.Context, originalURL string, opts service.ShortenOptions) (*entity.URL, error) {
 	return &entity.URL{
 		ShortID:  "abc123",
 		Original: originalURL,
 	}, nil
 }
 
-func (m *mockURLService) FindByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
+func (m *mockURLService) ShortenBatch(ctx context.Context, items []service.BatchItem) ([]service.BatchResult, error) {
+	results := make([]service.BatchResult, len(items))
+	for i, item := range items {
+		results[i] = service.BatchResult{Index: i, URL: &entity.URL{ShortID: "abc123", Original: item.OriginalURL}}
+	}
+	return results, nil
+}
+
+func (m *mockURLService) Resolve(ctx context.Context, domain, shortID string) (*entity.URL, error) {
 	return &entity.URL{
 		ShortID:  shortID,
+		Domain:   domain,
 		Original: "https://original.url",
 	}, nil
 }
 
+func (m *mockURLService) Unlock(ctx context.Context, domain, shortID, password string) (*entity.URL, error) {
+	return m.Resolve(ctx, domain, shortID)
+}
+
+func (m *mockURLService) Peek(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	return m.Resolve(ctx, domain, shortID)
+}
+
+func (m *mockURLService) Get(ctx context.Context, ownerID, domain, shortID string) (*entity.URL, error) {
+	return &entity.URL{ShortID: shortID, Domain: domain, Original: "https://original.url", OwnerID: ownerID}, nil
+}
+
+func (m *mockURLService) List(ctx context.Context, ownerID string, opts service.ListOptions) (*service.URLPage, error) {
+	return &service.URLPage{Items: []*entity.URL{}, Page: 1, PageSize: service.DefaultPageSize}, nil
+}
+
+func (m *mockURLService) Update(ctx context.Context, ownerID, domain, shortID string, opts service.UpdateOptions) (*entity.URL, error) {
+	return &entity.URL{ShortID: shortID, Domain: domain, Original: "https://original.url", OwnerID: ownerID}, nil
+}
+
+func (m *mockURLService) Delete(ctx context.Context, ownerID, domain, shortID string) error {
+	return nil
+}
+
+// --- Mock para UserService (usado pelo handler) ---
+
+type mockUserService struct {
+	username     string
+	password     string
+	userID       string
+	registerFunc func(context.Context, string, string) (*entity.User, error)
+}
+
+func (m *mockUserService) Register(ctx context.Context, username, password string) (*entity.User, error) {
+	return m.registerFunc(ctx, username, password)
+}
+
+func (m *mockUserService) Authenticate(ctx context.Context, username, password string) (*entity.User, error) {
+	if m.username == "" || username != m.username || password != m.password {
+		return nil, service.ErrInvalidCredentials
+	}
+	return &entity.User{ID: m.userID, Username: username}, nil
+}
+
+func (m *mockUserService) SetRole(ctx context.Context, userID, role string) (*entity.User, error) {
+	if userID != "user-2" {
+		return nil, service.ErrUserNotFound
+	}
+	return &entity.User{ID: userID, Username: "bob", Role: role}, nil
+}
+
+func (m *mockUserService) EnsureUser(ctx context.Context, id, username, password, role string) error {
+	return nil
+}
+
 // --- Mock para TokenValidator (usado pelo middleware) ---
 
 type mockTokenValidator struct{}
 
 func (m *mockTokenValidator) ValidateToken(token string) (map[string]interface{}, error) {
-	if token == "valid-token" {
-		return map[string]interface{}{"user_id": "123"}, nil
+	switch token {
+	case "valid-token":
+		return map[string]interface{}{"user_id": "123", "jti": "access-jti", "scopes": auth.ScopesForRole(auth.RoleEditor)}, nil
+	case "viewer-token":
+		return map[string]interface{}{"user_id": "456", "jti": "viewer-jti", "scopes": auth.ScopesForRole(auth.RoleViewer)}, nil
+	case "admin-token":
+		return map[string]interface{}{"user_id": "789", "jti": "admin-jti", "scopes": []interface{}{auth.ScopeAdmin}}, nil
+	case "revoked-token":
+		return map[string]interface{}{"user_id": "123", "jti": "revoked-jti"}, nil
 	}
 	return nil, auth.ErrInvalidToken
 }
 
+// --- Mock para TokenService
+type mockTokenService struct {
+	mockTokenValidator
+	refreshFunc func(context.Context, string) (*service.TokenPair, error)
+	logoutFunc  func(context.Context, map[string]interface{}, string) error
+}
+
+func (m *mockTokenService) Issue(ctx context.Context, user *entity.User) (*service.TokenPair, error) {
+	return &service.TokenPair{
+		AccessToken:      "access-for-" + user.ID,
+		AccessExpiresAt:  time.Now().Add(15 * time.Minute),
+		RefreshToken:     "refresh-for-" + user.ID,
+		RefreshExpiresAt: time.Now().Add(24 * time.Hour),
+	}, nil
+}
+
+func (m *mockTokenService) Refresh(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+	return m.refreshFunc(ctx, refreshToken)
+}
+
+func (m *mockTokenService) Logout(ctx context.Context, accessClaims map[string]interface{}, refreshToken string) error {
+	return m.logoutFunc(ctx, accessClaims, refreshToken)
+}
+
+func (m *mockTokenService) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return tokenID == "revoked-jti", nil
+}
+
+func (m *mockTokenService) JWKS() auth.JWKS {
+	return auth.JWKS{Keys: []auth.JWK{{KeyType: "OKP", KeyID: "key-1", Use: "sig", Algorithm: auth.AlgEdDSA, Curve: "Ed25519", X: "AAAA"}}}
+}
+
 // --- Testes ---
 
 func TestRouter_PublicLoginRoute(t *testing.T) {
-	authCfg := config.AuthConfig{
-		Username: "any",
-		Password: "any",
-		UserID:   "user123",
+	users := &mockUserService{
+		username: "any",
+		password: "any",
+		userID:   "user123",
 	}
 
 	router := apphttp.NewRouter(
-		apphttp.NewHandler(&mockURLService{}, authCfg),
+		apphttp.NewHandler(&mockURLService{}, users, &mockTokenService{}),
 		&mockTokenValidator{},
 	)
 
@@ -76,7 +182,7 @@ func TestRouter_PublicLoginRoute(t *testing.T) {
 
 func TestRouter_ProtectedShorten_Unauthorized(t *testing.T) {
 	router := apphttp.NewRouter(
-		apphttp.NewHandler(&mockURLService{}, config.AuthConfig{}),
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
 		&mockTokenValidator{},
 	)
 
@@ -91,7 +197,7 @@ func TestRouter_ProtectedShorten_Unauthorized(t *testing.T) {
 
 func TestRouter_ProtectedShorten_Authorized(t *testing.T) {
 	router := apphttp.NewRouter(
-		apphttp.NewHandler(&mockURLService{}, config.AuthConfig{}),
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
 		&mockTokenValidator{},
 	)
 
@@ -113,7 +219,7 @@ func TestRouter_ProtectedShorten_Authorized(t *testing.T) {
 
 func TestRouter_SwaggerRoute(t *testing.T) {
 	router := apphttp.NewRouter(
-		apphttp.NewHandler(&mockURLService{}, config.AuthConfig{}),
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
 		&mockTokenValidator{},
 	)
 
@@ -129,3 +235,386 @@ func TestRouter_SwaggerRoute(t *testing.T) {
 		http.StatusNotFound, // caso os arquivos não estejam sendo servidos
 	}, w.Code)
 }
+
+func TestRouter_PublicRedirect(t *testing.T) {
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
+		&mockTokenValidator{},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://original.url", w.Header().Get("Location"))
+}
+
+func TestRouter_PublicRegisterRoute(t *testing.T) {
+	users := &mockUserService{
+		registerFunc: func(ctx context.Context, username, password string) (*entity.User, error) {
+			return &entity.User{ID: "user456", Username: username}, nil
+		},
+	}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, users, &mockTokenService{}),
+		&mockTokenValidator{},
+	)
+
+	body, _ := json.Marshal(map[string]string{"username": "alice", "password": "correct-horse"})
+	req := httptest.NewRequest(http.MethodPost, "/api/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "user456")
+}
+
+func TestRouter_ProtectedListURLs(t *testing.T) {
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
+		&mockTokenValidator{},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/urls", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/urls", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "items")
+}
+
+func TestRouter_JWKS(t *testing.T) {
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
+		&mockTokenValidator{},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var jwks auth.JWKS
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "key-1", jwks.Keys[0].KeyID)
+}
+
+func TestRouter_Logout(t *testing.T) {
+	var revoked []string
+	tokens := &mockTokenService{
+		logoutFunc: func(ctx context.Context, claims map[string]interface{}, refreshToken string) error {
+			revoked = append(revoked, claims["jti"].(string), refreshToken)
+			return nil
+		},
+	}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, tokens),
+		tokens,
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", bytes.NewBufferString(`{"refresh_token":"refresh-1"}`))
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"access-jti", "refresh-1"}, revoked)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRouter_RevokedTokenRejected(t *testing.T) {
+	tokens := &mockTokenService{}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, tokens),
+		tokens,
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/urls", nil)
+	req.Header.Set("Authorization", "Bearer revoked-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "revoked")
+}
+
+func TestRouter_RefreshToken(t *testing.T) {
+	tokens := &mockTokenService{
+		refreshFunc: func(ctx context.Context, refreshToken string) (*service.TokenPair, error) {
+			if refreshToken != "refresh-1" {
+				return nil, service.ErrInvalidRefreshToken
+			}
+			return &service.TokenPair{AccessToken: "access-2", RefreshToken: "refresh-2", AccessExpiresAt: time.Now().Add(time.Minute)}, nil
+		},
+	}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, tokens),
+		tokens,
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewBufferString(`{"refresh_token":"refresh-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp dto.TokenResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "access-2", resp.AccessToken)
+	assert.Equal(t, "refresh-2", resp.RefreshToken)
+	assert.Equal(t, "Bearer", resp.TokenType)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/token/refresh", bytes.NewBufferString(`{"refresh_token":"refresh-1-reused"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// --- Mock para APIKeyService
+type mockAPIKeyService struct {
+	keys        []*entity.APIKey
+	deletedUser string
+}
+
+func (m *mockAPIKeyService) Create(ctx context.Context, userID, name string, scopes []string) (*entity.APIKey, string, error) {
+	if name == "" {
+		return nil, "", service.ErrInvalidAPIKeyName
+	}
+	if userID == m.deletedUser {
+		return nil, "", service.ErrUserNotFound
+	}
+	key := &entity.APIKey{ID: "key-1", UserID: userID, Name: name, Prefix: "usk_abcd", KeyHash: "hash", Scopes: scopes}
+	m.keys = append(m.keys, key)
+	return key, "usk_abcdsecret", nil
+}
+
+func (m *mockAPIKeyService) List(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *mockAPIKeyService) Revoke(ctx context.Context, userID, id string) error {
+	return service.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (map[string]interface{}, error) {
+	if key != "usk_abcdsecret" {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	return map[string]interface{}{"user_id": "123", "api_key_id": "key-1", "scopes": []string{auth.ScopeLinksRead}}, nil
+}
+
+func TestRouter_APIKeys(t *testing.T) {
+	apiKeys := &mockAPIKeyService{}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}, apphttp.WithAPIKeys(apiKeys)),
+		&mockTokenValidator{},
+		apphttp.WithAPIKeyAuth(apiKeys),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", bytes.NewBufferString(`{"name":"ci","scopes":["links:write"]}`))
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created dto.CreatedAPIKeyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "usk_abcdsecret", created.Key)
+	assert.Equal(t, "key-1", created.ID)
+
+	// Listing never returns the key or its hash.
+	req = httptest.NewRequest(http.MethodGet, "/api/keys", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"ci"`)
+	assert.NotContains(t, w.Body.String(), "secret")
+	assert.NotContains(t, w.Body.String(), "hash")
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/keys/unknown", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRouter_APIKeysOfDeletedUser(t *testing.T) {
+	apiKeys := &mockAPIKeyService{deletedUser: "123"}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}, apphttp.WithAPIKeys(apiKeys)),
+		&mockTokenValidator{},
+		apphttp.WithAPIKeyAuth(apiKeys),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", bytes.NewBufferString(`{"name":"ci","scopes":["links:read"]}`))
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRouter_APIKeysCannotManageKeys(t *testing.T) {
+	apiKeys := &mockAPIKeyService{}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}, apphttp.WithAPIKeys(apiKeys)),
+		&mockTokenValidator{},
+		apphttp.WithAPIKeyAuth(apiKeys),
+	)
+
+	// The key only holds links:read, so it must not mint a links:write key, list or revoke keys.
+	for _, tt := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/api/keys", `{"name":"escalated","scopes":["links:write","stats:read"]}`},
+		{http.MethodGet, "/api/keys", ""},
+		{http.MethodDelete, "/api/keys/key-2", ""},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("X-API-Key", "usk_abcdsecret")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, tt.method+" "+tt.path)
+	}
+	assert.Empty(t, apiKeys.keys)
+}
+
+func TestRouter_RequireScope(t *testing.T) {
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
+		&mockTokenValidator{},
+	)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"viewer can list links", http.MethodGet, "/api/urls", "viewer-token", "", http.StatusOK},
+		{"viewer cannot shorten", http.MethodPost, "/api/shorten", "viewer-token", `{"url":"https://example.com"}`, http.StatusForbidden},
+		{"viewer cannot delete", http.MethodDelete, "/api/urls/abc123", "viewer-token", "", http.StatusForbidden},
+		{"editor can shorten", http.MethodPost, "/api/shorten", "valid-token", `{"url":"https://example.com"}`, http.StatusOK},
+		{"admin scope implies links:write", http.MethodPost, "/api/shorten", "admin-token", `{"url":"https://example.com"}`, http.StatusOK},
+		{"editor cannot assign roles", http.MethodPut, "/api/admin/users/user-2/role", "valid-token", `{"role":"viewer"}`, http.StatusForbidden},
+		{"admin assigns roles", http.MethodPut, "/api/admin/users/user-2/role", "admin-token", `{"role":"viewer"}`, http.StatusOK},
+		{"admin assigns unknown role", http.MethodPut, "/api/admin/users/user-2/role", "admin-token", `{"role":"root"}`, http.StatusBadRequest},
+		{"admin assigns role to unknown user", http.MethodPut, "/api/admin/users/missing/role", "admin-token", `{"role":"viewer"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRouter_APIKeyScopes(t *testing.T) {
+	apiKeys := &mockAPIKeyService{}
+	router := apphttp.NewRouter(
+		apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}, apphttp.WithAPIKeys(apiKeys)),
+		&mockTokenValidator{},
+		apphttp.WithAPIKeyAuth(apiKeys),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/urls", nil)
+	req.Header.Set("X-API-Key", "usk_abcdsecret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(`{"url":"https://example.com"}`))
+	req.Header.Set("X-API-Key", "usk_abcdsecret")
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), auth.ScopeLinksWrite)
+}
+
+func TestRouter_RateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	limits := apphttp.RateLimits{Auth: ratelimit.Limit{Requests: 1, Window: time.Minute}}
+	newRouter := func(opts ...apphttp.RouterOption) http.Handler {
+		users := &mockUserService{username: "any", password: "any", userID: "user123"}
+		opts = append(opts, apphttp.WithRateLimits(ratelimit.NewMemoryLimiter(), limits))
+		return apphttp.NewRouter(apphttp.NewHandler(&mockURLService{}, users, &mockTokenService{}), &mockTokenValidator{}, opts...)
+	}
+	login := func(router http.Handler, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBufferString(`{"username":"any","password":"any"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	router := newRouter()
+	assert.Equal(t, http.StatusOK, login(router, "203.0.113.7:5000", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, login(router, "203.0.113.7:5000", "198.51.100.2"),
+		"a spoofed X-Forwarded-For must not reset the limit")
+
+	// Behind a trusted proxy, clients are told apart by the address it forwards.
+	router = newRouter(apphttp.WithTrustedProxies([]string{"10.0.0.0/8"}))
+	assert.Equal(t, http.StatusOK, login(router, "10.1.2.3:5000", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, login(router, "10.1.2.3:5000", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, login(router, "10.1.2.3:5000", "198.51.100.1"))
+}
+
+func TestRouter_RateLimitCountsFailedAuthentication(t *testing.T) {
+	limits := apphttp.RateLimits{API: ratelimit.Limit{Requests: 2, Window: time.Minute}}
+	router := apphttp.NewRouter(apphttp.NewHandler(&mockURLService{}, &mockUserService{}, &mockTokenService{}),
+		&mockTokenValidator{}, apphttp.WithRateLimits(ratelimit.NewMemoryLimiter(), limits))
+	listURLs := func(remoteAddr, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/urls", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, listURLs("203.0.113.7:5000", "guess-1"))
+	assert.Equal(t, http.StatusUnauthorized, listURLs("203.0.113.7:5000", "guess-2"))
+	assert.Equal(t, http.StatusTooManyRequests, listURLs("203.0.113.7:5000", "guess-3"),
+		"invalid tokens are limited before they are checked")
+	assert.Equal(t, http.StatusTooManyRequests, listURLs("203.0.113.7:5000", "valid-token"))
+	assert.NotEqual(t, http.StatusTooManyRequests, listURLs("198.51.100.1:5000", "valid-token"))
+}
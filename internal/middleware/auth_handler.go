@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,10 +10,21 @@ import (
 )
 
 // AuthMiddleware returns a Gin middleware that validates JWT tokens
-// and injects the user ID into the context if the token is valid.
-func AuthMiddleware(validator auth.TokenValidator) gin.HandlerFunc {
+// and injects the user ID, scopes and token claims into the context if the token is valid.
+// If the validator implements auth.RevocationChecker, revoked tokens are rejected as well.
+//
+// Requests may instead send an API key, as "Authorization: ApiKey <key>" or in the
+// X-API-Key header; it is checked with apiKeys, which may be nil to accept bearer tokens only.
+// API key requests get the same user_id and scopes context values, plus the api_key_id.
+func AuthMiddleware(validator auth.TokenValidator, apiKeys auth.APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+
+		if key, ok := apiKeyFromRequest(c, authHeader); ok {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			AbortWithError(c, http.StatusUnauthorized, "Authorization header is required", nil)
 			return
@@ -31,7 +43,84 @@ func AuthMiddleware(validator auth.TokenValidator) gin.HandlerFunc {
 			return
 		}
 
+		if checker, ok := validator.(auth.RevocationChecker); ok {
+			tokenID, _ := claims["jti"].(string)
+			if tokenID == "" {
+				AbortWithError(c, http.StatusUnauthorized, "jti claim is missing", nil)
+				return
+			}
+			revoked, err := checker.IsRevoked(c.Request.Context(), tokenID)
+			if err != nil {
+				AbortWithError(c, http.StatusServiceUnavailable, "Unable to verify token", err)
+				return
+			}
+			if revoked {
+				AbortWithError(c, http.StatusUnauthorized, "Token has been revoked", nil)
+				return
+			}
+		}
+
 		c.Set("user_id", userID)
+		c.Set("scopes", claimScopes(claims))
+		c.Set("token_claims", claims)
 		c.Next()
 	}
 }
+
+// apiKeyFromRequest extracts an API key from the Authorization or X-API-Key header.
+func apiKeyFromRequest(c *gin.Context, authHeader string) (string, bool) {
+	if strings.HasPrefix(authHeader, "ApiKey ") {
+		return strings.TrimSpace(strings.TrimPrefix(authHeader, "ApiKey ")), true
+	}
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key, true
+	}
+	return "", false
+}
+
+// authenticateAPIKey authenticates the request with an API key and continues the chain on success.
+func authenticateAPIKey(c *gin.Context, apiKeys auth.APIKeyAuthenticator, key string) {
+	if apiKeys == nil {
+		AbortWithError(c, http.StatusUnauthorized, "API keys are not accepted", nil)
+		return
+	}
+
+	claims, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAPIKey) {
+			AbortWithError(c, http.StatusUnauthorized, "Invalid API key", err)
+			return
+		}
+		AbortWithError(c, http.StatusServiceUnavailable, "Unable to verify API key", err)
+		return
+	}
+
+	userID, ok := claims["user_id"]
+	if !ok {
+		AbortWithError(c, http.StatusUnauthorized, "user_id claim is missing", nil)
+		return
+	}
+
+	c.Set("user_id", userID)
+	c.Set("scopes", claimScopes(claims))
+	c.Set("api_key_id", claims["api_key_id"])
+	c.Next()
+}
+
+// claimScopes returns the "scopes" claim, which is a []interface{} when decoded from a JWT.
+func claimScopes(claims map[string]interface{}) []string {
+	switch v := claims["scopes"].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		scopes := make([]string, 0, len(v))
+		for _, scope := range v {
+			if s, ok := scope.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
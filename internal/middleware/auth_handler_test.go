@@ -1,12 +1,14 @@
 package middleware_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/guttosm/url-shortener/internal/auth"
 	"github.com/guttosm/url-shortener/internal/middleware"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,7 +32,7 @@ func TestAuthMiddleware_NoAuthHeader(t *testing.T) {
 		},
 	}
 
-	router.Use(middleware.AuthMiddleware(mockValidator))
+	router.Use(middleware.AuthMiddleware(mockValidator, nil))
 	router.GET("/protected", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
@@ -53,7 +55,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 		},
 	}
 
-	router.Use(middleware.AuthMiddleware(mockValidator))
+	router.Use(middleware.AuthMiddleware(mockValidator, nil))
 	router.GET("/protected", func(c *gin.Context) {
 		c.Status(http.StatusOK)
 	})
@@ -77,7 +79,7 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 		},
 	}
 
-	router.Use(middleware.AuthMiddleware(mockValidator))
+	router.Use(middleware.AuthMiddleware(mockValidator, nil))
 	router.GET("/protected", func(c *gin.Context) {
 		userID, exists := c.Get("user_id")
 		assert.True(t, exists)
@@ -94,3 +96,187 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "123")
 }
+
+// MockRevocationChecker implements the auth.RevocationChecker interface
+type MockRevocationChecker struct {
+	MockTokenValidator
+	RevokedIDs map[string]bool
+}
+
+func (m *MockRevocationChecker) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return m.RevokedIDs[tokenID], nil
+}
+
+func TestAuthMiddleware_RevokedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	checker := &MockRevocationChecker{
+		MockTokenValidator: MockTokenValidator{
+			ValidateTokenFunc: func(token string) (map[string]interface{}, error) {
+				if token == "no_jti" {
+					return map[string]interface{}{"user_id": "123"}, nil
+				}
+				return map[string]interface{}{"user_id": "123", "jti": token}, nil
+			},
+		},
+		RevokedIDs: map[string]bool{"revoked": true},
+	}
+
+	router.Use(middleware.AuthMiddleware(checker, nil))
+	router.GET("/protected", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for token, status := range map[string]int{
+		"active":  http.StatusOK,
+		"revoked": http.StatusUnauthorized,
+		"no_jti":  http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, status, w.Code, token)
+	}
+}
+
+// MockAPIKeyAuthenticator implements the auth.APIKeyAuthenticator interface
+type MockAPIKeyAuthenticator struct {
+	Err error
+}
+
+func (m *MockAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, key string) (map[string]interface{}, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if key != "usk_valid" {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	return map[string]interface{}{"user_id": "machine-user", "api_key_id": "key-1"}, nil
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenValidator := &MockTokenValidator{
+		ValidateTokenFunc: func(token string) (map[string]interface{}, error) {
+			t.Fatalf("bearer validation should not run for API key requests")
+			return nil, nil
+		},
+	}
+
+	newRouter := func(apiKeys auth.APIKeyAuthenticator) *gin.Engine {
+		router := gin.New()
+		router.Use(middleware.AuthMiddleware(tokenValidator, apiKeys))
+		router.GET("/protected", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "api_key_id": c.GetString("api_key_id")})
+		})
+		return router
+	}
+
+	tests := []struct {
+		name    string
+		apiKeys auth.APIKeyAuthenticator
+		header  string
+		value   string
+		status  int
+	}{
+		{"authorization header", &MockAPIKeyAuthenticator{}, "Authorization", "ApiKey usk_valid", http.StatusOK},
+		{"x-api-key header", &MockAPIKeyAuthenticator{}, "X-API-Key", "usk_valid", http.StatusOK},
+		{"invalid key", &MockAPIKeyAuthenticator{}, "X-API-Key", "usk_invalid", http.StatusUnauthorized},
+		{"store unavailable", &MockAPIKeyAuthenticator{Err: errors.New("mongo down")}, "X-API-Key", "usk_valid", http.StatusServiceUnavailable},
+		{"api keys disabled", nil, "X-API-Key", "usk_valid", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+
+			newRouter(tt.apiKeys).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Contains(t, w.Body.String(), "machine-user")
+				assert.Contains(t, w.Body.String(), "key-1")
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(scopes interface{}) *gin.Engine {
+		router := gin.New()
+		router.Use(middleware.AuthMiddleware(&MockTokenValidator{
+			ValidateTokenFunc: func(token string) (map[string]interface{}, error) {
+				return map[string]interface{}{"user_id": "123", "scopes": scopes}, nil
+			},
+		}, nil))
+		router.GET("/links", middleware.RequireScope(auth.ScopeLinksRead), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		router.GET("/both", middleware.RequireScope(auth.ScopeLinksRead, auth.ScopeStatsRead), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name   string
+		scopes interface{}
+		path   string
+		status int
+	}{
+		// Scopes decoded from a JWT arrive as []interface{}.
+		{"granted", []interface{}{"links:read"}, "/links", http.StatusOK},
+		{"missing", []interface{}{"stats:read"}, "/links", http.StatusForbidden},
+		{"no scopes claim", nil, "/links", http.StatusForbidden},
+		{"all required", []interface{}{"links:read"}, "/both", http.StatusForbidden},
+		{"admin implies all", []string{"admin"}, "/both", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+
+			newRouter(tt.scopes).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "123")
+		if id := c.GetHeader("X-Test-Key"); id != "" {
+			c.Set("api_key_id", id)
+		}
+		c.Next()
+	})
+	router.POST("/keys", middleware.RequireSession(), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	send := func(keyID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/keys", nil)
+		req.Header.Set("X-Test-Key", keyID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send(""))
+	assert.Equal(t, http.StatusForbidden, send("key-1"))
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
+)
+
+// RateLimit returns a Gin middleware that limits how often each client may call the routes of a group.
+//
+// Behavior:
+// - Clients are identified by the api_key_id or user_id set by AuthMiddleware, falling back to the client IP,
+// so it limits users when it runs after AuthMiddleware and IP addresses when it runs before.
+// - Counters are kept per group, under "<group>:<client>", so groups with different limits do not share them.
+// - Every response carries the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers.
+// - Requests over the limit are rejected with 429 and a Retry-After header.
+// - If the limiter fails, the request is let through, so rate limiting never takes the API down.
+// - A nil limiter or a disabled limit turns the middleware into a no-op.
+//
+// Parameters:
+// - limiter (ratelimit.Limiter): Counts the requests.
+// - group (string): The name of the route group, e.g. "auth" or "api".
+// - limit (ratelimit.Limit): The limit of the route group.
+//
+// Returns:
+// - gin.HandlerFunc: The middleware.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	if limiter == nil || !limit.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	policy := strconv.Itoa(limit.Requests) + ";w=" + strconv.Itoa(ceilSeconds(limit.Window))
+
+	return func(c *gin.Context) {
+		result, err := limiter.Allow(c.Request.Context(), group+":"+rateLimitClient(c), limit)
+		if err != nil {
+			log.Printf("rate limit check failed: %v", err)
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(ceilSeconds(result.Reset))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+		c.Header("RateLimit-Policy", policy)
+
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			AbortWithError(c, http.StatusTooManyRequests, "Too many requests", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitClient identifies the client of a request: its API key, its user or its IP address.
+func rateLimitClient(c *gin.Context) string {
+	if id := c.GetString("api_key_id"); id != "" {
+		return "key:" + id
+	}
+	if id := c.GetString("user_id"); id != "" {
+		return "user:" + id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds rounds a duration up to whole seconds, so clients never retry too early.
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,79 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guttosm/url-shortener/internal/middleware"
+	"github.com/guttosm/url-shortener/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingLimiter implements ratelimit.Limiter and always fails.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis: connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("user_id", user)
+		}
+		c.Next()
+	})
+	router.Use(middleware.RateLimit(ratelimit.NewMemoryLimiter(), "api", ratelimit.Limit{Requests: 2, Window: time.Minute}))
+	router.GET("/limited", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("user-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusOK, send("user-1").Code)
+
+	w = send("user-1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Other clients have their own counters.
+	assert.Equal(t, http.StatusOK, send("user-2").Code)
+	assert.Equal(t, http.StatusOK, send("").Code)
+}
+
+func TestRateLimit_FailsOpen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RateLimit(failingLimiter{}, "api", ratelimit.Limit{Requests: 1, Window: time.Minute}))
+	router.GET("/limited", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guttosm/url-shortener/internal/auth"
+)
+
+// RequireScope returns a Gin middleware that only lets requests through if the caller,
+// as authenticated by AuthMiddleware, has every given scope. The admin scope satisfies any scope.
+//
+// It is meant to be composed per route, after AuthMiddleware:
+//
+//	protected.POST("/shorten", middleware.RequireScope(auth.ScopeLinksWrite), handler.ShortenURL)
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("scopes")
+		for _, scope := range scopes {
+			if !auth.HasScope(granted, scope) {
+				AbortWithError(c, http.StatusForbidden, "Missing required scope: "+scope, nil)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession returns a Gin middleware that rejects requests authenticated with an API key,
+// so only users signed in with a bearer token reach the route. It guards API key management,
+// where a key could otherwise mint keys with scopes it was not granted.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") != "" {
+			AbortWithError(c, http.StatusForbidden, "API keys cannot manage API keys", nil)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package qrcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The expected codewords below were produced by an independent encoder.
+
+func TestAddErrorCorrection_SingleBlock(t *testing.T) {
+	// "HELLO WORLD" in alphanumeric mode as version 1-Q, the worked example of the standard.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	ecc := []byte{168, 72, 22, 82, 217, 54, 156, 0, 46, 15, 180, 122, 16}
+
+	assert.Equal(t, append(data, ecc...), addErrorCorrection(data, 1, LevelQ))
+}
+
+func TestAddErrorCorrection_InterleavesBlocks(t *testing.T) {
+	// Version 5-Q splits its 62 data codewords into two blocks of 15 and two of 16, each with 18 EC codewords.
+	data := []byte{
+		67, 38, 135, 71, 71, 7, 51, 162, 242, 247, 54, 134, 242, 231, 39, 66,
+		246, 70, 246, 55, 50, 246, 118, 87, 71, 70, 150, 230, 114, 215, 55, 70,
+		23, 39, 70, 86, 67, 247, 38, 86, 99, 214, 230, 87, 119, 54, 198, 87,
+		71, 70, 87, 32, 236, 17, 236, 17, 236, 17, 236, 17, 236, 17,
+	}
+	want := []byte{
+		67, 66, 55, 198, 38, 246, 70, 87, 135, 70, 23, 71, 71, 246, 39, 70,
+		71, 55, 70, 87, 7, 50, 86, 32, 51, 246, 67, 236, 162, 118, 247, 17,
+		242, 87, 38, 236, 247, 71, 86, 17, 54, 70, 99, 236, 134, 150, 214, 17,
+		242, 230, 230, 236, 231, 114, 87, 17, 39, 215, 119, 236, 54, 17, 77, 247,
+		192, 55, 193, 255, 85, 176, 213, 190, 48, 118, 9, 20, 192, 60, 102, 58,
+		247, 183, 133, 117, 33, 238, 91, 110, 223, 75, 21, 135, 136, 188, 112, 82,
+		242, 52, 30, 96, 186, 203, 30, 186, 199, 81, 193, 29, 153, 101, 48, 45,
+		77, 225, 6, 245, 157, 131, 62, 198, 48, 76, 14, 115, 49, 114, 62, 221,
+		246, 2, 135, 35, 5, 236,
+	}
+
+	encoded := encodeData([]byte("https://sho.rt/docs/getting-started?ref=newsletter"), 5, LevelQ)
+	assert.Equal(t, data, encoded)
+	assert.Equal(t, want, addErrorCorrection(encoded, 5, LevelQ))
+}
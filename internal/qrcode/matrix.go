@@ -0,0 +1,283 @@
+package qrcode
+
+// matrix is the module grid of a QR code while it is being built.
+//
+// Fields:
+// - version (int): The version of the code.
+// - size (int): The number of modules per side.
+// - modules ([]bool): Whether each module is dark, row by row.
+// - function ([]bool): Whether each module belongs to a function pattern and must not hold data or be masked.
+type matrix struct {
+	version  int
+	size     int
+	modules  []bool
+	function []bool
+}
+
+// newMatrix creates an empty grid for a version.
+func newMatrix(version int) *matrix {
+	size := 17 + 4*version
+	return &matrix{
+		version:  version,
+		size:     size,
+		modules:  make([]bool, size*size),
+		function: make([]bool, size*size),
+	}
+}
+
+// setFunction sets a module of a function pattern.
+func (m *matrix) setFunction(x, y int, dark bool) {
+	m.modules[y*m.size+x] = dark
+	m.function[y*m.size+x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and the version information,
+// and reserves the format information with the given level and mask 0.
+func (m *matrix) drawFunctionPatterns(level Level) {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	m.drawFinder(3, 3)
+	m.drawFinder(m.size-4, 3)
+	m.drawFinder(3, m.size-4)
+
+	positions := alignmentPositions(m.version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.drawAlignment(x, y)
+		}
+	}
+
+	m.drawFormatBits(level, 0)
+	m.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator around the center x, y.
+func (m *matrix) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= m.size || yy >= m.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			m.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern around the center x, y.
+func (m *matrix) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the row and column coordinates of the alignment pattern centers of a version.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, 17+4*version-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// drawFormatBits draws both copies of the format information: the level and the mask,
+// protected by a BCH code.
+func (m *matrix) drawFormatBits(level Level, mask int) {
+	data := formatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true)
+}
+
+// drawVersion draws both copies of the version information of versions 7 and up,
+// protected by a BCH code.
+func (m *matrix) drawVersion() {
+	if m.version < 7 {
+		return
+	}
+	rem := m.version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := m.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>i&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, dark)
+		m.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the two-module wide columns zigzagging from the bottom right corner,
+// skipping function patterns. Modules left over stay light.
+func (m *matrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < m.size; vert++ {
+			y := vert
+			if upward {
+				y = m.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if m.function[y*m.size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				m.modules[y*m.size+x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by a mask pattern. Applying a mask twice undoes it.
+func (m *matrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !m.function[y*m.size+x] {
+				m.modules[y*m.size+x] = !m.modules[y*m.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the grid is to scan, using the four rules of the standard.
+func (m *matrix) penalty() int {
+	dark := func(x, y int) bool { return m.modules[y*m.size+x] }
+	score := 0
+
+	for _, transpose := range []bool{false, true} {
+		at := dark
+		if transpose {
+			at = func(x, y int) bool { return dark(y, x) }
+		}
+		for y := 0; y < m.size; y++ {
+			run := 1
+			for x := 1; x <= m.size; x++ {
+				if x < m.size && at(x, y) == at(x-1, y) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += penaltyRun + run - 5
+				}
+				run = 1
+			}
+			for x := 0; x+11 <= m.size; x++ {
+				if matchesFinderLike(func(i int) bool { return at(x+i, y) }) {
+					score += penaltyFinder
+				}
+			}
+		}
+	}
+
+	darkCount := 0
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if dark(x, y) {
+				darkCount++
+			}
+			if x+1 < m.size && y+1 < m.size {
+				c := dark(x, y)
+				if c == dark(x+1, y) && c == dark(x, y+1) && c == dark(x+1, y+1) {
+					score += penaltyBlock
+				}
+			}
+		}
+	}
+
+	total := m.size * m.size
+	score += abs(darkCount*2-total) * 10 / total * penaltyBalance
+	return score
+}
+
+// finderLike holds the patterns 1011101 0000 and 0000 1011101, which look like a finder pattern to scanners.
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// matchesFinderLike reports whether the eleven modules returned by at match one of the finderLike patterns.
+func matchesFinderLike(at func(i int) bool) bool {
+	for _, pattern := range finderLike {
+		matched := true
+		for i, want := range pattern {
+			if at(i) != want {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// abs returns the absolute value of an int.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,305 @@
+// Package qrcode encodes data as QR codes (ISO/IEC 18004) and renders them as PNG or SVG images.
+//
+// Data is always encoded in byte mode, which covers URLs of any character set.
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Level is the error correction level of a QR code: the share of the code that can be damaged
+// while it still scans.
+type Level int
+
+const (
+	// LevelL recovers about 7% of the code.
+	LevelL Level = iota
+	// LevelM recovers about 15% of the code.
+	LevelM
+	// LevelQ recovers about 25% of the code.
+	LevelQ
+	// LevelH recovers about 30% of the code.
+	LevelH
+)
+
+var (
+	// ErrInvalidLevel is returned when an error correction level is not L, M, Q or H.
+	ErrInvalidLevel = errors.New(`error correction level must be "L", "M", "Q" or "H"`)
+	// ErrDataTooLong is returned when data does not fit in the largest QR code at the requested level.
+	ErrDataTooLong = errors.New("data is too long for a QR code")
+)
+
+// formatBits maps a level to the two bits identifying it in the format information.
+var formatBits = [...]int{LevelL: 1, LevelM: 0, LevelQ: 3, LevelH: 2}
+
+// eccCodewordsPerBlock holds the error correction codewords of each block, by level and version.
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// eccBlocks holds the number of error correction blocks, by level and version.
+var eccBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Penalty weights of the mask evaluation rules.
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// ParseLevel parses an error correction level.
+//
+// Parameters:
+// - s (string): "L", "M", "Q" or "H", in any case.
+//
+// Returns:
+// - Level: The level.
+// - error: ErrInvalidLevel if the value is unknown.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	default:
+		return 0, ErrInvalidLevel
+	}
+}
+
+// String returns the letter of the level.
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// Code is an encoded QR code: a square grid of dark and light modules, without the quiet zone.
+//
+// Fields:
+// - Version (int): The version, from 1 to 40, which fixes the size.
+// - Size (int): The number of modules per side, 17 + 4*Version.
+type Code struct {
+	Version int
+	Size    int
+
+	modules []bool
+}
+
+// Dark reports whether the module in column x and row y is dark.
+// Coordinates outside the grid are light, like the quiet zone.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y*c.Size+x]
+}
+
+// Encode encodes data in the smallest QR code that holds it at the given level.
+//
+// Parameters:
+// - data ([]byte): The data, typically a URL.
+// - level (Level): The error correction level.
+//
+// Behavior:
+// - Encodes the data in byte mode and picks the smallest version it fits in.
+// - Tries all eight masks and keeps the one with the lowest penalty, as the standard requires.
+//
+// Returns:
+// - *Code: The QR code.
+// - error: ErrInvalidLevel for an unknown level, or ErrDataTooLong if the data does not fit in version 40.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < LevelL || level > LevelH {
+		return nil, ErrInvalidLevel
+	}
+
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+countBits(v)+8*len(data) <= dataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	codewords := addErrorCorrection(encodeData(data, version, level), version, level)
+
+	m := newMatrix(version)
+	m.drawFunctionPatterns(level)
+	m.drawCodewords(codewords)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.drawFormatBits(level, mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		m.applyMask(mask)
+	}
+	m.applyMask(bestMask)
+	m.drawFormatBits(level, bestMask)
+
+	return &Code{Version: version, Size: m.size, modules: m.modules}, nil
+}
+
+// countBits returns the length of the character count indicator of byte mode.
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// rawDataModules returns the number of modules left for data and error correction in a version,
+// once the function patterns, format and version information are placed.
+func rawDataModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// dataCodewords returns the number of data codewords of a version and level.
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// bitBuffer accumulates bits, most significant first.
+type bitBuffer struct {
+	bytes []byte
+	n     int
+}
+
+// append adds the low count bits of value.
+func (b *bitBuffer) append(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if value>>i&1 == 1 {
+			b.bytes[b.n/8] |= 0x80 >> (b.n % 8)
+		}
+		b.n++
+	}
+}
+
+// encodeData builds the data codewords: the byte mode segment, the terminator and the padding.
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := dataCodewords(version, level) * 8
+
+	var b bitBuffer
+	b.append(0b0100, 4)
+	b.append(len(data), countBits(version))
+	for _, c := range data {
+		b.append(int(c), 8)
+	}
+	b.append(0, min(4, capacity-b.n))
+	b.append(0, (8-b.n%8)%8)
+	for pad := 0xEC; b.n < capacity; pad ^= 0xEC ^ 0x11 {
+		b.append(pad, 8)
+	}
+	return b.bytes
+}
+
+// addErrorCorrection splits the data codewords into blocks, appends the Reed-Solomon codewords of each
+// block and interleaves the blocks.
+func addErrorCorrection(data []byte, version int, level Level) []byte {
+	numBlocks := eccBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	raw := rawDataModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsGenerator(eccLen)
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < numShort {
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// rsGenerator returns the coefficients of the Reed-Solomon generator polynomial of a degree,
+// highest power first and without the leading 1.
+func rsGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the Reed-Solomon error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies two elements of GF(2^8) modulo the QR code polynomial x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		hi := z & 0x80
+		z <<= 1
+		if hi != 0 {
+			z ^= 0x1D
+		}
+		if y>>i&1 == 1 {
+			z ^= x
+		}
+	}
+	return z
+}
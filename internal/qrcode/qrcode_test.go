@@ -0,0 +1,240 @@
+package qrcode_test
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formatInfo reads both copies of the 15 format bits of a code.
+func formatInfo(code *qrcode.Code) (first, second int) {
+	bit := func(dark bool, i int) int {
+		if dark {
+			return 1 << i
+		}
+		return 0
+	}
+	for i := 0; i <= 5; i++ {
+		first |= bit(code.Dark(8, i), i)
+	}
+	first |= bit(code.Dark(8, 7), 6) | bit(code.Dark(8, 8), 7) | bit(code.Dark(7, 8), 8)
+	for i := 9; i < 15; i++ {
+		first |= bit(code.Dark(14-i, 8), i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(code.Dark(code.Size-1-i, 8), i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(code.Dark(8, code.Size-15+i), i)
+	}
+	return first, second
+}
+
+func TestEncode_VersionSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		level   qrcode.Level
+		version int
+	}{
+		{"fits version 1 L", 17, qrcode.LevelL, 1},
+		{"overflows version 1 L", 18, qrcode.LevelL, 2},
+		{"fits version 1 H", 7, qrcode.LevelH, 1},
+		{"overflows version 1 H", 8, qrcode.LevelH, 2},
+		{"fits version 10 M", 213, qrcode.LevelM, 10},
+		{"largest code", 2953, qrcode.LevelL, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := qrcode.Encode(bytes.Repeat([]byte("a"), tt.length), tt.level)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, code.Version)
+			assert.Equal(t, 17+4*tt.version, code.Size)
+		})
+	}
+
+	_, err := qrcode.Encode(bytes.Repeat([]byte("a"), 2954), qrcode.LevelL)
+	assert.ErrorIs(t, err, qrcode.ErrDataTooLong)
+
+	_, err = qrcode.Encode([]byte("a"), qrcode.Level(7))
+	assert.ErrorIs(t, err, qrcode.ErrInvalidLevel)
+}
+
+func TestEncode_FunctionPatterns(t *testing.T) {
+	for _, level := range []qrcode.Level{qrcode.LevelL, qrcode.LevelM, qrcode.LevelQ, qrcode.LevelH} {
+		t.Run(level.String(), func(t *testing.T) {
+			code, err := qrcode.Encode([]byte("https://sho.rt/abc123"), level)
+			require.NoError(t, err)
+
+			for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+				for dy := -1; dy <= 7; dy++ {
+					for dx := -1; dx <= 7; dx++ {
+						ring := max(abs(dx-3), abs(dy-3))
+						want := ring != 2 && ring != 4
+						assert.Equal(t, want, code.Dark(corner[0]+dx, corner[1]+dy), "finder module %d,%d", corner[0]+dx, corner[1]+dy)
+					}
+				}
+			}
+			for i := 8; i < code.Size-8; i++ {
+				assert.Equal(t, i%2 == 0, code.Dark(i, 6), "horizontal timing module %d", i)
+				assert.Equal(t, i%2 == 0, code.Dark(6, i), "vertical timing module %d", i)
+			}
+			assert.True(t, code.Dark(8, code.Size-8), "dark module")
+
+			first, second := formatInfo(code)
+			assert.Equal(t, first, second, "both copies of the format bits match")
+
+			word := first ^ 0x5412
+			rem := word
+			for i := 14; i >= 10; i-- {
+				if rem>>i&1 == 1 {
+					rem ^= 0x537 << (i - 10)
+				}
+			}
+			assert.Zero(t, rem, "format bits are a BCH codeword")
+			assert.Equal(t, map[qrcode.Level]int{qrcode.LevelL: 1, qrcode.LevelM: 0, qrcode.LevelQ: 3, qrcode.LevelH: 2}[level], word>>13)
+		})
+	}
+}
+
+func TestEncode_KnownMatrix(t *testing.T) {
+	// "https://sho.rt/a" as version 1-L with mask 3, as produced by an independent encoder.
+	want := []string{
+		"#######.##....#######",
+		"#.....#..#....#.....#",
+		"#.###.#.#.###.#.###.#",
+		"#.###.#.##.#..#.###.#",
+		"#.###.#.#####.#.###.#",
+		"#.....#.......#.....#",
+		"#######.#.#.#.#######",
+		".........###.........",
+		"####..#.#.##.#..###.#",
+		"####.......#.########",
+		"#.##.###.....#...#.##",
+		".#..#..####.#..#.#.#.",
+		"####.##.##..###.##..#",
+		"........#.#.#.#.#....",
+		"#######....#..#.#....",
+		"#.....#..#...#.####..",
+		"#.###.#..#..#...#.#.#",
+		"#.###.#.#.#.####.....",
+		"#.###.#.##..#..#..#..",
+		"#.....#.#.#.#####...#",
+		"#######.#######.###..",
+	}
+
+	code, err := qrcode.Encode([]byte("https://sho.rt/a"), qrcode.LevelL)
+	require.NoError(t, err)
+	require.Equal(t, 1, code.Version)
+	first, _ := formatInfo(code)
+	require.Equal(t, 3, (first^0x5412)>>10&7, "mask")
+
+	got := make([]string, code.Size)
+	for y := range got {
+		var row strings.Builder
+		for x := 0; x < code.Size; x++ {
+			if code.Dark(x, y) {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		got[y] = row.String()
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := qrcode.ParseLevel(" q ")
+	require.NoError(t, err)
+	assert.Equal(t, qrcode.LevelQ, level)
+
+	_, err = qrcode.ParseLevel("X")
+	assert.ErrorIs(t, err, qrcode.ErrInvalidLevel)
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+		err  bool
+	}{
+		{in: "#000", want: color.NRGBA{A: 0xff}},
+		{in: "1a2b3c", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{in: "#FFFFFF80", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}},
+		{in: "#12345", err: true},
+		{in: "#gggggg", err: true},
+		{in: "red", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := qrcode.ParseColor(tt.in)
+			if tt.err {
+				assert.ErrorIs(t, err, qrcode.ErrInvalidColor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPNG(t *testing.T) {
+	code, err := qrcode.Encode([]byte("https://sho.rt/abc123"), qrcode.LevelM)
+	require.NoError(t, err)
+	fg := color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+	bg := color.NRGBA{R: 0xee, G: 0xee, B: 0xee, A: 0xff}
+
+	data, err := qrcode.PNG(code, qrcode.RenderOptions{Size: 256, Margin: 4, Foreground: fg, Background: bg})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	modules := code.Size + 8
+	scale := 256 / modules
+	assert.Equal(t, modules*scale, img.Bounds().Dx())
+	assert.Equal(t, modules*scale, img.Bounds().Dy())
+	assert.Equal(t, bg, color.NRGBAModel.Convert(img.At(0, 0)))
+	assert.Equal(t, fg, color.NRGBAModel.Convert(img.At(4*scale, 4*scale)), "top-left finder corner")
+
+	tiny, err := qrcode.PNG(code, qrcode.RenderOptions{Size: 1, Foreground: fg, Background: bg})
+	require.NoError(t, err)
+	img, err = png.Decode(bytes.NewReader(tiny))
+	require.NoError(t, err)
+	assert.Equal(t, code.Size, img.Bounds().Dx(), "never smaller than a pixel per module")
+}
+
+func TestSVG(t *testing.T) {
+	code, err := qrcode.Encode([]byte("https://sho.rt/abc123"), qrcode.LevelM)
+	require.NoError(t, err)
+
+	svg := string(qrcode.SVG(code, qrcode.RenderOptions{
+		Size:       300,
+		Margin:     2,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff},
+	}))
+
+	viewBox := code.Size + 4
+	assert.Contains(t, svg, `width="300" height="300"`)
+	assert.Contains(t, svg, `viewBox="0 0 `+strconv.Itoa(viewBox)+" "+strconv.Itoa(viewBox)+`"`)
+	assert.Contains(t, svg, `<rect width="`+strconv.Itoa(viewBox)+`" height="`+strconv.Itoa(viewBox)+`" fill="#ffffff" fill-opacity="0.000"/>`)
+	assert.Contains(t, svg, `<path fill="#000000" d="M2 2h7v1h-7z`, "first row starts with the finder pattern")
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,133 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// DefaultMargin is the quiet zone around a code recommended by the standard, in modules.
+const DefaultMargin = 4
+
+// ErrInvalidColor is returned when a color is not written as hex digits such as "#1a2b3c".
+var ErrInvalidColor = errors.New(`color must be a hex color such as "#000000" or "#00000000"`)
+
+// RenderOptions holds how a code is drawn.
+//
+// Fields:
+// - Size (int): The width and height of the image in pixels. PNG images are rounded down to a whole number of pixels per module, and are never smaller than one pixel per module.
+// - Margin (int): The width of the quiet zone around the code, in modules.
+// - Foreground (color.NRGBA): The color of dark modules.
+// - Background (color.NRGBA): The color of light modules and the quiet zone.
+type RenderOptions struct {
+	Size       int
+	Margin     int
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// PNG draws a code as a two-color PNG image.
+//
+// Parameters:
+// - code (*Code): The code.
+// - opts (RenderOptions): The size, margin and colors.
+//
+// Returns:
+// - []byte: The PNG image.
+// - error: An error if the image cannot be encoded.
+func PNG(code *Code, opts RenderOptions) ([]byte, error) {
+	modules := code.Size + 2*opts.Margin
+	scale := max(opts.Size/modules, 1)
+	side := modules * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			if code.Dark(x/scale-opts.Margin, y/scale-opts.Margin) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG draws a code as an SVG image, with one path for all dark modules.
+//
+// Parameters:
+// - code (*Code): The code.
+// - opts (RenderOptions): The size, margin and colors.
+//
+// Returns:
+// - []byte: The SVG document.
+func SVG(code *Code, opts RenderOptions) []byte {
+	modules := code.Size + 2*opts.Margin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" %s/>`+"\n", modules, modules, svgFill(opts.Background))
+	fmt.Fprintf(&b, `<path %s d="`, svgFill(opts.Foreground))
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; {
+			if !code.Dark(x, y) {
+				x++
+				continue
+			}
+			run := 1
+			for code.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x+opts.Margin, y+opts.Margin, run, run)
+			x += run
+		}
+	}
+	b.WriteString(`"/>` + "\n</svg>\n")
+	return []byte(b.String())
+}
+
+// svgFill returns the fill attributes drawing a color.
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/0xff, 'f', 3, 64))
+	}
+	return fill
+}
+
+// ParseColor parses a hex color.
+//
+// Parameters:
+// - s (string): "RGB", "RRGGBB" or "RRGGBBAA", optionally prefixed with "#".
+//
+// Returns:
+// - color.NRGBA: The color, opaque unless an alpha channel is given.
+// - error: ErrInvalidColor if the value is malformed.
+func ParseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, ErrInvalidColor
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultRetryInterval is how long a FallbackLimiter keeps using its fallback before trying the primary limiter again.
+const DefaultRetryInterval = 5 * time.Second
+
+// FallbackLimiter is a Limiter that switches to a fallback limiter while its primary limiter fails,
+// so an outage of Redis neither rejects every request nor turns rate limiting off.
+type FallbackLimiter struct {
+	primary       Limiter
+	fallback      Limiter
+	retryInterval time.Duration
+
+	mu        sync.Mutex
+	downUntil time.Time
+	now       func() time.Time
+}
+
+// NewFallbackLimiter creates a FallbackLimiter.
+//
+// Parameters:
+// - primary (Limiter): The limiter used while it works, usually a RedisLimiter.
+// - fallback (Limiter): The limiter used while the primary fails, usually a MemoryLimiter.
+// - retryInterval (time.Duration): How long the primary is skipped after a failure. Zero means DefaultRetryInterval.
+//
+// Returns:
+// - *FallbackLimiter: The limiter.
+func NewFallbackLimiter(primary, fallback Limiter, retryInterval time.Duration) *FallbackLimiter {
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+	return &FallbackLimiter{primary: primary, fallback: fallback, retryInterval: retryInterval, now: time.Now}
+}
+
+// Allow counts the request with the primary limiter, or with the fallback while the primary is down.
+// A failing primary is skipped for retryInterval, so requests are not slowed down by its timeouts.
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	down := l.now().Before(l.downUntil)
+	l.mu.Unlock()
+
+	if !down {
+		result, err := l.primary.Allow(ctx, key, limit)
+		if err == nil {
+			return result, nil
+		}
+
+		l.mu.Lock()
+		if !l.now().Before(l.downUntil) {
+			log.Printf("rate limiter unavailable, using in-memory limits for %s: %v", l.retryInterval, err)
+		}
+		l.downUntil = l.now().Add(l.retryInterval)
+		l.mu.Unlock()
+	}
+
+	return l.fallback.Allow(ctx, key, limit)
+}
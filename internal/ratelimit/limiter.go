@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit is returned when a limit is not of the form "<requests>/<window>".
+var ErrInvalidLimit = errors.New("rate limit must look like \"100/1m\"")
+
+// Limit is the number of requests allowed per sliding window.
+//
+// Fields:
+// - Requests (int): The number of requests allowed in any window. Zero disables the limit.
+// - Window (time.Duration): The length of the sliding window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// ParseLimit parses a limit such as "100/1m" or "10/30s".
+//
+// Parameters:
+// - value (string): The limit. An empty string or "0" means no limit.
+//
+// Returns:
+// - Limit: The parsed limit.
+// - error: ErrInvalidLimit if the value is malformed.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// Result is the outcome of counting a request against a limit.
+//
+// Fields:
+// - Allowed (bool): Whether the request is within the limit.
+// - Remaining (int): The number of further requests allowed in the current window.
+// - Reset (time.Duration): The time until a request slot frees up again.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	Reset     time.Duration
+}
+
+// Limiter counts requests per key against a sliding-window limit.
+type Limiter interface {
+	// Allow counts a request for key, unless the limit is already reached.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - key (string): The client and route group the request is counted for.
+	// - limit (Limit): The limit to enforce.
+	//
+	// Returns:
+	// - Result: Whether the request is allowed and the state of the window.
+	// - error: An error if the counter store is unavailable.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,91 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit(" 10 / 30s ")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Requests: 10, Window: 30 * time.Second}, limit)
+	assert.True(t, limit.Enabled())
+
+	for _, disabled := range []string{"", "0"} {
+		limit, err := ratelimit.ParseLimit(disabled)
+		assert.NoError(t, err)
+		assert.False(t, limit.Enabled())
+	}
+
+	for _, invalid := range []string{"10", "ten/1m", "10/forever", "-1/1m", "10/0s"} {
+		_, err := ratelimit.ParseLimit(invalid)
+		assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit, invalid)
+	}
+}
+
+func TestMemoryLimiter_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	limiter := ratelimit.NewMemoryLimiter()
+	limit := ratelimit.Limit{Requests: 2, Window: 100 * time.Millisecond}
+
+	first, _ := limiter.Allow(ctx, "client", limit)
+	second, _ := limiter.Allow(ctx, "client", limit)
+	third, _ := limiter.Allow(ctx, "client", limit)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, 0, third.Remaining)
+	assert.LessOrEqual(t, third.Reset, limit.Window)
+
+	other, _ := limiter.Allow(ctx, "other", limit)
+	assert.True(t, other.Allowed)
+
+	time.Sleep(limit.Window + 10*time.Millisecond)
+	again, _ := limiter.Allow(ctx, "client", limit)
+	assert.True(t, again.Allowed)
+}
+
+// stubLimiter implements ratelimit.Limiter with a fixed outcome and counts its calls.
+type stubLimiter struct {
+	result ratelimit.Result
+	err    error
+	calls  int
+}
+
+func (s *stubLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.calls++
+	return s.result, s.err
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 1, Window: time.Minute}
+	primary := &stubLimiter{err: errors.New("redis: connection refused")}
+	fallback := &stubLimiter{result: ratelimit.Result{Allowed: true}}
+	limiter := ratelimit.NewFallbackLimiter(primary, fallback, 50*time.Millisecond)
+
+	result, err := limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, fallback.calls)
+
+	// The primary is skipped until the retry interval has passed.
+	_, _ = limiter.Allow(ctx, "client", limit)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+
+	time.Sleep(60 * time.Millisecond)
+	primary.err = nil
+	primary.result = ratelimit.Result{Allowed: false}
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 2, primary.calls)
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a MemoryLimiter drops the windows of idle keys.
+const sweepInterval = time.Minute
+
+// memoryWindow holds the request times of one key, oldest first, and the window they are counted in.
+type memoryWindow struct {
+	hits   []time.Time
+	length time.Duration
+}
+
+// MemoryLimiter is a Limiter keeping its counters in process memory.
+// Each instance of the application counts on its own, so it is meant as a fallback for Redis.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a MemoryLimiter.
+//
+// Returns:
+// - *MemoryLimiter: The limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{windows: make(map[string]*memoryWindow), now: time.Now}
+}
+
+// Allow counts a request with a sliding-window log of request times.
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = &memoryWindow{}
+		l.windows[key] = w
+	}
+	w.length = limit.Window
+
+	cutoff := now.Add(-limit.Window)
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(cutoff) {
+		i++
+	}
+	w.hits = w.hits[i:]
+
+	allowed := len(w.hits) < limit.Requests
+	if allowed {
+		w.hits = append(w.hits, now)
+	}
+
+	reset := limit.Window
+	if len(w.hits) > 0 {
+		reset = w.hits[0].Add(limit.Window).Sub(now)
+	}
+	return Result{Allowed: allowed, Remaining: max(limit.Requests-len(w.hits), 0), Reset: reset}, nil
+}
+
+// sweep removes the keys whose requests have all left their window.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if len(w.hits) == 0 || now.Sub(w.hits[len(w.hits)-1]) > w.length {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript keeps one sorted set entry per allowed request, scored by its time in
+// microseconds, and drops entries older than the window before counting.
+// It uses the Redis clock so that all application instances agree on the window.
+//
+// KEYS[1]: the counter key. ARGV[1]: the window in microseconds. ARGV[2]: the limit. ARGV[3]: a unique member.
+// Returns {allowed, remaining, reset in microseconds}.
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[3])
+  redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+  count = count + 1
+  allowed = 1
+end
+
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local reset = window
+if oldest[2] then
+  reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, math.max(limit - count, 0), reset}
+`)
+
+// RedisLimiter is a Limiter sharing its counters between application instances through Redis.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter creates a RedisLimiter.
+//
+// Parameters:
+// - client (*redis.Client): The Redis client.
+//
+// Returns:
+// - *RedisLimiter: The limiter.
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+// Allow counts a request with a sliding-window log stored under "ratelimit:" + key.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return Result{}, err
+	}
+
+	values, err := slidingWindowScript.Run(ctx, l.client,
+		[]string{"ratelimit:" + key},
+		limit.Window.Microseconds(), limit.Requests, hex.EncodeToString(member),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:   values[0] == 1,
+		Remaining: int(values[1]),
+		Reset:     time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
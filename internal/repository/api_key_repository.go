@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// APIKeyRepository defines the interface for interacting with the persistent storage of API keys.
+//
+// Methods:
+// - Create: Stores a new API key.
+// - FindByHash: Retrieves an API key by the hash of its key.
+// - ListByUser: Retrieves the API keys of a user.
+// - Revoke: Marks an API key as revoked.
+// - TouchLastUsed: Records when an API key was last used.
+type APIKeyRepository interface {
+	// Create stores a new API key, assigning it an ID if it has none.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - key (*entity.APIKey): The API key to be stored.
+	//
+	// Returns:
+	// - error: An error if the insertion fails.
+	Create(ctx context.Context, key *entity.APIKey) error
+
+	// FindByHash retrieves an API key by the hash of its key.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - keyHash (string): The hex-encoded SHA-256 hash of the key.
+	//
+	// Returns:
+	// - *entity.APIKey: The API key if found, or nil if no matching document exists.
+	// - error: An error if the query fails.
+	FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+
+	// ListByUser retrieves the API keys of a user, newest first.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user.
+	//
+	// Returns:
+	// - []*entity.APIKey: The API keys of the user, including revoked ones.
+	// - error: An error if the query fails.
+	ListByUser(ctx context.Context, userID string) ([]*entity.APIKey, error)
+
+	// Revoke marks an active API key of a user as revoked.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user owning the key.
+	// - id (string): The ID of the API key.
+	// - revokedAt (time.Time): The revocation time.
+	//
+	// Returns:
+	// - bool: True if the key was revoked, false if the user has no active key with that ID.
+	// - error: An error if the update fails.
+	Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error)
+
+	// TouchLastUsed records when an API key was last used.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - id (string): The ID of the API key.
+	// - usedAt (time.Time): The time the key was used.
+	//
+	// Returns:
+	// - error: An error if the update fails.
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
@@ -9,23 +9,26 @@ import (
 // URLCacheRepository defines the interface for caching URL entities.
 //
 // Methods:
-// - GetByOriginalURL: Retrieves a URL entity from the cache using its original URL as the key.
-// - SetByOriginalURL: Caches a URL entity using its original URL as the key.
+// - GetByDedupeKey: Retrieves a reusable URL entity from the cache using its dedupe key.
+// - SetByDedupeKey: Caches a reusable URL entity using its dedupe key.
 // - GetByShortID: Retrieves a URL entity from the cache using its shortened ID as the key.
 // - SetByShortID: Caches a URL entity using its shortened ID as the key.
+// - SetMany: Caches several URL entities in one round trip.
+// - Delete: Removes a URL entity from the cache under both keys.
 type URLCacheRepository interface {
-	// GetByOriginalURL retrieves a URL entity from the cache using its original URL as the key.
+	// GetByDedupeKey retrieves a reusable URL entity from the cache using its dedupe key,
+	// which combines the dedupe scope, the owner for per-owner dedupe and the canonical URL.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
-	// - originalURL (string): The original URL to search for.
+	// - dedupeKey (string): The key built by entity.DedupeKeyFor.
 	//
 	// Returns:
 	// - *entity.URL: The URL entity if found, or nil if no matching key exists.
 	// - error: An error if the retrieval fails.
-	GetByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error)
+	GetByDedupeKey(ctx context.Context, dedupeKey string) (*entity.URL, error)
 
-	// SetByOriginalURL caches a URL entity using its original URL as the key.
+	// SetByDedupeKey caches a reusable URL entity using its entity.URL.DedupeKey as the key.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
@@ -33,20 +36,21 @@ type URLCacheRepository interface {
 	//
 	// Returns:
 	// - error: An error if the caching operation fails.
-	SetByOriginalURL(ctx context.Context, url *entity.URL) error
+	SetByDedupeKey(ctx context.Context, url *entity.URL) error
 
-	// GetByShortID retrieves a URL entity from the cache using its shortened ID as the key.
+	// GetByShortID retrieves a URL entity from the cache using its shortened ID on a domain as the key.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
 	// - shortID (string): The shortened ID to search for.
 	//
 	// Returns:
 	// - *entity.URL: The URL entity if found, or nil if no matching key exists.
 	// - error: An error if the retrieval fails.
-	GetByShortID(ctx context.Context, shortID string) (*entity.URL, error)
+	GetByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error)
 
-	// SetByShortID caches a URL entity using its shortened ID as the key.
+	// SetByShortID caches a URL entity using its shortened ID on its domain as the key, see entity.URL.Key.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
@@ -55,4 +59,25 @@ type URLCacheRepository interface {
 	// Returns:
 	// - error: An error if the caching operation fails.
 	SetByShortID(ctx context.Context, url *entity.URL) error
+
+	// SetMany caches several URL entities in one round trip.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - byShortID ([]*entity.URL): The URL entities to cache under their shortened ID.
+	// - byDedupeKey ([]*entity.URL): The URL entities to cache under their dedupe key.
+	//
+	// Returns:
+	// - error: An error if the caching operation fails.
+	SetMany(ctx context.Context, byShortID, byDedupeKey []*entity.URL) error
+
+	// Delete removes a URL entity from the cache under both its dedupe key and shortened ID keys.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - url (*entity.URL): The URL entity to be evicted.
+	//
+	// Returns:
+	// - error: An error if the eviction fails.
+	Delete(ctx context.Context, url *entity.URL) error
 }
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// ClickRepository defines the interface for storing and aggregating click events.
+//
+// Methods:
+// - SaveMany: Stores a batch of click events.
+// - Stats: Aggregates the click events of a shortened URL.
+// - Count: Counts the click events of a shortened URL since a time.
+type ClickRepository interface {
+	// SaveMany stores a batch of click events.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - clicks ([]*entity.Click): The click events to be saved.
+	//
+	// Returns:
+	// - error: An error if the insertion fails.
+	SaveMany(ctx context.Context, clicks []*entity.Click) error
+
+	// Stats aggregates the click events of a shortened URL.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID to aggregate.
+	// - bucket (string): The time bucket unit, "hour" or "day".
+	// - from (time.Time): The inclusive start of the period.
+	// - to (time.Time): The exclusive end of the period.
+	//
+	// Returns:
+	// - *entity.ClickStats: The aggregated statistics.
+	// - error: An error if the aggregation fails.
+	Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error)
+
+	// Count counts the click events recorded for a shortened URL since a time.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID.
+	// - since (time.Time): The inclusive start of the count, usually the creation time of the URL so the clicks of
+	// a deleted URL that used the same short ID are left out.
+	//
+	// Returns:
+	// - int64: The number of click events.
+	// - error: An error if the query fails.
+	Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error)
+}
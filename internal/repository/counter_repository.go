@@ -0,0 +1,20 @@
+package repository
+
+import "context"
+
+// CounterRepository defines the interface for atomically incremented named counters.
+//
+// Methods:
+// - Next: Increments a counter and returns its new value.
+type CounterRepository interface {
+	// Next increments a counter and returns its new value.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - name (string): The name of the counter.
+	//
+	// Returns:
+	// - int64: The counter value after the increment.
+	// - error: An error if the increment fails.
+	Next(ctx context.Context, name string) (int64, error)
+}
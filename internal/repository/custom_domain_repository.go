@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// ErrDuplicateCustomDomain is returned when a user already claimed a host, or when verifying a claim on a host
+// another claim was verified for first.
+var ErrDuplicateCustomDomain = errors.New("custom domain already exists")
+
+// CustomDomainRepository defines the interface for interacting with the persistent storage of custom domains.
+// A custom domain is stored per user claiming it; at most one claim per host is verified.
+//
+// Methods:
+// - Create: Stores a new claim on a custom domain.
+// - Find: Retrieves the claim of a user on a host.
+// - FindVerified: Retrieves the verified claim on a host.
+// - List: Retrieves the custom domains of a user, or of every user.
+// - MarkVerified: Records that the owner of a claim proved control of the domain.
+// - Delete: Removes the claim of a user on a host.
+type CustomDomainRepository interface {
+	// Create stores a new claim on a custom domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (*entity.CustomDomain): The custom domain to be stored.
+	//
+	// Returns:
+	// - error: ErrDuplicateCustomDomain if the user already claimed the host, or an error if the insertion fails.
+	Create(ctx context.Context, domain *entity.CustomDomain) error
+
+	// Find retrieves the claim of a user on a host.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - host (string): The lowercase host name.
+	// - ownerID (string): The ID of the user.
+	//
+	// Returns:
+	// - *entity.CustomDomain: The custom domain if found, or nil if the user has not claimed the host.
+	// - error: An error if the query fails.
+	Find(ctx context.Context, host, ownerID string) (*entity.CustomDomain, error)
+
+	// FindVerified retrieves the verified claim on a host.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - host (string): The lowercase host name.
+	//
+	// Returns:
+	// - *entity.CustomDomain: The verified custom domain, or nil if no claim on the host is verified.
+	// - error: An error if the query fails.
+	FindVerified(ctx context.Context, host string) (*entity.CustomDomain, error)
+
+	// List retrieves custom domains, sorted by host name.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): If set, only the domains of this user are returned.
+	//
+	// Returns:
+	// - []*entity.CustomDomain: The custom domains.
+	// - error: An error if the query fails.
+	List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error)
+
+	// MarkVerified records the time the owner of a claim proved control of the domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - host (string): The host name of the domain.
+	// - ownerID (string): The ID of the user owning the claim.
+	// - at (time.Time): The time of the verification.
+	//
+	// Returns:
+	// - error: ErrDuplicateCustomDomain if another claim on the host is verified, or an error if the update fails.
+	MarkVerified(ctx context.Context, host, ownerID string, at time.Time) error
+
+	// Delete removes the claim of a user on a host.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - host (string): The host name of the domain.
+	// - ownerID (string): The ID of the user owning the claim.
+	//
+	// Returns:
+	// - bool: True if a claim was removed, false if the user had not claimed the host.
+	// - error: An error if the deletion fails.
+	Delete(ctx context.Context, host, ownerID string) (bool, error)
+}
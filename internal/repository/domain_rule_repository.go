@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// DomainRuleRepository defines the interface for interacting with the persistent storage of domain allow and deny rules.
+//
+// Methods:
+// - Put: Stores a rule, replacing the rule of the same domain.
+// - List: Retrieves every rule.
+// - Delete: Removes the rule of a domain.
+type DomainRuleRepository interface {
+	// Put stores a rule, replacing any existing rule for the same domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - rule (*entity.DomainRule): The rule to be stored.
+	//
+	// Returns:
+	// - error: An error if the write fails.
+	Put(ctx context.Context, rule *entity.DomainRule) error
+
+	// List retrieves every rule, sorted by domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	//
+	// Returns:
+	// - []*entity.DomainRule: The stored rules.
+	// - error: An error if the query fails.
+	List(ctx context.Context) ([]*entity.DomainRule, error)
+
+	// Delete removes the rule of a domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The domain of the rule.
+	//
+	// Returns:
+	// - bool: True if a rule was removed, false if the domain had none.
+	// - error: An error if the deletion fails.
+	Delete(ctx context.Context, domain string) (bool, error)
+}
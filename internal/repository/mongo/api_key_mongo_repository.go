@@ -0,0 +1,159 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// apiKeyMongoRepository is a MongoDB implementation of the APIKeyRepository interface.
+//
+// Fields:
+// - collection (*mongo.Collection): The MongoDB collection used to store API keys.
+type apiKeyMongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAPIKeyMongoRepository creates a new instance of apiKeyMongoRepository.
+//
+// Parameters:
+// - col (*mongo.Collection): The MongoDB collection to be used for API key storage.
+//
+// Returns:
+// - repository.APIKeyRepository: An instance of the APIKeyRepository interface backed by MongoDB.
+func NewAPIKeyMongoRepository(col *mongo.Collection) repository.APIKeyRepository {
+	return &apiKeyMongoRepository{collection: col}
+}
+
+// EnsureAPIKeyIndexes creates the indexes required by the API keys collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - col (*mongo.Collection): The MongoDB collection used for API key storage.
+//
+// Behavior:
+// - Creates a unique index on key_hash, used to authenticate requests.
+// - Creates a compound index on user_id and created_at for listing a user's keys.
+//
+// Returns:
+// - error: An error if index creation fails.
+func EnsureAPIKeyIndexes(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("key_hash_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("user_created_at"),
+		},
+	})
+	return err
+}
+
+// Create stores a new API key in the MongoDB collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - key (*entity.APIKey): The API key to be stored.
+//
+// Behavior:
+// - Assigns a new ObjectID hex string as the key ID if none is set.
+// - Sets the CreatedAt field to the current time.
+//
+// Returns:
+// - error: An error if the insertion fails.
+func (r *apiKeyMongoRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	if key.ID == "" {
+		key.ID = primitive.NewObjectID().Hex()
+	}
+	key.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// FindByHash retrieves an API key by the hash of its key.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - keyHash (string): The hex-encoded SHA-256 hash of the key.
+//
+// Returns:
+// - *entity.APIKey: The API key if found, or nil if no matching document exists.
+// - error: An error if the query fails.
+func (r *apiKeyMongoRepository) FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByUser retrieves the API keys of a user, newest first.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - userID (string): The ID of the user.
+//
+// Returns:
+// - []*entity.APIKey: The API keys of the user, including revoked ones.
+// - error: An error if the query fails.
+func (r *apiKeyMongoRepository) ListByUser(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*entity.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke marks an active API key of a user as revoked.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - userID (string): The ID of the user owning the key.
+// - id (string): The ID of the API key.
+// - revokedAt (time.Time): The revocation time.
+//
+// Returns:
+// - bool: True if the key was revoked, false if the user has no active key with that ID.
+// - error: An error if the update fails.
+func (r *apiKeyMongoRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// TouchLastUsed records when an API key was last used.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - id (string): The ID of the API key.
+// - usedAt (time.Time): The time the key was used.
+//
+// Returns:
+// - error: An error if the update fails.
+func (r *apiKeyMongoRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
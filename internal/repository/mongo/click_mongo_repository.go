@@ -0,0 +1,195 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// statsTopN is the number of entries returned for each grouped statistic.
+const statsTopN = 10
+
+// clickMongoRepository is a MongoDB implementation of the ClickRepository interface.
+//
+// Fields:
+// - collection (*mongo.Collection): The MongoDB collection used to store click events.
+type clickMongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewClickMongoRepository creates a new instance of clickMongoRepository.
+//
+// Parameters:
+// - col (*mongo.Collection): The MongoDB collection to be used for click storage.
+//
+// Returns:
+// - repository.ClickRepository: An instance of the ClickRepository interface backed by MongoDB.
+func NewClickMongoRepository(col *mongo.Collection) repository.ClickRepository {
+	return &clickMongoRepository{collection: col}
+}
+
+// legacyClickIndex is the name of the former index on short_id and timestamp, replaced by the per-domain index.
+const legacyClickIndex = "short_id_timestamp"
+
+// EnsureClickIndexes creates the indexes required by the clicks collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - col (*mongo.Collection): The MongoDB collection used for click storage.
+//
+// Behavior:
+// - Creates a compound index on domain, short_id and timestamp to serve per-link statistics.
+// - Drops the former index on short_id and timestamp, which the per-domain queries no longer use.
+//
+// Returns:
+// - error: An error if index creation fails.
+func EnsureClickIndexes(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "short_id", Value: 1}, {Key: "timestamp", Value: 1}},
+		Options: options.Index().SetName("domain_short_id_timestamp"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = col.Indexes().DropOne(ctx, legacyClickIndex)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// SaveMany stores a batch of click events with a single unordered InsertMany.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - clicks ([]*entity.Click): The click events to be saved.
+//
+// Returns:
+// - error: An error if the insertion fails.
+func (r *clickMongoRepository) SaveMany(ctx context.Context, clicks []*entity.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(clicks))
+	for i, click := range clicks {
+		docs[i] = click
+	}
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// Stats aggregates the click events of a shortened URL in a single $facet pipeline.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+// - shortID (string): The shortened ID to aggregate.
+// - bucket (string): The time bucket unit, "hour" or "day".
+// - from (time.Time): The inclusive start of the period.
+// - to (time.Time): The exclusive end of the period.
+//
+// Returns:
+// - *entity.ClickStats: The aggregated statistics.
+// - error: An error if the aggregation fails.
+func (r *clickMongoRepository) Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+	match := shortIDFilter(domain, shortID)
+	match["timestamp"] = bson.M{"$gte": from, "$lt": to}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{
+				bson.M{"$count": "count"},
+			},
+			"unique": bson.A{
+				bson.M{"$group": bson.M{"_id": "$visitor_id"}},
+				bson.M{"$count": "count"},
+			},
+			"timeline": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": bucket}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"referrers":   groupByField("$referrer"),
+			"user_agents": groupByField("$ua_family"),
+			"countries":   groupByField("$country"),
+			"campaigns":   groupByField("$campaign"),
+			"variants":    groupByField("$variant"),
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total      []struct{ Count int64 }  `bson:"total"`
+		Unique     []struct{ Count int64 }  `bson:"unique"`
+		Timeline   []entity.TimeBucketCount `bson:"timeline"`
+		Referrers  []entity.KeyCount        `bson:"referrers"`
+		UserAgents []entity.KeyCount        `bson:"user_agents"`
+		Countries  []entity.KeyCount        `bson:"countries"`
+		Campaigns  []entity.KeyCount        `bson:"campaigns"`
+		Variants   []entity.KeyCount        `bson:"variants"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	stats := &entity.ClickStats{}
+	if len(results) == 0 {
+		return stats, nil
+	}
+	res := results[0]
+	if len(res.Total) > 0 {
+		stats.TotalClicks = res.Total[0].Count
+	}
+	if len(res.Unique) > 0 {
+		stats.UniqueVisitors = res.Unique[0].Count
+	}
+	stats.Timeline = res.Timeline
+	stats.Referrers = res.Referrers
+	stats.UserAgents = res.UserAgents
+	stats.Countries = res.Countries
+	stats.Campaigns = res.Campaigns
+	stats.Variants = res.Variants
+	return stats, nil
+}
+
+// groupByField builds a $facet sub-pipeline counting clicks per value of field, most frequent first.
+func groupByField(field string) bson.A {
+	return bson.A{
+		bson.M{"$group": bson.M{"_id": bson.M{"$ifNull": bson.A{field, ""}}, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": statsTopN},
+	}
+}
+
+// Count counts the click events of a shortened URL since a time with CountDocuments, served by the
+// domain_short_id_timestamp index.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+// - shortID (string): The shortened ID.
+// - since (time.Time): The inclusive start of the count.
+//
+// Returns:
+// - int64: The number of click events.
+// - error: An error if the query fails.
+func (r *clickMongoRepository) Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error) {
+	filter := shortIDFilter(domain, shortID)
+	filter["timestamp"] = bson.M{"$gte": since}
+	return r.collection.CountDocuments(ctx, filter)
+}
@@ -0,0 +1,184 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// customDomainMongoRepository is a MongoDB implementation of the CustomDomainRepository interface.
+//
+// Fields:
+// - collection (*mongo.Collection): The MongoDB collection used to store custom domains, one document per user claiming a host.
+type customDomainMongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewCustomDomainMongoRepository creates a new instance of customDomainMongoRepository.
+//
+// Parameters:
+// - col (*mongo.Collection): The MongoDB collection to be used for custom domain storage.
+//
+// Returns:
+// - repository.CustomDomainRepository: An instance of the CustomDomainRepository interface backed by MongoDB.
+func NewCustomDomainMongoRepository(col *mongo.Collection) repository.CustomDomainRepository {
+	return &customDomainMongoRepository{collection: col}
+}
+
+// EnsureCustomDomainIndexes creates the indexes required by the custom domain collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - col (*mongo.Collection): The MongoDB collection used for custom domain storage.
+//
+// Behavior:
+// - Creates a unique index on host and owner_id so each user claims a host once.
+// - Creates a unique index on host covering verified claims only, so unverified claims cannot block the real owner
+// and the first verification of a host wins.
+// - Creates an index on owner_id and host to serve per-user listings.
+//
+// Returns:
+// - error: An error if index creation fails.
+func EnsureCustomDomainIndexes(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "host", Value: 1}, {Key: "owner_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("host_owner_unique"),
+		},
+		{
+			Keys: bson.D{{Key: "host", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("verified_host_unique").
+				SetPartialFilterExpression(bson.M{"verified_at": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "host", Value: 1}},
+			Options: options.Index().SetName("owner_host"),
+		},
+	})
+	return err
+}
+
+// Create stores a new claim on a custom domain in the MongoDB collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (*entity.CustomDomain): The custom domain to be stored.
+//
+// Returns:
+// - error: repository.ErrDuplicateCustomDomain if the user already claimed the host, or an error if the insertion fails.
+func (r *customDomainMongoRepository) Create(ctx context.Context, domain *entity.CustomDomain) error {
+	_, err := r.collection.InsertOne(ctx, domain)
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrDuplicateCustomDomain
+	}
+	return err
+}
+
+// Find retrieves the claim of a user on a host.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - host (string): The lowercase host name.
+// - ownerID (string): The ID of the user.
+//
+// Returns:
+// - *entity.CustomDomain: The custom domain if found, or nil if no matching document exists.
+// - error: An error if the query fails.
+func (r *customDomainMongoRepository) Find(ctx context.Context, host, ownerID string) (*entity.CustomDomain, error) {
+	return r.findOne(ctx, bson.M{"host": host, "owner_id": ownerID})
+}
+
+// FindVerified retrieves the verified claim on a host.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - host (string): The lowercase host name.
+//
+// Returns:
+// - *entity.CustomDomain: The verified custom domain, or nil if no claim on the host is verified.
+// - error: An error if the query fails.
+func (r *customDomainMongoRepository) FindVerified(ctx context.Context, host string) (*entity.CustomDomain, error) {
+	return r.findOne(ctx, bson.M{"host": host, "verified_at": bson.M{"$exists": true}})
+}
+
+// findOne retrieves the custom domain matching a filter, or nil if none does.
+func (r *customDomainMongoRepository) findOne(ctx context.Context, filter bson.M) (*entity.CustomDomain, error) {
+	var domain entity.CustomDomain
+	err := r.collection.FindOne(ctx, filter).Decode(&domain)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// List retrieves custom domains, sorted by host name.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - ownerID (string): If set, only the domains of this user are returned.
+//
+// Returns:
+// - []*entity.CustomDomain: The custom domains.
+// - error: An error if the query fails.
+func (r *customDomainMongoRepository) List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error) {
+	query := bson.M{}
+	if ownerID != "" {
+		query["owner_id"] = ownerID
+	}
+
+	cursor, err := r.collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "host", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []*entity.CustomDomain{}
+	if err := cursor.All(ctx, &domains); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// MarkVerified records the time the owner of a claim proved control of the domain.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - host (string): The host name of the domain.
+// - ownerID (string): The ID of the user owning the claim.
+// - at (time.Time): The time of the verification.
+//
+// Returns:
+// - error: repository.ErrDuplicateCustomDomain if another claim on the host is verified, or an error if the update fails.
+func (r *customDomainMongoRepository) MarkVerified(ctx context.Context, host, ownerID string, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"host": host, "owner_id": ownerID}, bson.M{"$set": bson.M{"verified_at": at}})
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrDuplicateCustomDomain
+	}
+	return err
+}
+
+// Delete removes the claim of a user on a host.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - host (string): The host name of the domain.
+// - ownerID (string): The ID of the user owning the claim.
+//
+// Returns:
+// - bool: True if a claim was removed, false if the user had not claimed the host.
+// - error: An error if the deletion fails.
+func (r *customDomainMongoRepository) Delete(ctx context.Context, host, ownerID string) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"host": host, "owner_id": ownerID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
@@ -0,0 +1,81 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// domainRuleMongoRepository is a MongoDB implementation of the DomainRuleRepository interface.
+//
+// Fields:
+// - collection (*mongo.Collection): The MongoDB collection used to store domain rules, keyed by domain.
+type domainRuleMongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDomainRuleMongoRepository creates a new instance of domainRuleMongoRepository.
+//
+// Parameters:
+// - col (*mongo.Collection): The MongoDB collection to be used for domain rule storage.
+//
+// Returns:
+// - repository.DomainRuleRepository: An instance of the DomainRuleRepository interface backed by MongoDB.
+func NewDomainRuleMongoRepository(col *mongo.Collection) repository.DomainRuleRepository {
+	return &domainRuleMongoRepository{collection: col}
+}
+
+// Put stores a rule, replacing any existing rule for the same domain.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - rule (*entity.DomainRule): The rule to be stored.
+//
+// Returns:
+// - error: An error if the write fails.
+func (r *domainRuleMongoRepository) Put(ctx context.Context, rule *entity.DomainRule) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": rule.Domain}, rule, options.Replace().SetUpsert(true))
+	return err
+}
+
+// List retrieves every rule, sorted by domain.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+//
+// Returns:
+// - []*entity.DomainRule: The stored rules.
+// - error: An error if the query fails.
+func (r *domainRuleMongoRepository) List(ctx context.Context) ([]*entity.DomainRule, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	rules := []*entity.DomainRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Delete removes the rule of a domain.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The domain of the rule.
+//
+// Returns:
+// - bool: True if a rule was removed, false if the domain had none.
+// - error: An error if the deletion fails.
+func (r *domainRuleMongoRepository) Delete(ctx context.Context, domain string) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": domain})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
@@ -3,33 +3,108 @@ package mongo
 import (
 	"context"
 	"errors"
+	"regexp"
 	"time"
 
 	"github.com/guttosm/url-shortener/internal/entity"
 	"github.com/guttosm/url-shortener/internal/repository"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// clearableURLFields lists optional URL fields that Update removes when they are unset on the entity.
+var clearableURLFields = []string{"expires_at", "max_clicks", "interstitial", "forward_query", "redirect_rules", "variants"}
+
+// legacyShortIDIndex is the name of the former unique index on short_id alone, replaced by the per-domain index.
+const legacyShortIDIndex = "short_id_unique"
+
+// maxSaveAttempts is the number of times Save tries to insert a URL before giving up on short ID collisions.
+const maxSaveAttempts = 5
+
 // urlMongoRepository is a MongoDB implementation of the URLRepository interface.
 //
 // Fields:
 // - collection (*mongo.Collection): The MongoDB collection used to store and retrieve URL entities.
+// - nextShortID (repository.ShortIDFunc): Produces a replacement short ID when an insert collides. May be nil.
 type urlMongoRepository struct {
-	collection *mongo.Collection
+	collection  *mongo.Collection
+	nextShortID repository.ShortIDFunc
 }
 
 // NewURLMongoRepository creates a new instance of urlMongoRepository.
 //
 // Parameters:
 // - col (*mongo.Collection): The MongoDB collection to be used for URL storage.
+// - nextShortID (repository.ShortIDFunc): Produces a replacement short ID on collision. If nil, collisions are returned as errors.
 //
 // Returns:
 // - repository.URLRepository: An instance of the URLRepository interface backed by MongoDB.
-func NewURLMongoRepository(col *mongo.Collection) repository.URLRepository {
+func NewURLMongoRepository(col *mongo.Collection, nextShortID repository.ShortIDFunc) repository.URLRepository {
 	return &urlMongoRepository{
-		collection: col,
+		collection:  col,
+		nextShortID: nextShortID,
+	}
+}
+
+// EnsureURLIndexes creates the indexes required by the URL collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - col (*mongo.Collection): The MongoDB collection used for URL storage.
+//
+// Behavior:
+// - Creates a unique index on domain and short_id so IDs colliding within a domain are rejected atomically.
+// - Drops the former unique index on short_id alone, which would keep IDs unique across domains.
+// - Creates a TTL index on expires_at so expired documents are removed by MongoDB.
+// - Creates an index on owner_id and created_at to serve per-user listings.
+// - Creates indexes on canonical_url and original_url to find existing mappings of a URL.
+//
+// Returns:
+// - error: An error if index creation fails.
+func EnsureURLIndexes(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "domain", Value: 1}, {Key: "short_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("domain_short_id_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("owner_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "canonical_url", Value: 1}},
+			Options: options.Index().SetName("canonical_url"),
+		},
+		{
+			Keys:    bson.D{{Key: "original_url", Value: 1}},
+			Options: options.Index().SetName("original_url"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = col.Indexes().DropOne(ctx, legacyShortIDIndex)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
+
+// shortIDFilter returns the filter matching the URL with a shortened ID on a domain.
+// URLs on the default domain have no domain field, which a nil value matches.
+func shortIDFilter(domain, shortID string) bson.M {
+	if domain == "" {
+		return bson.M{"domain": nil, "short_id": shortID}
 	}
+	return bson.M{"domain": domain, "short_id": shortID}
 }
 
 // Save stores a new URL entity in the MongoDB collection.
@@ -39,29 +114,255 @@ func NewURLMongoRepository(col *mongo.Collection) repository.URLRepository {
 // - url (*entity.URL): The URL entity to be saved.
 //
 // Behavior:
-// - Sets the CreatedAt field of the URL entity to the current time.
+// - Sets the CreatedAt and UpdatedAt fields of the URL entity to the current time.
 // - Inserts the URL entity into the MongoDB collection.
+// - On a duplicate short_id, replaces a generated ShortID with a fresh one and retries, up to maxSaveAttempts times.
 //
 // Returns:
-// - error: An error if the insertion fails.
+// - error: repository.ErrDuplicateShortID if the ID stays taken, or an error if the insertion fails.
 func (r *urlMongoRepository) Save(ctx context.Context, url *entity.URL) error {
 	url.CreatedAt = time.Now()
-	_, err := r.collection.InsertOne(ctx, url)
-	return err
+	url.UpdatedAt = url.CreatedAt
+
+	for attempt := 1; ; attempt++ {
+		_, err := r.collection.InsertOne(ctx, url)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if url.Alias || r.nextShortID == nil || attempt >= maxSaveAttempts {
+			return repository.ErrDuplicateShortID
+		}
+
+		shortID, genErr := r.nextShortID(ctx)
+		if genErr != nil {
+			return genErr
+		}
+		url.ShortID = shortID
+	}
+}
+
+// SaveMany stores several new URL entities with unordered InsertMany calls.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - urls ([]*entity.URL): The URL entities to be saved.
+//
+// Behavior:
+// - Sets the CreatedAt and UpdatedAt fields of every URL entity to the current time.
+// - Inserts all entities with one unordered InsertMany, so a failing document does not stop the others.
+// - Entities rejected for a duplicate short_id get a fresh ShortID, unless they are aliases.
+// - Those entities are inserted again with another InsertMany, up to maxSaveAttempts rounds.
+//
+// Returns:
+// - []error: The error of each entity, by index: nil if it was saved, repository.ErrDuplicateShortID if its short ID stays taken, or the write error.
+// - error: An error if a batch fails for a reason other than individual write errors.
+func (r *urlMongoRepository) SaveMany(ctx context.Context, urls []*entity.URL) ([]error, error) {
+	now := time.Now()
+	for _, url := range urls {
+		url.CreatedAt = now
+		url.UpdatedAt = now
+	}
+
+	errs := make([]error, len(urls))
+	pending := make([]int, len(urls))
+	for i := range urls {
+		pending[i] = i
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		docs := make([]interface{}, len(pending))
+		for i, idx := range pending {
+			docs[i] = urls[idx]
+		}
+
+		_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		if err == nil {
+			break
+		}
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			return nil, err
+		}
+
+		var retry []int
+		for _, writeErr := range bulkErr.WriteErrors {
+			idx := pending[writeErr.Index]
+			if !mongo.IsDuplicateKeyError(writeErr.WriteError) {
+				errs[idx] = writeErr.WriteError
+				continue
+			}
+			if urls[idx].Alias || r.nextShortID == nil || attempt >= maxSaveAttempts {
+				errs[idx] = repository.ErrDuplicateShortID
+				continue
+			}
+			shortID, genErr := r.nextShortID(ctx)
+			if genErr != nil {
+				errs[idx] = genErr
+				continue
+			}
+			urls[idx].ShortID = shortID
+			retry = append(retry, idx)
+		}
+		pending = retry
+	}
+
+	return errs, nil
+}
+
+// ImportMany stores imported URL entities under their own short IDs.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - urls ([]*entity.URL): The URL entities to be stored.
+// - overwrite (bool): Whether an entity replaces an existing URL with the same short ID.
+//
+// Behavior:
+// - Keeps the CreatedAt of each entity, defaulting it to the current time, and sets UpdatedAt to the current time.
+// - Without overwrite, inserts all entities with one unordered InsertMany; duplicates are reported, not retried.
+// - Short IDs are matched within the domain of each entity.
+// - With overwrite, reads the URLs about to be replaced, then upserts all entities with one unordered BulkWrite.
+//
+// Returns:
+// - []error: The error of each entity, by index: nil if it was stored, repository.ErrDuplicateShortID on a conflict without overwrite, or the write error.
+// - []*entity.URL: The previous versions of the URLs that were replaced.
+// - error: An error if the batch fails for a reason other than individual write errors.
+func (r *urlMongoRepository) ImportMany(ctx context.Context, urls []*entity.URL, overwrite bool) ([]error, []*entity.URL, error) {
+	errs := make([]error, len(urls))
+	if len(urls) == 0 {
+		return errs, nil, nil
+	}
+
+	now := time.Now()
+	keys := make(bson.A, len(urls))
+	for i, url := range urls {
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = now
+		}
+		url.UpdatedAt = now
+		url.ID = ""
+		keys[i] = shortIDFilter(url.Domain, url.ShortID)
+	}
+
+	var replaced []*entity.URL
+	var err error
+	if overwrite {
+		cursor, findErr := r.collection.Find(ctx, bson.M{"$or": keys})
+		if findErr != nil {
+			return nil, nil, findErr
+		}
+		if err := cursor.All(ctx, &replaced); err != nil {
+			return nil, nil, err
+		}
+
+		models := make([]mongo.WriteModel, len(urls))
+		for i, url := range urls {
+			models[i] = mongo.NewReplaceOneModel().
+				SetFilter(shortIDFilter(url.Domain, url.ShortID)).
+				SetReplacement(url).
+				SetUpsert(true)
+		}
+		_, err = r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	} else {
+		docs := make([]interface{}, len(urls))
+		for i, url := range urls {
+			docs[i] = url
+		}
+		_, err = r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	}
+	if err == nil {
+		return errs, replaced, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		return nil, nil, err
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !overwrite && mongo.IsDuplicateKeyError(writeErr.WriteError) {
+			errs[writeErr.Index] = repository.ErrDuplicateShortID
+		} else {
+			errs[writeErr.Index] = writeErr.WriteError
+		}
+	}
+	return errs, replaced, nil
+}
+
+// ForEach streams URL entities from a cursor in insertion order.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - ownerID (string): If set, only URLs created by this user are visited.
+// - fn (func(*entity.URL) error): Called for every URL entity. Returning an error stops the iteration.
+//
+// Returns:
+// - error: The error returned by fn, or an error if the query or decoding fails.
+func (r *urlMongoRepository) ForEach(ctx context.Context, ownerID string, fn func(*entity.URL) error) error {
+	query := bson.M{}
+	if ownerID != "" {
+		query["owner_id"] = ownerID
+	}
+
+	cursor, err := r.collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var url entity.URL
+		if err := cursor.Decode(&url); err != nil {
+			return err
+		}
+		if err := fn(&url); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
 }
 
-// FindByOriginalURL retrieves a URL entity by its original URL.
+// FindByOriginalURL retrieves a reusable URL entity by the canonical form of its original URL.
+//
+// Custom aliases, URLs on a custom domain or with an expiry, click limit, password, interstitial, query forwarding,
+// redirect rules or variants and URLs created with entity.DedupeNever are not reusable and are skipped.
+// Documents stored before canonical forms were recorded match when their original URL equals the canonical URL.
 //
 // Parameters:
 // - ctx (context.Context): The context for the operation.
-// - originalURL (string): The original URL to search for.
+// - canonicalURL (string): The canonical URL to search for.
+// - ownerID (string): If set, only URLs this user created with entity.DedupeOwner match; otherwise only globally shared URLs match.
 //
 // Returns:
 // - *entity.URL: The URL entity if found, or nil if no matching document exists.
 // - error: An error if the query fails.
-func (r *urlMongoRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error) {
+func (r *urlMongoRepository) FindByOriginalURL(ctx context.Context, canonicalURL, ownerID string) (*entity.URL, error) {
+	filter := bson.M{
+		"alias":          bson.M{"$ne": true},
+		"expires_at":     bson.M{"$exists": false},
+		"max_clicks":     bson.M{"$exists": false},
+		"password_hash":  bson.M{"$exists": false},
+		"interstitial":   bson.M{"$ne": true},
+		"forward_query":  bson.M{"$ne": true},
+		"redirect_rules": bson.M{"$exists": false},
+		"variants":       bson.M{"$exists": false},
+		"domain":         bson.M{"$exists": false},
+	}
+	if ownerID != "" {
+		filter["canonical_url"] = canonicalURL
+		filter["dedupe"] = entity.DedupeOwner
+		filter["owner_id"] = ownerID
+	} else {
+		filter["$or"] = bson.A{
+			bson.M{"canonical_url": canonicalURL},
+			bson.M{"original_url": canonicalURL, "canonical_url": bson.M{"$exists": false}},
+		}
+		filter["dedupe"] = bson.M{"$exists": false}
+	}
+
 	var url entity.URL
-	err := r.collection.FindOne(ctx, bson.M{"original": originalURL}).Decode(&url)
+	err := r.collection.FindOne(ctx, filter).Decode(&url)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
@@ -71,18 +372,19 @@ func (r *urlMongoRepository) FindByOriginalURL(ctx context.Context, originalURL
 	return &url, nil
 }
 
-// FindByShortID retrieves a URL entity by its shortened ID.
+// FindByShortID retrieves a URL entity by its shortened ID on a domain.
 //
 // Parameters:
 // - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
 // - shortID (string): The shortened ID to search for.
 //
 // Returns:
 // - *entity.URL: The URL entity if found, or nil if no matching document exists.
 // - error: An error if the query fails.
-func (r *urlMongoRepository) FindByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
+func (r *urlMongoRepository) FindByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error) {
 	var url entity.URL
-	err := r.collection.FindOne(ctx, bson.M{"short_id": shortID}).Decode(&url)
+	err := r.collection.FindOne(ctx, shortIDFilter(domain, shortID)).Decode(&url)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
@@ -90,4 +392,133 @@ func (r *urlMongoRepository) FindByShortID(ctx context.Context, shortID string)
 		return nil, err
 	}
 	return &url, nil
-}
\ No newline at end of file
+}
+
+// ConsumeClick atomically increments a URL's click counter while it is below the limit.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+// - shortID (string): The shortened ID of the URL.
+// - maxClicks (int64): The click limit of the URL.
+//
+// Returns:
+// - bool: True if the click was counted, false if the limit was already reached.
+// - error: An error if the update fails.
+func (r *urlMongoRepository) ConsumeClick(ctx context.Context, domain, shortID string, maxClicks int64) (bool, error) {
+	filter := shortIDFilter(domain, shortID)
+	filter["clicks"] = bson.M{"$lt": maxClicks}
+	res, err := r.collection.UpdateOne(ctx,
+		filter,
+		bson.M{"$inc": bson.M{"clicks": 1}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// List retrieves a page of URL entities matching a filter.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - filter (repository.URLListFilter): The filter, sort order and page bounds.
+//
+// Returns:
+// - []*entity.URL: The URL entities in the requested page.
+// - int64: The total number of URL entities matching the filter.
+// - error: An error if the query fails.
+func (r *urlMongoRepository) List(ctx context.Context, filter repository.URLListFilter) ([]*entity.URL, int64, error) {
+	query := bson.M{"owner_id": filter.OwnerID}
+	if filter.Domain != "" {
+		query["domain"] = filter.Domain
+	}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		query["$or"] = bson.A{
+			bson.M{"short_id": pattern},
+			bson.M{"original_url": pattern},
+		}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	direction := 1
+	if filter.SortDesc {
+		direction = -1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: filter.SortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetSkip(filter.Skip).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	urls := make([]*entity.URL, 0)
+	if err := cursor.All(ctx, &urls); err != nil {
+		return nil, 0, err
+	}
+	return urls, total, nil
+}
+
+// Update stores changes to an existing URL entity, identified by its domain and shortened ID.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - url (*entity.URL): The URL entity with its new values.
+//
+// Behavior:
+// - Sets UpdatedAt to the current time.
+// - Overwrites every field except _id and clicks, and unsets optional fields that are empty on the entity.
+//
+// Returns:
+// - error: An error if the update fails.
+func (r *urlMongoRepository) Update(ctx context.Context, url *entity.URL) error {
+	url.UpdatedAt = time.Now()
+
+	raw, err := bson.Marshal(url)
+	if err != nil {
+		return err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return err
+	}
+	delete(set, "_id")
+	delete(set, "clicks")
+
+	unset := bson.M{}
+	for _, field := range clearableURLFields {
+		if _, ok := set[field]; !ok {
+			unset[field] = ""
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err = r.collection.UpdateOne(ctx, shortIDFilter(url.Domain, url.ShortID), update)
+	return err
+}
+
+// Delete removes a URL entity by its shortened ID on a domain.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+// - shortID (string): The shortened ID of the URL to remove.
+//
+// Returns:
+// - error: An error if the deletion fails.
+func (r *urlMongoRepository) Delete(ctx context.Context, domain, shortID string) error {
+	_, err := r.collection.DeleteOne(ctx, shortIDFilter(domain, shortID))
+	return err
+}
@@ -0,0 +1,154 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userMongoRepository is a MongoDB implementation of the UserRepository interface.
+//
+// Fields:
+// - collection (*mongo.Collection): The MongoDB collection used to store user accounts.
+type userMongoRepository struct {
+	collection *mongo.Collection
+}
+
+// NewUserMongoRepository creates a new instance of userMongoRepository.
+//
+// Parameters:
+// - col (*mongo.Collection): The MongoDB collection to be used for user storage.
+//
+// Returns:
+// - repository.UserRepository: An instance of the UserRepository interface backed by MongoDB.
+func NewUserMongoRepository(col *mongo.Collection) repository.UserRepository {
+	return &userMongoRepository{collection: col}
+}
+
+// EnsureUserIndexes creates the indexes required by the users collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - col (*mongo.Collection): The MongoDB collection used for user storage.
+//
+// Behavior:
+// - Creates a unique index on username.
+//
+// Returns:
+// - error: An error if index creation fails.
+func EnsureUserIndexes(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("username_unique"),
+	})
+	return err
+}
+
+// Create stores a new user in the MongoDB collection.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - user (*entity.User): The user to be stored.
+//
+// Behavior:
+// - Assigns a new ObjectID hex string as the user ID if none is set.
+// - Sets the CreatedAt field to the current time.
+//
+// Returns:
+// - error: repository.ErrDuplicateUsername if the username is taken, repository.ErrDuplicateUserID if the ID is
+// taken, or an error if the insertion fails.
+func (r *userMongoRepository) Create(ctx context.Context, user *entity.User) error {
+	if user.ID == "" {
+		user.ID = primitive.NewObjectID().Hex()
+	}
+	user.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		if isDuplicateID(err) {
+			return repository.ErrDuplicateUserID
+		}
+		return repository.ErrDuplicateUsername
+	}
+	return err
+}
+
+// isDuplicateID reports whether a duplicate key error was raised by the _id index rather than a unique index
+// of another field.
+func isDuplicateID(err error) bool {
+	var writeErr mongo.WriteException
+	if !errors.As(err, &writeErr) {
+		return false
+	}
+	for _, we := range writeErr.WriteErrors {
+		if strings.Contains(we.Message, "index: _id_ ") {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByUsername retrieves a user by username.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - username (string): The username to search for.
+//
+// Returns:
+// - *entity.User: The user if found, or nil if no matching document exists.
+// - error: An error if the query fails.
+func (r *userMongoRepository) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	return r.findOne(ctx, bson.M{"username": username})
+}
+
+// FindByID retrieves a user by ID.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - id (string): The user ID to search for.
+//
+// Returns:
+// - *entity.User: The user if found, or nil if no matching document exists.
+// - error: An error if the query fails.
+func (r *userMongoRepository) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// UpdateRole changes the role of a user.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - id (string): The ID of the user.
+// - role (string): The new role.
+//
+// Returns:
+// - bool: True if the user exists.
+// - error: An error if the update fails.
+func (r *userMongoRepository) UpdateRole(ctx context.Context, id, role string) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// findOne decodes the first user matching filter, mapping no documents to nil.
+func (r *userMongoRepository) findOne(ctx context.Context, filter bson.M) (*entity.User, error) {
+	var user entity.User
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordAttemptRepository defines the interface for counting failed password attempts on protected URLs.
+//
+// Methods:
+// - Failures: Reads the failures counted in the current window.
+// - RecordFailure: Counts a failed attempt.
+// - Reset: Clears the failures of a URL.
+type PasswordAttemptRepository interface {
+	// Failures reads the number of failed attempts counted in the current window.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID of the URL.
+	//
+	// Returns:
+	// - int64: The number of failed attempts.
+	// - time.Duration: The time until the window ends and the count is cleared. Zero if nothing is counted.
+	// - error: An error if the lookup fails.
+	Failures(ctx context.Context, shortID string) (int64, time.Duration, error)
+
+	// RecordFailure counts a failed attempt, starting a window if none is running. The count is atomic, so callers
+	// can record an attempt before checking it and rely on the returned count under concurrent attempts.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID of the URL.
+	// - window (time.Duration): How long failures are counted, measured from the first failure.
+	//
+	// Returns:
+	// - int64: The number of failed attempts including this one.
+	// - error: An error if the operation fails.
+	RecordFailure(ctx context.Context, shortID string, window time.Duration) (int64, error)
+
+	// Reset clears the failed attempts of a URL.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID of the URL.
+	//
+	// Returns:
+	// - error: An error if the operation fails.
+	Reset(ctx context.Context, shortID string) error
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// QRCodeCacheRepository defines the interface for caching rendered QR code images.
+//
+// Methods:
+// - Get: Retrieves a rendered image.
+// - Set: Caches a rendered image.
+type QRCodeCacheRepository interface {
+	// Get retrieves a rendered image of a URL's QR code.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID of the URL.
+	// - variant (string): Identifies the rendering, derived from the encoded content and the image options.
+	//
+	// Returns:
+	// - []byte: The image, or nil if it is not cached.
+	// - error: An error if the retrieval fails.
+	Get(ctx context.Context, shortID, variant string) ([]byte, error)
+
+	// Set caches a rendered image of a URL's QR code.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID of the URL.
+	// - variant (string): Identifies the rendering, derived from the encoded content and the image options.
+	// - image ([]byte): The image.
+	// - ttl (time.Duration): How long the image is kept.
+	//
+	// Returns:
+	// - error: An error if the caching operation fails.
+	Set(ctx context.Context, shortID, variant string, image []byte, ttl time.Duration) error
+}
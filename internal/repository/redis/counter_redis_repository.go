@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// counterRedisRepository is a Redis implementation of the CounterRepository interface.
+//
+// Fields:
+// - client (*redis.Client): The Redis client used to increment counters.
+type counterRedisRepository struct {
+	client *redis.Client
+}
+
+// NewCounterRedisRepository creates a new instance of counterRedisRepository.
+//
+// Parameters:
+// - client (*redis.Client): The Redis client to be used for counters.
+//
+// Returns:
+// - repository.CounterRepository: An instance of the CounterRepository interface backed by Redis.
+func NewCounterRedisRepository(client *redis.Client) repository.CounterRepository {
+	return &counterRedisRepository{client: client}
+}
+
+// Next atomically increments the named counter using INCR.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - name (string): The name of the counter.
+//
+// Returns:
+// - int64: The counter value after the increment.
+// - error: An error if the increment fails.
+func (r *counterRedisRepository) Next(ctx context.Context, name string) (int64, error) {
+	return r.client.Incr(ctx, "counter:"+name).Result()
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// recordFailureScript increments the failure counter and starts its window on the first failure,
+// in one step so a counter can never be left without an expiry.
+//
+// KEYS[1] is the counter key; ARGV[1] is the window in milliseconds.
+var recordFailureScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// passwordAttemptRedisRepository is a Redis implementation of the PasswordAttemptRepository interface.
+//
+// Fields:
+// - client (*redis.Client): The Redis client used to store the failure counters.
+type passwordAttemptRedisRepository struct {
+	client *redis.Client
+}
+
+// NewPasswordAttemptRedisRepository creates a new instance of passwordAttemptRedisRepository.
+//
+// Parameters:
+// - client (*redis.Client): The Redis client to be used for the failure counters.
+//
+// Returns:
+// - repository.PasswordAttemptRepository: An instance of the PasswordAttemptRepository interface backed by Redis.
+func NewPasswordAttemptRedisRepository(client *redis.Client) repository.PasswordAttemptRepository {
+	return &passwordAttemptRedisRepository{client: client}
+}
+
+// Failures reads the counter and its remaining time to live in one round trip.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - shortID (string): The shortened ID of the URL.
+//
+// Returns:
+// - int64: The number of failed attempts, zero if the counter does not exist.
+// - time.Duration: The remaining time to live of the counter.
+// - error: An error if the lookup fails.
+func (r *passwordAttemptRedisRepository) Failures(ctx context.Context, shortID string) (int64, time.Duration, error) {
+	key := passwordAttemptKey(shortID)
+	pipe := r.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+
+	n, err := get.Int64()
+	if err == redis.Nil {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, max(ttl.Val(), 0), nil
+}
+
+// RecordFailure increments the counter with INCR, setting its expiry on the first failure.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - shortID (string): The shortened ID of the URL.
+// - window (time.Duration): How long failures are counted, measured from the first failure.
+//
+// Returns:
+// - int64: The number of failed attempts including this one.
+// - error: An error if the write fails.
+func (r *passwordAttemptRedisRepository) RecordFailure(ctx context.Context, shortID string, window time.Duration) (int64, error) {
+	return recordFailureScript.Run(ctx, r.client, []string{passwordAttemptKey(shortID)}, window.Milliseconds()).Int64()
+}
+
+// Reset deletes the counter.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - shortID (string): The shortened ID of the URL.
+//
+// Returns:
+// - error: An error if the deletion fails.
+func (r *passwordAttemptRedisRepository) Reset(ctx context.Context, shortID string) error {
+	return r.client.Del(ctx, passwordAttemptKey(shortID)).Err()
+}
+
+// passwordAttemptKey returns the Redis key of the failure counter of a URL.
+func passwordAttemptKey(shortID string) string {
+	return "url:password-failures:" + shortID
+}
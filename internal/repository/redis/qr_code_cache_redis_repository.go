@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// qrCodeCacheRedisRepository is a Redis implementation of the QRCodeCacheRepository interface.
+//
+// Fields:
+// - client (*redis.Client): The Redis client used to store the images.
+type qrCodeCacheRedisRepository struct {
+	client *redis.Client
+}
+
+// NewQRCodeCacheRedisRepository creates a new instance of qrCodeCacheRedisRepository.
+//
+// Parameters:
+// - client (*redis.Client): The Redis client to be used for the images.
+//
+// Returns:
+// - repository.QRCodeCacheRepository: An instance of the QRCodeCacheRepository interface backed by Redis.
+func NewQRCodeCacheRedisRepository(client *redis.Client) repository.QRCodeCacheRepository {
+	return &qrCodeCacheRedisRepository{client: client}
+}
+
+// Get reads an image stored under "qr:<shortID>:<variant>".
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - shortID (string): The shortened ID of the URL.
+// - variant (string): Identifies the rendering.
+//
+// Returns:
+// - []byte: The image, or nil if the key does not exist.
+// - error: An error if the retrieval fails.
+func (r *qrCodeCacheRedisRepository) Get(ctx context.Context, shortID, variant string) ([]byte, error) {
+	image, err := r.client.Get(ctx, qrCodeKey(shortID, variant)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return image, err
+}
+
+// Set stores an image under "qr:<shortID>:<variant>" with an expiry.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - shortID (string): The shortened ID of the URL.
+// - variant (string): Identifies the rendering.
+// - image ([]byte): The image.
+// - ttl (time.Duration): How long the image is kept.
+//
+// Returns:
+// - error: An error if the write fails.
+func (r *qrCodeCacheRedisRepository) Set(ctx context.Context, shortID, variant string, image []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, qrCodeKey(shortID, variant), image, ttl).Err()
+}
+
+// qrCodeKey returns the Redis key of a rendered QR code.
+func qrCodeKey(shortID, variant string) string {
+	return "qr:" + shortID + ":" + variant
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// revokedTokenRedisRepository is a Redis implementation of the RevokedTokenRepository interface.
+//
+// Fields:
+// - client (*redis.Client): The Redis client used to store revoked token IDs.
+type revokedTokenRedisRepository struct {
+	client *redis.Client
+}
+
+// NewRevokedTokenRedisRepository creates a new instance of revokedTokenRedisRepository.
+//
+// Parameters:
+// - client (*redis.Client): The Redis client to be used for the revocation list.
+//
+// Returns:
+// - repository.RevokedTokenRepository: An instance of the RevokedTokenRepository interface backed by Redis.
+func NewRevokedTokenRedisRepository(client *redis.Client) repository.RevokedTokenRepository {
+	return &revokedTokenRedisRepository{client: client}
+}
+
+// Revoke stores the token ID with SETNX, expiring the entry together with the token.
+// Tokens that already expired are not stored, since they are rejected anyway.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - tokenID (string): The ID of the token.
+// - expiresAt (time.Time): The expiry of the token.
+//
+// Returns:
+// - bool: True if the token was revoked by this call, false if it had already been revoked.
+// - error: An error if the write fails.
+func (r *revokedTokenRedisRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return true, nil
+	}
+	return r.client.SetNX(ctx, "revoked:jti:"+tokenID, 1, ttl).Result()
+}
+
+// IsRevoked checks whether the token ID is in the revocation list.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - tokenID (string): The ID of the token.
+//
+// Returns:
+// - bool: True if the token was revoked.
+// - error: An error if the lookup fails.
+func (r *revokedTokenRedisRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := r.client.Exists(ctx, "revoked:jti:"+tokenID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
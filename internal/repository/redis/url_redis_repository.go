@@ -10,6 +10,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// defaultCacheTTL is the maximum time a URL entity stays in the cache.
+const defaultCacheTTL = time.Hour
+
 // urlRedisRepository is a Redis implementation of the URLCacheRepository interface.
 //
 // Fields:
@@ -29,21 +32,21 @@ func NewURLRedisRepository(client *redis.Client) repository.URLCacheRepository {
 	return &urlRedisRepository{client: client}
 }
 
-// GetByOriginalURL retrieves a URL entity from Redis by its original URL.
+// GetByDedupeKey retrieves a reusable URL entity from Redis by its dedupe key.
 //
 // Parameters:
 // - ctx (context.Context): The context for the operation.
-// - originalURL (string): The original URL to search for.
+// - dedupeKey (string): The key built by entity.DedupeKeyFor.
 //
 // Behavior:
-// - Constructs a Redis key using the original URL.
+// - Constructs a Redis key using the dedupe key, e.g. "url:dedupe:owner:<owner>:<url>".
 // - Retrieves the cached URL entity from Redis and unmarshal it into an entity.URL object.
 //
 // Returns:
 // - *entity.URL: The URL entity if found, or nil if no matching key exists.
 // - error: An error if the retrieval or unmarshalling fails.
-func (r *urlRedisRepository) GetByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error) {
-	key := "url:original:" + originalURL
+func (r *urlRedisRepository) GetByDedupeKey(ctx context.Context, dedupeKey string) (*entity.URL, error) {
+	key := dedupeCacheKey(dedupeKey)
 	val, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		return nil, err
@@ -56,39 +59,43 @@ func (r *urlRedisRepository) GetByOriginalURL(ctx context.Context, originalURL s
 	return &url, nil
 }
 
-// SetByOriginalURL caches a URL entity in Redis using its original URL as the key.
+// SetByDedupeKey caches a reusable URL entity in Redis using its dedupe key.
 //
 // Parameters:
 // - ctx (context.Context): The context for the operation.
 // - url (*entity.URL): The URL entity to be cached.
 //
 // Behavior:
-// - Constructs a Redis key using the original URL.
-// - Marshals the URL entity into JSON and stores it in Redis with a 1-hour expiration.
+// - Constructs a Redis key using the URL's entity.URL.DedupeKey. URLs without one are not cached.
+// - Marshals the URL entity into JSON and stores it in Redis for at most one hour,
+// capped at the URL's remaining lifetime.
 //
 // Returns:
 // - error: An error if the caching operation fails.
-func (r *urlRedisRepository) SetByOriginalURL(ctx context.Context, url *entity.URL) error {
-	key := "url:original:" + url.Original
-	data, _ := json.Marshal(url)
-	return r.client.Set(ctx, key, data, time.Hour).Err()
+func (r *urlRedisRepository) SetByDedupeKey(ctx context.Context, url *entity.URL) error {
+	dedupeKey := url.DedupeKey()
+	if dedupeKey == "" {
+		return nil
+	}
+	return set(ctx, r.client, dedupeCacheKey(dedupeKey), url)
 }
 
 // GetByShortID retrieves a URL entity from Redis by its shortened ID.
 //
 // Parameters:
 // - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain of the URL, or an empty string for the default domain.
 // - shortID (string): The shortened ID to search for.
 //
 // Behavior:
-// - Constructs a Redis key using the shortened ID.
+// - Constructs a Redis key using the domain and shortened ID.
 // - Retrieves the cached URL entity from Redis and unmarshal it into an entity.URL object.
 //
 // Returns:
 // - *entity.URL: The URL entity if found, or nil if no matching key exists.
 // - error: An error if the retrieval or unmarshalling fails.
-func (r *urlRedisRepository) GetByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
-	key := "url:short_id:" + shortID
+func (r *urlRedisRepository) GetByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	key := shortIDCacheKey(entity.LinkKey(domain, shortID))
 	val, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		return nil, err
@@ -108,13 +115,86 @@ func (r *urlRedisRepository) GetByShortID(ctx context.Context, shortID string) (
 // - url (*entity.URL): The URL entity to be cached.
 //
 // Behavior:
-// - Constructs a Redis key using the shortened ID.
-// - Marshals the URL entity into JSON and stores it in Redis with a 1-hour expiration.
+// - Constructs a Redis key using the domain and shortened ID.
+// - Marshals the URL entity into JSON and stores it in Redis for at most one hour,
+// capped at the URL's remaining lifetime.
 //
 // Returns:
 // - error: An error if the caching operation fails.
 func (r *urlRedisRepository) SetByShortID(ctx context.Context, url *entity.URL) error {
-	key := "url:short_id:" + url.ShortID
+	return set(ctx, r.client, shortIDCacheKey(url.Key()), url)
+}
+
+// SetMany caches several URL entities in Redis with one pipelined round trip.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - byShortID ([]*entity.URL): The URL entities to cache under their shortened ID.
+// - byDedupeKey ([]*entity.URL): The URL entities to cache under their dedupe key.
+//
+// Behavior:
+// - Uses the same keys and TTLs as SetByShortID and SetByDedupeKey.
+//
+// Returns:
+// - error: An error if the pipeline fails.
+func (r *urlRedisRepository) SetMany(ctx context.Context, byShortID, byDedupeKey []*entity.URL) error {
+	if len(byShortID) == 0 && len(byDedupeKey) == 0 {
+		return nil
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, url := range byShortID {
+			_ = set(ctx, pipe, shortIDCacheKey(url.Key()), url)
+		}
+		for _, url := range byDedupeKey {
+			if dedupeKey := url.DedupeKey(); dedupeKey != "" {
+				_ = set(ctx, pipe, dedupeCacheKey(dedupeKey), url)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// Delete removes a URL entity from Redis under both its dedupe key and shortened ID keys.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - url (*entity.URL): The URL entity to be evicted.
+//
+// Returns:
+// - error: An error if the eviction fails.
+func (r *urlRedisRepository) Delete(ctx context.Context, url *entity.URL) error {
+	return r.client.Del(ctx, dedupeCacheKey(url.DedupeKey()), shortIDCacheKey(url.Key())).Err()
+}
+
+// shortIDCacheKey returns the Redis key caching a URL under its link key, see entity.URL.Key.
+func shortIDCacheKey(linkKey string) string {
+	return "url:short_id:" + linkKey
+}
+
+// dedupeCacheKey returns the Redis key caching the reusable mapping of a dedupe key.
+func dedupeCacheKey(dedupeKey string) string {
+	return "url:dedupe:" + dedupeKey
+}
+
+// set stores a URL entity under key with a TTL that never outlives the URL itself.
+// Already-expired URLs are not cached. cmd is the client or a pipeline.
+func set(ctx context.Context, cmd redis.Cmdable, key string, url *entity.URL) error {
+	ttl := cacheTTL(url, time.Now())
+	if ttl <= 0 {
+		return nil
+	}
 	data, _ := json.Marshal(url)
-	return r.client.Set(ctx, key, data, time.Hour).Err()
+	return cmd.Set(ctx, key, data, ttl).Err()
+}
+
+// cacheTTL returns defaultCacheTTL capped at the time remaining until the URL expires.
+func cacheTTL(url *entity.URL, now time.Time) time.Duration {
+	ttl := defaultCacheTTL
+	if url.ExpiresAt != nil {
+		if remaining := url.ExpiresAt.Sub(now); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
 }
@@ -67,10 +67,10 @@ func TestURLRedisRepository(t *testing.T) {
 	}
 
 	// Set and Get by Original URL
-	err := repository.SetByOriginalURL(ctx, url)
+	err := repository.SetByDedupeKey(ctx, url)
 	assert.NoError(t, err)
 
-	result, err := repository.GetByOriginalURL(ctx, url.Original)
+	result, err := repository.GetByDedupeKey(ctx, url.DedupeKey())
 	assert.NoError(t, err)
 	assert.Equal(t, url.ShortID, result.ShortID)
 
@@ -78,7 +78,56 @@ func TestURLRedisRepository(t *testing.T) {
 	err = repository.SetByShortID(ctx, url)
 	assert.NoError(t, err)
 
-	result, err = repository.GetByShortID(ctx, url.ShortID)
+	result, err = repository.GetByShortID(ctx, url.Domain, url.ShortID)
 	assert.NoError(t, err)
 	assert.Equal(t, url.Original, result.Original)
 }
+
+func TestURLRedisRepository_TTLCappedByExpiry(t *testing.T) {
+	client, teardown := setupRedis(t)
+	defer teardown()
+
+	repository := repo.NewURLRedisRepository(client)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(30 * time.Second)
+	url := &entity.URL{
+		ShortID:   "exp123",
+		Original:  "https://example.com/expiring",
+		ExpiresAt: &expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	err := repository.SetByShortID(ctx, url)
+	assert.NoError(t, err)
+
+	ttl, err := client.TTL(ctx, "url:short_id:exp123").Result()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, 30*time.Second)
+	assert.Greater(t, ttl, time.Duration(0))
+}
+
+func TestURLRedisRepository_CarriesRedirectRules(t *testing.T) {
+	client, teardown := setupRedis(t)
+	defer teardown()
+
+	repository := repo.NewURLRedisRepository(client)
+	ctx := context.Background()
+
+	endsAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	url := &entity.URL{
+		ShortID:  "app123",
+		Original: "https://example.com/app",
+		RedirectRules: []entity.RedirectRule{
+			{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}},
+			{Destination: "https://example.com/br", Countries: []string{"BR"}, Languages: []string{"pt"}, EndsAt: &endsAt},
+		},
+		CreatedAt: time.Now(),
+	}
+
+	assert.NoError(t, repository.SetByShortID(ctx, url))
+
+	result, err := repository.GetByShortID(ctx, url.Domain, url.ShortID)
+	assert.NoError(t, err)
+	assert.Equal(t, url.RedirectRules, result.RedirectRules)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RevokedTokenRepository defines the interface for storing the IDs ("jti") of tokens revoked before they expire.
+//
+// Methods:
+// - Revoke: Marks a token as revoked until it expires.
+// - IsRevoked: Checks whether a token was revoked.
+type RevokedTokenRepository interface {
+	// Revoke marks a token as revoked until it expires.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - tokenID (string): The ID of the token.
+	// - expiresAt (time.Time): The expiry of the token, after which the entry can be discarded.
+	//
+	// Returns:
+	// - bool: True if the token was revoked by this call, false if it had already been revoked.
+	// - error: An error if the operation fails.
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error)
+
+	// IsRevoked checks whether a token was revoked.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - tokenID (string): The ID of the token.
+	//
+	// Returns:
+	// - bool: True if the token was revoked.
+	// - error: An error if the lookup fails.
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
@@ -2,16 +2,50 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"github.com/guttosm/url-shortener/internal/entity"
 )
 
+// ErrDuplicateShortID is returned when a URL cannot be saved because its short ID is already taken on its domain.
+var ErrDuplicateShortID = errors.New("short ID already exists")
+
+// URLListFilter holds the criteria for listing URL entities.
+//
+// Fields:
+// - OwnerID (string): Only URLs created by this user are returned.
+// - Domain (string): If set, only URLs on this custom domain are returned.
+// - Query (string): If set, only URLs whose short ID or original URL contains it (case-insensitive) are returned.
+// - SortField (string): The document field to sort by.
+// - SortDesc (bool): Whether to sort in descending order.
+// - Skip (int64): The number of matching URLs to skip.
+// - Limit (int64): The maximum number of URLs to return.
+type URLListFilter struct {
+	OwnerID   string
+	Domain    string
+	Query     string
+	SortField string
+	SortDesc  bool
+	Skip      int64
+	Limit     int64
+}
+
+// ShortIDFunc produces a fresh short ID, used by repositories to retry a save after a collision.
+type ShortIDFunc func(ctx context.Context) (string, error)
+
 // URLRepository defines the interface for interacting with the persistent storage of URL entities.
 //
 // Methods:
 // - Save: Stores a new URL entity in the database.
+// - SaveMany: Stores several new URL entities in one batch.
+// - ImportMany: Stores imported URL entities under their own short IDs.
+// - ForEach: Streams URL entities one at a time.
 // - FindByShortID: Retrieves a URL entity by its shortened ID.
-// - FindByOriginalURL: Retrieves a URL entity by its original URL.
+// - FindByOriginalURL: Retrieves a reusable URL entity by the canonical form of its original URL.
+// - ConsumeClick: Counts a redirect against a URL's click limit.
+// - List: Retrieves a page of URL entities matching a filter.
+// - Update: Stores changes to an existing URL entity.
+// - Delete: Removes a URL entity.
 type URLRepository interface {
 	// Save stores a new URL entity in the database.
 	//
@@ -20,28 +54,116 @@ type URLRepository interface {
 	// - url (*entity.URL): The URL entity to be saved.
 	//
 	// Returns:
-	// - error: An error if the save operation fails.
+	// - error: ErrDuplicateShortID if the short ID is taken and cannot be regenerated, or an error if the save operation fails.
 	Save(ctx context.Context, url *entity.URL) error
 
-	// FindByShortID retrieves a URL entity by its shortened ID.
+	// SaveMany stores several new URL entities in one batch, with the same short ID retry semantics as Save.
+	// A failure of one entity does not prevent the others from being stored.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - urls ([]*entity.URL): The URL entities to be saved.
+	//
+	// Returns:
+	// - []error: The error of each entity, by index: nil if it was saved, ErrDuplicateShortID if its short ID is taken, or the write error.
+	// - error: An error if the batch as a whole fails, in which case the per-entity errors are nil.
+	SaveMany(ctx context.Context, urls []*entity.URL) ([]error, error)
+
+	// ImportMany stores imported URL entities under their own short IDs, which are never regenerated.
+	// A failure of one entity does not prevent the others from being stored.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - urls ([]*entity.URL): The URL entities to be stored.
+	// - overwrite (bool): Whether an entity replaces an existing URL with the same short ID instead of being rejected.
+	//
+	// Returns:
+	// - []error: The error of each entity, by index: nil if it was stored, ErrDuplicateShortID if its short ID is taken and overwrite is false, or the write error.
+	// - []*entity.URL: The previous versions of the URLs that were replaced.
+	// - error: An error if the batch as a whole fails.
+	ImportMany(ctx context.Context, urls []*entity.URL, overwrite bool) ([]error, []*entity.URL, error)
+
+	// ForEach streams URL entities in insertion order without loading them all into memory.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): If set, only URLs created by this user are visited.
+	// - fn (func(*entity.URL) error): Called for every URL entity. Returning an error stops the iteration.
+	//
+	// Returns:
+	// - error: The error returned by fn, or an error if the query fails.
+	ForEach(ctx context.Context, ownerID string, fn func(*entity.URL) error) error
+
+	// FindByShortID retrieves a URL entity by its shortened ID on a domain.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
 	// - shortID (string): The shortened ID to search for.
 	//
 	// Returns:
 	// - *entity.URL: The URL entity if found, or nil if no matching document exists.
 	// - error: An error if the query fails.
-	FindByShortID(ctx context.Context, shortID string) (*entity.URL, error)
+	FindByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error)
 
-	// FindByOriginalURL retrieves a URL entity by its original URL.
+	// FindByOriginalURL retrieves a reusable URL entity by the canonical form of its original URL.
+	// Custom aliases, URLs with an expiry, click limit, password or interstitial and URLs created with entity.DedupeNever are excluded
+	// so they are never reused for other requests.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
-	// - originalURL (string): The original URL to search for.
+	// - canonicalURL (string): The canonical URL to search for.
+	// - ownerID (string): If set, only URLs this user created with entity.DedupeOwner match; otherwise only URLs created with entity.DedupeGlobal match.
 	//
 	// Returns:
 	// - *entity.URL: The URL entity if found, or nil if no matching document exists.
 	// - error: An error if the query fails.
-	FindByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error)
+	FindByOriginalURL(ctx context.Context, canonicalURL, ownerID string) (*entity.URL, error)
+
+	// ConsumeClick counts a redirect against a URL's click limit.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID of the URL.
+	// - maxClicks (int64): The click limit of the URL.
+	//
+	// Returns:
+	// - bool: True if the click was counted, false if the limit was already reached.
+	// - error: An error if the update fails.
+	ConsumeClick(ctx context.Context, domain, shortID string, maxClicks int64) (bool, error)
+
+	// List retrieves a page of URL entities matching a filter.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - filter (URLListFilter): The filter, sort order and page bounds.
+	//
+	// Returns:
+	// - []*entity.URL: The URL entities in the requested page.
+	// - int64: The total number of URL entities matching the filter.
+	// - error: An error if the query fails.
+	List(ctx context.Context, filter URLListFilter) ([]*entity.URL, int64, error)
+
+	// Update stores changes to an existing URL entity, identified by its domain and shortened ID.
+	// The click counter is left untouched so concurrent redirects are not lost.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - url (*entity.URL): The URL entity with its new values.
+	//
+	// Returns:
+	// - error: An error if the update fails.
+	Update(ctx context.Context, url *entity.URL) error
+
+	// Delete removes a URL entity by its shortened ID on a domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID of the URL to remove.
+	//
+	// Returns:
+	// - error: An error if the deletion fails.
+	Delete(ctx context.Context, domain, shortID string) error
 }
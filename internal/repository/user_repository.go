@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+var (
+	// ErrDuplicateUsername is returned when a user cannot be created because the username is already taken.
+	ErrDuplicateUsername = errors.New("username already exists")
+	// ErrDuplicateUserID is returned when a user cannot be created because its ID belongs to another user.
+	ErrDuplicateUserID = errors.New("user ID already exists")
+)
+
+// UserRepository defines the interface for interacting with the persistent storage of user accounts.
+//
+// Methods:
+// - Create: Stores a new user.
+// - FindByUsername: Retrieves a user by username.
+// - FindByID: Retrieves a user by ID.
+// - UpdateRole: Changes the role of a user.
+type UserRepository interface {
+	// Create stores a new user, assigning it an ID if it has none.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - user (*entity.User): The user to be stored.
+	//
+	// Returns:
+	// - error: ErrDuplicateUsername if the username is taken, ErrDuplicateUserID if the ID is taken,
+	// or an error if the insertion fails.
+	Create(ctx context.Context, user *entity.User) error
+
+	// FindByUsername retrieves a user by username.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - username (string): The username to search for.
+	//
+	// Returns:
+	// - *entity.User: The user if found, or nil if no matching document exists.
+	// - error: An error if the query fails.
+	FindByUsername(ctx context.Context, username string) (*entity.User, error)
+
+	// FindByID retrieves a user by ID.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - id (string): The user ID to search for.
+	//
+	// Returns:
+	// - *entity.User: The user if found, or nil if no matching document exists.
+	// - error: An error if the query fails.
+	FindByID(ctx context.Context, id string) (*entity.User, error)
+
+	// UpdateRole changes the role of a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - id (string): The ID of the user.
+	// - role (string): The new role.
+	//
+	// Returns:
+	// - bool: True if the user exists.
+	// - error: An error if the update fails.
+	UpdateRole(ctx context.Context, id, role string) (bool, error)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MinAliasLength is the minimum number of characters in a custom alias.
+	MinAliasLength = 3
+	// MaxAliasLength is the maximum number of characters in a custom alias.
+	MaxAliasLength = 32
+)
+
+var (
+	// ErrInvalidAlias is returned when a custom alias has an invalid length or characters.
+	ErrInvalidAlias = errors.New("alias must be 3-32 characters of letters, digits, '-' or '_' and start with a letter or digit")
+	// ErrReservedAlias is returned when a custom alias collides with a reserved route name.
+	ErrReservedAlias = errors.New("alias is reserved")
+	// ErrAliasTaken is returned when a custom alias is already in use.
+	ErrAliasTaken = errors.New("alias is already taken")
+)
+
+// aliasPattern matches the allowed alias charset; length is checked separately.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// reservedAliases lists path segments served by the application itself.
+var reservedAliases = map[string]struct{}{
+	"api":         {},
+	"swagger":     {},
+	"health":      {},
+	"login":       {},
+	"admin":       {},
+	"static":      {},
+	"favicon.ico": {},
+	"robots.txt":  {},
+	".well-known": {},
+}
+
+// ValidateAlias checks that a custom alias can be used as a short ID.
+//
+// Parameters:
+// - alias (string): The requested alias.
+//
+// Returns:
+// - error: ErrInvalidAlias or ErrReservedAlias if the alias cannot be used, otherwise nil.
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength || !aliasPattern.MatchString(alias) {
+		return ErrInvalidAlias
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return ErrReservedAlias
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr error
+	}{
+		{name: "valid slug", alias: "spring-sale"},
+		{name: "valid with underscore", alias: "promo_2024"},
+		{name: "too short", alias: "ab", wantErr: service.ErrInvalidAlias},
+		{name: "too long", alias: strings.Repeat("a", 33), wantErr: service.ErrInvalidAlias},
+		{name: "invalid characters", alias: "spring sale!", wantErr: service.ErrInvalidAlias},
+		{name: "leading dash", alias: "-sale", wantErr: service.ErrInvalidAlias},
+		{name: "reserved word", alias: "swagger", wantErr: service.ErrReservedAlias},
+		{name: "reserved word is case-insensitive", alias: "API", wantErr: service.ErrReservedAlias},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateAlias(tt.alias)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// BucketHour groups click statistics by hour.
+	BucketHour = "hour"
+	// BucketDay groups click statistics by day.
+	BucketDay = "day"
+)
+
+// ErrInvalidBucket is returned when statistics are requested with an unsupported time bucket.
+var ErrInvalidBucket = errors.New("bucket must be \"hour\" or \"day\"")
+
+// flushTimeout bounds how long a single batch write may take.
+const flushTimeout = 5 * time.Second
+
+// AnalyticsConfig holds the settings of the asynchronous click queue.
+//
+// Fields:
+// - BufferSize (int): The number of click events the queue holds before new ones are dropped.
+// - BatchSize (int): The maximum number of click events written per batch.
+// - FlushInterval (time.Duration): The maximum time a click event waits before being written.
+type AnalyticsConfig struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// ClickInfo holds the request details captured for a click event.
+//
+// Fields:
+// - ShortID (string): The shortened ID that was visited.
+// - Domain (string): The custom domain of the visited URL, or an empty string for the default domain.
+// - IP (string): The client IP address.
+// - Referrer (string): The Referer header sent by the client.
+// - UserAgent (string): The User-Agent header sent by the client.
+// - Country (string): The client country code, if known.
+// - Campaign (string): The utm_campaign parameter of the URL the client was redirected to, if any.
+// - Variant (string): The name of the variant the client was assigned to, if any.
+type ClickInfo struct {
+	ShortID   string
+	Domain    string
+	IP        string
+	Referrer  string
+	UserAgent string
+	Country   string
+	Campaign  string
+	Variant   string
+}
+
+// AnalyticsService defines the interface for recording and aggregating click events.
+//
+// Methods:
+// - Record: Queues a click event for asynchronous storage.
+// - Stats: Aggregates the click events of a shortened URL.
+// - Count: Counts the click events of a shortened URL since a time.
+// - Close: Stops accepting events and flushes the queue.
+type AnalyticsService interface {
+	// Record queues a click event for asynchronous storage without blocking.
+	//
+	// Parameters:
+	// - info (ClickInfo): The request details of the click.
+	Record(info ClickInfo)
+
+	// Stats aggregates the click events of a shortened URL.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID to aggregate.
+	// - bucket (string): The time bucket unit, BucketHour or BucketDay.
+	// - from (time.Time): The inclusive start of the period.
+	// - to (time.Time): The exclusive end of the period.
+	//
+	// Returns:
+	// - *entity.ClickStats: The aggregated statistics.
+	// - error: ErrInvalidBucket for an unsupported bucket, or an error if the aggregation fails.
+	Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error)
+
+	// Count counts the click events of a shortened URL since a time.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID.
+	// - since (time.Time): The inclusive start of the count, usually the creation time of the URL.
+	//
+	// Returns:
+	// - int64: The number of click events.
+	// - error: An error if the query fails.
+	Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error)
+
+	// Close stops accepting events and blocks until queued events are written.
+	Close()
+}
+
+// analyticsService is an AnalyticsService that writes click events in batches from a buffered channel.
+//
+// Fields:
+// - repo (repository.ClickRepository): The repository for click storage.
+// - queue (chan *entity.Click): The buffered queue of pending click events.
+// - batchSize (int): The maximum number of click events written per batch.
+// - flushInterval (time.Duration): The maximum time a click event waits before being written.
+// - mu (sync.RWMutex): Guards queue against sends after Close.
+// - closed (bool): Whether Close has been called.
+// - dropped (atomic.Int64): The number of click events dropped because the queue was full.
+// - done (sync.WaitGroup): Tracks the worker goroutine.
+type analyticsService struct {
+	repo          repository.ClickRepository
+	queue         chan *entity.Click
+	batchSize     int
+	flushInterval time.Duration
+	mu            sync.RWMutex
+	closed        bool
+	dropped       atomic.Int64
+	done          sync.WaitGroup
+}
+
+// NewAnalyticsService creates a new AnalyticsService and starts its background writer.
+//
+// Parameters:
+// - repo (repository.ClickRepository): The repository for click storage.
+// - cfg (AnalyticsConfig): The queue settings. Non-positive values fall back to defaults.
+//
+// Returns:
+// - AnalyticsService: An instance of the AnalyticsService interface.
+func NewAnalyticsService(repo repository.ClickRepository, cfg AnalyticsConfig) AnalyticsService {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	s := &analyticsService{
+		repo:          repo,
+		queue:         make(chan *entity.Click, cfg.BufferSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+	}
+	s.done.Add(1)
+	go s.run()
+	return s
+}
+
+// Record queues a click event for asynchronous storage without blocking.
+//
+// Behavior:
+// - Builds the click event with a coarse IP, user agent family and visitor hash.
+// - Drops the event if the queue is full or the service is closed, so redirects never wait on storage.
+func (s *analyticsService) Record(info ClickInfo) {
+	click := NewClick(info, time.Now())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.queue <- click:
+	default:
+		if n := s.dropped.Add(1); n%1000 == 1 {
+			log.Printf("analytics queue full, %d click events dropped so far", n)
+		}
+	}
+}
+
+// Stats aggregates the click events of a shortened URL.
+func (s *analyticsService) Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+	if bucket != BucketHour && bucket != BucketDay {
+		return nil, ErrInvalidBucket
+	}
+	return s.repo.Stats(ctx, domain, shortID, bucket, from, to)
+}
+
+// Count counts the click events of a shortened URL since a time.
+func (s *analyticsService) Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error) {
+	return s.repo.Count(ctx, domain, shortID, since)
+}
+
+// Close stops accepting events and blocks until queued events are written.
+func (s *analyticsService) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.done.Wait()
+}
+
+// run writes queued click events in batches until the queue is closed and drained.
+func (s *analyticsService) run() {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entity.Click, 0, s.batchSize)
+	for {
+		select {
+		case click, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes a batch of click events, logging failures since no caller is waiting on them.
+func (s *analyticsService) flush(batch []*entity.Click) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if err := s.repo.SaveMany(ctx, batch); err != nil {
+		log.Printf("failed to store %d click events: %v", len(batch), err)
+	}
+}
+
+// NewClick builds a click event from request details.
+//
+// Parameters:
+// - info (ClickInfo): The request details of the click.
+// - now (time.Time): The time of the click.
+//
+// Behavior:
+// - Truncates the IP to a /24 (IPv4) or /48 (IPv6) network so full addresses are never stored.
+// - Derives the visitor ID from a hash of the full IP and user agent.
+//
+// Returns:
+// - *entity.Click: The click event.
+func NewClick(info ClickInfo, now time.Time) *entity.Click {
+	return &entity.Click{
+		ShortID:   info.ShortID,
+		Domain:    info.Domain,
+		Timestamp: now.UTC(),
+		Referrer:  info.Referrer,
+		UserAgent: info.UserAgent,
+		UAFamily:  UserAgentFamily(info.UserAgent),
+		IP:        CoarseIP(info.IP),
+		Country:   info.Country,
+		Campaign:  info.Campaign,
+		Variant:   info.Variant,
+		VisitorID: visitorID(info.IP, info.UserAgent),
+	}
+}
+
+// CoarseIP truncates an IP address to its /24 (IPv4) or /48 (IPv6) network.
+//
+// Parameters:
+// - ip (string): The IP address.
+//
+// Returns:
+// - string: The network address, or an empty string if ip is not a valid address.
+func CoarseIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// visitorID returns a short one-way hash identifying a visitor by IP and user agent.
+func visitorID(ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockClickRepository struct {
+	mock.Mock
+	mu    sync.Mutex
+	saved []*entity.Click
+}
+
+func (m *MockClickRepository) SaveMany(ctx context.Context, clicks []*entity.Click) error {
+	m.mu.Lock()
+	m.saved = append(m.saved, clicks...)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MockClickRepository) Stats(ctx context.Context, domain, shortID, bucket string, from, to time.Time) (*entity.ClickStats, error) {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID), bucket, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.ClickStats), args.Error(1)
+}
+
+func (m *MockClickRepository) Count(ctx context.Context, domain, shortID string, since time.Time) (int64, error) {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID), since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockClickRepository) savedClicks() []*entity.Click {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*entity.Click(nil), m.saved...)
+}
+
+func TestAnalyticsService_RecordFlushesOnClose(t *testing.T) {
+	repo := new(MockClickRepository)
+	svc := service.NewAnalyticsService(repo, service.AnalyticsConfig{BatchSize: 10, FlushInterval: time.Hour})
+
+	for i := 0; i < 25; i++ {
+		svc.Record(service.ClickInfo{ShortID: "abc123", IP: "203.0.113.7", UserAgent: "curl/8.0"})
+	}
+	svc.Close()
+
+	saved := repo.savedClicks()
+	assert.Len(t, saved, 25)
+	assert.Equal(t, "abc123", saved[0].ShortID)
+	assert.Equal(t, "curl", saved[0].UAFamily)
+	assert.Equal(t, "203.0.113.0", saved[0].IP)
+}
+
+func TestAnalyticsService_RecordFlushesOnInterval(t *testing.T) {
+	repo := new(MockClickRepository)
+	svc := service.NewAnalyticsService(repo, service.AnalyticsConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer svc.Close()
+
+	svc.Record(service.ClickInfo{ShortID: "abc123"})
+
+	assert.Eventually(t, func() bool {
+		return len(repo.savedClicks()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAnalyticsService_RecordAfterCloseIsDropped(t *testing.T) {
+	repo := new(MockClickRepository)
+	svc := service.NewAnalyticsService(repo, service.AnalyticsConfig{})
+	svc.Close()
+
+	assert.NotPanics(t, func() {
+		svc.Record(service.ClickInfo{ShortID: "abc123"})
+	})
+	assert.Empty(t, repo.savedClicks())
+}
+
+func TestAnalyticsService_Stats(t *testing.T) {
+	ctx := context.Background()
+	from := time.Now().Add(-time.Hour)
+	to := time.Now()
+	expected := &entity.ClickStats{TotalClicks: 3, UniqueVisitors: 2}
+
+	repo := new(MockClickRepository)
+	repo.On("Stats", ctx, "abc123", service.BucketHour, from, to).Return(expected, nil)
+
+	svc := service.NewAnalyticsService(repo, service.AnalyticsConfig{})
+	defer svc.Close()
+
+	stats, err := svc.Stats(ctx, "", "abc123", service.BucketHour, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stats)
+
+	_, err = svc.Stats(ctx, "", "abc123", "week", from, to)
+	assert.ErrorIs(t, err, service.ErrInvalidBucket)
+}
+
+func TestNewClick(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := service.NewClick(service.ClickInfo{ShortID: "abc123", IP: "198.51.100.23", UserAgent: "Mozilla/5.0 Firefox/125.0", Country: "BR", Variant: "b"}, now)
+	second := service.NewClick(service.ClickInfo{ShortID: "abc123", IP: "198.51.100.99", UserAgent: "Mozilla/5.0 Firefox/125.0"}, now)
+
+	assert.Equal(t, now, first.Timestamp)
+	assert.Equal(t, "198.51.100.0", first.IP)
+	assert.Equal(t, "Firefox", first.UAFamily)
+	assert.Equal(t, "BR", first.Country)
+	assert.Equal(t, "b", first.Variant)
+	assert.Equal(t, first.IP, second.IP)
+	assert.NotEqual(t, first.VisitorID, second.VisitorID)
+}
+
+func TestCoarseIP(t *testing.T) {
+	assert.Equal(t, "192.0.2.0", service.CoarseIP("192.0.2.55"))
+	assert.Equal(t, "2001:db8:abcd::", service.CoarseIP("2001:db8:abcd:12::1"))
+	assert.Equal(t, "", service.CoarseIP("not-an-ip"))
+}
+
+func TestUserAgentFamily(t *testing.T) {
+	tests := map[string]string{
+		"":           "Unknown",
+		"curl/8.4.0": "curl",
+		"Mozilla/5.0 (compatible; Googlebot/2.1)":                                                  "Bot",
+		"Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 Chrome/124.0 Safari/537.36":              "Chrome",
+		"Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 Chrome/124.0 Safari/537.36 Edg/124.0":    "Edge",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Safari/604.1": "Safari",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0":                   "Firefox",
+		"SomethingElse/1.0": "Other",
+	}
+	for ua, family := range tests {
+		assert.Equal(t, family, service.UserAgentFamily(ua), ua)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so leaked keys are easy to recognize and scan for.
+	APIKeyPrefix = "usk_"
+	// apiKeyBytes is the number of random bytes in an API key.
+	apiKeyBytes = 32
+	// apiKeyDisplayLength is the number of leading key characters stored for display.
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+	// maxAPIKeyNameLength is the maximum length of an API key name.
+	maxAPIKeyNameLength = 64
+	// lastUsedResolution limits how often the last-used timestamp of a key is written.
+	lastUsedResolution = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when a user has no active API key with the given ID.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKeyName is returned when an API key name is empty or too long.
+	ErrInvalidAPIKeyName = errors.New("API key name must be between 1 and 64 characters")
+	// ErrInvalidScope is returned when a scope is unknown.
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrScopeNotGranted is returned when an API key is requested with a scope its user does not have.
+	ErrScopeNotGranted = errors.New("scope exceeds the user's role")
+)
+
+// APIKeyService defines the interface for managing API keys and authenticating requests made with them.
+//
+// Methods:
+// - Create: Creates an API key for a user.
+// - List: Lists the API keys of a user.
+// - Revoke: Revokes an API key of a user.
+// - AuthenticateAPIKey: Authenticates a request made with an API key.
+type APIKeyService interface {
+	// Create creates an API key for a user.
+	// Keys can only be granted scopes the user's role has; without requested scopes,
+	// the key gets every scope of the role except admin.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user the key acts as.
+	// - name (string): A label for the key.
+	// - scopes ([]string): The scopes granted to the key.
+	//
+	// Returns:
+	// - *entity.APIKey: The stored key.
+	// - string: The key itself, which is not stored and cannot be retrieved again.
+	// - error: ErrInvalidAPIKeyName, ErrInvalidScope, ErrScopeNotGranted, or an error if the operation fails.
+	Create(ctx context.Context, userID, name string, scopes []string) (*entity.APIKey, string, error)
+
+	// List lists the API keys of a user, newest first.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user.
+	//
+	// Returns:
+	// - []*entity.APIKey: The keys of the user, including revoked ones.
+	// - error: An error if the operation fails.
+	List(ctx context.Context, userID string) ([]*entity.APIKey, error)
+
+	// Revoke revokes an API key of a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user owning the key.
+	// - id (string): The ID of the key.
+	//
+	// Returns:
+	// - error: ErrAPIKeyNotFound if the user has no active key with that ID, or an error if the operation fails.
+	Revoke(ctx context.Context, userID, id string) error
+
+	// AuthenticateAPIKey authenticates a request made with an API key and records its use.
+	// The scopes of the key are limited to those of the user's current role, so demoting
+	// a user also restricts their existing keys.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - key (string): The API key sent by the client.
+	//
+	// Returns:
+	// - map[string]interface{}: The "user_id", "api_key_id" and "scopes" of the key.
+	// - error: auth.ErrInvalidAPIKey, or an error if the lookup fails.
+	AuthenticateAPIKey(ctx context.Context, key string) (map[string]interface{}, error)
+}
+
+// apiKeyService is the implementation of APIKeyService.
+type apiKeyService struct {
+	repo  repository.APIKeyRepository
+	users repository.UserRepository
+}
+
+// NewAPIKeyService creates an APIKeyService.
+//
+// Parameters:
+// - repo (repository.APIKeyRepository): The repository storing hashed API keys.
+// - users (repository.UserRepository): Looks up the role of the users keys act as.
+//
+// Returns:
+// - APIKeyService: The API key service.
+func NewAPIKeyService(repo repository.APIKeyRepository, users repository.UserRepository) APIKeyService {
+	return &apiKeyService{repo: repo, users: users}
+}
+
+// Create generates a random key and stores its hash.
+func (s *apiKeyService) Create(ctx context.Context, userID, name string, scopes []string) (*entity.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, "", ErrInvalidAPIKeyName
+	}
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	roleScopes, err := s.roleScopes(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if roleScopes == nil {
+		return nil, "", ErrUserNotFound
+	}
+	if len(normalized) == 0 {
+		for _, scope := range roleScopes {
+			if scope != auth.ScopeAdmin {
+				normalized = append(normalized, scope)
+			}
+		}
+	}
+	for _, scope := range normalized {
+		if !auth.HasScope(roleScopes, scope) {
+			return nil, "", ErrScopeNotGranted
+		}
+	}
+
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	raw := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key := &entity.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  raw[:apiKeyDisplayLength],
+		KeyHash: hashAPIKey(raw),
+		Scopes:  normalized,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+// List lists the API keys of a user.
+func (s *apiKeyService) List(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Revoke revokes an API key of a user.
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id string) error {
+	revoked, err := s.repo.Revoke(ctx, userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey looks the key up by its hash and rejects unknown or revoked keys.
+// The last-used timestamp is written at most once per lastUsedResolution, and failing to
+// write it does not fail the request.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, raw string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(raw, APIKeyPrefix) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByHash(ctx, hashAPIKey(raw))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.IsRevoked() {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	roleScopes, err := s.roleScopes(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if roleScopes == nil {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	scopes := []string{}
+	for _, scope := range key.Scopes {
+		if auth.HasScope(roleScopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			log.Printf("failed to record use of API key %s: %v", key.ID, err)
+		}
+	}
+
+	return map[string]interface{}{
+		"user_id":    key.UserID,
+		"api_key_id": key.ID,
+		"scopes":     scopes,
+	}, nil
+}
+
+// roleScopes returns the scopes of the user's current role, or nil if the user does not exist.
+func (s *apiKeyService) roleScopes(ctx context.Context, userID string) ([]string, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return auth.ScopesForRole(user.Role), nil
+}
+
+// hashAPIKey returns the hex-encoded SHA-256 hash of a key.
+// API keys carry 256 bits of randomness, so a fast unsalted hash is enough and allows indexed lookups.
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates, deduplicates and sorts scopes.
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	normalized := []string{}
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !auth.IsValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
@@ -0,0 +1,181 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeyRepository is an in-memory APIKeyRepository.
+type fakeAPIKeyRepository struct {
+	keys     []*entity.APIKey
+	touches  int
+	touchErr error
+}
+
+func (r *fakeAPIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	key.ID = key.Prefix
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	for _, k := range r.keys {
+		if k.KeyHash == keyHash {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAPIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	var keys []*entity.APIKey
+	for _, k := range r.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	for _, k := range r.keys {
+		if k.ID == id && k.UserID == userID && k.RevokedAt == nil {
+			k.RevokedAt = &revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	r.touches++
+	if r.touchErr != nil {
+		return r.touchErr
+	}
+	for _, k := range r.keys {
+		if k.ID == id {
+			k.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+// newAPIKeyTestUsers returns a user repository with an editor (user-1), a viewer (user-2) and an admin (user-3).
+func newAPIKeyTestUsers() *fakeUserRepository {
+	users := newFakeUserRepository()
+	users.users["editor"] = &entity.User{ID: "user-1", Username: "editor", Role: auth.RoleEditor}
+	users.users["viewer"] = &entity.User{ID: "user-2", Username: "viewer", Role: auth.RoleViewer}
+	users.users["admin"] = &entity.User{ID: "user-3", Username: "admin", Role: auth.RoleAdmin}
+	return users
+}
+
+func TestAPIKeyService_CreateStoresOnlyHash(t *testing.T) {
+	repo := &fakeAPIKeyRepository{}
+	svc := service.NewAPIKeyService(repo, newAPIKeyTestUsers())
+
+	key, raw, err := svc.Create(context.Background(), "user-1", " ci ", []string{"links:write", "Links:Write", "stats:read"})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(raw, service.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(raw, key.Prefix))
+	assert.Equal(t, "ci", key.Name)
+	assert.Equal(t, []string{"links:write", "stats:read"}, key.Scopes)
+	assert.NotContains(t, key.KeyHash, raw)
+	assert.Len(t, key.KeyHash, 64)
+}
+
+func TestAPIKeyService_CreateValidation(t *testing.T) {
+	svc := service.NewAPIKeyService(&fakeAPIKeyRepository{}, newAPIKeyTestUsers())
+
+	_, _, err := svc.Create(context.Background(), "user-1", "  ", nil)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKeyName)
+
+	_, _, err = svc.Create(context.Background(), "user-1", "ci", []string{"links write"})
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	_, _, err = svc.Create(context.Background(), "user-2", "ci", []string{auth.ScopeLinksWrite})
+	assert.ErrorIs(t, err, service.ErrScopeNotGranted)
+
+	_, _, err = svc.Create(context.Background(), "user-1", "ci", []string{auth.ScopeAdmin})
+	assert.ErrorIs(t, err, service.ErrScopeNotGranted)
+}
+
+func TestAPIKeyService_DefaultScopesExcludeAdmin(t *testing.T) {
+	svc := service.NewAPIKeyService(&fakeAPIKeyRepository{}, newAPIKeyTestUsers())
+
+	key, _, err := svc.Create(context.Background(), "user-3", "ci", nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{auth.ScopeLinksRead, auth.ScopeLinksWrite, auth.ScopeStatsRead}, key.Scopes)
+}
+
+func TestAPIKeyService_ScopesLimitedByCurrentRole(t *testing.T) {
+	ctx := context.Background()
+	users := newAPIKeyTestUsers()
+	svc := service.NewAPIKeyService(&fakeAPIKeyRepository{}, users)
+
+	_, raw, err := svc.Create(ctx, "user-1", "ci", []string{auth.ScopeLinksWrite, auth.ScopeStatsRead})
+	require.NoError(t, err)
+
+	users.users["editor"].Role = auth.RoleViewer
+
+	claims, err := svc.AuthenticateAPIKey(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.ScopeStatsRead}, claims["scopes"])
+}
+
+func TestAPIKeyService_AuthenticateAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAPIKeyRepository{}
+	svc := service.NewAPIKeyService(repo, newAPIKeyTestUsers())
+
+	key, raw, err := svc.Create(ctx, "user-1", "ci", []string{"links:write"})
+	require.NoError(t, err)
+
+	claims, err := svc.AuthenticateAPIKey(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["user_id"])
+	assert.Equal(t, key.ID, claims["api_key_id"])
+	assert.Equal(t, []string{"links:write"}, claims["scopes"])
+	assert.NotNil(t, key.LastUsedAt)
+
+	// The last-used timestamp is not rewritten on every request.
+	_, err = svc.AuthenticateAPIKey(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.touches)
+
+	_, err = svc.AuthenticateAPIKey(ctx, raw+"x")
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	_, err = svc.AuthenticateAPIKey(ctx, "not-a-key")
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	assert.ErrorIs(t, svc.Revoke(ctx, "user-2", key.ID), service.ErrAPIKeyNotFound)
+	require.NoError(t, svc.Revoke(ctx, "user-1", key.ID))
+	assert.ErrorIs(t, svc.Revoke(ctx, "user-1", key.ID), service.ErrAPIKeyNotFound)
+
+	_, err = svc.AuthenticateAPIKey(ctx, raw)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_LastUsedFailureDoesNotFailAuthentication(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeAPIKeyRepository{touchErr: errors.New("write failed")}
+	svc := service.NewAPIKeyService(repo, newAPIKeyTestUsers())
+
+	_, raw, err := svc.Create(ctx, "user-1", "ci", nil)
+	require.NoError(t, err)
+
+	_, err = svc.AuthenticateAPIKey(ctx, raw)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTMParams holds the campaign parameters merged into a destination URL.
+// Empty fields are left out.
+//
+// Fields:
+// - Source (string): utm_source, the site or newsletter sending the traffic.
+// - Medium (string): utm_medium, the marketing medium, e.g. "email" or "cpc".
+// - Campaign (string): utm_campaign, the campaign name.
+// - Term (string): utm_term, the paid search keywords.
+// - Content (string): utm_content, which ad or link was clicked.
+type UTMParams struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// Values returns the non-empty parameters by their query parameter names.
+//
+// Returns:
+// - url.Values: The parameters, empty if no field is set.
+func (p UTMParams) Values() url.Values {
+	values := url.Values{}
+	for name, value := range map[string]string{
+		"utm_source":   p.Source,
+		"utm_medium":   p.Medium,
+		"utm_campaign": p.Campaign,
+		"utm_term":     p.Term,
+		"utm_content":  p.Content,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// utmFromURL returns the UTM parameters carried by the query string of a URL. Parameter names are matched
+// case-insensitively, and a URL that cannot be parsed carries none.
+func utmFromURL(rawURL string) UTMParams {
+	var p UTMParams
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return p
+	}
+	for name, values := range u.Query() {
+		var field *string
+		switch strings.ToLower(name) {
+		case "utm_source":
+			field = &p.Source
+		case "utm_medium":
+			field = &p.Medium
+		case "utm_campaign":
+			field = &p.Campaign
+		case "utm_term":
+			field = &p.Term
+		case "utm_content":
+			field = &p.Content
+		default:
+			continue
+		}
+		if *field == "" && len(values) > 0 {
+			*field = values[0]
+		}
+	}
+	return p
+}
+
+// names returns the query parameter names of the non-empty fields.
+func (p UTMParams) names() []string {
+	var names []string
+	for name := range p.Values() {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Apply merges the parameters into a URL, see MergeQuery.
+//
+// Parameters:
+// - rawURL (string): The destination URL.
+//
+// Returns:
+// - string: The URL carrying the parameters, unchanged if no field is set.
+func (p UTMParams) Apply(rawURL string) string {
+	return MergeQuery(rawURL, p.Values())
+}
+
+// MergeQuery adds query parameters to a URL.
+//
+// Parameters:
+// - rawURL (string): The URL.
+// - params (url.Values): The parameters to add.
+//
+// Behavior:
+// - Parameters of the URL named like one of params are replaced; the others keep their order and encoding.
+// - The added parameters follow the existing ones, sorted by name. The fragment is kept.
+// - URLs that cannot be parsed are returned unchanged.
+//
+// Returns:
+// - string: The merged URL.
+func MergeQuery(rawURL string, params url.Values) string {
+	if len(params) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		if name, err := url.QueryUnescape(queryParamName(param)); err == nil {
+			if _, replaced := params[name]; replaced {
+				continue
+			}
+		}
+		kept = append(kept, param)
+	}
+	if added := params.Encode(); added != "" {
+		kept = append(kept, added)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// CampaignOf returns the utm_campaign parameter of a URL, or an empty string if it has none.
+//
+// Parameters:
+// - rawURL (string): The URL.
+//
+// Returns:
+// - string: The campaign name.
+func CampaignOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("utm_campaign")
+}
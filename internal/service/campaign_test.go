@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		params url.Values
+		want   string
+	}{
+		{
+			name:   "no parameters",
+			rawURL: "https://example.com/a?b=1",
+			want:   "https://example.com/a?b=1",
+		},
+		{
+			name:   "adds parameters sorted by name",
+			rawURL: "https://example.com/a",
+			params: url.Values{"utm_source": {"news letter"}, "utm_medium": {"email"}},
+			want:   "https://example.com/a?utm_medium=email&utm_source=news+letter",
+		},
+		{
+			name:   "replaces parameters and keeps the others as written",
+			rawURL: "https://example.com/a?z=%2F&utm_source=old&utm_source=older&a=1#frag",
+			params: url.Values{"utm_source": {"new"}},
+			want:   "https://example.com/a?z=%2F&a=1&utm_source=new#frag",
+		},
+		{
+			name:   "matches encoded parameter names",
+			rawURL: "https://example.com/?utm%5Fsource=old",
+			params: url.Values{"utm_source": {"new"}},
+			want:   "https://example.com/?utm_source=new",
+		},
+		{
+			name:   "unparseable URL is unchanged",
+			rawURL: "https://exa mple.com/%zz",
+			params: url.Values{"a": {"1"}},
+			want:   "https://exa mple.com/%zz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, service.MergeQuery(tt.rawURL, tt.params))
+		})
+	}
+}
+
+func TestUTMParams_Apply(t *testing.T) {
+	utm := service.UTMParams{Source: "newsletter", Medium: " email ", Campaign: "spring sale", Content: ""}
+
+	got := utm.Apply("https://example.com/shop?utm_campaign=old&id=7")
+
+	assert.Equal(t, "https://example.com/shop?id=7&utm_campaign=spring+sale&utm_medium=email&utm_source=newsletter", got)
+	assert.Equal(t, "spring sale", service.CampaignOf(got))
+	assert.Equal(t, "https://example.com/", service.UTMParams{}.Apply("https://example.com/"))
+}
+
+func TestRedirectURL(t *testing.T) {
+	query := url.Values{"utm_campaign": {"summer"}}
+
+	destination, _ := service.RedirectURL(&entity.URL{Original: "https://example.com/?utm_campaign=spring"}, service.Visit{Query: query})
+	assert.Equal(t, "https://example.com/?utm_campaign=spring", destination)
+
+	destination, _ = service.RedirectURL(&entity.URL{Original: "https://example.com/?utm_campaign=spring", ForwardQuery: true}, service.Visit{Query: query})
+	assert.Equal(t, "https://example.com/?utm_campaign=summer", destination)
+}
+
+func TestShorten_CampaignOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("UTM fields are merged into the destination", func(t *testing.T) {
+		tagged := "https://example.com/docs?utm_campaign=launch&utm_source=blog"
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		cache.On("GetByDedupeKey", ctx, "global:"+tagged).Return(nil, nil)
+		repo.On("FindByOriginalURL", ctx, tagged, "").Return(nil, nil)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+		cache.On("SetByDedupeKey", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Shorten(ctx, "https://example.com/docs", service.ShortenOptions{
+			UTM: service.UTMParams{Source: "blog", Campaign: "launch"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, tagged, result.Original)
+	})
+
+	t.Run("UTM fields stay in the dedupe key when tracking parameters are dropped", func(t *testing.T) {
+		spring := "https://example.com/docs?utm_campaign=spring"
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		cache.On("GetByDedupeKey", ctx, "global:"+spring).Return(nil, nil)
+		repo.On("FindByOriginalURL", ctx, spring, "").Return(nil, nil)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+		cache.On("SetByDedupeKey", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache, service.WithCanonicalizer(service.Canonicalizer{DropTrackingParams: true}))
+		result, err := svc.Shorten(ctx, "https://example.com/docs?utm_campaign=autumn&fbclid=abc", service.ShortenOptions{
+			UTM: service.UTMParams{Campaign: "spring"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/docs?fbclid=abc&utm_campaign=spring", result.Original)
+		assert.Equal(t, spring, result.Canonical)
+		assert.Equal(t, "global:"+spring, result.DedupeKey())
+		cache.AssertExpectations(t)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("UTM fields stay in the dedupe key of an updated destination", func(t *testing.T) {
+		existing := &entity.URL{ShortID: "abc123", Original: "https://example.com/docs", OwnerID: "user-1"}
+		destination := "https://example.com/docs?UTM_Campaign=autumn&fbclid=abc"
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		repo.On("FindByShortID", ctx, "abc123").Return(existing, nil)
+		repo.On("Update", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("Delete", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache, service.WithCanonicalizer(service.Canonicalizer{DropTrackingParams: true}))
+		result, err := svc.Update(ctx, "user-1", "", "abc123", service.UpdateOptions{Original: &destination})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/docs?UTM_Campaign=autumn", result.Canonical)
+	})
+
+	t.Run("forwarding links are never reused", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Shorten(ctx, "https://example.com/docs", service.ShortenOptions{ForwardQuery: true})
+
+		assert.NoError(t, err)
+		assert.True(t, result.ForwardQuery)
+		assert.False(t, result.Reusable())
+		cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "FindByOriginalURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
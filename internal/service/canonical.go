@@ -0,0 +1,260 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts maps schemes to the port used when a URL names none.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// trackingParams lists query parameters that only identify the source of a visit.
+// Parameters starting with "utm_" are tracking parameters too.
+var trackingParams = map[string]struct{}{
+	"fbclid":  {},
+	"gclid":   {},
+	"dclid":   {},
+	"gbraid":  {},
+	"wbraid":  {},
+	"msclkid": {},
+	"yclid":   {},
+	"twclid":  {},
+	"igshid":  {},
+	"mc_cid":  {},
+	"mc_eid":  {},
+	"_ga":     {},
+	"_hsenc":  {},
+	"_hsmi":   {},
+}
+
+// Canonicalizer rewrites URLs to a canonical form, so equivalent URLs share one short ID.
+// The zero value keeps tracking parameters.
+//
+// Fields:
+// - DropTrackingParams (bool): Whether utm_* and click ID parameters such as fbclid and gclid are removed.
+type Canonicalizer struct {
+	DropTrackingParams bool
+
+	keep map[string]bool
+}
+
+// Keeping returns a copy of the canonicalizer that keeps the named tracking parameters even if tracking
+// parameters are dropped.
+//
+// Parameters:
+// - names (...string): The lowercase names of the parameters to keep, e.g. "utm_campaign".
+//
+// Returns:
+// - Canonicalizer: The canonicalizer keeping the parameters.
+func (c Canonicalizer) Keeping(names ...string) Canonicalizer {
+	if len(names) == 0 {
+		return c
+	}
+	keep := make(map[string]bool, len(c.keep)+len(names))
+	for name := range c.keep {
+		keep[name] = true
+	}
+	for _, name := range names {
+		keep[name] = true
+	}
+	c.keep = keep
+	return c
+}
+
+// canonicalDestination returns the canonical form of a stored destination. The UTM parameters it carries are
+// kept even when tracking parameters are dropped, like those set by the options of a shorten request, so
+// links for different campaigns keep different dedupe keys after an update or an import.
+func canonicalDestination(c Canonicalizer, original string) string {
+	return c.Keeping(utmFromURL(original).names()...).Canonicalize(original)
+}
+
+// Canonicalize returns the canonical form of a URL.
+//
+// Parameters:
+// - rawURL (string): The URL as given by the user.
+//
+// Behavior:
+// - Lowercases the scheme and host, converts internationalized hosts to punycode and removes a trailing dot.
+// - Removes the port if it is the default port of the scheme.
+// - Uses "/" as the path of URLs without one, resolves "." and ".." segments, decodes percent-encoded
+// unreserved characters and uppercases the remaining percent-encodings.
+// - Sorts query parameters by name, drops an empty query and fragment, and removes tracking parameters if enabled.
+// - URLs that cannot be parsed or have no host are returned trimmed but otherwise unchanged.
+//
+// Returns:
+// - string: The canonical URL.
+func (c Canonicalizer) Canonicalize(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	canonical := &url.URL{
+		Scheme: u.Scheme,
+		User:   u.User,
+		Host:   host,
+	}
+
+	path := removeDotSegments(normalizePercentEncoding(u.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	canonical.RawPath = path
+	canonical.Path, _ = url.PathUnescape(path)
+
+	canonical.RawQuery = c.canonicalQuery(u.RawQuery)
+	if u.Fragment != "" {
+		canonical.RawFragment = normalizePercentEncoding(u.EscapedFragment())
+		canonical.Fragment, _ = url.PathUnescape(canonical.RawFragment)
+	}
+	return canonical.String()
+}
+
+// canonicalQuery sorts the parameters of a raw query by name, keeping the order of repeated parameters,
+// and drops tracking parameters if enabled.
+func (c Canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		if c.DropTrackingParams && isTrackingParam(param) && !c.keep[paramName(param)] {
+			continue
+		}
+		params = append(params, normalizePercentEncoding(param))
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryParamName(params[i]) < queryParamName(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// queryParamName returns the name of a "name=value" query parameter.
+func queryParamName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	return name
+}
+
+// paramName returns the decoded, lowercase name of a "name=value" query parameter, or an empty string if it
+// cannot be decoded.
+func paramName(param string) string {
+	name, err := url.QueryUnescape(queryParamName(param))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(name)
+}
+
+// isTrackingParam reports whether a "name=value" query parameter only identifies the source of a visit.
+func isTrackingParam(param string) bool {
+	name := paramName(param)
+	if name == "" {
+		return false
+	}
+	if strings.HasPrefix(name, "utm_") {
+		return true
+	}
+	_, ok := trackingParams[name]
+	return ok
+}
+
+// normalizePercentEncoding decodes percent-encoded unreserved characters (RFC 3986, section 2.3)
+// and uppercases the hex digits of the other percent-encodings.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." segments of a path (RFC 3986, section 5.2.4),
+// keeping empty segments and the trailing slash.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+// isUnreserved reports whether a byte is an unreserved URI character, which never needs percent-encoding.
+func isUnreserved(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		b == '-' || b == '.' || b == '_' || b == '~'
+}
+
+// isHex reports whether a byte is a hexadecimal digit.
+func isHex(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'f' || 'A' <= b && b <= 'F'
+}
+
+// unhex returns the value of a hexadecimal digit.
+func unhex(b byte) byte {
+	switch {
+	case '0' <= b && b <= '9':
+		return b - '0'
+	case 'a' <= b && b <= 'f':
+		return b - 'a' + 10
+	default:
+		return b - 'A' + 10
+	}
+}
@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCanonicalizer(t *testing.T) {
+	keep := service.Canonicalizer{}
+	drop := service.Canonicalizer{DropTrackingParams: true}
+
+	cases := []struct {
+		canon    service.Canonicalizer
+		raw      string
+		expected string
+	}{
+		{keep, "HTTP://Example.COM", "http://example.com/"},
+		{keep, "http://example.com/", "http://example.com/"},
+		{keep, "  https://example.com.:443/a/./b/../c/  ", "https://example.com/a/c/"},
+		{keep, "http://example.com:80/path", "http://example.com/path"},
+		{keep, "https://example.com:8443/path", "https://example.com:8443/path"},
+		{keep, "http://[2001:DB8::1]:80/", "http://[2001:db8::1]/"},
+		{keep, "https://bücher.example/Straße", "https://xn--bcher-kva.example/Stra%C3%9Fe"},
+		{keep, "https://example.com/%7euser/%2fdocs%3f", "https://example.com/~user/%2Fdocs%3F"},
+		{keep, "https://example.com/Path?b=2&a=1&a=0#Top", "https://example.com/Path?a=1&a=0&b=2#Top"},
+		{keep, "https://example.com/?", "https://example.com/"},
+		{keep, "https://example.com/?utm_source=x&id=7", "https://example.com/?id=7&utm_source=x"},
+		{drop, "https://example.com/?utm_source=x&UTM_Medium=y&fbclid=abc&id=7", "https://example.com/?id=7"},
+		{drop, "http://example.com/?utm_source=x", "http://example.com/"},
+		{drop.Keeping("utm_campaign"), "https://example.com/?utm_source=x&UTM_Campaign=spring&gclid=1&id=7", "https://example.com/?UTM_Campaign=spring&id=7"},
+		{keep.Keeping("utm_campaign"), "https://example.com/?utm_source=x&utm_campaign=spring", "https://example.com/?utm_campaign=spring&utm_source=x"},
+		{keep, "not a url", "not a url"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, tc.canon.Canonicalize(tc.raw), tc.raw)
+	}
+}
+
+func TestShorten_EquivalentURLsShareMapping(t *testing.T) {
+	ctx := context.Background()
+	canonical := "http://example.com/"
+	existing := &entity.URL{ShortID: "abc123", Original: "http://example.com", Canonical: canonical}
+
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	cache.On("GetByDedupeKey", ctx, "global:"+canonical).Return(nil, errors.New("cache miss"))
+	repo.On("FindByOriginalURL", ctx, canonical, "").Return(existing, nil)
+	cache.On("SetByDedupeKey", ctx, existing).Return(nil)
+	cache.On("SetByShortID", ctx, existing).Return(nil)
+
+	svc := service.NewURLService(repo, cache, service.WithCanonicalizer(service.Canonicalizer{DropTrackingParams: true}))
+	for _, raw := range []string{"HTTP://Example.com/", "http://example.com", "http://example.com/?utm_source=x"} {
+		result, err := svc.Shorten(ctx, raw, service.ShortenOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "abc123", result.ShortID, raw)
+	}
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestShorten_StoresRawAndCanonicalForms(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	cache.On("GetByDedupeKey", ctx, "global:https://example.com/docs").Return(nil, errors.New("cache miss"))
+	repo.On("FindByOriginalURL", ctx, "https://example.com/docs", "").Return((*entity.URL)(nil), nil)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByDedupeKey", ctx, mock.Anything).Return(nil)
+	cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, "HTTPS://EXAMPLE.com:443/docs", service.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTPS://EXAMPLE.com:443/docs", result.Original)
+	assert.Equal(t, "https://example.com/docs", result.Canonical)
+}
+
+func TestShorten_DedupeScopes(t *testing.T) {
+	ctx := context.Background()
+	canonical := "https://example.com/docs"
+
+	t.Run("owner scope only reuses the owner's mappings", func(t *testing.T) {
+		existing := &entity.URL{ShortID: "own123", Original: canonical, Canonical: canonical, OwnerID: "user-1", Dedupe: entity.DedupeOwner}
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		cache.On("GetByDedupeKey", ctx, "owner:user-1:"+canonical).Return(nil, errors.New("cache miss"))
+		repo.On("FindByOriginalURL", ctx, canonical, "user-1").Return(existing, nil)
+		cache.On("SetByDedupeKey", ctx, existing).Return(nil)
+		cache.On("SetByShortID", ctx, existing).Return(nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Shorten(ctx, canonical, service.ShortenOptions{OwnerID: "user-1", Dedupe: entity.DedupeOwner})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "own123", result.ShortID)
+		repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("never scope always creates a new mapping", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache, service.WithDedupe(entity.DedupeNever))
+		result, err := svc.Shorten(ctx, canonical, service.ShortenOptions{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.DedupeNever, result.Dedupe)
+		assert.Empty(t, result.DedupeKey())
+		cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+		cache.AssertNotCalled(t, "SetByDedupeKey", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "FindByOriginalURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown scope is rejected", func(t *testing.T) {
+		svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+		_, err := svc.Shorten(ctx, canonical, service.ShortenOptions{Dedupe: "sometimes"})
+
+		assert.ErrorIs(t, err, service.ErrInvalidDedupe)
+	})
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// DefaultCustomDomainRefresh is how long a CustomDomainService serves its cached verified domains before reading
+	// them again, which bounds how long domains verified or removed through another instance take to apply.
+	DefaultCustomDomainRefresh = 30 * time.Second
+	// DomainChallengePrefix is prepended to a custom domain to name the DNS TXT record proving control of it.
+	DomainChallengePrefix = "_url-shortener-challenge."
+	// DomainChallengeValuePrefix starts the value of the DNS TXT record proving control of a custom domain.
+	DomainChallengeValuePrefix = "url-shortener-verification="
+	// customDomainTokenBytes is the number of random bytes in a verification token.
+	customDomainTokenBytes = 16
+)
+
+var (
+	// ErrCustomDomainTaken is returned when registering or verifying a custom domain another user verified,
+	// or registering one the user already registered.
+	ErrCustomDomainTaken = errors.New("custom domain is already registered")
+	// ErrCustomDomainNotFound is returned when a user has no custom domain with the given host name.
+	ErrCustomDomainNotFound = errors.New("custom domain not found")
+	// ErrCustomDomainNotVerified is returned when creating URLs on a custom domain whose owner has not proved control of it.
+	ErrCustomDomainNotVerified = errors.New("custom domain is not verified")
+	// ErrCustomDomainInUse is returned when removing a custom domain that still serves URLs.
+	ErrCustomDomainInUse = errors.New("custom domain still has short URLs")
+	// ErrDomainVerificationFailed is returned when the DNS TXT record proving control of a custom domain is missing.
+	ErrDomainVerificationFailed = errors.New("domain verification record not found")
+)
+
+// TXTResolver looks up DNS TXT records. *net.Resolver satisfies it.
+type TXTResolver interface {
+	// LookupTXT returns the TXT records of a DNS name.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the lookup.
+	// - name (string): The DNS name.
+	//
+	// Returns:
+	// - []string: The TXT records.
+	// - error: An error if the lookup fails, including when the name does not exist.
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainChallenge is the DNS TXT record the owner of a custom domain publishes to prove control of it.
+//
+// Fields:
+// - Name (string): The DNS name of the record.
+// - Value (string): The value of the record.
+type DomainChallenge struct {
+	Name  string
+	Value string
+}
+
+// ChallengeFor returns the DNS TXT record proving control of a custom domain.
+//
+// Parameters:
+// - domain (*entity.CustomDomain): The custom domain.
+//
+// Returns:
+// - DomainChallenge: The record, e.g. "_url-shortener-challenge.go.acme.com" with "url-shortener-verification=<token>".
+func ChallengeFor(domain *entity.CustomDomain) DomainChallenge {
+	return DomainChallenge{
+		Name:  DomainChallengePrefix + domain.Host,
+		Value: DomainChallengeValuePrefix + domain.Token,
+	}
+}
+
+// CustomDomainService defines the interface for managing custom domains and resolving request hosts to them.
+//
+// Methods:
+// - Register: Registers a custom domain for a user.
+// - Verify: Checks the DNS TXT record of a custom domain.
+// - List: Lists the custom domains of a user.
+// - Delete: Removes a custom domain of a user.
+// - Authorize: Checks that a user may create URLs on a custom domain.
+// - Resolve: Maps a request host to the domain its short IDs belong to.
+type CustomDomainService interface {
+	// Register registers a custom domain for a user. The domain serves URLs once verified.
+	// Other users may register the same host until one of them verifies it.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the user registering the domain.
+	// - host (string): The host name, e.g. "go.acme.com".
+	//
+	// Returns:
+	// - *entity.CustomDomain: The registered, unverified domain. See ChallengeFor for the record to publish.
+	// - error: ErrInvalidDomain, ErrCustomDomainTaken, or an error if the operation fails.
+	Register(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error)
+
+	// Verify looks the DNS TXT record of a custom domain up and marks the domain verified once it is found.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - host (string): The host name of the domain.
+	//
+	// Returns:
+	// - *entity.CustomDomain: The verified domain.
+	// - error: ErrCustomDomainNotFound, ErrDomainVerificationFailed, ErrCustomDomainTaken if another user verified the
+	// host first, or an error if the operation fails.
+	Verify(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error)
+
+	// List lists the custom domains of a user, sorted by host name.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the user.
+	//
+	// Returns:
+	// - []*entity.CustomDomain: The custom domains.
+	// - error: An error if the operation fails.
+	List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error)
+
+	// Delete removes a custom domain of a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - host (string): The host name of the domain.
+	//
+	// Returns:
+	// - error: ErrCustomDomainNotFound, ErrCustomDomainInUse if URLs remain on the domain, or an error if the operation fails.
+	Delete(ctx context.Context, ownerID, host string) error
+
+	// Authorize checks that a user may create URLs on a custom domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the user creating URLs.
+	// - host (string): The host name of the domain.
+	//
+	// Returns:
+	// - string: The normalized host name.
+	// - error: ErrCustomDomainNotFound if the user has no such domain, ErrCustomDomainNotVerified, or an error if the lookup fails.
+	Authorize(ctx context.Context, ownerID, host string) (string, error)
+
+	// Resolve maps the host of a request to the domain its short IDs belong to.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - host (string): The request host, without a port.
+	//
+	// Returns:
+	// - string: The host if it is a verified custom domain, or an empty string for the default domain.
+	// - error: An error if the domains cannot be read.
+	Resolve(ctx context.Context, host string) (string, error)
+}
+
+// customDomainService is the implementation of CustomDomainService.
+type customDomainService struct {
+	repo     repository.CustomDomainRepository
+	urls     repository.URLRepository
+	resolver TXTResolver
+	refresh  time.Duration
+
+	mu       sync.RWMutex
+	verified map[string]bool
+	loadedAt time.Time
+}
+
+// NewCustomDomainService creates a CustomDomainService.
+//
+// Parameters:
+// - repo (repository.CustomDomainRepository): The repository storing the domains.
+// - urls (repository.URLRepository): The repository checked for URLs before a domain is removed.
+// - resolver (TXTResolver): The resolver verification records are looked up with, usually net.DefaultResolver.
+// - refresh (time.Duration): How long cached verified domains are used before being read again. Zero means DefaultCustomDomainRefresh.
+//
+// Returns:
+// - CustomDomainService: The custom domain service.
+func NewCustomDomainService(repo repository.CustomDomainRepository, urls repository.URLRepository, resolver TXTResolver, refresh time.Duration) CustomDomainService {
+	if refresh <= 0 {
+		refresh = DefaultCustomDomainRefresh
+	}
+	return &customDomainService{repo: repo, urls: urls, resolver: resolver, refresh: refresh}
+}
+
+// Register validates the host and stores a claim on it with a fresh verification token.
+// Hosts need at least two labels, so single-label names such as "localhost" are rejected.
+// Only verified hosts are refused, so a user claiming a host they do not control cannot block its real owner.
+func (s *customDomainService) Register(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error) {
+	host, err := normalizeCustomDomain(host)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := s.repo.FindVerified(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if verified != nil {
+		return nil, ErrCustomDomainTaken
+	}
+
+	b := make([]byte, customDomainTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	domain := &entity.CustomDomain{
+		Host:      host,
+		OwnerID:   ownerID,
+		Token:     hex.EncodeToString(b),
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, domain); err != nil {
+		if errors.Is(err, repository.ErrDuplicateCustomDomain) {
+			return nil, ErrCustomDomainTaken
+		}
+		return nil, err
+	}
+	return domain, nil
+}
+
+// Verify looks the challenge record up, marks the domain verified and drops the cached domains so it serves
+// URLs immediately. Verifying an already verified domain returns it without a lookup. When several users claim
+// a host, the first to verify it wins; the repository rejects later verifications atomically.
+func (s *customDomainService) Verify(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error) {
+	domain, err := s.find(ctx, ownerID, host)
+	if err != nil {
+		return nil, err
+	}
+	if domain.IsVerified() {
+		return domain, nil
+	}
+
+	challenge := ChallengeFor(domain)
+	records, err := s.resolver.LookupTXT(ctx, challenge.Name)
+	if err != nil || !containsRecord(records, challenge.Value) {
+		return nil, ErrDomainVerificationFailed
+	}
+
+	now := time.Now()
+	if err := s.repo.MarkVerified(ctx, domain.Host, domain.OwnerID, now); err != nil {
+		if errors.Is(err, repository.ErrDuplicateCustomDomain) {
+			return nil, ErrCustomDomainTaken
+		}
+		return nil, err
+	}
+	domain.VerifiedAt = &now
+	s.invalidate()
+	return domain, nil
+}
+
+// List lists the custom domains of a user.
+func (s *customDomainService) List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error) {
+	return s.repo.List(ctx, ownerID)
+}
+
+// Delete removes a domain without URLs, then drops the cached domains. Unverified claims never have URLs.
+// URLs are not removed with their domain, so a domain freed for another user never serves the previous owner's URLs.
+func (s *customDomainService) Delete(ctx context.Context, ownerID, host string) error {
+	domain, err := s.find(ctx, ownerID, host)
+	if err != nil {
+		return err
+	}
+
+	if domain.IsVerified() {
+		_, total, err := s.urls.List(ctx, repository.URLListFilter{
+			OwnerID:   domain.OwnerID,
+			Domain:    domain.Host,
+			SortField: "_id",
+			Limit:     1,
+		})
+		if err != nil {
+			return err
+		}
+		if total > 0 {
+			return ErrCustomDomainInUse
+		}
+	}
+
+	deleted, err := s.repo.Delete(ctx, domain.Host, domain.OwnerID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCustomDomainNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Authorize reads the domain from the repository rather than the cache, so a domain verified a moment ago is usable.
+func (s *customDomainService) Authorize(ctx context.Context, ownerID, host string) (string, error) {
+	domain, err := s.find(ctx, ownerID, host)
+	if err != nil {
+		return "", err
+	}
+	if !domain.IsVerified() {
+		return "", ErrCustomDomainNotVerified
+	}
+	return domain.Host, nil
+}
+
+// Resolve serves the answer from the cached set of verified domains, reading it again once stale.
+func (s *customDomainService) Resolve(ctx context.Context, host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if err := s.load(ctx); err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.verified[host] {
+		return host, nil
+	}
+	return "", nil
+}
+
+// find retrieves the claim of a user on a host.
+func (s *customDomainService) find(ctx context.Context, ownerID, host string) (*entity.CustomDomain, error) {
+	host, err := normalizeCustomDomain(host)
+	if err != nil {
+		return nil, ErrCustomDomainNotFound
+	}
+	domain, err := s.repo.Find(ctx, host, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if domain == nil {
+		return nil, ErrCustomDomainNotFound
+	}
+	return domain, nil
+}
+
+// load reads the verified domains from the repository unless the cached ones are still fresh.
+func (s *customDomainService) load(ctx context.Context) error {
+	s.mu.RLock()
+	fresh := !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.refresh
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	domains, err := s.repo.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	verified := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		if domain.IsVerified() {
+			verified[domain.Host] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.verified = verified
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// invalidate drops the cached domains so the next request reads them again.
+func (s *customDomainService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// normalizeCustomDomain lowercases and validates a custom domain, which must have at least two labels
+// and cannot be a wildcard.
+func normalizeCustomDomain(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "*.") {
+		return "", ErrInvalidDomain
+	}
+	host, err := normalizeDomain(host)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(host, ".") {
+		return "", ErrInvalidDomain
+	}
+	return host, nil
+}
+
+// containsRecord reports whether one of the TXT records equals value, ignoring surrounding spaces.
+func containsRecord(records []string, value string) bool {
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return true
+		}
+	}
+	return false
+}
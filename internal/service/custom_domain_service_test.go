@@ -0,0 +1,306 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCustomDomainRepository is an in-memory CustomDomainRepository.
+type fakeCustomDomainRepository struct {
+	claims []*entity.CustomDomain
+	lists  int
+}
+
+func newFakeCustomDomainRepository(claims ...*entity.CustomDomain) *fakeCustomDomainRepository {
+	return &fakeCustomDomainRepository{claims: claims}
+}
+
+func (r *fakeCustomDomainRepository) claim(host, ownerID string) *entity.CustomDomain {
+	for _, domain := range r.claims {
+		if domain.Host == host && domain.OwnerID == ownerID {
+			return domain
+		}
+	}
+	return nil
+}
+
+func (r *fakeCustomDomainRepository) Create(ctx context.Context, domain *entity.CustomDomain) error {
+	if r.claim(domain.Host, domain.OwnerID) != nil {
+		return repository.ErrDuplicateCustomDomain
+	}
+	copied := *domain
+	r.claims = append(r.claims, &copied)
+	return nil
+}
+
+func (r *fakeCustomDomainRepository) Find(ctx context.Context, host, ownerID string) (*entity.CustomDomain, error) {
+	domain := r.claim(host, ownerID)
+	if domain == nil {
+		return nil, nil
+	}
+	copied := *domain
+	return &copied, nil
+}
+
+func (r *fakeCustomDomainRepository) FindVerified(ctx context.Context, host string) (*entity.CustomDomain, error) {
+	for _, domain := range r.claims {
+		if domain.Host == host && domain.IsVerified() {
+			copied := *domain
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeCustomDomainRepository) List(ctx context.Context, ownerID string) ([]*entity.CustomDomain, error) {
+	r.lists++
+	var domains []*entity.CustomDomain
+	for _, domain := range r.claims {
+		if ownerID == "" || domain.OwnerID == ownerID {
+			copied := *domain
+			domains = append(domains, &copied)
+		}
+	}
+	return domains, nil
+}
+
+func (r *fakeCustomDomainRepository) MarkVerified(ctx context.Context, host, ownerID string, at time.Time) error {
+	if verified, _ := r.FindVerified(ctx, host); verified != nil && verified.OwnerID != ownerID {
+		return repository.ErrDuplicateCustomDomain
+	}
+	if domain := r.claim(host, ownerID); domain != nil {
+		domain.VerifiedAt = &at
+	}
+	return nil
+}
+
+func (r *fakeCustomDomainRepository) Delete(ctx context.Context, host, ownerID string) (bool, error) {
+	for i, domain := range r.claims {
+		if domain.Host == host && domain.OwnerID == ownerID {
+			r.claims = append(r.claims[:i], r.claims[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeTXTResolver answers TXT lookups from a map of DNS names to records.
+type fakeTXTResolver map[string][]string
+
+func (r fakeTXTResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+func TestCustomDomain_RegisterNormalizesHost(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCustomDomainService(newFakeCustomDomainRepository(), new(MockURLRepository), fakeTXTResolver{}, 0)
+
+	domain, err := svc.Register(ctx, "user-1", " Go.Acme.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", domain.Host)
+	assert.Equal(t, "user-1", domain.OwnerID)
+	assert.NotEmpty(t, domain.Token)
+	assert.False(t, domain.IsVerified())
+
+	challenge := service.ChallengeFor(domain)
+	assert.Equal(t, "_url-shortener-challenge.go.acme.com", challenge.Name)
+	assert.Equal(t, "url-shortener-verification="+domain.Token, challenge.Value)
+
+	_, err = svc.Register(ctx, "user-1", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainTaken)
+}
+
+func TestCustomDomain_PendingClaimsDoNotBlockTheOwner(t *testing.T) {
+	ctx := context.Background()
+	resolver := fakeTXTResolver{}
+	svc := service.NewCustomDomainService(newFakeCustomDomainRepository(), new(MockURLRepository), resolver, 0)
+
+	// A squatter claims the host first but cannot publish the record.
+	squatter, err := svc.Register(ctx, "squatter", "go.acme.com")
+	require.NoError(t, err)
+	owner, err := svc.Register(ctx, "owner", "go.acme.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, squatter.Token, owner.Token)
+
+	challenge := service.ChallengeFor(owner)
+	resolver[challenge.Name] = []string{challenge.Value}
+
+	_, err = svc.Verify(ctx, "squatter", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrDomainVerificationFailed)
+	verified, err := svc.Verify(ctx, "owner", "go.acme.com")
+	require.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+
+	// Once verified, the host is taken for everyone else.
+	_, err = svc.Register(ctx, "someone-else", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainTaken)
+	_, err = svc.Authorize(ctx, "squatter", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotVerified)
+	host, err := svc.Resolve(ctx, "go.acme.com")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", host)
+
+	// A pending claim publishing its record after the host was verified loses.
+	challenge = service.ChallengeFor(squatter)
+	resolver[challenge.Name] = []string{challenge.Value}
+	_, err = svc.Verify(ctx, "squatter", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainTaken)
+}
+
+func TestCustomDomain_RegisterRejectsInvalidHosts(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCustomDomainService(newFakeCustomDomainRepository(), new(MockURLRepository), fakeTXTResolver{}, 0)
+
+	for _, host := range []string{"localhost", "*.acme.com", "acme..com", ""} {
+		_, err := svc.Register(ctx, "user-1", host)
+		assert.ErrorIs(t, err, service.ErrInvalidDomain, host)
+	}
+}
+
+func TestCustomDomain_VerifyChecksTXTRecord(t *testing.T) {
+	ctx := context.Background()
+	resolver := fakeTXTResolver{}
+	svc := service.NewCustomDomainService(newFakeCustomDomainRepository(), new(MockURLRepository), resolver, 0)
+
+	domain, err := svc.Register(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+
+	_, err = svc.Verify(ctx, "user-1", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrDomainVerificationFailed)
+
+	challenge := service.ChallengeFor(domain)
+	resolver[challenge.Name] = []string{"v=spf1 -all", "url-shortener-verification=wrong"}
+	_, err = svc.Verify(ctx, "user-1", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrDomainVerificationFailed)
+
+	_, err = svc.Verify(ctx, "user-2", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotFound)
+
+	resolver[challenge.Name] = append(resolver[challenge.Name], challenge.Value)
+	verified, err := svc.Verify(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+}
+
+func TestCustomDomain_AuthorizeRequiresOwnedVerifiedDomain(t *testing.T) {
+	ctx := context.Background()
+	resolver := fakeTXTResolver{}
+	svc := service.NewCustomDomainService(newFakeCustomDomainRepository(), new(MockURLRepository), resolver, 0)
+
+	domain, err := svc.Register(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+
+	_, err = svc.Authorize(ctx, "user-1", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotVerified)
+
+	challenge := service.ChallengeFor(domain)
+	resolver[challenge.Name] = []string{challenge.Value}
+	_, err = svc.Verify(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+
+	host, err := svc.Authorize(ctx, "user-1", "GO.ACME.COM")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", host)
+
+	_, err = svc.Authorize(ctx, "user-2", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotFound)
+}
+
+func TestCustomDomain_ResolveServesVerifiedDomainsFromCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeCustomDomainRepository()
+	resolver := fakeTXTResolver{}
+	svc := service.NewCustomDomainService(repo, new(MockURLRepository), resolver, time.Hour)
+
+	domain, err := svc.Register(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+
+	host, err := svc.Resolve(ctx, "go.acme.com")
+	require.NoError(t, err)
+	assert.Empty(t, host, "unverified domains resolve to the default domain")
+
+	challenge := service.ChallengeFor(domain)
+	resolver[challenge.Name] = []string{challenge.Value}
+	_, err = svc.Verify(ctx, "user-1", "go.acme.com")
+	require.NoError(t, err)
+
+	host, err = svc.Resolve(ctx, "Go.Acme.com.")
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", host)
+
+	host, err = svc.Resolve(ctx, "localhost")
+	require.NoError(t, err)
+	assert.Empty(t, host)
+	assert.Equal(t, 2, repo.lists, "verifying reloads the cache once, later lookups are cached")
+}
+
+func TestCustomDomain_DeleteRefusesDomainWithURLs(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeCustomDomainRepository(&entity.CustomDomain{Host: "go.acme.com", OwnerID: "user-1", VerifiedAt: new(time.Time)})
+	urls := new(MockURLRepository)
+	svc := service.NewCustomDomainService(repo, urls, fakeTXTResolver{}, 0)
+
+	filter := mock.MatchedBy(func(f repository.URLListFilter) bool {
+		return f.Domain == "go.acme.com" && f.OwnerID == "user-1"
+	})
+	urls.On("List", ctx, filter).Return([]*entity.URL{{ShortID: "abc123", Domain: "go.acme.com"}}, int64(1), nil).Once()
+
+	err := svc.Delete(ctx, "user-1", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainInUse)
+	assert.NotNil(t, repo.claim("go.acme.com", "user-1"))
+
+	err = svc.Delete(ctx, "user-2", "go.acme.com")
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotFound)
+
+	urls.On("List", ctx, filter).Return([]*entity.URL{}, int64(0), nil).Once()
+	require.NoError(t, svc.Delete(ctx, "user-1", "go.acme.com"))
+	assert.Nil(t, repo.claim("go.acme.com", "user-1"))
+
+	// Unverified claims have no URLs and are removed without looking for any.
+	_, err = svc.Register(ctx, "user-2", "go.acme.com")
+	require.NoError(t, err)
+	require.NoError(t, svc.Delete(ctx, "user-2", "go.acme.com"))
+	urls.AssertNumberOfCalls(t, "List", 2)
+}
+
+func TestShorten_OnCustomDomain(t *testing.T) {
+	ctx := context.Background()
+	domains := newFakeCustomDomainRepository(
+		&entity.CustomDomain{Host: "go.acme.com", OwnerID: "user-1", VerifiedAt: new(time.Time)},
+		&entity.CustomDomain{Host: "pending.acme.com", OwnerID: "user-1"},
+	)
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+	repo.On("FindByShortID", ctx, "go.acme.com/sale").Return(nil, nil)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	customDomains := service.NewCustomDomainService(domains, repo, fakeTXTResolver{}, 0)
+	svc := service.NewURLService(repo, cache, service.WithCustomDomains(customDomains))
+
+	result, err := svc.Shorten(ctx, "https://example.com/sale", service.ShortenOptions{OwnerID: "user-1", Alias: "sale", Domain: "Go.Acme.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "go.acme.com", result.Domain)
+	assert.Equal(t, "sale", result.ShortID)
+	assert.Equal(t, "go.acme.com/sale", result.Key())
+
+	_, err = svc.Shorten(ctx, "https://example.com/sale", service.ShortenOptions{OwnerID: "user-1", Domain: "pending.acme.com"})
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotVerified)
+
+	_, err = svc.Shorten(ctx, "https://example.com/sale", service.ShortenOptions{OwnerID: "user-2", Domain: "go.acme.com"})
+	assert.ErrorIs(t, err, service.ErrCustomDomainNotFound)
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDestinationBlocked is matched by every DestinationError, so callers can detect
+// any rejected destination with errors.Is.
+var ErrDestinationBlocked = errors.New("destination URL is not allowed")
+
+const (
+	// DestinationInvalid is the code of destinations that cannot be parsed or have no host.
+	DestinationInvalid = "invalid_destination"
+	// DestinationBlockedScheme is the code of destinations using a scheme such as javascript: or data:.
+	DestinationBlockedScheme = "blocked_scheme"
+	// DestinationPrivateAddress is the code of destinations pointing at loopback, private or link-local addresses.
+	DestinationPrivateAddress = "private_address"
+	// DestinationDomainDenied is the code of destinations on the domain deny list.
+	DestinationDomainDenied = "domain_denied"
+	// DestinationDomainNotAllowed is the code of destinations missing from a non-empty domain allow list.
+	DestinationDomainNotAllowed = "domain_not_allowed"
+	// DestinationBadReputation is the code of destinations flagged by a ReputationChecker.
+	DestinationBadReputation = "bad_reputation"
+)
+
+// hostLookupTimeout bounds the DNS lookup of a destination host.
+const hostLookupTimeout = 2 * time.Second
+
+// blockedSchemes lists the schemes that run code or embed content instead of pointing at a resource.
+var blockedSchemes = map[string]struct{}{
+	"javascript": {},
+	"data":       {},
+	"vbscript":   {},
+	"file":       {},
+	"blob":       {},
+}
+
+// DestinationError is returned when a DestinationPolicy rejects a destination URL.
+//
+// Fields:
+// - Code (string): A stable code identifying the rule that rejected the URL, e.g. DestinationBlockedScheme.
+// - Reason (string): A human-readable explanation.
+type DestinationError struct {
+	Code   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *DestinationError) Error() string {
+	return ErrDestinationBlocked.Error() + " (" + e.Code + "): " + e.Reason
+}
+
+// Unwrap makes errors.Is(err, ErrDestinationBlocked) match every DestinationError.
+func (e *DestinationError) Unwrap() error {
+	return ErrDestinationBlocked
+}
+
+// DestinationPolicy decides whether a URL may be shortened.
+type DestinationPolicy interface {
+	// Check inspects a destination URL.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - u (*url.URL): The parsed destination URL.
+	//
+	// Returns:
+	// - error: A *DestinationError if the URL is rejected, another error if the check itself fails, or nil.
+	Check(ctx context.Context, u *url.URL) error
+}
+
+// DestinationPolicyFunc adapts a function to the DestinationPolicy interface.
+type DestinationPolicyFunc func(ctx context.Context, u *url.URL) error
+
+// Check calls f(ctx, u).
+func (f DestinationPolicyFunc) Check(ctx context.Context, u *url.URL) error {
+	return f(ctx, u)
+}
+
+// DestinationPolicyChain runs several policies in order and stops at the first rejection.
+type DestinationPolicyChain []DestinationPolicy
+
+// Check runs every policy of the chain in order.
+func (chain DestinationPolicyChain) Check(ctx context.Context, u *url.URL) error {
+	for _, policy := range chain {
+		if err := policy.Check(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckDestination parses a destination URL and runs a policy on it.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - policy (DestinationPolicy): The policy to apply. Nil accepts every URL.
+// - rawURL (string): The destination URL.
+//
+// Returns:
+// - error: A *DestinationError if the URL cannot be parsed or is rejected, another error if a check fails, or nil.
+func CheckDestination(ctx context.Context, policy DestinationPolicy, rawURL string) error {
+	if policy == nil {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return &DestinationError{Code: DestinationInvalid, Reason: "URL cannot be parsed"}
+	}
+	return policy.Check(ctx, u)
+}
+
+// SchemePolicy rejects URLs whose scheme runs code or embeds content, such as javascript: and data:,
+// and URLs without a host.
+var SchemePolicy DestinationPolicy = DestinationPolicyFunc(func(_ context.Context, u *url.URL) error {
+	if _, blocked := blockedSchemes[u.Scheme]; blocked {
+		return &DestinationError{Code: DestinationBlockedScheme, Reason: u.Scheme + ": URLs cannot be shortened"}
+	}
+	if u.Hostname() == "" {
+		return &DestinationError{Code: DestinationInvalid, Reason: "URL has no host"}
+	}
+	return nil
+})
+
+// HostResolver resolves host names to IP addresses. *net.Resolver implements it.
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// privateAddressPolicy is the DestinationPolicy returned by NewPrivateAddressPolicy.
+type privateAddressPolicy struct {
+	resolver HostResolver
+}
+
+// NewPrivateAddressPolicy creates a policy rejecting URLs that point into private networks,
+// so short links cannot be used to reach internal services.
+//
+// Parameters:
+// - resolver (HostResolver): Resolves host names so names pointing at private addresses are rejected too. Nil only checks IP literals and well-known local names.
+//
+// Behavior:
+// - Rejects loopback, private, link-local, carrier-grade NAT and unspecified addresses, IPv4 and IPv6.
+// - Rejects "localhost", ".localhost", ".local" and ".internal" names, names without a dot and numeric hosts such as "0x7f.1".
+// - Lookups that fail or time out are not treated as rejections, since the host may simply not exist yet.
+//
+// Returns:
+// - DestinationPolicy: The policy.
+func NewPrivateAddressPolicy(resolver HostResolver) DestinationPolicy {
+	return &privateAddressPolicy{resolver: resolver}
+}
+
+// Check implements DestinationPolicy.
+func (p *privateAddressPolicy) Check(ctx context.Context, u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateIP(ip) {
+			return privateAddressError(host)
+		}
+		return nil
+	}
+	if isLocalHostName(host) {
+		return privateAddressError(host)
+	}
+
+	if p.resolver == nil {
+		return nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+	defer cancel()
+	addrs, err := p.resolver.LookupIPAddr(lookupCtx, host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return privateAddressError(host)
+		}
+	}
+	return nil
+}
+
+// privateAddressError builds the rejection of a host in a private network.
+func privateAddressError(host string) error {
+	return &DestinationError{Code: DestinationPrivateAddress, Reason: host + " is a private or local address"}
+}
+
+// carrierGradeNAT is the shared address space of RFC 6598, not reachable from the internet.
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateIP reports whether an IP address is not publicly routable.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		carrierGradeNAT.Contains(ip)
+}
+
+// isLocalHostName reports whether a host name only resolves inside a local network,
+// or is a numeric form of an IP address that net.ParseIP does not accept but browsers do.
+func isLocalHostName(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return true
+	}
+	if !strings.Contains(host, ".") {
+		return true
+	}
+	last := host[strings.LastIndex(host, ".")+1:]
+	if strings.HasPrefix(last, "0x") {
+		return true
+	}
+	return strings.Trim(last, "0123456789") == ""
+}
+
+// ReputationVerdict is the outcome of a reputation lookup.
+//
+// Fields:
+// - Malicious (bool): Whether the URL is known to be harmful.
+// - Category (string): The kind of threat, e.g. "phishing" or "malware", if known.
+type ReputationVerdict struct {
+	Malicious bool
+	Category  string
+}
+
+// ReputationChecker looks destination URLs up in a list or service of known harmful URLs.
+type ReputationChecker interface {
+	// Lookup checks the reputation of a URL.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - u (*url.URL): The destination URL.
+	//
+	// Returns:
+	// - ReputationVerdict: The verdict.
+	// - error: An error if the lookup fails.
+	Lookup(ctx context.Context, u *url.URL) (ReputationVerdict, error)
+}
+
+// reputationPolicy is the DestinationPolicy returned by NewReputationPolicy.
+type reputationPolicy struct {
+	checker ReputationChecker
+}
+
+// NewReputationPolicy creates a policy rejecting URLs a ReputationChecker flags as malicious.
+// A failing lookup is logged and the URL accepted, so an outage of the checker does not stop shortening.
+//
+// Parameters:
+// - checker (ReputationChecker): The reputation source.
+//
+// Returns:
+// - DestinationPolicy: The policy.
+func NewReputationPolicy(checker ReputationChecker) DestinationPolicy {
+	return &reputationPolicy{checker: checker}
+}
+
+// Check implements DestinationPolicy.
+func (p *reputationPolicy) Check(ctx context.Context, u *url.URL) error {
+	verdict, err := p.checker.Lookup(ctx, u)
+	if err != nil {
+		log.Printf("reputation lookup failed for %s: %v", u.Hostname(), err)
+		return nil
+	}
+	if !verdict.Malicious {
+		return nil
+	}
+	reason := "URL is flagged as harmful"
+	if verdict.Category != "" {
+		reason = "URL is flagged as " + verdict.Category
+	}
+	return &DestinationError{Code: DestinationBadReputation, Reason: reason}
+}
+
+// domainAndParents returns a host followed by each of its parent domains, e.g.
+// "a.b.example.com", "b.example.com", "example.com" and "com".
+func domainAndParents(host string) []string {
+	domains := []string{host}
+	for {
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return domains
+		}
+		host = host[i+1:]
+		domains = append(domains, host)
+	}
+}
@@ -0,0 +1,233 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDomainRuleRepository is an in-memory DomainRuleRepository.
+type fakeDomainRuleRepository struct {
+	rules map[string]*entity.DomainRule
+	lists int
+}
+
+func (r *fakeDomainRuleRepository) Put(ctx context.Context, rule *entity.DomainRule) error {
+	if r.rules == nil {
+		r.rules = make(map[string]*entity.DomainRule)
+	}
+	r.rules[rule.Domain] = rule
+	return nil
+}
+
+func (r *fakeDomainRuleRepository) List(ctx context.Context) ([]*entity.DomainRule, error) {
+	r.lists++
+	rules := []*entity.DomainRule{}
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Domain < rules[j].Domain })
+	return rules, nil
+}
+
+func (r *fakeDomainRuleRepository) Delete(ctx context.Context, domain string) (bool, error) {
+	if _, ok := r.rules[domain]; !ok {
+		return false, nil
+	}
+	delete(r.rules, domain)
+	return true, nil
+}
+
+// staticResolver resolves every host to the same addresses.
+type staticResolver []string
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	for _, ip := range r {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+// destinationCode returns the code of a *service.DestinationError, or "" if err is not one.
+func destinationCode(err error) string {
+	var destErr *service.DestinationError
+	if errors.As(err, &destErr) {
+		return destErr.Code
+	}
+	return ""
+}
+
+func TestSchemePolicy(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]string{
+		"https://example.com/page":           "",
+		"javascript:alert(document.cookie)":  service.DestinationBlockedScheme,
+		"JavaScript:alert(1)":                service.DestinationBlockedScheme,
+		"data:text/html;base64,PHNjcmlwdD4=": service.DestinationBlockedScheme,
+		"file:///etc/passwd":                 service.DestinationBlockedScheme,
+		"https:///no-host":                   service.DestinationInvalid,
+		"http://[::1":                        service.DestinationInvalid,
+	}
+	for raw, code := range cases {
+		err := service.CheckDestination(ctx, service.SchemePolicy, raw)
+		assert.Equal(t, code, destinationCode(err), raw)
+		if code != "" {
+			assert.ErrorIs(t, err, service.ErrDestinationBlocked, raw)
+		}
+	}
+}
+
+func TestPrivateAddressPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := service.NewPrivateAddressPolicy(nil)
+	cases := map[string]bool{
+		"https://example.com":            false,
+		"https://93.184.216.34/":         false,
+		"http://127.0.0.1:8080/admin":    true,
+		"http://10.0.0.5/":               true,
+		"http://192.168.1.1/":            true,
+		"http://169.254.169.254/latest/": true,
+		"http://100.64.0.1/":             true,
+		"http://0.0.0.0/":                true,
+		"http://[::1]/":                  true,
+		"http://[fd00::1]/":              true,
+		"http://localhost:3000/":         true,
+		"http://api.localhost/":          true,
+		"http://printer.local/":          true,
+		"http://intranet/":               true,
+		"http://2130706433/":             true,
+		"http://0x7f.1/":                 true,
+	}
+	for raw, blocked := range cases {
+		err := service.CheckDestination(ctx, policy, raw)
+		if blocked {
+			assert.Equal(t, service.DestinationPrivateAddress, destinationCode(err), raw)
+		} else {
+			assert.NoError(t, err, raw)
+		}
+	}
+
+	resolving := service.NewPrivateAddressPolicy(staticResolver{"93.184.216.34", "10.1.2.3"})
+	err := service.CheckDestination(ctx, resolving, "https://rebind.example.com/")
+	assert.Equal(t, service.DestinationPrivateAddress, destinationCode(err))
+
+	public := service.NewPrivateAddressPolicy(staticResolver{"93.184.216.34"})
+	assert.NoError(t, service.CheckDestination(ctx, public, "https://example.com/"))
+}
+
+func TestDomainRuleService(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeDomainRuleRepository{}
+	rules := service.NewDomainRuleService(repo, 0)
+
+	_, err := rules.Put(ctx, "not a domain", entity.DomainActionDeny, "", "admin")
+	assert.ErrorIs(t, err, service.ErrInvalidDomain)
+	_, err = rules.Put(ctx, "example.com", "block", "", "admin")
+	assert.ErrorIs(t, err, service.ErrInvalidDomainAction)
+
+	rule, err := rules.Put(ctx, "*.Phish.Example.", entity.DomainActionDeny, "phishing kit", "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "phish.example", rule.Domain)
+
+	err = service.CheckDestination(ctx, rules, "https://login.phish.example/account")
+	assert.Equal(t, service.DestinationDomainDenied, destinationCode(err))
+	assert.NoError(t, service.CheckDestination(ctx, rules, "https://example.com"))
+
+	// Cached rules are reused until they change.
+	lists := repo.lists
+	_ = service.CheckDestination(ctx, rules, "https://example.com")
+	assert.Equal(t, lists, repo.lists)
+
+	// Once a domain is allowed, only allowed domains pass; the most specific rule wins.
+	_, err = rules.Put(ctx, "example.com", entity.DomainActionAllow, "", "admin")
+	require.NoError(t, err)
+	_, err = rules.Put(ctx, "ads.example.com", entity.DomainActionDeny, "", "admin")
+	require.NoError(t, err)
+	assert.NoError(t, service.CheckDestination(ctx, rules, "https://docs.example.com/guide"))
+	assert.Equal(t, service.DestinationDomainDenied, destinationCode(service.CheckDestination(ctx, rules, "https://x.ads.example.com")))
+	assert.Equal(t, service.DestinationDomainNotAllowed, destinationCode(service.CheckDestination(ctx, rules, "https://other.org")))
+
+	require.NoError(t, rules.Delete(ctx, "example.com"))
+	assert.ErrorIs(t, rules.Delete(ctx, "example.com"), service.ErrDomainRuleNotFound)
+	assert.NoError(t, service.CheckDestination(ctx, rules, "https://other.org"))
+
+	listed, err := rules.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestFileReputationChecker(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	content := "# known bad destinations\n" +
+		"malware.example malware\n" +
+		"\n" +
+		"https://sites.example.net/evil-page phishing\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	checker, err := service.NewFileReputationChecker(path)
+	require.NoError(t, err)
+	policy := service.NewReputationPolicy(checker)
+
+	err = service.CheckDestination(ctx, policy, "https://cdn.MALWARE.example/payload.exe")
+	assert.Equal(t, service.DestinationBadReputation, destinationCode(err))
+	assert.Contains(t, err.Error(), "malware")
+
+	err = service.CheckDestination(ctx, policy, "https://sites.example.net/evil-page?id=1")
+	assert.Equal(t, service.DestinationBadReputation, destinationCode(err))
+	assert.NoError(t, service.CheckDestination(ctx, policy, "https://sites.example.net/good-page"))
+
+	_, err = service.NewFileReputationChecker(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestDestinationPolicyChain_FailingReputationLookupIsIgnored(t *testing.T) {
+	failing := service.NewReputationPolicy(reputationFunc(func() (service.ReputationVerdict, error) {
+		return service.ReputationVerdict{}, errors.New("lookup timed out")
+	}))
+	chain := service.DestinationPolicyChain{service.SchemePolicy, failing}
+
+	assert.NoError(t, service.CheckDestination(context.Background(), chain, "https://example.com"))
+	assert.Equal(t, service.DestinationBlockedScheme,
+		destinationCode(service.CheckDestination(context.Background(), chain, "javascript:alert(1)")))
+}
+
+// reputationFunc adapts a function to the ReputationChecker interface.
+type reputationFunc func() (service.ReputationVerdict, error)
+
+func (f reputationFunc) Lookup(ctx context.Context, u *url.URL) (service.ReputationVerdict, error) {
+	return f()
+}
+
+func TestShorten_RejectedDestination(t *testing.T) {
+	mockRepo := new(MockURLRepository)
+	mockCache := new(MockURLCacheRepository)
+	svc := service.NewURLService(mockRepo, mockCache, service.WithDestinationPolicy(service.SchemePolicy))
+
+	_, err := svc.Shorten(context.Background(), "javascript:alert(1)", service.ShortenOptions{})
+	assert.ErrorIs(t, err, service.ErrDestinationBlocked)
+
+	results, err := svc.ShortenBatch(context.Background(), []service.BatchItem{{OriginalURL: "data:text/html,hi"}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, service.ErrDestinationBlocked)
+
+	mockRepo.On("FindByShortID", mock.Anything, "abc123").Return(&entity.URL{ShortID: "abc123", OwnerID: "user-1"}, nil)
+	bad := "javascript:alert(1)"
+	_, err = svc.Update(context.Background(), "user-1", "", "abc123", service.UpdateOptions{Original: &bad})
+	assert.ErrorIs(t, err, service.ErrDestinationBlocked)
+
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
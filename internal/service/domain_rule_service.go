@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+// DefaultDomainRuleRefresh is how long a DomainRuleService serves its cached rules before reading them again,
+// which bounds how long rules changed through another instance take to apply.
+const DefaultDomainRuleRefresh = 30 * time.Second
+
+var (
+	// ErrInvalidDomain is returned when a domain rule is given a value that is not a domain name.
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrInvalidDomainAction is returned when a domain rule action is neither "allow" nor "deny".
+	ErrInvalidDomainAction = errors.New(`domain action must be "allow" or "deny"`)
+	// ErrDomainRuleNotFound is returned when a domain has no rule.
+	ErrDomainRuleNotFound = errors.New("domain rule not found")
+)
+
+// domainPattern matches lowercase domain names: dot-separated labels of letters, digits and inner hyphens.
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DomainRuleService defines the interface for managing the domain allow and deny lists.
+// It is also the DestinationPolicy enforcing them.
+//
+// Methods:
+// - Put: Allows or denies a domain.
+// - List: Lists the rules.
+// - Delete: Removes the rule of a domain.
+// - Check: Rejects destinations the rules do not allow.
+type DomainRuleService interface {
+	DestinationPolicy
+
+	// Put allows or denies a domain and its subdomains, replacing any existing rule for the domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The domain, e.g. "example.com".
+	// - action (string): entity.DomainActionAllow or entity.DomainActionDeny.
+	// - reason (string): Why the rule is added.
+	// - createdBy (string): The ID of the user adding the rule.
+	//
+	// Returns:
+	// - *entity.DomainRule: The stored rule.
+	// - error: ErrInvalidDomain, ErrInvalidDomainAction, or an error if the operation fails.
+	Put(ctx context.Context, domain, action, reason, createdBy string) (*entity.DomainRule, error)
+
+	// List lists the rules, sorted by domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	//
+	// Returns:
+	// - []*entity.DomainRule: The rules.
+	// - error: An error if the operation fails.
+	List(ctx context.Context) ([]*entity.DomainRule, error)
+
+	// Delete removes the rule of a domain.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The domain of the rule.
+	//
+	// Returns:
+	// - error: ErrDomainRuleNotFound if the domain has no rule, or an error if the operation fails.
+	Delete(ctx context.Context, domain string) error
+}
+
+// domainRuleService is the implementation of DomainRuleService.
+type domainRuleService struct {
+	repo    repository.DomainRuleRepository
+	refresh time.Duration
+
+	mu       sync.RWMutex
+	rules    map[string]string
+	hasAllow bool
+	loadedAt time.Time
+}
+
+// NewDomainRuleService creates a DomainRuleService.
+//
+// Parameters:
+// - repo (repository.DomainRuleRepository): The repository storing the rules.
+// - refresh (time.Duration): How long cached rules are used before being read again. Zero means DefaultDomainRuleRefresh.
+//
+// Returns:
+// - DomainRuleService: The domain rule service.
+func NewDomainRuleService(repo repository.DomainRuleRepository, refresh time.Duration) DomainRuleService {
+	if refresh <= 0 {
+		refresh = DefaultDomainRuleRefresh
+	}
+	return &domainRuleService{repo: repo, refresh: refresh}
+}
+
+// Put validates and stores a rule, then drops the cached rules so it applies immediately.
+func (s *domainRuleService) Put(ctx context.Context, domain, action, reason, createdBy string) (*entity.DomainRule, error) {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	if action != entity.DomainActionAllow && action != entity.DomainActionDeny {
+		return nil, ErrInvalidDomainAction
+	}
+
+	rule := &entity.DomainRule{
+		Domain:    domain,
+		Action:    action,
+		Reason:    strings.TrimSpace(reason),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Put(ctx, rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// List lists the rules, sorted by domain.
+func (s *domainRuleService) List(ctx context.Context) ([]*entity.DomainRule, error) {
+	return s.repo.List(ctx)
+}
+
+// Delete removes the rule of a domain, then drops the cached rules.
+func (s *domainRuleService) Delete(ctx context.Context, domain string) error {
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return ErrDomainRuleNotFound
+	}
+	deleted, err := s.repo.Delete(ctx, domain)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDomainRuleNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Check rejects destinations the domain rules do not allow.
+//
+// Behavior:
+// - The rule of the most specific matching domain applies, so "allow docs.example.com" overrides "deny example.com".
+// - A denied domain is rejected with DestinationDomainDenied.
+// - Once any domain is allowed, domains without an allow rule are rejected with DestinationDomainNotAllowed.
+func (s *domainRuleService) Check(ctx context.Context, u *url.URL) error {
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range domainAndParents(host) {
+		switch s.rules[domain] {
+		case entity.DomainActionAllow:
+			return nil
+		case entity.DomainActionDeny:
+			return &DestinationError{Code: DestinationDomainDenied, Reason: domain + " is on the deny list"}
+		}
+	}
+	if s.hasAllow {
+		return &DestinationError{Code: DestinationDomainNotAllowed, Reason: host + " is not on the allow list"}
+	}
+	return nil
+}
+
+// load reads the rules from the repository unless the cached ones are still fresh.
+func (s *domainRuleService) load(ctx context.Context) error {
+	s.mu.RLock()
+	fresh := !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.refresh
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	rules, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byDomain := make(map[string]string, len(rules))
+	hasAllow := false
+	for _, rule := range rules {
+		byDomain[rule.Domain] = rule.Action
+		hasAllow = hasAllow || rule.Action == entity.DomainActionAllow
+	}
+
+	s.mu.Lock()
+	s.rules = byDomain
+	s.hasAllow = hasAllow
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// invalidate drops the cached rules so the next check reads them again.
+func (s *domainRuleService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// normalizeDomain lowercases a domain, strips a leading "*." and a trailing dot, and validates it.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return "", ErrInvalidDomain
+	}
+	return domain, nil
+}
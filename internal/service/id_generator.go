@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+// base62Alphabet is the character set used to encode short IDs.
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// DefaultShortIDLength is the length of short IDs produced by the default generator.
+const DefaultShortIDLength = 6
+
+// shortIDCounterName is the name of the counter backing sequential generators.
+const shortIDCounterName = "short_id"
+
+// ErrInvalidIDLength is returned when a generator is configured with a non-positive length.
+var ErrInvalidIDLength = errors.New("short ID length must be positive")
+
+// IDGenerator defines the interface for producing new short IDs.
+//
+// Methods:
+// - Generate: Produces a new short ID candidate.
+type IDGenerator interface {
+	// Generate produces a new short ID candidate.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	//
+	// Returns:
+	// - string: The generated short ID.
+	// - error: An error if the ID could not be generated.
+	Generate(ctx context.Context) (string, error)
+}
+
+// randomIDGenerator produces short IDs from cryptographically random base62 characters.
+//
+// Collisions are possible and are resolved by the repository, which retries Save with a
+// fresh ID from this generator when the unique index on short_id rejects a duplicate.
+//
+// Fields:
+// - length (int): The number of characters in each generated ID.
+type randomIDGenerator struct {
+	length int
+}
+
+// NewRandomIDGenerator creates an IDGenerator that produces random base62 IDs.
+//
+// Parameters:
+// - length (int): The number of characters in each generated ID.
+//
+// Returns:
+// - IDGenerator: The random ID generator.
+// - error: ErrInvalidIDLength if length is not positive.
+func NewRandomIDGenerator(length int) (IDGenerator, error) {
+	if length <= 0 {
+		return nil, ErrInvalidIDLength
+	}
+	return &randomIDGenerator{length: length}, nil
+}
+
+// Generate produces a random base62 ID of the configured length.
+func (g *randomIDGenerator) Generate(_ context.Context) (string, error) {
+	max := big.NewInt(int64(len(base62Alphabet)))
+	id := make([]byte, g.length)
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = base62Alphabet[n.Int64()]
+	}
+	return string(id), nil
+}
+
+// counterIDGenerator produces short IDs by base62-encoding a monotonically increasing counter.
+//
+// Fields:
+// - counter (repository.CounterRepository): The shared counter source.
+// - offset (uint64): Added to every counter value so IDs have at least the configured length.
+type counterIDGenerator struct {
+	counter repository.CounterRepository
+	offset  uint64
+}
+
+// NewCounterIDGenerator creates an IDGenerator that base62-encodes a shared counter.
+//
+// Parameters:
+// - counter (repository.CounterRepository): The shared counter source.
+// - minLength (int): The minimum number of characters in each generated ID.
+//
+// Returns:
+// - IDGenerator: The counter-based ID generator.
+// - error: ErrInvalidIDLength if minLength is not positive.
+func NewCounterIDGenerator(counter repository.CounterRepository, minLength int) (IDGenerator, error) {
+	if minLength <= 0 {
+		return nil, ErrInvalidIDLength
+	}
+	return &counterIDGenerator{
+		counter: counter,
+		offset:  minValueForLength(uint64(len(base62Alphabet)), minLength),
+	}, nil
+}
+
+// Generate base62-encodes the next counter value.
+func (g *counterIDGenerator) Generate(ctx context.Context) (string, error) {
+	n, err := g.counter.Next(ctx, shortIDCounterName)
+	if err != nil {
+		return "", err
+	}
+	return encodeBase(uint64(n)+g.offset, base62Alphabet), nil
+}
+
+// hashidsIDGenerator produces non-sequential-looking IDs from a shared counter using
+// a salted, hashids-style alphabet shuffle.
+//
+// Fields:
+// - counter (repository.CounterRepository): The shared counter source.
+// - alphabet (string): The alphabet shuffled once with the salt.
+// - salt (string): The secret salt used to shuffle the alphabet.
+// - offset (uint64): Added to every counter value so IDs have at least the configured length.
+type hashidsIDGenerator struct {
+	counter  repository.CounterRepository
+	alphabet string
+	salt     string
+	offset   uint64
+}
+
+// NewHashidsIDGenerator creates an IDGenerator that encodes a shared counter hashids-style.
+//
+// Parameters:
+// - counter (repository.CounterRepository): The shared counter source.
+// - salt (string): The secret salt that makes the encoding unique to this deployment.
+// - minLength (int): The minimum number of characters in each generated ID.
+//
+// Returns:
+// - IDGenerator: The hashids-style ID generator.
+// - error: ErrInvalidIDLength if minLength is not positive.
+func NewHashidsIDGenerator(counter repository.CounterRepository, salt string, minLength int) (IDGenerator, error) {
+	if minLength <= 0 {
+		return nil, ErrInvalidIDLength
+	}
+	// The lottery character takes one position, so the encoded value needs one less.
+	valueLength := minLength - 1
+	if valueLength < 1 {
+		valueLength = 1
+	}
+	return &hashidsIDGenerator{
+		counter:  counter,
+		alphabet: consistentShuffle(base62Alphabet, salt),
+		salt:     salt,
+		offset:   minValueForLength(uint64(len(base62Alphabet)), valueLength),
+	}, nil
+}
+
+// Generate encodes the next counter value hashids-style.
+//
+// Behavior:
+// - Picks a lottery character from the alphabet based on the value.
+// - Reshuffles the alphabet with the lottery, salt and alphabet itself.
+// - Encodes the value in the reshuffled alphabet and prefixes the lottery character.
+//
+// The lottery character determines the alphabet used for the rest of the ID, so the
+// encoding stays reversible and therefore collision-free.
+func (g *hashidsIDGenerator) Generate(ctx context.Context) (string, error) {
+	n, err := g.counter.Next(ctx, shortIDCounterName)
+	if err != nil {
+		return "", err
+	}
+	value := uint64(n) + g.offset
+
+	lottery := g.alphabet[value%uint64(len(g.alphabet))]
+	buffer := string(lottery) + g.salt + g.alphabet
+	alphabet := consistentShuffle(g.alphabet, buffer[:len(g.alphabet)])
+
+	return string(lottery) + encodeBase(value, alphabet), nil
+}
+
+// encodeBase encodes a number in the base given by the length of alphabet.
+func encodeBase(n uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	if n == 0 {
+		return string(alphabet[0])
+	}
+	var out []byte
+	for n > 0 {
+		out = append(out, alphabet[n%base])
+		n /= base
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// minValueForLength returns the smallest number whose encoding in the given base has length digits.
+func minValueForLength(base uint64, length int) uint64 {
+	if length <= 1 {
+		return 0
+	}
+	value := uint64(1)
+	for i := 1; i < length; i++ {
+		value *= base
+	}
+	return value
+}
+
+// consistentShuffle deterministically shuffles alphabet using salt, as done by hashids.
+func consistentShuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+	out := []byte(alphabet)
+	for i, v, p := len(out)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		c := int(salt[v])
+		p += c
+		j := (c + v + p) % i
+		out[i], out[j] = out[j], out[i]
+		v++
+	}
+	return string(out)
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCounter struct {
+	value int64
+}
+
+func (c *fakeCounter) Next(ctx context.Context, name string) (int64, error) {
+	c.value++
+	return c.value, nil
+}
+
+var base62Pattern = regexp.MustCompile(`^[0-9a-zA-Z]+$`)
+
+func TestRandomIDGenerator(t *testing.T) {
+	gen, err := service.NewRandomIDGenerator(8)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := gen.Generate(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, id, 8)
+		assert.Regexp(t, base62Pattern, id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, 1000)
+}
+
+func TestRandomIDGenerator_InvalidLength(t *testing.T) {
+	_, err := service.NewRandomIDGenerator(0)
+	assert.ErrorIs(t, err, service.ErrInvalidIDLength)
+}
+
+func TestCounterIDGenerator(t *testing.T) {
+	gen, err := service.NewCounterIDGenerator(&fakeCounter{}, 4)
+	require.NoError(t, err)
+
+	first, err := gen.Generate(context.Background())
+	require.NoError(t, err)
+	second, err := gen.Generate(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "1001", first)
+	assert.Equal(t, "1002", second)
+}
+
+func TestHashidsIDGenerator(t *testing.T) {
+	gen, err := service.NewHashidsIDGenerator(&fakeCounter{}, "pepper", 6)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id, err := gen.Generate(context.Background())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(id), 6)
+		assert.Regexp(t, base62Pattern, id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, 10000)
+}
+
+func TestHashidsIDGenerator_SaltChangesOutput(t *testing.T) {
+	genA, err := service.NewHashidsIDGenerator(&fakeCounter{}, "salt-a", 6)
+	require.NoError(t, err)
+	genB, err := service.NewHashidsIDGenerator(&fakeCounter{}, "salt-b", 6)
+	require.NoError(t, err)
+
+	idA, _ := genA.Generate(context.Background())
+	idB, _ := genB.Generate(context.Background())
+
+	assert.NotEqual(t, idA, idB)
+}
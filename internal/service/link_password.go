@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DefaultPasswordAttempts is the number of wrong passwords accepted per URL before it is locked.
+	DefaultPasswordAttempts = 5
+	// DefaultPasswordLockout is how long failed attempts are counted, measured from the first one.
+	DefaultPasswordLockout = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidLinkPassword is returned when a URL password is longer than bcrypt can hash.
+	ErrInvalidLinkPassword = errors.New("password must be at most 72 bytes")
+	// ErrPasswordRequired is returned when resolving a password-protected URL without a password.
+	ErrPasswordRequired = errors.New("short URL is password protected")
+	// ErrWrongPassword is returned when the password of a protected URL does not match.
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrTooManyAttempts is matched by every AttemptsError, so callers can detect a locked URL with errors.Is.
+	ErrTooManyAttempts = errors.New("too many failed password attempts")
+)
+
+// AttemptsError is returned when a protected URL is locked after too many wrong passwords.
+//
+// Fields:
+// - RetryAfter (time.Duration): The time until passwords are accepted again.
+type AttemptsError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *AttemptsError) Error() string {
+	return ErrTooManyAttempts.Error() + ", retry in " + e.RetryAfter.Round(time.Second).String()
+}
+
+// Unwrap makes errors.Is(err, ErrTooManyAttempts) match every AttemptsError.
+func (e *AttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// WithPasswordThrottle locks password-protected URLs after too many wrong passwords.
+// Without it, wrong passwords are only slowed down by the cost of bcrypt.
+//
+// Parameters:
+// - attempts (repository.PasswordAttemptRepository): The store of the failure counters.
+// - maxFailures (int): The number of wrong passwords accepted per window. Values below 1 mean DefaultPasswordAttempts.
+// - window (time.Duration): How long failures are counted. Zero means DefaultPasswordLockout.
+//
+// Returns:
+// - URLServiceOption: The option applying the throttle.
+func WithPasswordThrottle(attempts repository.PasswordAttemptRepository, maxFailures int, window time.Duration) URLServiceOption {
+	return func(s *urlService) {
+		if maxFailures < 1 {
+			maxFailures = DefaultPasswordAttempts
+		}
+		if window <= 0 {
+			window = DefaultPasswordLockout
+		}
+		s.attempts = attempts
+		s.maxFailures = int64(maxFailures)
+		s.lockout = window
+	}
+}
+
+// hashPassword replaces the plaintext password of the options with its bcrypt hash.
+func (o *ShortenOptions) hashPassword() error {
+	if o.Password == "" {
+		return nil
+	}
+	if len(o.Password) > MaxPasswordLength {
+		return ErrInvalidLinkPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(o.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	o.passwordHash = string(hash)
+	return nil
+}
+
+// Unlock resolves a URL after checking its password.
+//
+// Behavior:
+// - URLs without a password are resolved like Resolve does, ignoring the password.
+// - Interstitial URLs are resolved as confirmed, since Unlock is only called once the visitor chose to continue.
+// - Every attempt is counted as a failure before the password is compared, so concurrent guesses cannot all pass
+// the check before any of them is counted. A correct password clears the failures and counts a click.
+// - A URL is locked once the count exceeds the allowed failures, so guesses made while locked reveal nothing.
+// - Failed attempts are counted per link, see entity.URL.Key, so equal IDs on different domains are throttled apart.
+// - A failing failure store is logged and the password checked anyway, so an outage of Redis does not lock every URL.
+func (s *urlService) Unlock(ctx context.Context, domain, shortID, password string) (*entity.URL, error) {
+	url, err := s.lookup(ctx, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if url.IsExpired(time.Now()) {
+		return nil, ErrExpired
+	}
+	if url.IsProtected() {
+		if err := s.checkPassword(ctx, url, password); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.consumeClick(ctx, url); err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+// checkPassword compares a password with the hash of a protected URL, enforcing the throttle.
+func (s *urlService) checkPassword(ctx context.Context, url *entity.URL, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	if s.attempts != nil {
+		attempts, err := s.attempts.RecordFailure(ctx, url.Key(), s.lockout)
+		if err != nil {
+			log.Printf("failed to record password attempt of %s: %v", url.Key(), err)
+		} else if attempts > s.maxFailures {
+			return &AttemptsError{RetryAfter: s.retryAfter(ctx, url)}
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+
+	if s.attempts != nil {
+		if err := s.attempts.Reset(ctx, url.Key()); err != nil {
+			log.Printf("failed to reset password attempts of %s: %v", url.Key(), err)
+		}
+	}
+	return nil
+}
+
+// retryAfter returns the time until a locked URL accepts passwords again, or the whole lockout if it cannot be read.
+func (s *urlService) retryAfter(ctx context.Context, url *entity.URL) time.Duration {
+	_, retryAfter, err := s.attempts.Failures(ctx, url.Key())
+	if err != nil || retryAfter <= 0 {
+		return s.lockout
+	}
+	return retryAfter
+}
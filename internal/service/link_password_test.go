@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakePasswordAttempts is an in-memory repository.PasswordAttemptRepository.
+type fakePasswordAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int64
+}
+
+func (f *fakePasswordAttempts) Failures(_ context.Context, shortID string) (int64, time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failures[shortID], time.Minute, nil
+}
+
+func (f *fakePasswordAttempts) RecordFailure(_ context.Context, shortID string, _ time.Duration) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[shortID]++
+	return f.failures[shortID], nil
+}
+
+func (f *fakePasswordAttempts) Reset(_ context.Context, shortID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.failures, shortID)
+	return nil
+}
+
+func TestShorten_PasswordIsHashedAndNeverReused(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, "https://example.com/internal", service.ShortenOptions{Password: "open sesame"})
+
+	require.NoError(t, err)
+	assert.True(t, result.IsProtected())
+	assert.NotContains(t, result.PasswordHash, "open sesame")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(result.PasswordHash), []byte("open sesame")))
+	cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "SetByDedupeKey", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "FindByOriginalURL", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = svc.Shorten(ctx, "https://example.com/internal", service.ShortenOptions{Password: strings.Repeat("x", 73)})
+	assert.ErrorIs(t, err, service.ErrInvalidLinkPassword)
+}
+
+func TestUnlock(t *testing.T) {
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	protected := &entity.URL{ShortID: "docs", Original: "https://example.com/internal", PasswordHash: string(hash), MaxClicks: 10}
+
+	newService := func(attempts *fakePasswordAttempts) (service.URLService, *MockURLRepository) {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		cache.On("GetByShortID", ctx, "docs").Return(protected, nil)
+		repo.On("ConsumeClick", ctx, "docs", int64(10)).Return(true, nil)
+		return service.NewURLService(repo, cache, service.WithPasswordThrottle(attempts, 2, time.Minute)), repo
+	}
+
+	t.Run("resolve asks for the password without counting a click", func(t *testing.T) {
+		svc, repo := newService(&fakePasswordAttempts{failures: map[string]int64{}})
+
+		_, err := svc.Resolve(ctx, "", "docs")
+
+		assert.ErrorIs(t, err, service.ErrPasswordRequired)
+		repo.AssertNotCalled(t, "ConsumeClick", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("correct password counts a click and clears failures", func(t *testing.T) {
+		attempts := &fakePasswordAttempts{failures: map[string]int64{"docs": 1}}
+		svc, repo := newService(attempts)
+
+		url, err := svc.Unlock(ctx, "", "docs", "secret")
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/internal", url.Original)
+		assert.Zero(t, attempts.failures["docs"])
+		repo.AssertCalled(t, "ConsumeClick", ctx, "docs", int64(10))
+	})
+
+	t.Run("wrong passwords lock the link", func(t *testing.T) {
+		attempts := &fakePasswordAttempts{failures: map[string]int64{}}
+		svc, repo := newService(attempts)
+
+		for i := 0; i < 2; i++ {
+			_, err := svc.Unlock(ctx, "", "docs", "guess")
+			assert.ErrorIs(t, err, service.ErrWrongPassword)
+		}
+		_, err := svc.Unlock(ctx, "", "docs", "secret")
+
+		var attemptsErr *service.AttemptsError
+		require.True(t, errors.As(err, &attemptsErr))
+		assert.ErrorIs(t, err, service.ErrTooManyAttempts)
+		assert.Equal(t, time.Minute, attemptsErr.RetryAfter)
+		repo.AssertNotCalled(t, "ConsumeClick", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent wrong passwords cannot exceed the limit", func(t *testing.T) {
+		attempts := &fakePasswordAttempts{failures: map[string]int64{}}
+		svc, _ := newService(attempts)
+
+		const guesses = 20
+		errs := make(chan error, guesses)
+		var wg sync.WaitGroup
+		for i := 0; i < guesses; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.Unlock(ctx, "", "docs", "guess")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		compared := 0
+		for err := range errs {
+			if errors.Is(err, service.ErrWrongPassword) {
+				compared++
+			} else {
+				assert.ErrorIs(t, err, service.ErrTooManyAttempts)
+			}
+		}
+		assert.Equal(t, 2, compared, "only the allowed failures may reach the password comparison")
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		svc, _ := newService(&fakePasswordAttempts{failures: map[string]int64{}})
+
+		_, err := svc.Unlock(ctx, "", "docs", "")
+
+		assert.ErrorIs(t, err, service.ErrPasswordRequired)
+	})
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+// LinkPreview describes where a short URL leads, for visitors to check before following it.
+//
+// Fields:
+// - ShortID (string): The shortened ID.
+// - Domain (string): The custom domain of the URL, or an empty string for the default domain.
+// - Destination (string): The original URL. Empty for password-protected URLs, whose destination is only revealed with the password.
+// - Protected (bool): Whether visitors must enter a password before being redirected.
+// - Interstitial (bool): Whether the URL always shows the preview before redirecting.
+// - CreatedAt (time.Time): The timestamp when the URL was created.
+// - OwnerName (string): The username of the owner. Empty if the URL has no owner or the account no longer exists.
+// - Clicks (int64): The number of recorded redirects.
+type LinkPreview struct {
+	ShortID      string
+	Domain       string
+	Destination  string
+	Protected    bool
+	Interstitial bool
+	CreatedAt    time.Time
+	OwnerName    string
+	Clicks       int64
+}
+
+// PreviewService defines the interface for describing short URLs without redirecting.
+//
+// Methods:
+// - Preview: Describes a short URL.
+type PreviewService interface {
+	// Preview describes a short URL without counting a click.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain the ID was requested on, or an empty string for the default domain.
+	// - shortID (string): The shortened ID.
+	//
+	// Returns:
+	// - *LinkPreview: The description of the URL.
+	// - error: ErrNotFound if the ID is unknown, ErrExpired if it has expired, or an error if a lookup fails.
+	Preview(ctx context.Context, domain, shortID string) (*LinkPreview, error)
+}
+
+// previewService is the implementation of PreviewService.
+type previewService struct {
+	urls   URLService
+	users  repository.UserRepository
+	clicks repository.ClickRepository
+}
+
+// NewPreviewService creates a PreviewService.
+//
+// Parameters:
+// - urls (URLService): The service looking short URLs up.
+// - users (repository.UserRepository): The repository the owner names are read from.
+// - clicks (repository.ClickRepository): The repository the clicks are counted in. Nil uses the click counter of URLs with a click limit.
+//
+// Returns:
+// - PreviewService: The preview service.
+func NewPreviewService(urls URLService, users repository.UserRepository, clicks repository.ClickRepository) PreviewService {
+	return &previewService{urls: urls, users: users, clicks: clicks}
+}
+
+// Preview looks the URL, its owner and its click count up.
+func (s *previewService) Preview(ctx context.Context, domain, shortID string) (*LinkPreview, error) {
+	url, err := s.urls.Peek(ctx, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &LinkPreview{
+		ShortID:      url.ShortID,
+		Domain:       url.Domain,
+		Protected:    url.IsProtected(),
+		Interstitial: url.Interstitial,
+		CreatedAt:    url.CreatedAt,
+		Clicks:       url.Clicks,
+	}
+	if !preview.Protected {
+		preview.Destination = url.Original
+	}
+
+	if url.OwnerID != "" {
+		owner, err := s.users.FindByID(ctx, url.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil {
+			preview.OwnerName = owner.Username
+		}
+	}
+
+	if s.clicks != nil {
+		if preview.Clicks, err = s.clicks.Count(ctx, url.Domain, url.ShortID, url.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return preview, nil
+}
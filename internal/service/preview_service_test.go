@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewService(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	users := newFakeUserRepository()
+	users.users["alice"] = &entity.User{ID: "user-1", Username: "alice"}
+
+	newService := func(url *entity.URL, clicks *MockClickRepository) service.PreviewService {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		cache.On("GetByShortID", ctx, url.ShortID).Return(url, nil)
+		urls := service.NewURLService(repo, cache)
+		if clicks == nil {
+			return service.NewPreviewService(urls, users, nil)
+		}
+		return service.NewPreviewService(urls, users, clicks)
+	}
+
+	t.Run("describes the link without counting a click", func(t *testing.T) {
+		url := &entity.URL{ShortID: "abc123", Original: "https://example.com/a", OwnerID: "user-1", CreatedAt: createdAt, MaxClicks: 5, Interstitial: true}
+		clicks := new(MockClickRepository)
+		clicks.On("Count", ctx, "abc123", createdAt).Return(int64(42), nil)
+
+		preview, err := newService(url, clicks).Preview(ctx, "", "abc123")
+
+		require.NoError(t, err)
+		assert.Equal(t, &service.LinkPreview{
+			ShortID:      "abc123",
+			Destination:  "https://example.com/a",
+			Interstitial: true,
+			CreatedAt:    createdAt,
+			OwnerName:    "alice",
+			Clicks:       42,
+		}, preview)
+	})
+
+	t.Run("hides the destination of protected links", func(t *testing.T) {
+		url := &entity.URL{ShortID: "docs", Original: "https://example.com/internal", PasswordHash: "hash", Clicks: 3}
+
+		preview, err := newService(url, nil).Preview(ctx, "", "docs")
+
+		require.NoError(t, err)
+		assert.True(t, preview.Protected)
+		assert.Empty(t, preview.Destination)
+		assert.Empty(t, preview.OwnerName)
+		assert.Equal(t, int64(3), preview.Clicks)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		url := &entity.URL{ShortID: "old", Original: "https://example.com", ExpiresAt: &expiresAt}
+
+		_, err := newService(url, nil).Preview(ctx, "", "old")
+
+		assert.ErrorIs(t, err, service.ErrExpired)
+	})
+
+	t.Run("click count failure", func(t *testing.T) {
+		url := &entity.URL{ShortID: "abc123", Original: "https://example.com/a"}
+		clicks := new(MockClickRepository)
+		clicks.On("Count", ctx, "abc123", time.Time{}).Return(int64(0), errors.New("mongo down"))
+
+		_, err := newService(url, clicks).Preview(ctx, "", "abc123")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestResolve_InterstitialNeedsConfirmation(t *testing.T) {
+	ctx := context.Background()
+	url := &entity.URL{ShortID: "abc123", Original: "https://example.com", Interstitial: true}
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	cache.On("GetByShortID", ctx, "abc123").Return(url, nil)
+	svc := service.NewURLService(repo, cache)
+
+	_, err := svc.Resolve(ctx, "", "abc123")
+	assert.ErrorIs(t, err, service.ErrConfirmationRequired)
+
+	confirmed, err := svc.Unlock(ctx, "", "abc123", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", confirmed.Original)
+	repo.AssertNotCalled(t, "ConsumeClick", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/qrcode"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// QRCodeFormatPNG renders QR codes as PNG images.
+	QRCodeFormatPNG = "png"
+	// QRCodeFormatSVG renders QR codes as SVG images.
+	QRCodeFormatSVG = "svg"
+)
+
+const (
+	// DefaultQRCodeSize is the width and height of QR code images when none is given, in pixels.
+	DefaultQRCodeSize = 256
+	// MinQRCodeSize and MaxQRCodeSize bound the size of QR code images, in pixels.
+	MinQRCodeSize = 64
+	MaxQRCodeSize = 2048
+	// MaxQRCodeMargin bounds the quiet zone around QR codes, in modules.
+	MaxQRCodeMargin = 16
+	// DefaultQRCodeCacheTTL is how long rendered QR codes are cached.
+	DefaultQRCodeCacheTTL = 24 * time.Hour
+)
+
+// ErrInvalidQRCodeOptions is returned when a QR code is requested with an unknown format, level or color,
+// or with a size or margin out of range.
+var ErrInvalidQRCodeOptions = errors.New("invalid QR code options")
+
+// QRCodeOptions holds how a QR code is rendered. Zero values select the defaults.
+//
+// Fields:
+// - Format (string): QRCodeFormatPNG or QRCodeFormatSVG (default PNG).
+// - Size (int): The width and height of the image in pixels, from MinQRCodeSize to MaxQRCodeSize (default DefaultQRCodeSize).
+// - Level (string): The error correction level, "L", "M", "Q" or "H" (default "M").
+// - Margin (*int): The quiet zone in modules, from 0 to MaxQRCodeMargin (default qrcode.DefaultMargin).
+// - Foreground (string): The hex color of dark modules (default "#000000").
+// - Background (string): The hex color of light modules, with an optional alpha channel (default "#ffffff").
+type QRCodeOptions struct {
+	Format     string
+	Size       int
+	Level      string
+	Margin     *int
+	Foreground string
+	Background string
+}
+
+// QRCodeImage is a rendered QR code.
+//
+// Fields:
+// - ContentType (string): The media type of the image, "image/png" or "image/svg+xml".
+// - Data ([]byte): The image.
+type QRCodeImage struct {
+	ContentType string
+	Data        []byte
+}
+
+// QRCodeService defines the interface for rendering the QR codes of short URLs.
+//
+// Methods:
+// - Render: Renders the QR code of a short URL.
+type QRCodeService interface {
+	// Render renders a QR code encoding a short URL, serving it from the cache when it was rendered before.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - shortID (string): The shortened ID the code belongs to.
+	// - content (string): The text to encode, the public short URL.
+	// - opts (QRCodeOptions): The format, size, level, margin and colors.
+	//
+	// Returns:
+	// - *QRCodeImage: The image.
+	// - error: ErrInvalidQRCodeOptions if an option is invalid, or an error if the code cannot be rendered.
+	Render(ctx context.Context, shortID, content string, opts QRCodeOptions) (*QRCodeImage, error)
+}
+
+// qrCodeService is the implementation of QRCodeService.
+type qrCodeService struct {
+	cache repository.QRCodeCacheRepository
+	ttl   time.Duration
+}
+
+// NewQRCodeService creates a QRCodeService.
+//
+// Parameters:
+// - cache (repository.QRCodeCacheRepository): The cache of rendered images. Nil renders every request.
+// - ttl (time.Duration): How long rendered images are cached. Zero means DefaultQRCodeCacheTTL.
+//
+// Returns:
+// - QRCodeService: The QR code service.
+func NewQRCodeService(cache repository.QRCodeCacheRepository, ttl time.Duration) QRCodeService {
+	if ttl <= 0 {
+		ttl = DefaultQRCodeCacheTTL
+	}
+	return &qrCodeService{cache: cache, ttl: ttl}
+}
+
+// qrCodeSpec holds validated QRCodeOptions.
+type qrCodeSpec struct {
+	format string
+	level  qrcode.Level
+	render qrcode.RenderOptions
+}
+
+// Render validates the options, then reads the image from the cache or renders and caches it.
+// Cache failures are logged and the image rendered, so an outage of the cache does not stop QR codes.
+func (s *qrCodeService) Render(ctx context.Context, shortID, content string, opts QRCodeOptions) (*QRCodeImage, error) {
+	spec, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	variant := spec.variant(content)
+	if s.cache != nil {
+		data, err := s.cache.Get(ctx, shortID, variant)
+		if err != nil {
+			log.Printf("failed to read cached QR code of %s: %v", shortID, err)
+		} else if data != nil {
+			return &QRCodeImage{ContentType: spec.contentType(), Data: data}, nil
+		}
+	}
+
+	code, err := qrcode.Encode([]byte(content), spec.level)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if spec.format == QRCodeFormatSVG {
+		data = qrcode.SVG(code, spec.render)
+	} else if data, err = qrcode.PNG(code, spec.render); err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Set(ctx, shortID, variant, data, s.ttl); err != nil {
+			log.Printf("failed to cache QR code of %s: %v", shortID, err)
+		}
+	}
+	return &QRCodeImage{ContentType: spec.contentType(), Data: data}, nil
+}
+
+// validate applies the defaults and checks every option.
+func (o QRCodeOptions) validate() (qrCodeSpec, error) {
+	spec := qrCodeSpec{
+		format: strings.ToLower(strings.TrimSpace(o.Format)),
+		render: qrcode.RenderOptions{Size: o.Size, Margin: qrcode.DefaultMargin},
+	}
+	switch spec.format {
+	case "":
+		spec.format = QRCodeFormatPNG
+	case QRCodeFormatPNG, QRCodeFormatSVG:
+	default:
+		return spec, fmt.Errorf(`%w: format must be "png" or "svg"`, ErrInvalidQRCodeOptions)
+	}
+
+	if spec.render.Size == 0 {
+		spec.render.Size = DefaultQRCodeSize
+	}
+	if spec.render.Size < MinQRCodeSize || spec.render.Size > MaxQRCodeSize {
+		return spec, fmt.Errorf("%w: size must be between %d and %d", ErrInvalidQRCodeOptions, MinQRCodeSize, MaxQRCodeSize)
+	}
+
+	if o.Margin != nil {
+		if *o.Margin < 0 || *o.Margin > MaxQRCodeMargin {
+			return spec, fmt.Errorf("%w: margin must be between 0 and %d", ErrInvalidQRCodeOptions, MaxQRCodeMargin)
+		}
+		spec.render.Margin = *o.Margin
+	}
+
+	spec.level = qrcode.LevelM
+	if o.Level != "" {
+		level, err := qrcode.ParseLevel(o.Level)
+		if err != nil {
+			return spec, fmt.Errorf("%w: %v", ErrInvalidQRCodeOptions, err)
+		}
+		spec.level = level
+	}
+
+	var err error
+	if spec.render.Foreground, err = parseQRCodeColor(o.Foreground, "#000000"); err != nil {
+		return spec, err
+	}
+	if spec.render.Background, err = parseQRCodeColor(o.Background, "#ffffff"); err != nil {
+		return spec, err
+	}
+	return spec, nil
+}
+
+// parseQRCodeColor parses a color option, using a fallback when it is empty.
+func parseQRCodeColor(value, fallback string) (c color.NRGBA, err error) {
+	if value == "" {
+		value = fallback
+	}
+	c, err = qrcode.ParseColor(value)
+	if err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidQRCodeOptions, err)
+	}
+	return c, nil
+}
+
+// contentType returns the media type of the image format.
+func (s qrCodeSpec) contentType() string {
+	if s.format == QRCodeFormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// variant identifies the image rendered from some content with these options, as a cache key.
+func (s qrCodeSpec) variant(content string) string {
+	fg, bg := s.render.Foreground, s.render.Background
+	key := strings.Join([]string{
+		s.format,
+		strconv.Itoa(s.render.Size),
+		s.level.String(),
+		strconv.Itoa(s.render.Margin),
+		fmt.Sprintf("%02x%02x%02x%02x", fg.R, fg.G, fg.B, fg.A),
+		fmt.Sprintf("%02x%02x%02x%02x", bg.R, bg.G, bg.B, bg.A),
+		content,
+	}, "\n")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeQRCodeCache struct {
+	images map[string][]byte
+	ttl    time.Duration
+	err    error
+}
+
+func (c *fakeQRCodeCache) Get(ctx context.Context, shortID, variant string) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.images[shortID+":"+variant], nil
+}
+
+func (c *fakeQRCodeCache) Set(ctx context.Context, shortID, variant string, image []byte, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.images == nil {
+		c.images = make(map[string][]byte)
+	}
+	c.images[shortID+":"+variant] = image
+	c.ttl = ttl
+	return nil
+}
+
+func TestQRCodeService_Render(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches each rendering separately", func(t *testing.T) {
+		cache := &fakeQRCodeCache{}
+		s := service.NewQRCodeService(cache, time.Hour)
+
+		png, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", png.ContentType)
+		assert.True(t, bytes.HasPrefix(png.Data, []byte("\x89PNG")))
+		assert.Len(t, cache.images, 1)
+		assert.Equal(t, time.Hour, cache.ttl)
+
+		again, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{Format: "PNG", Level: "m", Size: service.DefaultQRCodeSize})
+		require.NoError(t, err)
+		assert.Equal(t, png.Data, again.Data)
+		assert.Len(t, cache.images, 1, "defaults and explicit values share the cache entry")
+
+		margin := 0
+		svg, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{Format: "svg", Margin: &margin})
+		require.NoError(t, err)
+		assert.Equal(t, "image/svg+xml", svg.ContentType)
+		assert.Len(t, cache.images, 2)
+
+		_, err = s.Render(ctx, "abc123", "https://other.example/abc123", service.QRCodeOptions{})
+		require.NoError(t, err)
+		assert.Len(t, cache.images, 3, "the encoded URL is part of the key")
+	})
+
+	t.Run("serves cached images", func(t *testing.T) {
+		cache := &fakeQRCodeCache{}
+		s := service.NewQRCodeService(cache, 0)
+
+		_, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, service.DefaultQRCodeCacheTTL, cache.ttl)
+		for key := range cache.images {
+			cache.images[key] = []byte("cached")
+		}
+
+		image, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("cached"), image.Data)
+	})
+
+	t.Run("renders when the cache fails", func(t *testing.T) {
+		s := service.NewQRCodeService(&fakeQRCodeCache{err: errors.New("redis down")}, 0)
+
+		image, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", service.QRCodeOptions{})
+		require.NoError(t, err)
+		assert.NotEmpty(t, image.Data)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		s := service.NewQRCodeService(nil, 0)
+		tooWide := service.MaxQRCodeMargin + 1
+
+		for name, opts := range map[string]service.QRCodeOptions{
+			"format":     {Format: "jpeg"},
+			"small":      {Size: service.MinQRCodeSize - 1},
+			"large":      {Size: service.MaxQRCodeSize + 1},
+			"margin":     {Margin: &tooWide},
+			"level":      {Level: "X"},
+			"foreground": {Foreground: "black"},
+			"background": {Background: "#12"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := s.Render(ctx, "abc123", "https://sho.rt/abc123", opts)
+				assert.ErrorIs(t, err, service.ErrInvalidQRCodeOptions)
+			})
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// MaxRedirectRules is the number of redirect rules a URL may have.
+const MaxRedirectRules = 20
+
+// ErrInvalidRedirectRule is returned when a redirect rule has no destination, an unknown operating system,
+// device, language or country, or a time window that ends before it starts.
+var ErrInvalidRedirectRule = errors.New("invalid redirect rule")
+
+// languageTagPattern matches lowercase BCP 47 language tags such as "en", "pt-br" or "zh-hant-tw".
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// knownOS and knownDevices list the values redirect rules may match on.
+var (
+	knownOS      = []string{entity.OSiOS, entity.OSAndroid, entity.OSWindows, entity.OSMacOS, entity.OSLinux, entity.OSChromeOS}
+	knownDevices = []string{entity.DeviceMobile, entity.DeviceTablet, entity.DeviceDesktop, entity.DeviceBot}
+)
+
+// CountryLocator finds the country of IP addresses. *geoip.Reader implements it.
+type CountryLocator interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country of an IP address.
+	//
+	// Parameters:
+	// - ip (net.IP): The IP address.
+	//
+	// Returns:
+	// - string: The country code, or an empty string if it is unknown.
+	// - error: An error if the lookup fails.
+	Country(ip net.IP) (string, error)
+}
+
+// Visit holds the details of a request to a short URL that decide where it is redirected.
+//
+// Fields:
+// - Query (url.Values): The query parameters, forwarded to destinations of URLs that ask for it.
+// - UserAgent (string): The User-Agent header, matched by the OS and device conditions of redirect rules.
+// - AcceptLanguage (string): The Accept-Language header, matched by the language conditions.
+// - Country (string): The ISO 3166-1 alpha-2 code of the visitor's country, if known.
+// - Time (time.Time): The time of the visit, matched by the time windows.
+// - IP (string): The visitor's IP address, hashed with the user agent to assign the visitor a variant.
+// - Variant (string): The name of the variant the visitor was assigned to on an earlier visit, if any.
+type Visit struct {
+	Query          url.Values
+	UserAgent      string
+	AcceptLanguage string
+	Country        string
+	Time           time.Time
+	IP             string
+	Variant        string
+}
+
+// RedirectURL returns where a visit to a short URL is redirected.
+//
+// Parameters:
+// - url (*entity.URL): The resolved URL entity.
+// - visit (Visit): The details of the visit.
+//
+// Behavior:
+// - Uses the destination of the first redirect rule matching the visit.
+// - Otherwise assigns the visitor a variant, see ChooseVariant, keyed by the short ID, IP and user agent.
+// - Otherwise uses the original URL.
+// - Merges the query parameters of the visit into the destination if the URL forwards them.
+//
+// Returns:
+// - string: The destination URL.
+// - string: The name of the variant the visitor was assigned to, or an empty string if none was.
+func RedirectURL(url *entity.URL, visit Visit) (string, string) {
+	destination, variant := url.Original, ""
+	if rule := MatchRedirectRule(url.RedirectRules, visit); rule != nil {
+		destination = rule.Destination
+	} else if len(url.Variants) > 0 {
+		visitor := ""
+		if visit.IP != "" {
+			visitor = url.ShortID + "|" + visitorID(visit.IP, visit.UserAgent)
+		}
+		if v := ChooseVariant(url.Variants, visit.Variant, visitor); v != nil {
+			destination, variant = v.Destination, v.Name
+		}
+	}
+	if url.ForwardQuery {
+		destination = MergeQuery(destination, visit.Query)
+	}
+	return destination, variant
+}
+
+// MatchRedirectRule returns the first rule matching a visit.
+//
+// Parameters:
+// - rules ([]entity.RedirectRule): The rules, in order.
+// - visit (Visit): The details of the visit.
+//
+// Returns:
+// - *entity.RedirectRule: The first rule whose conditions all match, or nil if none does.
+func MatchRedirectRule(rules []entity.RedirectRule, visit Visit) *entity.RedirectRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	os := UserAgentOS(visit.UserAgent)
+	device := UserAgentDevice(visit.UserAgent)
+	language := PreferredLanguage(visit.AcceptLanguage)
+	country := strings.ToUpper(visit.Country)
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Active(visit.Time) {
+			continue
+		}
+		if len(rule.OS) > 0 && !slices.Contains(rule.OS, os) {
+			continue
+		}
+		if len(rule.Devices) > 0 && !slices.Contains(rule.Devices, device) {
+			continue
+		}
+		if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, country) {
+			continue
+		}
+		if len(rule.Languages) > 0 && !slices.ContainsFunc(rule.Languages, func(tag string) bool {
+			return language == tag || strings.HasPrefix(language, tag+"-")
+		}) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// PreferredLanguage returns the language a visitor prefers most.
+//
+// Parameters:
+// - acceptLanguage (string): The Accept-Language header, e.g. "pt-BR,pt;q=0.9,en;q=0.8".
+//
+// Returns:
+// - string: The lowercase tag with the highest quality, the first one on ties, or an empty string if none is given.
+func PreferredLanguage(acceptLanguage string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+	return best
+}
+
+// normalizeRedirectRules validates redirect rules and brings their conditions to the case they are matched in.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - policy (DestinationPolicy): The policy every rule destination must pass. Nil accepts every URL.
+// - rules ([]entity.RedirectRule): The rules.
+//
+// Returns:
+// - []entity.RedirectRule: The normalized rules, nil if there are none.
+// - error: ErrInvalidRedirectRule, a *DestinationError for a rejected destination, or an error if a check fails.
+func normalizeRedirectRules(ctx context.Context, policy DestinationPolicy, rules []entity.RedirectRule) ([]entity.RedirectRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > MaxRedirectRules {
+		return nil, fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidRedirectRule, MaxRedirectRules)
+	}
+
+	normalized := make([]entity.RedirectRule, len(rules))
+	for i, rule := range rules {
+		invalid := func(reason string, args ...any) error {
+			return fmt.Errorf("%w: rule %d: %s", ErrInvalidRedirectRule, i+1, fmt.Sprintf(reason, args...))
+		}
+
+		rule.Destination = strings.TrimSpace(rule.Destination)
+		if rule.Destination == "" {
+			return nil, invalid("destination is required")
+		}
+		if !isAbsoluteHTTPURL(rule.Destination) {
+			return nil, invalid("destination must be an absolute http or https URL")
+		}
+		if err := CheckDestination(ctx, policy, rule.Destination); err != nil {
+			return nil, err
+		}
+
+		rule.OS = normalizeValues(rule.OS, strings.ToLower)
+		for _, os := range rule.OS {
+			if !slices.Contains(knownOS, os) {
+				return nil, invalid("unknown operating system %q", os)
+			}
+		}
+		rule.Devices = normalizeValues(rule.Devices, strings.ToLower)
+		for _, device := range rule.Devices {
+			if !slices.Contains(knownDevices, device) {
+				return nil, invalid("unknown device %q", device)
+			}
+		}
+		rule.Languages = normalizeValues(rule.Languages, func(s string) string {
+			return strings.ReplaceAll(strings.ToLower(s), "_", "-")
+		})
+		for _, language := range rule.Languages {
+			if !languageTagPattern.MatchString(language) {
+				return nil, invalid("invalid language %q", language)
+			}
+		}
+		rule.Countries = normalizeValues(rule.Countries, strings.ToUpper)
+		for _, country := range rule.Countries {
+			if len(country) != 2 || strings.Trim(country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+				return nil, invalid("invalid country %q", country)
+			}
+		}
+		if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+			return nil, invalid("ends_at must be after starts_at")
+		}
+		normalized[i] = rule
+	}
+	return normalized, nil
+}
+
+// normalizeValues trims and converts the values of a condition, dropping blank ones.
+func normalizeValues(values []string, convert func(string) string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, convert(v))
+		}
+	}
+	return out
+}
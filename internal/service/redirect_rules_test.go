@@ -0,0 +1,208 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+)
+
+func TestMatchRedirectRule(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	rules := []entity.RedirectRule{
+		{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}},
+		{Destination: "https://play.google.com/store/apps/details?id=app", OS: []string{entity.OSAndroid}},
+		{Destination: "https://example.com/sale", StartsAt: &past, EndsAt: &now},
+		{Destination: "https://example.com/br", Languages: []string{"pt"}, Countries: []string{"BR"}},
+		{Destination: "https://example.com/tablet", Devices: []string{entity.DeviceTablet}, StartsAt: &future},
+	}
+
+	tests := []struct {
+		name  string
+		visit service.Visit
+		want  string
+	}{
+		{name: "iOS", visit: service.Visit{UserAgent: iPhoneUA, Time: now}, want: "https://apps.apple.com/app/id1"},
+		{name: "Android", visit: service.Visit{UserAgent: androidUA, Time: now}, want: "https://play.google.com/store/apps/details?id=app"},
+		{name: "window ends exclusively", visit: service.Visit{UserAgent: desktopUA, Time: now}, want: ""},
+		{name: "inside window", visit: service.Visit{UserAgent: desktopUA, Time: now.Add(-time.Minute)}, want: "https://example.com/sale"},
+		{
+			name:  "language prefix and country",
+			visit: service.Visit{UserAgent: desktopUA, AcceptLanguage: "en;q=0.5, pt-BR", Country: "br", Time: now},
+			want:  "https://example.com/br",
+		},
+		{
+			name:  "country alone does not match",
+			visit: service.Visit{UserAgent: desktopUA, AcceptLanguage: "en", Country: "BR", Time: now},
+			want:  "",
+		},
+		{
+			name:  "window not started",
+			visit: service.Visit{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Tablet PC 2.0)", Time: now},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := service.MatchRedirectRule(rules, tt.visit)
+			if tt.want == "" {
+				assert.Nil(t, rule)
+				return
+			}
+			require.NotNil(t, rule)
+			assert.Equal(t, tt.want, rule.Destination)
+		})
+	}
+}
+
+func TestRedirectURL_RulesAndForwardedQuery(t *testing.T) {
+	url := &entity.URL{
+		Original:      "https://example.com/app",
+		ForwardQuery:  true,
+		RedirectRules: []entity.RedirectRule{{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}}},
+	}
+
+	destination, _ := service.RedirectURL(url, service.Visit{Query: map[string][]string{"ref": {"mail"}}, UserAgent: iPhoneUA})
+	assert.Equal(t, "https://apps.apple.com/app/id1?ref=mail", destination)
+
+	destination, _ = service.RedirectURL(url, service.Visit{Query: map[string][]string{"ref": {"mail"}}, UserAgent: desktopUA})
+	assert.Equal(t, "https://example.com/app?ref=mail", destination)
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "", service.PreferredLanguage(""))
+	assert.Equal(t, "pt-br", service.PreferredLanguage("pt-BR,pt;q=0.9,en;q=0.8"))
+	assert.Equal(t, "en", service.PreferredLanguage("de;q=0.7, en;q=0.9, fr;q=0.9"))
+	assert.Equal(t, "fr", service.PreferredLanguage("*, en;q=bad, fr;q=0.1"))
+}
+
+func TestUserAgentOSAndDevice(t *testing.T) {
+	tests := []struct {
+		ua     string
+		os     string
+		device string
+	}{
+		{ua: iPhoneUA, os: entity.OSiOS, device: entity.DeviceMobile},
+		{ua: androidUA, os: entity.OSAndroid, device: entity.DeviceMobile},
+		{ua: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", os: entity.OSAndroid, device: entity.DeviceTablet},
+		{ua: "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)", os: entity.OSiOS, device: entity.DeviceTablet},
+		{ua: desktopUA, os: entity.OSWindows, device: entity.DeviceDesktop},
+		{ua: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15", os: entity.OSMacOS, device: entity.DeviceDesktop},
+		{ua: "Mozilla/5.0 (X11; CrOS x86_64 15633.69.0) Chrome/119.0 Safari/537.36", os: entity.OSChromeOS, device: entity.DeviceDesktop},
+		{ua: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", os: "", device: entity.DeviceBot},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.os, service.UserAgentOS(tt.ua), tt.ua)
+		assert.Equal(t, tt.device, service.UserAgentDevice(tt.ua), tt.ua)
+	}
+}
+
+func TestShorten_RedirectRules(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rules are normalized and the link is never reused", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Shorten(ctx, "https://example.com/app", service.ShortenOptions{
+			RedirectRules: []entity.RedirectRule{{
+				Destination: "https://example.com/br",
+				OS:          []string{" iOS "},
+				Languages:   []string{"pt_BR"},
+				Countries:   []string{"br"},
+			}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []entity.RedirectRule{{
+			Destination: "https://example.com/br",
+			OS:          []string{entity.OSiOS},
+			Languages:   []string{"pt-br"},
+			Countries:   []string{"BR"},
+		}}, result.RedirectRules)
+		assert.False(t, result.Reusable())
+		cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+	})
+
+	endsAt := time.Now()
+	startsAt := endsAt.Add(time.Hour)
+	invalid := map[string]entity.RedirectRule{
+		"missing destination":  {OS: []string{entity.OSiOS}},
+		"relative destination": {Destination: "/app"},
+		"unknown OS":           {Destination: "https://example.com", OS: []string{"symbian"}},
+		"unknown device":       {Destination: "https://example.com", Devices: []string{"watch"}},
+		"invalid language":     {Destination: "https://example.com", Languages: []string{"english!"}},
+		"invalid country":      {Destination: "https://example.com", Countries: []string{"BRA"}},
+		"window ends first":    {Destination: "https://example.com", StartsAt: &startsAt, EndsAt: &endsAt},
+	}
+	for name, rule := range invalid {
+		t.Run(name, func(t *testing.T) {
+			svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+			_, err := svc.Shorten(ctx, "https://example.com/app", service.ShortenOptions{RedirectRules: []entity.RedirectRule{rule}})
+			assert.ErrorIs(t, err, service.ErrInvalidRedirectRule)
+		})
+	}
+}
+
+func TestUpdate_RemovesRedirectRules(t *testing.T) {
+	ctx := context.Background()
+	existing := &entity.URL{
+		ShortID:       "abc123",
+		Original:      "https://example.com/app",
+		OwnerID:       "user-1",
+		RedirectRules: []entity.RedirectRule{{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}}},
+	}
+
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	repo.On("FindByShortID", ctx, "abc123").Return(existing, nil)
+	repo.On("Update", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("Delete", ctx, mock.Anything).Return(nil)
+
+	rules := []entity.RedirectRule{}
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Update(ctx, "user-1", "", "abc123", service.UpdateOptions{RedirectRules: &rules})
+
+	require.NoError(t, err)
+	assert.Empty(t, result.RedirectRules)
+}
+
+func TestImport_CSVRedirectRules(t *testing.T) {
+	ctx := context.Background()
+	input := strings.Join([]string{
+		"original_url,short_id,redirect_rules",
+		`https://example.com/a,a1,"[{""destination"":""https://apps.apple.com/app/id1"",""os"":[""IOS""]}]"`,
+		`https://example.com/b,b2,"[{""destination"":""ftp://example.com/b""}]"`,
+	}, "\n")
+
+	repo := new(MockURLRepository)
+	repo.On("ImportMany", ctx, mock.MatchedBy(func(urls []*entity.URL) bool {
+		return len(urls) == 1 && len(urls[0].RedirectRules) == 1 && urls[0].RedirectRules[0].OS[0] == entity.OSiOS
+	}), false).Return([]error{nil}, nil, nil)
+
+	svc := service.NewTransferService(repo, new(MockURLCacheRepository))
+	report, err := svc.Import(ctx, strings.NewReader(input), service.ImportOptions{Format: service.FormatCSV})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Imported)
+	assert.Equal(t, int64(1), report.Failed)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reputationReloadInterval is how often a FileReputationChecker looks for changes to its file.
+const reputationReloadInterval = 30 * time.Second
+
+// FileReputationChecker is a ReputationChecker reading known harmful domains and URLs from a local file,
+// such as a blocklist feed downloaded by a cron job.
+//
+// Each line holds an entry and, optionally, a category separated by whitespace, e.g. "evil.example phishing".
+// Entries with a scheme ("https://host/path") flag URLs starting with them; other entries are domains and flag
+// the domain and its subdomains. Blank lines and lines starting with "#" are ignored.
+type FileReputationChecker struct {
+	path string
+
+	mu        sync.RWMutex
+	domains   map[string]string
+	prefixes  map[string]string
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// NewFileReputationChecker creates a FileReputationChecker and loads its file.
+// The file is read again whenever its modification time changes.
+//
+// Parameters:
+// - path (string): The path of the list.
+//
+// Returns:
+// - *FileReputationChecker: The checker.
+// - error: An error if the file cannot be read.
+func NewFileReputationChecker(path string) (*FileReputationChecker, error) {
+	c := &FileReputationChecker{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the file again.
+//
+// Returns:
+// - error: An error if the file cannot be read, in which case the previous entries are kept.
+func (c *FileReputationChecker) Reload() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	domains := make(map[string]string)
+	prefixes := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		entry := strings.ToLower(fields[0])
+		category := ""
+		if len(fields) > 1 {
+			category = fields[1]
+		}
+		if strings.Contains(entry, "://") {
+			prefixes[entry] = category
+		} else {
+			domains[strings.TrimSuffix(entry, ".")] = category
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.domains = domains
+	c.prefixes = prefixes
+	c.modTime = info.ModTime()
+	c.lastCheck = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// Lookup flags a URL whose domain, or one of its parent domains, or whose prefix is listed in the file.
+func (c *FileReputationChecker) Lookup(_ context.Context, u *url.URL) (ReputationVerdict, error) {
+	c.reloadIfChanged()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range domainAndParents(host) {
+		if category, ok := c.domains[domain]; ok {
+			return ReputationVerdict{Malicious: true, Category: category}, nil
+		}
+	}
+
+	raw := strings.ToLower(u.String())
+	for prefix, category := range c.prefixes {
+		if strings.HasPrefix(raw, prefix) {
+			return ReputationVerdict{Malicious: true, Category: category}, nil
+		}
+	}
+	return ReputationVerdict{}, nil
+}
+
+// reloadIfChanged reloads the file if it changed, checking at most every reputationReloadInterval.
+// A file that cannot be read keeps the previous entries.
+func (c *FileReputationChecker) reloadIfChanged() {
+	c.mu.Lock()
+	if time.Since(c.lastCheck) < reputationReloadInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastCheck = time.Now()
+	modTime := c.modTime
+	c.mu.Unlock()
+
+	info, err := os.Stat(c.path)
+	if err != nil || info.ModTime().Equal(modTime) {
+		return
+	}
+	_ = c.Reload()
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// MaxBatchSize is the largest number of URLs accepted by ShortenBatch.
+	MaxBatchSize = 500
+	// DefaultBatchConcurrency is the number of batch items prepared at the same time when none is configured.
+	DefaultBatchConcurrency = 8
+)
+
+// ErrBatchTooLarge is returned when a batch holds more than MaxBatchSize URLs.
+var ErrBatchTooLarge = errors.New("batch exceeds the maximum number of URLs")
+
+// BatchItem is one URL of a batch shorten request.
+//
+// Fields:
+// - OriginalURL (string): The original URL to be shortened.
+// - Options (ShortenOptions): The optional settings for the shortened URL.
+type BatchItem struct {
+	OriginalURL string
+	Options     ShortenOptions
+}
+
+// BatchResult is the outcome of one item of a batch shorten request.
+//
+// Fields:
+// - Index (int): The position of the item in the request.
+// - URL (*entity.URL): The shortened URL entity, or nil if the item failed.
+// - Err (error): The reason the item failed, or nil.
+type BatchResult struct {
+	Index int
+	URL   *entity.URL
+	Err   error
+}
+
+// preparedItem is a batch item after validation and lookup, before it is stored.
+type preparedItem struct {
+	url   *entity.URL
+	isNew bool
+}
+
+// ShortenBatch shortens several URLs with bounded concurrency.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - items ([]BatchItem): The URLs to shorten and their options.
+//
+// Behavior:
+// - Validates each item and looks up reusable mappings and alias conflicts, at most batchConcurrency items at a time.
+// - Items in the batch with the same dedupe key and redirect type share one new mapping.
+// - Stores all new mappings with one repository.URLRepository.SaveMany call and caches them with one SetMany call.
+// - An alias taken by an earlier item of the same batch on the same domain fails with ErrAliasTaken.
+//
+// Returns:
+// - []BatchResult: One result per item, in the order of the items.
+// - error: ErrBatchTooLarge, or an error if the batch cannot be stored.
+func (s *urlService) ShortenBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	if len(items) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]BatchResult, len(items))
+	prepared := make([]preparedItem, len(items))
+
+	sem := make(chan struct{}, s.batchConcurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		results[i].Index = i
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			prepared[i], results[i].Err = s.prepareBatchItem(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	// owners[pos] lists the result indexes served by toSave[pos].
+	var toSave []*entity.URL
+	var owners [][]int
+	shared := make(map[string]int)
+	for i, p := range prepared {
+		if results[i].Err != nil {
+			continue
+		}
+		results[i].URL = p.url
+		if !p.isNew {
+			continue
+		}
+		if p.url.Reusable() {
+			key := strconv.Itoa(p.url.RedirectStatus()) + " " + p.url.DedupeKey()
+			if pos, ok := shared[key]; ok {
+				owners[pos] = append(owners[pos], i)
+				results[i].URL = toSave[pos]
+				continue
+			}
+			shared[key] = len(toSave)
+		}
+		toSave = append(toSave, p.url)
+		owners = append(owners, []int{i})
+	}
+	if len(toSave) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.repo.SaveMany(ctx, toSave)
+	if err != nil {
+		return nil, err
+	}
+
+	var byShortID, byDedupeKey []*entity.URL
+	for pos, url := range toSave {
+		if err := errs[pos]; err != nil {
+			if url.Alias && errors.Is(err, repository.ErrDuplicateShortID) {
+				err = ErrAliasTaken
+			}
+			for _, i := range owners[pos] {
+				results[i].URL = nil
+				results[i].Err = err
+			}
+			continue
+		}
+		byShortID = append(byShortID, url)
+		if url.Reusable() {
+			byDedupeKey = append(byDedupeKey, url)
+		}
+	}
+	_ = s.cacheRepo.SetMany(ctx, byShortID, byDedupeKey)
+
+	return results, nil
+}
+
+// prepareBatchItem validates a batch item and returns either an existing mapping to reuse
+// or a new, not yet stored mapping.
+func (s *urlService) prepareBatchItem(ctx context.Context, item BatchItem) (preparedItem, error) {
+	opts := item.Options
+	item.OriginalURL = opts.UTM.Apply(item.OriginalURL)
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return preparedItem{}, ErrInvalidExpiry
+	}
+	if err := CheckDestination(ctx, s.destinations, item.OriginalURL); err != nil {
+		return preparedItem{}, err
+	}
+	if err := s.normalizeRouting(ctx, &opts); err != nil {
+		return preparedItem{}, err
+	}
+	if err := s.resolveDomain(ctx, &opts); err != nil {
+		return preparedItem{}, err
+	}
+	if err := s.resolveDedupe(&opts); err != nil {
+		return preparedItem{}, err
+	}
+	if err := opts.hashPassword(); err != nil {
+		return preparedItem{}, err
+	}
+
+	if opts.Alias != "" {
+		if err := s.checkAlias(ctx, opts.Domain, opts.Alias); err != nil {
+			return preparedItem{}, err
+		}
+		url := s.newURL(opts.Alias, item.OriginalURL, opts)
+		url.Alias = true
+		return preparedItem{url: url, isNew: true}, nil
+	}
+
+	if opts.reusable() {
+		url, err := s.findReusable(ctx, s.canonicalURL(item.OriginalURL, opts), opts)
+		if err != nil {
+			return preparedItem{}, err
+		}
+		if url != nil {
+			return preparedItem{url: url}, nil
+		}
+	}
+
+	shortID, err := s.idGen.Generate(ctx)
+	if err != nil {
+		return preparedItem{}, err
+	}
+	return preparedItem{url: s.newURL(shortID, item.OriginalURL, opts), isNew: true}, nil
+}
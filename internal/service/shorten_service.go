@@ -2,34 +2,386 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/guttosm/url-shortener/internal/entity"
 	"github.com/guttosm/url-shortener/internal/repository"
-	"github.com/rs/xid"
 )
 
+var (
+	// ErrNotFound is returned when a shortened ID does not map to any URL.
+	ErrNotFound = errors.New("short URL not found")
+	// ErrExpired is returned when a URL has passed its expiry time or click limit.
+	ErrExpired = errors.New("short URL has expired")
+	// ErrInvalidExpiry is returned when a requested expiry time is not in the future.
+	ErrInvalidExpiry = errors.New("expires_at must be in the future")
+	// ErrForbidden is returned when a user acts on a URL they do not own.
+	ErrForbidden = errors.New("short URL belongs to another user")
+	// ErrInvalidSort is returned when a listing is requested with an unsupported sort field.
+	ErrInvalidSort = errors.New("unsupported sort field")
+	// ErrConfirmationRequired is returned when resolving an interstitial URL the visitor has not confirmed yet.
+	ErrConfirmationRequired = errors.New("short URL must be confirmed before redirecting")
+	// ErrInvalidDedupe is returned when a dedupe policy is not "global", "owner" or "never".
+	ErrInvalidDedupe = errors.New(`dedupe must be "global", "owner" or "never"`)
+)
+
+const (
+	// DefaultPageSize is the number of URLs per page when none is requested.
+	DefaultPageSize = 20
+	// MaxPageSize is the largest number of URLs returned per page.
+	MaxPageSize = 100
+)
+
+// sortableURLFields lists the fields URL listings can be sorted by.
+var sortableURLFields = map[string]struct{}{
+	"created_at":   {},
+	"updated_at":   {},
+	"short_id":     {},
+	"original_url": {},
+	"expires_at":   {},
+}
+
+// ShortenOptions holds the optional settings for a shortened URL.
+//
+// Fields:
+// - OwnerID (string): The ID of the user creating the URL.
+// - Domain (string): The verified custom domain of the owner the URL is served from. Empty means the default domain.
+// - Alias (string): A custom short ID requested by the user. Empty means one is generated.
+// - RedirectType (int): The HTTP status used when redirecting. Zero means entity.DefaultRedirectStatus.
+// - ExpiresAt (*time.Time): The time after which the URL stops redirecting. Nil means it never expires.
+// - MaxClicks (int64): The number of redirects allowed before the URL expires. Zero means unlimited.
+// - Dedupe (string): Which existing mappings may be returned: entity.DedupeGlobal, entity.DedupeOwner or entity.DedupeNever. Empty means the service default.
+// - Password (string): The password visitors must enter before being redirected. Empty means the URL is public.
+// - Interstitial (bool): Whether visitors are shown a preview and must confirm before being redirected.
+// - ForwardQuery (bool): Whether the query string of the short URL is merged into the destination when redirecting.
+// - UTM (UTMParams): Campaign parameters merged into the destination before it is stored.
+// - RedirectRules ([]entity.RedirectRule): Rules sending matching visitors to other destinations, in order.
+// - Variants ([]entity.Variant): Weighted destinations splitting the visitors no redirect rule matches.
+type ShortenOptions struct {
+	OwnerID       string
+	Domain        string
+	Alias         string
+	RedirectType  int
+	ExpiresAt     *time.Time
+	MaxClicks     int64
+	Dedupe        string
+	Password      string
+	Interstitial  bool
+	ForwardQuery  bool
+	UTM           UTMParams
+	RedirectRules []entity.RedirectRule
+	Variants      []entity.Variant
+
+	passwordHash string
+}
+
+// ListOptions holds the paging, sorting and filtering settings for listing URLs.
+//
+// Fields:
+// - Domain (string): If set, only URLs on this custom domain are returned.
+// - Query (string): If set, only URLs whose short ID or original URL contains it are returned.
+// - Sort (string): The field to sort by, prefixed with "-" for descending order. Defaults to "-created_at".
+// - Page (int): The 1-based page number. Defaults to 1.
+// - PageSize (int): The number of URLs per page. Defaults to DefaultPageSize, capped at MaxPageSize.
+type ListOptions struct {
+	Domain   string
+	Query    string
+	Sort     string
+	Page     int
+	PageSize int
+}
+
+// URLPage holds one page of a URL listing.
+//
+// Fields:
+// - Items ([]*entity.URL): The URLs in the page.
+// - Total (int64): The total number of URLs matching the listing.
+// - Page (int): The 1-based page number.
+// - PageSize (int): The number of URLs per page.
+type URLPage struct {
+	Items    []*entity.URL
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// UpdateOptions holds the changes to apply to an existing URL. Nil fields are left unchanged.
+//
+// Fields:
+// - Original (*string): The new destination URL.
+// - ExpiresAt (*time.Time): The new expiry time.
+// - RemoveExpiry (bool): Whether to remove the expiry time so the URL never expires.
+// - Interstitial (*bool): Whether visitors must confirm the destination before being redirected. Nil keeps the current setting.
+// - ForwardQuery (*bool): Whether the query string of the short URL is forwarded to the destination. Nil keeps the current setting.
+// - RedirectRules (*[]entity.RedirectRule): The new redirect rules, replacing the current ones. An empty list removes them; nil keeps them.
+// - Variants (*[]entity.Variant): The new variants, replacing the current ones. An empty list removes them; nil keeps them.
+type UpdateOptions struct {
+	Original      *string
+	ExpiresAt     *time.Time
+	RemoveExpiry  bool
+	Interstitial  *bool
+	ForwardQuery  *bool
+	RedirectRules *[]entity.RedirectRule
+	Variants      *[]entity.Variant
+}
+
+// reusable reports whether the options allow returning an existing mapping: they set no custom domain, expiry time,
+// click limit, password, interstitial, query forwarding, redirect rules or variants and do not ask for entity.DedupeNever.
+func (o ShortenOptions) reusable() bool {
+	return o.Domain == "" && o.ExpiresAt == nil && o.MaxClicks == 0 && o.Password == "" && !o.Interstitial && !o.ForwardQuery &&
+		len(o.RedirectRules) == 0 && len(o.Variants) == 0 && o.Dedupe != entity.DedupeNever
+}
+
+// normalizeRouting validates the redirect rules and variants of the options and normalizes them in place.
+func (s *urlService) normalizeRouting(ctx context.Context, opts *ShortenOptions) error {
+	rules, err := normalizeRedirectRules(ctx, s.destinations, opts.RedirectRules)
+	if err != nil {
+		return err
+	}
+	variants, err := normalizeVariants(ctx, s.destinations, opts.Variants)
+	if err != nil {
+		return err
+	}
+	opts.RedirectRules, opts.Variants = rules, variants
+	return nil
+}
+
+// ValidateDedupe checks that a dedupe policy is known.
+//
+// Parameters:
+// - dedupe (string): entity.DedupeGlobal, entity.DedupeOwner or entity.DedupeNever.
+//
+// Returns:
+// - error: ErrInvalidDedupe if the policy is unknown.
+func ValidateDedupe(dedupe string) error {
+	switch dedupe {
+	case entity.DedupeGlobal, entity.DedupeOwner, entity.DedupeNever:
+		return nil
+	default:
+		return ErrInvalidDedupe
+	}
+}
+
 // URLService defines the interface for URL shortening and retrieval services.
 //
 // Methods:
 // - Shorten: Shortens a given original URL and stores it in the database and cache.
-// - FindByShortID: Retrieves the original URL associated with a given shortened ID.
+// - ShortenBatch: Shortens several URLs in one call.
+// - Resolve: Retrieves the URL entity associated with a given shortened ID.
+// - Unlock: Retrieves a password-protected or interstitial URL entity once the visitor passed its checks.
+// - Peek: Retrieves a URL entity without counting a click.
 type URLService interface {
 	// Shorten shortens a given original URL and stores it in the database and cache.
 	//
 	// Parameters:
 	// - ctx (context.Context): The context for the operation.
 	// - originalURL (string): The original URL to be shortened.
+	// - opts (ShortenOptions): The optional settings for the shortened URL.
 	//
 	// Returns:
 	// - *entity.URL: The shortened URL entity.
-	// - error: An error if the operation fails.
-	Shorten(ctx context.Context, originalURL string) (*entity.URL, error)
+	// - error: A *DestinationError if the destination is rejected, or an error if the operation fails.
+	Shorten(ctx context.Context, originalURL string, opts ShortenOptions) (*entity.URL, error)
+
+	// ShortenBatch shortens several URLs with bounded concurrency.
+	// Each item is handled like a Shorten call, and a failing item does not fail the others.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - items ([]BatchItem): The URLs to shorten and their options.
+	//
+	// Returns:
+	// - []BatchResult: One result per item, in the order of the items.
+	// - error: ErrBatchTooLarge if there are more than MaxBatchSize items, or an error if the batch cannot be stored.
+	ShortenBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error)
+
+	// Resolve retrieves the URL entity associated with a given shortened ID.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain the ID was requested on, or an empty string for the default domain.
+	// - shortID (string): The shortened ID to resolve.
+	//
+	// Returns:
+	// - *entity.URL: The URL entity mapped to the shortened ID.
+	// - error: ErrNotFound if the ID is unknown, ErrExpired if it has expired, ErrConfirmationRequired if it is interstitial, ErrPasswordRequired if it is password protected, or an error if the lookup fails.
+	Resolve(ctx context.Context, domain, shortID string) (*entity.URL, error)
+
+	// Unlock retrieves the URL entity associated with a given shortened ID after checking its password.
+	// Interstitial URLs are treated as confirmed by the visitor.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain the ID was requested on, or an empty string for the default domain.
+	// - shortID (string): The shortened ID to resolve.
+	// - password (string): The password entered by the visitor.
+	//
+	// Returns:
+	// - *entity.URL: The URL entity mapped to the shortened ID.
+	// - error: ErrNotFound, ErrExpired, ErrPasswordRequired if the password is empty, ErrWrongPassword, an *AttemptsError if the URL is locked, or an error if the lookup fails.
+	Unlock(ctx context.Context, domain, shortID, password string) (*entity.URL, error)
+
+	// Peek retrieves the URL entity associated with a given shortened ID without counting a click,
+	// for showing where it leads.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - domain (string): The custom domain the ID was requested on, or an empty string for the default domain.
+	// - shortID (string): The shortened ID to look up.
+	//
+	// Returns:
+	// - *entity.URL: The URL entity mapped to the shortened ID.
+	// - error: ErrNotFound if the ID is unknown, ErrExpired if it has expired, or an error if the lookup fails.
+	Peek(ctx context.Context, domain, shortID string) (*entity.URL, error)
+
+	// Get retrieves a URL owned by a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID of the URL.
+	//
+	// Returns:
+	// - *entity.URL: The URL entity.
+	// - error: ErrNotFound if the ID is unknown, ErrForbidden if the user does not own it, or an error if the lookup fails.
+	Get(ctx context.Context, ownerID, domain, shortID string) (*entity.URL, error)
+
+	// List retrieves a page of the URLs owned by a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - opts (ListOptions): The paging, sorting and filtering settings.
+	//
+	// Returns:
+	// - *URLPage: The requested page.
+	// - error: ErrInvalidSort for an unsupported sort field, or an error if the query fails.
+	List(ctx context.Context, ownerID string, opts ListOptions) (*URLPage, error)
+
+	// Update changes the destination or expiry of a URL owned by a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID of the URL.
+	// - opts (UpdateOptions): The changes to apply.
+	//
+	// Returns:
+	// - *entity.URL: The updated URL entity.
+	// - error: ErrNotFound, ErrForbidden, ErrInvalidExpiry, a *DestinationError, or an error if the update fails.
+	Update(ctx context.Context, ownerID, domain, shortID string, opts UpdateOptions) (*entity.URL, error)
+
+	// Delete removes a URL owned by a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - ownerID (string): The ID of the requesting user.
+	// - domain (string): The custom domain of the URL, or an empty string for the default domain.
+	// - shortID (string): The shortened ID of the URL.
+	//
+	// Returns:
+	// - error: ErrNotFound, ErrForbidden, or an error if the deletion fails.
+	Delete(ctx context.Context, ownerID, domain, shortID string) error
 }
 
 type urlService struct {
-	repo      repository.URLRepository
-	cacheRepo repository.URLCacheRepository
+	repo             repository.URLRepository
+	cacheRepo        repository.URLCacheRepository
+	idGen            IDGenerator
+	batchConcurrency int
+	destinations     DestinationPolicy
+	canonicalizer    Canonicalizer
+	dedupe           string
+	attempts         repository.PasswordAttemptRepository
+	maxFailures      int64
+	lockout          time.Duration
+	customDomains    CustomDomainService
+}
+
+// URLServiceOption configures optional dependencies of the URL service.
+type URLServiceOption func(*urlService)
+
+// WithIDGenerator sets the generator used to create short IDs.
+//
+// Parameters:
+// - gen (IDGenerator): The short ID generator.
+//
+// Returns:
+// - URLServiceOption: The option applying the generator.
+func WithIDGenerator(gen IDGenerator) URLServiceOption {
+	return func(s *urlService) {
+		s.idGen = gen
+	}
+}
+
+// WithBatchConcurrency sets how many items of a batch are prepared at the same time.
+//
+// Parameters:
+// - n (int): The maximum number of concurrent lookups. Values below 1 mean DefaultBatchConcurrency.
+//
+// Returns:
+// - URLServiceOption: The option applying the limit.
+func WithBatchConcurrency(n int) URLServiceOption {
+	return func(s *urlService) {
+		s.batchConcurrency = n
+	}
+}
+
+// WithDestinationPolicy screens the destination of every new or updated URL.
+//
+// Parameters:
+// - policy (DestinationPolicy): The policy, usually a DestinationPolicyChain.
+//
+// Returns:
+// - URLServiceOption: The option applying the policy.
+func WithDestinationPolicy(policy DestinationPolicy) URLServiceOption {
+	return func(s *urlService) {
+		s.destinations = policy
+	}
+}
+
+// WithCanonicalizer sets how original URLs are canonicalized before looking up existing mappings.
+//
+// Parameters:
+// - c (Canonicalizer): The canonicalizer. Without this option, tracking parameters are kept.
+//
+// Returns:
+// - URLServiceOption: The option applying the canonicalizer.
+func WithCanonicalizer(c Canonicalizer) URLServiceOption {
+	return func(s *urlService) {
+		s.canonicalizer = c
+	}
+}
+
+// WithDedupe sets the dedupe policy of requests that do not choose one.
+//
+// Parameters:
+// - dedupe (string): entity.DedupeGlobal, entity.DedupeOwner or entity.DedupeNever. Without this option, entity.DedupeGlobal is used.
+//
+// Returns:
+// - URLServiceOption: The option applying the policy.
+func WithDedupe(dedupe string) URLServiceOption {
+	return func(s *urlService) {
+		s.dedupe = dedupe
+	}
+}
+
+// WithCustomDomains lets URLs be created on the verified custom domains of their owner.
+//
+// Parameters:
+// - domains (CustomDomainService): The service holding the registered custom domains.
+// Without this option, every request for a custom domain is rejected with ErrCustomDomainNotFound.
+//
+// Returns:
+// - URLServiceOption: The option applying the service.
+func WithCustomDomains(domains CustomDomainService) URLServiceOption {
+	return func(s *urlService) {
+		s.customDomains = domains
+	}
 }
 
 // NewURLService creates a new instance of URLService.
@@ -37,14 +389,28 @@ type urlService struct {
 // Parameters:
 // - repo (repository.URLRepository): The repository for persistent URL storage.
 // - cache (repository.URLCacheRepository): The repository for caching URL entities.
+// - opts (...URLServiceOption): Optional dependencies. Without WithIDGenerator, random IDs of DefaultShortIDLength are used.
 //
 // Returns:
 // - URLService: An instance of the URLService interface.
-func NewURLService(repo repository.URLRepository, cache repository.URLCacheRepository) URLService {
-	return &urlService{
+func NewURLService(repo repository.URLRepository, cache repository.URLCacheRepository, opts ...URLServiceOption) URLService {
+	s := &urlService{
 		repo:      repo,
 		cacheRepo: cache,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.idGen == nil {
+		s.idGen, _ = NewRandomIDGenerator(DefaultShortIDLength)
+	}
+	if s.batchConcurrency < 1 {
+		s.batchConcurrency = DefaultBatchConcurrency
+	}
+	if s.dedupe == "" {
+		s.dedupe = entity.DedupeGlobal
+	}
+	return s
 }
 
 // Shorten shortens a given original URL and stores it in the database and cache.
@@ -52,42 +418,446 @@ func NewURLService(repo repository.URLRepository, cache repository.URLCacheRepos
 // Parameters:
 // - ctx (context.Context): The context for the operation.
 // - originalURL (string): The original URL to be shortened.
+// - opts (ShortenOptions): The optional settings for the shortened URL.
 //
 // Behavior:
-// - Checks the cache for the original URL. If found, returns it.
-// - Checks the database for the original URL. If found, caches it and returns it.
+// - Merges the UTM parameters of the options into the original URL first, replacing any it already carries.
+// - Rejects an expiry time that is not in the future.
+// - Rejects destinations refused by the destination policy, including those of redirect rules and variants,
+// invalid redirect rules or variants, unknown dedupe policies and custom domains the owner has not verified.
+// - If an alias is requested, validates it and stores it as a new mapping (see shortenWithAlias).
+// - Checks the cache for the canonical form of the original URL, which keeps the UTM parameters of the options.
+// If found with matching options, returns it.
+// - Checks the database for the canonical URL. If found with matching options, caches it and returns it.
+// - New mappings store both the original URL, used when redirecting, and its canonical form.
 // - If not found, generates a new shortened ID, stores it in the database, and caches it.
+// - Existing mappings are only returned within the dedupe policy of the request (see resolveDedupe):
+// any globally shared mapping, only the owner's own per-owner mappings, or none at all.
+// - URLs on a custom domain, with an expiry or click limit or the "never" policy are always stored as new mappings and never reused.
 //
 // Returns:
 // - *entity.URL: The shortened URL entity.
-// - error: An error if the operation fails.
-func (s *urlService) Shorten(ctx context.Context, originalURL string) (*entity.URL, error) {
-	url, err := s.cacheRepo.GetByOriginalURL(ctx, originalURL)
-	if err == nil && url != nil {
-		return url, nil
+// - error: ErrInvalidExpiry, ErrInvalidAlias, ErrReservedAlias, ErrAliasTaken, ErrCustomDomainNotFound or ErrCustomDomainNotVerified for rejected options,
+// a *DestinationError for a rejected destination, or an error if the operation fails.
+func (s *urlService) Shorten(ctx context.Context, originalURL string, opts ShortenOptions) (*entity.URL, error) {
+	originalURL = opts.UTM.Apply(originalURL)
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	if err := CheckDestination(ctx, s.destinations, originalURL); err != nil {
+		return nil, err
+	}
+	if err := s.normalizeRouting(ctx, &opts); err != nil {
+		return nil, err
+	}
+	if err := s.resolveDomain(ctx, &opts); err != nil {
+		return nil, err
+	}
+	if err := s.resolveDedupe(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.hashPassword(); err != nil {
+		return nil, err
+	}
+	if opts.Alias != "" {
+		return s.shortenWithAlias(ctx, originalURL, opts)
+	}
+	if !opts.reusable() {
+		return s.shortenNew(ctx, originalURL, opts)
 	}
 
-	url, err = s.repo.FindByOriginalURL(ctx, originalURL)
+	url, err := s.findReusable(ctx, s.canonicalURL(originalURL, opts), opts)
 	if err != nil {
 		return nil, err
 	}
 	if url != nil {
-		_ = s.cacheRepo.SetByOriginalURL(ctx, url)
+		return url, nil
+	}
+
+	return s.shortenNew(ctx, originalURL, opts)
+}
+
+// canonicalURL returns the canonical form of a destination that existing mappings are looked up by.
+// The UTM parameters set by the options are kept even when tracking parameters are dropped, so a link tagged
+// for one campaign is never reused for another, or for untagged requests.
+func (s *urlService) canonicalURL(originalURL string, opts ShortenOptions) string {
+	return s.canonicalizer.Keeping(opts.UTM.names()...).Canonicalize(originalURL)
+}
+
+// resolveDomain normalizes the custom domain of the options and checks that the owner verified it.
+func (s *urlService) resolveDomain(ctx context.Context, opts *ShortenOptions) error {
+	if opts.Domain == "" {
+		return nil
+	}
+	if s.customDomains == nil {
+		return ErrCustomDomainNotFound
+	}
+	domain, err := s.customDomains.Authorize(ctx, opts.OwnerID, opts.Domain)
+	if err != nil {
+		return err
+	}
+	opts.Domain = domain
+	return nil
+}
+
+// resolveDedupe applies the default dedupe policy to the options and validates it.
+// Per-owner dedupe without an owner falls back to global dedupe.
+func (s *urlService) resolveDedupe(opts *ShortenOptions) error {
+	if opts.Dedupe == "" {
+		opts.Dedupe = s.dedupe
+	}
+	if err := ValidateDedupe(opts.Dedupe); err != nil {
+		return err
+	}
+	if opts.Dedupe == entity.DedupeOwner && opts.OwnerID == "" {
+		opts.Dedupe = entity.DedupeGlobal
+	}
+	return nil
+}
+
+// findReusable looks for an existing mapping of a canonical URL that matches the options,
+// first in the cache and then in the database, caching a database hit under both keys.
+// The cache key and the database query are both limited to the dedupe scope of the options.
+// It returns nil if there is none.
+func (s *urlService) findReusable(ctx context.Context, canonicalURL string, opts ShortenOptions) (*entity.URL, error) {
+	url, err := s.cacheRepo.GetByDedupeKey(ctx, entity.DedupeKeyFor(opts.Dedupe, opts.OwnerID, canonicalURL))
+	if err == nil && url != nil && matchesOptions(url, opts) {
+		return url, nil
+	}
+
+	ownerID := ""
+	if opts.Dedupe == entity.DedupeOwner {
+		ownerID = opts.OwnerID
+	}
+	url, err = s.repo.FindByOriginalURL(ctx, canonicalURL, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if url != nil && matchesOptions(url, opts) {
+		_ = s.cacheRepo.SetByDedupeKey(ctx, url)
 		_ = s.cacheRepo.SetByShortID(ctx, url)
 		return url, nil
 	}
+	return nil, nil
+}
+
+// shortenNew stores a new mapping under a generated short ID.
+//
+// Behavior:
+// - Generates a short ID, saves the mapping and caches it by short ID.
+// - Caches it by dedupe key too, unless it has limits or a dedupe policy that make it unsuitable for reuse.
+func (s *urlService) shortenNew(ctx context.Context, originalURL string, opts ShortenOptions) (*entity.URL, error) {
+	shortID, err := s.idGen.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := s.newURL(shortID, originalURL, opts)
+	if err := s.repo.Save(ctx, url); err != nil {
+		return nil, err
+	}
+	if url.Reusable() {
+		_ = s.cacheRepo.SetByDedupeKey(ctx, url)
+	}
+	_ = s.cacheRepo.SetByShortID(ctx, url)
 
-	url = &entity.URL{
-		ShortID:   xid.New().String()[:6],
-		Original:  originalURL,
-		CreatedAt: time.Now(),
+	return url, nil
+}
+
+// shortenWithAlias stores a new mapping under a user-chosen alias.
+//
+// Behavior:
+// - Validates the alias charset, length and reserved words.
+// - Rejects the alias if the repository already holds a URL with that short ID on the requested domain.
+// - Saves the mapping without regenerating the ID on collision and caches it by short ID only,
+// so the original-URL lookup never hands an alias to another caller.
+func (s *urlService) shortenWithAlias(ctx context.Context, originalURL string, opts ShortenOptions) (*entity.URL, error) {
+	if err := s.checkAlias(ctx, opts.Domain, opts.Alias); err != nil {
+		return nil, err
 	}
 
+	url := s.newURL(opts.Alias, originalURL, opts)
+	url.Alias = true
+
 	if err := s.repo.Save(ctx, url); err != nil {
+		if errors.Is(err, repository.ErrDuplicateShortID) {
+			return nil, ErrAliasTaken
+		}
+		return nil, err
+	}
+	_ = s.cacheRepo.SetByShortID(ctx, url)
+
+	return url, nil
+}
+
+// checkAlias validates an alias and rejects it if the repository already holds a URL with that short ID on the domain.
+func (s *urlService) checkAlias(ctx context.Context, domain, alias string) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.FindByShortID(ctx, domain, alias)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrAliasTaken
+	}
+	return nil
+}
+
+// Resolve retrieves the URL entity associated with a given shortened ID.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - domain (string): The custom domain the ID was requested on, or an empty string for the default domain.
+// - shortID (string): The shortened ID to resolve.
+//
+// Behavior:
+// - Looks the shortened ID on the domain up in the cache, then the database (see lookup).
+// - Rejects URLs whose expiry time has passed.
+// - Rejects interstitial and password-protected URLs without counting a click; they are resolved with Unlock.
+// - For URLs with a click limit, counts this redirect and rejects it once the limit is reached.
+//
+// Returns:
+// - *entity.URL: The URL entity mapped to the shortened ID.
+// - error: ErrNotFound if the ID is unknown, ErrExpired if it has expired, ErrPasswordRequired if it is password protected, or an error if the lookup fails.
+func (s *urlService) Resolve(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	url, err := s.lookup(ctx, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if url.IsExpired(time.Now()) {
+		return nil, ErrExpired
+	}
+	if url.Interstitial {
+		return nil, ErrConfirmationRequired
+	}
+	if url.IsProtected() {
+		return nil, ErrPasswordRequired
+	}
+	if err := s.consumeClick(ctx, url); err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+// Peek retrieves a URL entity from the cache or the database and rejects it once expired,
+// without counting a click against its limit.
+func (s *urlService) Peek(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	url, err := s.lookup(ctx, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if url.IsExpired(time.Now()) {
+		return nil, ErrExpired
+	}
+	return url, nil
+}
+
+// consumeClick counts a redirect against the click limit of a URL, if it has one.
+// It returns ErrExpired once the limit is used up.
+func (s *urlService) consumeClick(ctx context.Context, url *entity.URL) error {
+	if url.MaxClicks == 0 {
+		return nil
+	}
+	counted, err := s.repo.ConsumeClick(ctx, url.Domain, url.ShortID, url.MaxClicks)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return ErrExpired
+	}
+	return nil
+}
+
+// lookup retrieves a URL entity by domain and short ID from the cache, falling back to the database
+// and refilling the cache on a hit.
+func (s *urlService) lookup(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	url, err := s.cacheRepo.GetByShortID(ctx, domain, shortID)
+	if err == nil && url != nil {
+		return url, nil
+	}
+
+	url, err = s.repo.FindByShortID(ctx, domain, shortID)
+	if err != nil {
 		return nil, err
 	}
-	_ = s.cacheRepo.SetByOriginalURL(ctx, url)
+	if url == nil {
+		return nil, ErrNotFound
+	}
+
 	_ = s.cacheRepo.SetByShortID(ctx, url)
+	return url, nil
+}
+
+// Get retrieves a URL owned by a user.
+//
+// Behavior:
+// - Reads the URL from the database rather than the cache so the result is current.
+// - Rejects users other than the owner.
+func (s *urlService) Get(ctx context.Context, ownerID, domain, shortID string) (*entity.URL, error) {
+	url, err := s.repo.FindByShortID(ctx, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrNotFound
+	}
+	if url.OwnerID != ownerID {
+		return nil, ErrForbidden
+	}
+	return url, nil
+}
+
+// List retrieves a page of the URLs owned by a user.
+//
+// Behavior:
+// - Applies the page size default and cap, and defaults to the newest URLs first.
+// - Rejects sort fields outside sortableURLFields.
+func (s *urlService) List(ctx context.Context, ownerID string, opts ListOptions) (*URLPage, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.PageSize > MaxPageSize {
+		opts.PageSize = MaxPageSize
+	}
+	if opts.Sort == "" {
+		opts.Sort = "-created_at"
+	}
+
+	sortField := strings.TrimPrefix(opts.Sort, "-")
+	if _, ok := sortableURLFields[sortField]; !ok {
+		return nil, ErrInvalidSort
+	}
+
+	items, total, err := s.repo.List(ctx, repository.URLListFilter{
+		OwnerID:   ownerID,
+		Domain:    strings.ToLower(opts.Domain),
+		Query:     opts.Query,
+		SortField: sortField,
+		SortDesc:  strings.HasPrefix(opts.Sort, "-"),
+		Skip:      int64((opts.Page - 1) * opts.PageSize),
+		Limit:     int64(opts.PageSize),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &URLPage{
+		Items:    items,
+		Total:    total,
+		Page:     opts.Page,
+		PageSize: opts.PageSize,
+	}, nil
+}
+
+// Update changes the destination or expiry of a URL owned by a user.
+//
+// Behavior:
+// - Rejects users other than the owner and expiry times that are not in the future.
+// - Rejects a new destination refused by the destination policy.
+// - Stores the changes and evicts the URL from the cache under both its old keys.
+func (s *urlService) Update(ctx context.Context, ownerID, domain, shortID string, opts UpdateOptions) (*entity.URL, error) {
+	url, err := s.Get(ctx, ownerID, domain, shortID)
+	if err != nil {
+		return nil, err
+	}
+	previous := *url
+
+	if opts.Original != nil {
+		if err := CheckDestination(ctx, s.destinations, *opts.Original); err != nil {
+			return nil, err
+		}
+		url.Original = *opts.Original
+		url.Canonical = canonicalDestination(s.canonicalizer, url.Original)
+	}
+	if opts.RemoveExpiry {
+		url.ExpiresAt = nil
+	} else if opts.ExpiresAt != nil {
+		if !opts.ExpiresAt.After(time.Now()) {
+			return nil, ErrInvalidExpiry
+		}
+		url.ExpiresAt = opts.ExpiresAt
+	}
+	if opts.Interstitial != nil {
+		url.Interstitial = *opts.Interstitial
+	}
+	if opts.ForwardQuery != nil {
+		url.ForwardQuery = *opts.ForwardQuery
+	}
+	if opts.RedirectRules != nil {
+		if url.RedirectRules, err = normalizeRedirectRules(ctx, s.destinations, *opts.RedirectRules); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Variants != nil {
+		if url.Variants, err = normalizeVariants(ctx, s.destinations, *opts.Variants); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, url); err != nil {
+		return nil, err
+	}
+	_ = s.cacheRepo.Delete(ctx, &previous)
 
 	return url, nil
 }
+
+// Delete removes a URL owned by a user and evicts it from the cache.
+func (s *urlService) Delete(ctx context.Context, ownerID, domain, shortID string) error {
+	url, err := s.Get(ctx, ownerID, domain, shortID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, url.Domain, url.ShortID); err != nil {
+		return err
+	}
+	_ = s.cacheRepo.Delete(ctx, url)
+	return nil
+}
+
+// newURL builds a URL entity for a new mapping from the shorten options.
+func (s *urlService) newURL(shortID, originalURL string, opts ShortenOptions) *entity.URL {
+	url := &entity.URL{
+		ShortID:       shortID,
+		Domain:        opts.Domain,
+		Original:      originalURL,
+		Canonical:     s.canonicalURL(originalURL, opts),
+		OwnerID:       opts.OwnerID,
+		RedirectType:  opts.RedirectType,
+		ExpiresAt:     opts.ExpiresAt,
+		MaxClicks:     opts.MaxClicks,
+		PasswordHash:  opts.passwordHash,
+		Interstitial:  opts.Interstitial,
+		ForwardQuery:  opts.ForwardQuery,
+		RedirectRules: opts.RedirectRules,
+		Variants:      opts.Variants,
+		CreatedAt:     time.Now(),
+	}
+	if opts.Dedupe != entity.DedupeGlobal {
+		url.Dedupe = opts.Dedupe
+	}
+	return url
+}
+
+// matchesOptions reports whether an existing URL entity can be reused for a request with the given options.
+// Custom aliases, URLs with limits and URLs of another dedupe scope or owner are never reused for other requests,
+// nor URLs redirecting with another status, so a request without a redirect type never gets a permanent redirect.
+func matchesOptions(url *entity.URL, opts ShortenOptions) bool {
+	if !url.Reusable() || url.DedupeScope() != opts.Dedupe {
+		return false
+	}
+	if opts.Dedupe == entity.DedupeOwner && url.OwnerID != opts.OwnerID {
+		return false
+	}
+	redirectType := opts.RedirectType
+	if redirectType == 0 {
+		redirectType = entity.DefaultRedirectStatus
+	}
+	return url.RedirectStatus() == redirectType
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
 	"github.com/guttosm/url-shortener/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -21,41 +22,93 @@ func (m *MockURLRepository) Save(ctx context.Context, url *entity.URL) error {
 	return args.Error(0)
 }
 
-func (m *MockURLRepository) FindByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error) {
-	args := m.Called(ctx, originalURL)
+func (m *MockURLRepository) SaveMany(ctx context.Context, urls []*entity.URL) ([]error, error) {
+	args := m.Called(ctx, urls)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]error), args.Error(1)
+}
+
+func (m *MockURLRepository) ImportMany(ctx context.Context, urls []*entity.URL, overwrite bool) ([]error, []*entity.URL, error) {
+	args := m.Called(ctx, urls, overwrite)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	replaced, _ := args.Get(1).([]*entity.URL)
+	return args.Get(0).([]error), replaced, args.Error(2)
+}
+
+func (m *MockURLRepository) ForEach(ctx context.Context, ownerID string, fn func(*entity.URL) error) error {
+	args := m.Called(ctx, ownerID, fn)
+	if urls, ok := args.Get(0).([]*entity.URL); ok {
+		for _, url := range urls {
+			if err := fn(url); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockURLRepository) FindByOriginalURL(ctx context.Context, canonicalURL, ownerID string) (*entity.URL, error) {
+	args := m.Called(ctx, canonicalURL, ownerID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*entity.URL), args.Error(1)
 }
 
-func (m *MockURLRepository) FindByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
-	args := m.Called(ctx, shortID)
+func (m *MockURLRepository) FindByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID))
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*entity.URL), args.Error(1)
 }
 
+func (m *MockURLRepository) ConsumeClick(ctx context.Context, domain, shortID string, maxClicks int64) (bool, error) {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID), maxClicks)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockURLRepository) List(ctx context.Context, filter repository.URLListFilter) ([]*entity.URL, int64, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*entity.URL), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockURLRepository) Update(ctx context.Context, url *entity.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Delete(ctx context.Context, domain, shortID string) error {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID))
+	return args.Error(0)
+}
+
 type MockURLCacheRepository struct {
 	mock.Mock
 }
 
-func (m *MockURLCacheRepository) GetByOriginalURL(ctx context.Context, originalURL string) (*entity.URL, error) {
-	args := m.Called(ctx, originalURL)
+func (m *MockURLCacheRepository) GetByDedupeKey(ctx context.Context, dedupeKey string) (*entity.URL, error) {
+	args := m.Called(ctx, dedupeKey)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*entity.URL), args.Error(1)
 }
 
-func (m *MockURLCacheRepository) SetByOriginalURL(ctx context.Context, url *entity.URL) error {
+func (m *MockURLCacheRepository) SetByDedupeKey(ctx context.Context, url *entity.URL) error {
 	args := m.Called(ctx, url)
 	return args.Error(0)
 }
 
-func (m *MockURLCacheRepository) GetByShortID(ctx context.Context, shortID string) (*entity.URL, error) {
-	args := m.Called(ctx, shortID)
+func (m *MockURLCacheRepository) GetByShortID(ctx context.Context, domain, shortID string) (*entity.URL, error) {
+	args := m.Called(ctx, entity.LinkKey(domain, shortID))
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -67,9 +120,19 @@ func (m *MockURLCacheRepository) SetByShortID(ctx context.Context, url *entity.U
 	return args.Error(0)
 }
 
+func (m *MockURLCacheRepository) SetMany(ctx context.Context, byShortID, byDedupeKey []*entity.URL) error {
+	args := m.Called(ctx, byShortID, byDedupeKey)
+	return args.Error(0)
+}
+
+func (m *MockURLCacheRepository) Delete(ctx context.Context, url *entity.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
 func TestShorten_URLExistsInCache(t *testing.T) {
 	ctx := context.Background()
-	original := "https://example.com"
+	original := "https://example.com/"
 	expected := &entity.URL{
 		ShortID:   "abc123",
 		Original:  original,
@@ -79,19 +142,19 @@ func TestShorten_URLExistsInCache(t *testing.T) {
 	cache := new(MockURLCacheRepository)
 	repo := new(MockURLRepository)
 
-	cache.On("GetByOriginalURL", ctx, original).Return(expected, nil)
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(expected, nil)
 
 	svc := service.NewURLService(repo, cache)
-	result, err := svc.Shorten(ctx, original)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
-	cache.AssertCalled(t, "GetByOriginalURL", ctx, original)
+	cache.AssertCalled(t, "GetByDedupeKey", ctx, "global:"+original)
 }
 
 func TestShorten_URLExistsInDB(t *testing.T) {
 	ctx := context.Background()
-	original := "https://example.com"
+	original := "https://example.com/"
 	expected := &entity.URL{
 		ShortID:   "abc123",
 		Original:  original,
@@ -101,13 +164,13 @@ func TestShorten_URLExistsInDB(t *testing.T) {
 	cache := new(MockURLCacheRepository)
 	repo := new(MockURLRepository)
 
-	cache.On("GetByOriginalURL", ctx, original).Return(nil, errors.New("cache miss"))
-	repo.On("FindByOriginalURL", ctx, original).Return(expected, nil)
-	cache.On("SetByOriginalURL", ctx, expected).Return(nil)
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(nil, errors.New("cache miss"))
+	repo.On("FindByOriginalURL", ctx, original, "").Return(expected, nil)
+	cache.On("SetByDedupeKey", ctx, expected).Return(nil)
 	cache.On("SetByShortID", ctx, expected).Return(nil)
 
 	svc := service.NewURLService(repo, cache)
-	result, err := svc.Shorten(ctx, original)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
@@ -115,21 +178,467 @@ func TestShorten_URLExistsInDB(t *testing.T) {
 
 func TestShorten_URLDoesNotExist(t *testing.T) {
 	ctx := context.Background()
-	original := "https://example.com"
+	original := "https://example.com/"
 
 	cache := new(MockURLCacheRepository)
 	repo := new(MockURLRepository)
 
-	cache.On("GetByOriginalURL", ctx, original).Return(nil, errors.New("cache miss"))
-	repo.On("FindByOriginalURL", ctx, original).Return((*entity.URL)(nil), nil)
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(nil, errors.New("cache miss"))
+	repo.On("FindByOriginalURL", ctx, original, "").Return((*entity.URL)(nil), nil)
 	repo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
-	cache.On("SetByOriginalURL", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByDedupeKey", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
 	cache.On("SetByShortID", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
 
 	svc := service.NewURLService(repo, cache)
-	result, err := svc.Shorten(ctx, original)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, original, result.Original)
 	assert.Len(t, result.ShortID, 6)
 }
+
+func TestShorten_ExistingURLWithDifferentRedirectType(t *testing.T) {
+	ctx := context.Background()
+	original := "https://example.com/"
+	existing := &entity.URL{
+		ShortID:  "abc123",
+		Original: original,
+	}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(existing, nil)
+	repo.On("FindByOriginalURL", ctx, original, "").Return(existing, nil)
+	repo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByDedupeKey", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{RedirectType: 301})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, existing.ShortID, result.ShortID)
+	assert.Equal(t, 301, result.RedirectStatus())
+	repo.AssertCalled(t, "Save", mock.Anything, mock.AnythingOfType("*entity.URL"))
+}
+
+func TestShorten_ExistingPermanentRedirectNotReusedByDefault(t *testing.T) {
+	ctx := context.Background()
+	original := "https://example.com/"
+	existing := &entity.URL{ShortID: "abc123", Original: original, RedirectType: 301}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(existing, nil)
+	repo.On("FindByOriginalURL", ctx, original, "").Return(existing, nil)
+	repo.On("Save", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByDedupeKey", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", mock.Anything, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, existing.ShortID, result.ShortID)
+	assert.Equal(t, entity.DefaultRedirectStatus, result.RedirectStatus())
+}
+
+func TestResolve_URLExistsInCache(t *testing.T) {
+	ctx := context.Background()
+	expected := &entity.URL{ShortID: "abc123", Original: "https://example.com"}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByShortID", ctx, "abc123").Return(expected, nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Resolve(ctx, "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	repo.AssertNotCalled(t, "FindByShortID", mock.Anything, mock.Anything)
+}
+
+func TestResolve_URLExistsInDB(t *testing.T) {
+	ctx := context.Background()
+	expected := &entity.URL{ShortID: "abc123", Original: "https://example.com"}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByShortID", ctx, "abc123").Return(nil, errors.New("cache miss"))
+	repo.On("FindByShortID", ctx, "abc123").Return(expected, nil)
+	cache.On("SetByShortID", ctx, expected).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Resolve(ctx, "", "abc123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	cache.AssertCalled(t, "SetByShortID", ctx, expected)
+}
+
+func TestResolve_URLNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByShortID", ctx, "missing").Return(nil, errors.New("cache miss"))
+	repo.On("FindByShortID", ctx, "missing").Return(nil, nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Resolve(ctx, "", "missing")
+
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	assert.Nil(t, result)
+}
+
+func TestShorten_WithAlias(t *testing.T) {
+	ctx := context.Background()
+	original := "https://example.com/spring"
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	repo.On("FindByShortID", ctx, "spring-sale").Return(nil, nil)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{Alias: "spring-sale"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "spring-sale", result.ShortID)
+	assert.True(t, result.Alias)
+	cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "SetByDedupeKey", mock.Anything, mock.Anything)
+}
+
+func TestShorten_AliasTaken(t *testing.T) {
+	ctx := context.Background()
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	repo.On("FindByShortID", ctx, "spring-sale").Return(&entity.URL{ShortID: "spring-sale"}, nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, "https://example.com", service.ShortenOptions{Alias: "spring-sale"})
+
+	assert.ErrorIs(t, err, service.ErrAliasTaken)
+	assert.Nil(t, result)
+	repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestShorten_AliasTakenConcurrently(t *testing.T) {
+	ctx := context.Background()
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	repo.On("FindByShortID", ctx, "spring-sale").Return(nil, nil)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(repository.ErrDuplicateShortID)
+
+	svc := service.NewURLService(repo, cache)
+	_, err := svc.Shorten(ctx, "https://example.com", service.ShortenOptions{Alias: "spring-sale"})
+
+	assert.ErrorIs(t, err, service.ErrAliasTaken)
+}
+
+func TestShorten_InvalidAlias(t *testing.T) {
+	svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+	_, err := svc.Shorten(context.Background(), "https://example.com", service.ShortenOptions{Alias: "api"})
+
+	assert.ErrorIs(t, err, service.ErrReservedAlias)
+}
+
+func TestShorten_DoesNotReuseCachedAlias(t *testing.T) {
+	ctx := context.Background()
+	original := "https://example.com/"
+	aliased := &entity.URL{ShortID: "spring-sale", Original: original, Alias: true}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByDedupeKey", ctx, "global:"+original).Return(aliased, nil)
+	repo.On("FindByOriginalURL", ctx, original, "").Return(nil, nil)
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByDedupeKey", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, original, service.ShortenOptions{})
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, "spring-sale", result.ShortID)
+	assert.False(t, result.Alias)
+}
+
+func TestShorten_InvalidExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+	_, err := svc.Shorten(context.Background(), "https://example.com", service.ShortenOptions{ExpiresAt: &past})
+
+	assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+}
+
+func TestShorten_WithLimitsAlwaysCreatesNewURL(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("SetByShortID", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Shorten(ctx, "https://example.com", service.ShortenOptions{ExpiresAt: &expiresAt, MaxClicks: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &expiresAt, result.ExpiresAt)
+	assert.Equal(t, int64(10), result.MaxClicks)
+	cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "SetByDedupeKey", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "FindByOriginalURL", mock.Anything, mock.Anything)
+}
+
+func TestResolve_Expired(t *testing.T) {
+	ctx := context.Background()
+	expiredAt := time.Now().Add(-time.Minute)
+	expired := &entity.URL{ShortID: "abc123", Original: "https://example.com", ExpiresAt: &expiredAt}
+
+	cache := new(MockURLCacheRepository)
+	cache.On("GetByShortID", ctx, "abc123").Return(expired, nil)
+
+	svc := service.NewURLService(new(MockURLRepository), cache)
+	result, err := svc.Resolve(ctx, "", "abc123")
+
+	assert.ErrorIs(t, err, service.ErrExpired)
+	assert.Nil(t, result)
+}
+
+func TestResolve_MaxClicks(t *testing.T) {
+	ctx := context.Background()
+	limited := &entity.URL{ShortID: "abc123", Original: "https://example.com", MaxClicks: 1}
+
+	t.Run("click counted", func(t *testing.T) {
+		cache := new(MockURLCacheRepository)
+		repo := new(MockURLRepository)
+		cache.On("GetByShortID", ctx, "abc123").Return(limited, nil)
+		repo.On("ConsumeClick", ctx, "abc123", int64(1)).Return(true, nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Resolve(ctx, "", "abc123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, limited, result)
+	})
+
+	t.Run("limit reached", func(t *testing.T) {
+		cache := new(MockURLCacheRepository)
+		repo := new(MockURLRepository)
+		cache.On("GetByShortID", ctx, "abc123").Return(limited, nil)
+		repo.On("ConsumeClick", ctx, "abc123", int64(1)).Return(false, nil)
+
+		svc := service.NewURLService(repo, cache)
+		_, err := svc.Resolve(ctx, "", "abc123")
+
+		assert.ErrorIs(t, err, service.ErrExpired)
+	})
+}
+
+func TestGet_Ownership(t *testing.T) {
+	ctx := context.Background()
+	owned := &entity.URL{ShortID: "abc123", Original: "https://example.com", OwnerID: "user-1"}
+
+	repo := new(MockURLRepository)
+	repo.On("FindByShortID", ctx, "abc123").Return(owned, nil)
+	repo.On("FindByShortID", ctx, "missing").Return(nil, nil)
+
+	svc := service.NewURLService(repo, new(MockURLCacheRepository))
+
+	result, err := svc.Get(ctx, "user-1", "", "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, owned, result)
+
+	_, err = svc.Get(ctx, "user-2", "", "abc123")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	_, err = svc.Get(ctx, "user-1", "", "missing")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestList_AppliesDefaultsAndFilter(t *testing.T) {
+	ctx := context.Background()
+	items := []*entity.URL{{ShortID: "abc123", OwnerID: "user-1"}}
+
+	repo := new(MockURLRepository)
+	repo.On("List", ctx, repository.URLListFilter{
+		OwnerID:   "user-1",
+		Query:     "example",
+		SortField: "created_at",
+		SortDesc:  true,
+		Skip:      0,
+		Limit:     service.DefaultPageSize,
+	}).Return(items, int64(1), nil)
+	repo.On("List", ctx, repository.URLListFilter{
+		OwnerID:   "user-1",
+		SortField: "short_id",
+		Skip:      200,
+		Limit:     service.MaxPageSize,
+	}).Return([]*entity.URL{}, int64(1), nil)
+
+	svc := service.NewURLService(repo, new(MockURLCacheRepository))
+
+	page, err := svc.List(ctx, "user-1", service.ListOptions{Query: "example"})
+	assert.NoError(t, err)
+	assert.Equal(t, items, page.Items)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, 1, page.Page)
+
+	page, err = svc.List(ctx, "user-1", service.ListOptions{Sort: "short_id", Page: 3, PageSize: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, service.MaxPageSize, page.PageSize)
+}
+
+func TestList_InvalidSort(t *testing.T) {
+	svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+	_, err := svc.List(context.Background(), "user-1", service.ListOptions{Sort: "password"})
+
+	assert.ErrorIs(t, err, service.ErrInvalidSort)
+
+	_, err = svc.List(context.Background(), "user-1", service.ListOptions{Sort: "-clicks"})
+	assert.ErrorIs(t, err, service.ErrInvalidSort, "clicks are not stored on every URL")
+}
+
+func TestUpdate_ChangesDestinationAndEvictsCache(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	existing := &entity.URL{ShortID: "abc123", Original: "https://old.example.com", OwnerID: "user-1", ExpiresAt: &expiresAt}
+	newDestination := "https://new.example.com"
+
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	repo.On("FindByShortID", ctx, "abc123").Return(existing, nil)
+	repo.On("Update", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+	cache.On("Delete", ctx, mock.MatchedBy(func(u *entity.URL) bool {
+		return u.Original == "https://old.example.com"
+	})).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	result, err := svc.Update(ctx, "user-1", "", "abc123", service.UpdateOptions{Original: &newDestination, RemoveExpiry: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, newDestination, result.Original)
+	assert.Nil(t, result.ExpiresAt)
+	cache.AssertExpectations(t)
+}
+
+func TestUpdate_Forbidden(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockURLRepository)
+	repo.On("FindByShortID", ctx, "abc123").Return(&entity.URL{ShortID: "abc123", OwnerID: "user-1"}, nil)
+
+	svc := service.NewURLService(repo, new(MockURLCacheRepository))
+	_, err := svc.Update(ctx, "user-2", "", "abc123", service.UpdateOptions{})
+
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestDelete_RemovesAndEvictsCache(t *testing.T) {
+	ctx := context.Background()
+	existing := &entity.URL{ShortID: "abc123", Original: "https://example.com", OwnerID: "user-1"}
+
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	repo.On("FindByShortID", ctx, "abc123").Return(existing, nil)
+	repo.On("Delete", ctx, "abc123").Return(nil)
+	cache.On("Delete", ctx, existing).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	err := svc.Delete(ctx, "user-1", "", "abc123")
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
+
+func TestShortenBatch_PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	existing := &entity.URL{ShortID: "abc123", Original: "https://example.com/old"}
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	cache.On("GetByDedupeKey", ctx, "global:https://example.com/old").Return(existing, nil)
+	cache.On("GetByDedupeKey", ctx, "global:https://example.com/new").Return(nil, errors.New("cache miss"))
+	repo.On("FindByOriginalURL", ctx, "https://example.com/new", "").Return((*entity.URL)(nil), nil)
+	repo.On("FindByShortID", ctx, "taken").Return(&entity.URL{ShortID: "taken"}, nil)
+	repo.On("FindByShortID", ctx, "launch").Return(nil, nil)
+	repo.On("SaveMany", ctx, mock.MatchedBy(func(urls []*entity.URL) bool { return len(urls) == 2 })).
+		Return([]error{nil, nil}, nil)
+	cache.On("SetMany", ctx, mock.AnythingOfType("[]*entity.URL"), mock.AnythingOfType("[]*entity.URL")).Return(nil)
+
+	svc := service.NewURLService(repo, cache, service.WithBatchConcurrency(2))
+	results, err := svc.ShortenBatch(ctx, []service.BatchItem{
+		{OriginalURL: "https://example.com/old"},
+		{OriginalURL: "https://example.com/new"},
+		{OriginalURL: "https://example.com/a", Options: service.ShortenOptions{Alias: "taken"}},
+		{OriginalURL: "https://example.com/b", Options: service.ShortenOptions{Alias: "launch"}},
+		{OriginalURL: "https://example.com/new"},
+		{OriginalURL: "https://example.com/c", Options: service.ShortenOptions{Alias: "api"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 6)
+	for i, r := range results {
+		assert.Equal(t, i, r.Index)
+	}
+	assert.Equal(t, existing, results[0].URL)
+	assert.NoError(t, results[1].Err)
+	assert.Len(t, results[1].URL.ShortID, 6)
+	assert.ErrorIs(t, results[2].Err, service.ErrAliasTaken)
+	assert.Nil(t, results[2].URL)
+	assert.Equal(t, "launch", results[3].URL.ShortID)
+	assert.Same(t, results[1].URL, results[4].URL)
+	assert.ErrorIs(t, results[5].Err, service.ErrReservedAlias)
+	repo.AssertNumberOfCalls(t, "SaveMany", 1)
+}
+
+func TestShortenBatch_AliasTakenConcurrently(t *testing.T) {
+	ctx := context.Background()
+
+	cache := new(MockURLCacheRepository)
+	repo := new(MockURLRepository)
+
+	repo.On("FindByShortID", ctx, "launch").Return(nil, nil)
+	repo.On("SaveMany", ctx, mock.AnythingOfType("[]*entity.URL")).
+		Return([]error{nil, repository.ErrDuplicateShortID}, nil)
+	cache.On("SetMany", ctx, mock.AnythingOfType("[]*entity.URL"), []*entity.URL(nil)).Return(nil)
+
+	svc := service.NewURLService(repo, cache)
+	results, err := svc.ShortenBatch(ctx, []service.BatchItem{
+		{OriginalURL: "https://example.com/a", Options: service.ShortenOptions{Alias: "launch"}},
+		{OriginalURL: "https://example.com/b", Options: service.ShortenOptions{Alias: "launch"}},
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, service.ErrAliasTaken)
+	assert.Nil(t, results[1].URL)
+}
+
+func TestShortenBatch_TooLarge(t *testing.T) {
+	svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+	_, err := svc.ShortenBatch(context.Background(), make([]service.BatchItem, service.MaxBatchSize+1))
+
+	assert.ErrorIs(t, err, service.ErrBatchTooLarge)
+}
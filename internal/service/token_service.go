@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+)
+
+const (
+	// DefaultAccessTokenTTL is the lifetime of access tokens when none is configured.
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is the lifetime of refresh tokens when none is configured.
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is malformed, expired, revoked or not a refresh token.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrWrongTokenType is returned when a refresh token is presented as an access token.
+	ErrWrongTokenType = errors.New("token is not an access token")
+)
+
+// TokenPair is the access and refresh token returned on login and refresh.
+//
+// Fields:
+// - AccessToken (string): The short-lived token sent with API requests.
+// - AccessExpiresAt (time.Time): The expiry of the access token.
+// - RefreshToken (string): The long-lived, single-use token exchanged for a new pair.
+// - RefreshExpiresAt (time.Time): The expiry of the refresh token.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// TokenConfig holds the lifetimes of issued tokens.
+//
+// Fields:
+// - AccessTTL (time.Duration): The lifetime of access tokens.
+// - RefreshTTL (time.Duration): The lifetime of refresh tokens.
+type TokenConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// TokenService defines the interface for issuing, refreshing and revoking tokens.
+// It also validates access tokens for AuthMiddleware, including the revocation check.
+//
+// Methods:
+// - Issue: Issues a new token pair for a user.
+// - Refresh: Exchanges a refresh token for a new token pair.
+// - Logout: Revokes an access token and, optionally, its refresh token.
+// - JWKS: Returns the public keys that verify issued tokens.
+// - ValidateToken: Validates an access token.
+// - IsRevoked: Checks whether a token was revoked.
+type TokenService interface {
+	auth.RevocationChecker
+
+	// Issue issues a new token pair for a user.
+	// The access token carries the role of the user and the scopes it grants.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - user (*entity.User): The user.
+	//
+	// Returns:
+	// - *TokenPair: The issued tokens.
+	// - error: An error if signing fails.
+	Issue(ctx context.Context, user *entity.User) (*TokenPair, error)
+
+	// Refresh exchanges a refresh token for a new token pair.
+	// Refresh tokens are single-use: the presented token is revoked, and presenting it again fails.
+	// The user is looked up again, so role changes take effect on the next refresh.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - refreshToken (string): The refresh token.
+	//
+	// Returns:
+	// - *TokenPair: The new tokens.
+	// - error: ErrInvalidRefreshToken, or an error if the operation fails.
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+
+	// Logout revokes an access token and, if given, a refresh token of the same user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - accessClaims (map[string]interface{}): The claims of the validated access token.
+	// - refreshToken (string): The refresh token to revoke, or empty.
+	//
+	// Returns:
+	// - error: ErrInvalidRefreshToken if the refresh token is invalid or belongs to another user, or an error if the operation fails.
+	Logout(ctx context.Context, accessClaims map[string]interface{}, refreshToken string) error
+
+	// JWKS returns the public keys that verify issued tokens.
+	JWKS() auth.JWKS
+}
+
+// tokenService is the implementation of TokenService.
+type tokenService struct {
+	issuer    auth.TokenIssuer
+	validator auth.TokenValidator
+	revoked   repository.RevokedTokenRepository
+	users     repository.UserRepository
+	cfg       TokenConfig
+}
+
+// NewTokenService creates a TokenService.
+//
+// Parameters:
+// - issuer (auth.TokenIssuer): Signs new tokens.
+// - validator (auth.TokenValidator): Verifies token signatures and expiry.
+// - revoked (repository.RevokedTokenRepository): Stores the IDs of revoked tokens.
+// - users (repository.UserRepository): Looks up the current role of users on refresh.
+// - cfg (TokenConfig): The token lifetimes; zero values fall back to the defaults.
+//
+// Returns:
+// - TokenService: The token service.
+func NewTokenService(issuer auth.TokenIssuer, validator auth.TokenValidator, revoked repository.RevokedTokenRepository, users repository.UserRepository, cfg TokenConfig) TokenService {
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = DefaultAccessTokenTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = DefaultRefreshTokenTTL
+	}
+	return &tokenService{issuer: issuer, validator: validator, revoked: revoked, users: users, cfg: cfg}
+}
+
+// Issue issues a new access and refresh token for a user.
+// Users stored before roles existed get auth.DefaultRole.
+func (s *tokenService) Issue(ctx context.Context, user *entity.User) (*TokenPair, error) {
+	role := user.Role
+	if role == "" {
+		role = auth.DefaultRole
+	}
+
+	access, err := s.issuer.IssueToken(auth.TokenClaims{
+		UserID: user.ID,
+		Type:   auth.TokenTypeAccess,
+		Role:   role,
+		Scopes: auth.ScopesForRole(role),
+	}, s.cfg.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.issuer.IssueToken(auth.TokenClaims{UserID: user.ID, Type: auth.TokenTypeRefresh}, s.cfg.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      access.Value,
+		AccessExpiresAt:  access.ExpiresAt,
+		RefreshToken:     refresh.Value,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}, nil
+}
+
+// Refresh validates and revokes a refresh token, then issues a new token pair for its user.
+// Revoking with SETNX semantics means two concurrent refreshes with the same token cannot both succeed.
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := s.validateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revokedNow, err := s.revokeClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !revokedNow {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.users.FindByID(ctx, claimString(claims, "user_id"))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.Issue(ctx, user)
+}
+
+// Logout revokes the access token and, if given, the refresh token.
+func (s *tokenService) Logout(ctx context.Context, accessClaims map[string]interface{}, refreshToken string) error {
+	if refreshToken != "" {
+		claims, err := s.validateRefreshToken(refreshToken)
+		if err != nil {
+			return err
+		}
+		if claimString(claims, "user_id") != claimString(accessClaims, "user_id") {
+			return ErrInvalidRefreshToken
+		}
+		if _, err := s.revokeClaims(ctx, claims); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.revokeClaims(ctx, accessClaims)
+	return err
+}
+
+// JWKS returns the public keys that verify issued tokens.
+func (s *tokenService) JWKS() auth.JWKS {
+	return s.issuer.JWKS()
+}
+
+// ValidateToken validates an access token; refresh tokens are rejected.
+func (s *tokenService) ValidateToken(tokenString string) (map[string]interface{}, error) {
+	claims, err := s.validator.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claimString(claims, "typ") != auth.TokenTypeAccess {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+// IsRevoked checks whether a token was revoked.
+func (s *tokenService) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.revoked.IsRevoked(ctx, tokenID)
+}
+
+// validateRefreshToken validates a refresh token, mapping every failure to ErrInvalidRefreshToken.
+func (s *tokenService) validateRefreshToken(refreshToken string) (map[string]interface{}, error) {
+	claims, err := s.validator.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+	}
+	if claimString(claims, "typ") != auth.TokenTypeRefresh || claimString(claims, "jti") == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// revokeClaims adds the token described by the claims to the revocation list until it expires.
+func (s *tokenService) revokeClaims(ctx context.Context, claims map[string]interface{}) (bool, error) {
+	tokenID := claimString(claims, "jti")
+	if tokenID == "" {
+		return false, auth.ErrInvalidToken
+	}
+
+	// JSON numbers are decoded as float64.
+	exp, _ := claims["exp"].(float64)
+	return s.revoked.Revoke(ctx, tokenID, time.Unix(int64(exp), 0))
+}
+
+// claimString returns a string claim, or an empty string if it is missing or not a string.
+func claimString(claims map[string]interface{}, name string) string {
+	v, _ := claims[name].(string)
+	return v
+}
@@ -0,0 +1,172 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRevokedTokenRepository is an in-memory RevokedTokenRepository.
+type fakeRevokedTokenRepository struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func (r *fakeRevokedTokenRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.revoked == nil {
+		r.revoked = map[string]time.Time{}
+	}
+	if _, ok := r.revoked[tokenID]; ok {
+		return false, nil
+	}
+	r.revoked[tokenID] = expiresAt
+	return true, nil
+}
+
+func (r *fakeRevokedTokenRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[tokenID]
+	return ok, nil
+}
+
+func newTestTokenService(t *testing.T) (service.TokenService, *fakeRevokedTokenRepository) {
+	svc, revoked, _ := newTestTokenServiceWithUsers(t)
+	return svc, revoked
+}
+
+func newTestTokenServiceWithUsers(t *testing.T) (service.TokenService, *fakeRevokedTokenRepository, *fakeUserRepository) {
+	t.Helper()
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(key)
+	require.NoError(t, err)
+
+	users := newFakeUserRepository()
+	users.users["alice"] = &entity.User{ID: "user-1", Username: "alice", Role: auth.RoleEditor}
+	users.users["bob"] = &entity.User{ID: "user-2", Username: "bob"}
+
+	revoked := &fakeRevokedTokenRepository{}
+	svc := service.NewTokenService(auth.NewJWTIssuer(keys), auth.NewJWTValidator(keys), revoked, users, service.TokenConfig{})
+	return svc, revoked, users
+}
+
+// testUser returns a user for token tests, with the ID used in the fake user repository.
+func testUser(id string) *entity.User {
+	return &entity.User{ID: id, Role: auth.RoleEditor}
+}
+
+func TestTokenService_IssueAndValidate(t *testing.T) {
+	svc, _ := newTestTokenService(t)
+
+	pair, err := svc.Issue(context.Background(), testUser("user-1"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(service.DefaultAccessTokenTTL), pair.AccessExpiresAt, 2*time.Second)
+	assert.WithinDuration(t, time.Now().Add(service.DefaultRefreshTokenTTL), pair.RefreshExpiresAt, 2*time.Second)
+
+	claims, err := svc.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["user_id"])
+
+	_, err = svc.ValidateToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrWrongTokenType)
+}
+
+func TestTokenService_RefreshRotates(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestTokenService(t)
+
+	pair, err := svc.Issue(ctx, testUser("user-1"))
+	require.NoError(t, err)
+
+	refreshed, err := svc.Refresh(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, refreshed.RefreshToken)
+
+	claims, err := svc.ValidateToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["user_id"])
+
+	// A refresh token can only be used once.
+	_, err = svc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	// Access tokens cannot be used to refresh.
+	_, err = svc.Refresh(ctx, refreshed.AccessToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+	_, err = svc.Refresh(ctx, "not-a-token")
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+}
+
+func TestTokenService_Logout(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestTokenService(t)
+
+	pair, err := svc.Issue(ctx, testUser("user-1"))
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Logout(ctx, claims, pair.RefreshToken))
+
+	revoked, err := svc.IsRevoked(ctx, claims["jti"].(string))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = svc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+}
+
+func TestTokenService_LogoutRejectsOtherUsersRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	svc, revoked := newTestTokenService(t)
+
+	mine, err := svc.Issue(ctx, testUser("user-1"))
+	require.NoError(t, err)
+	theirs, err := svc.Issue(ctx, testUser("user-2"))
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(mine.AccessToken)
+	require.NoError(t, err)
+
+	err = svc.Logout(ctx, claims, theirs.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	assert.Empty(t, revoked.revoked)
+}
+
+func TestTokenService_AccessTokenCarriesRoleScopes(t *testing.T) {
+	svc, _, _ := newTestTokenServiceWithUsers(t)
+
+	pair, err := svc.Issue(context.Background(), &entity.User{ID: "user-3", Role: auth.RoleViewer})
+	require.NoError(t, err)
+
+	claims, err := svc.ValidateToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleViewer, claims["role"])
+	assert.ElementsMatch(t, []interface{}{auth.ScopeLinksRead, auth.ScopeStatsRead}, claims["scopes"])
+}
+
+func TestTokenService_RefreshPicksUpRoleChanges(t *testing.T) {
+	ctx := context.Background()
+	svc, _, users := newTestTokenServiceWithUsers(t)
+
+	pair, err := svc.Issue(ctx, testUser("user-1"))
+	require.NoError(t, err)
+
+	users.users["alice"].Role = auth.RoleViewer
+
+	refreshed, err := svc.Refresh(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	claims, err := svc.ValidateToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleViewer, claims["role"])
+}
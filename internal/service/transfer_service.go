@@ -0,0 +1,764 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// FormatCSV selects comma-separated values with a header row.
+	FormatCSV = "csv"
+	// FormatJSONL selects one JSON object per line.
+	FormatJSONL = "jsonl"
+
+	// ConflictSkip keeps the existing URL when an imported short ID is already taken.
+	ConflictSkip = "skip"
+	// ConflictOverwrite replaces the existing URL when an imported short ID is already taken.
+	ConflictOverwrite = "overwrite"
+
+	// MaxReportedImportErrors is the number of row errors kept in an import report; later ones are only counted.
+	MaxReportedImportErrors = 100
+
+	// importBatchSize is the number of rows stored per repository call.
+	importBatchSize = 1000
+	// maxJSONLLineBytes is the longest JSONL line accepted on import.
+	maxJSONLLineBytes = 1 << 20
+)
+
+var (
+	// ErrUnsupportedFormat is returned when an import or export is requested in an unknown format.
+	ErrUnsupportedFormat = errors.New("format must be \"csv\" or \"jsonl\"")
+	// ErrInvalidConflictMode is returned when an import is requested with an unknown conflict mode.
+	ErrInvalidConflictMode = errors.New("on_conflict must be \"skip\" or \"overwrite\"")
+	// ErrMissingImportColumns is returned when a CSV import has no short_id or original_url column.
+	ErrMissingImportColumns = errors.New("CSV header must contain short_id and original_url")
+	// ErrInvalidShortID is returned for an imported row whose short ID cannot be served.
+	ErrInvalidShortID = errors.New("short_id must be 1-32 characters of letters, digits, '-' or '_' and start with a letter or digit")
+	// ErrInvalidImportURL is returned for an imported row whose original URL is not an absolute http or https URL.
+	ErrInvalidImportURL = errors.New("original_url must be an absolute http or https URL")
+	// ErrInvalidRedirectType is returned for an imported row with an unsupported redirect type.
+	ErrInvalidRedirectType = errors.New("redirect_type must be 301, 302, 307 or 308")
+	// ErrNegativeCount is returned for an imported row with a negative click limit or click count.
+	ErrNegativeCount = errors.New("max_clicks and clicks must not be negative")
+	// ErrInvalidPasswordHash is returned for an imported row whose password hash is not a bcrypt hash.
+	ErrInvalidPasswordHash = errors.New("password_hash must be a bcrypt hash")
+)
+
+// transferColumns lists the CSV columns, in the order they are exported.
+var transferColumns = []string{
+	"short_id", "original_url", "owner_id", "alias", "redirect_type",
+	"expires_at", "max_clicks", "clicks", "created_at", "dedupe",
+	"password_hash", "interstitial", "forward_query", "redirect_rules", "variants",
+	"domain",
+}
+
+// ImportOptions holds the settings of an import.
+//
+// Fields:
+// - Format (string): FormatCSV or FormatJSONL.
+// - OnConflict (string): ConflictSkip or ConflictOverwrite. Defaults to ConflictSkip.
+// - Progress (func(ImportReport)): If set, called with the running totals after every stored batch.
+type ImportOptions struct {
+	Format     string
+	OnConflict string
+	Progress   func(ImportReport)
+}
+
+// ExportOptions holds the settings of an export.
+//
+// Fields:
+// - Format (string): FormatCSV or FormatJSONL.
+// - OwnerID (string): If set, only URLs created by this user are exported.
+type ExportOptions struct {
+	Format  string
+	OwnerID string
+}
+
+// ImportRowError describes a row that could not be imported.
+//
+// Fields:
+// - Line (int): The 1-based line of the row in the input.
+// - ShortID (string): The short ID of the row, if it could be read.
+// - Err (error): The reason the row was rejected.
+type ImportRowError struct {
+	Line    int
+	ShortID string
+	Err     error
+}
+
+// ImportReport holds the totals of an import.
+//
+// Fields:
+// - Processed (int64): The number of rows read.
+// - Imported (int64): The number of rows stored under a new short ID.
+// - Overwritten (int64): The number of rows that replaced an existing URL.
+// - Skipped (int64): The number of rows skipped because their short ID was taken.
+// - Failed (int64): The number of invalid or unwritable rows.
+// - Errors ([]ImportRowError): The first MaxReportedImportErrors failed rows.
+type ImportReport struct {
+	Processed   int64
+	Imported    int64
+	Overwritten int64
+	Skipped     int64
+	Failed      int64
+	Errors      []ImportRowError
+}
+
+// fail counts a failed row and keeps its error while there is room in the report.
+func (r *ImportReport) fail(line int, shortID string, err error) {
+	r.Failed++
+	if len(r.Errors) < MaxReportedImportErrors {
+		r.Errors = append(r.Errors, ImportRowError{Line: line, ShortID: shortID, Err: err})
+	}
+}
+
+// TransferService defines the interface for moving URL mappings in and out of the shortener in bulk.
+//
+// Methods:
+// - Import: Stores the URL mappings read from CSV or JSONL, keeping their short IDs.
+// - Export: Writes every URL mapping as CSV or JSONL.
+type TransferService interface {
+	// Import stores the URL mappings read from CSV or JSONL, keeping their short IDs.
+	// Rows are streamed and stored in batches, so inputs of any size can be imported.
+	// Rows with a domain keep their short ID on that custom domain; they are served once the domain is registered and verified.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - r (io.Reader): The input.
+	// - opts (ImportOptions): The format, conflict mode and progress callback.
+	//
+	// Returns:
+	// - *ImportReport: The totals of the rows processed so far, also when an error is returned.
+	// - error: ErrUnsupportedFormat, ErrInvalidConflictMode, ErrMissingImportColumns, or an error if reading or storing fails.
+	Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+
+	// Export writes every URL mapping as CSV or JSONL, streaming them from the repository.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - w (io.Writer): The output.
+	// - opts (ExportOptions): The format and owner filter.
+	//
+	// Returns:
+	// - int64: The number of URL mappings written.
+	// - error: ErrUnsupportedFormat, or an error if reading or writing fails.
+	Export(ctx context.Context, w io.Writer, opts ExportOptions) (int64, error)
+}
+
+// transferService is the implementation of TransferService.
+type transferService struct {
+	repo          repository.URLRepository
+	cacheRepo     repository.URLCacheRepository
+	canonicalizer Canonicalizer
+	destinations  DestinationPolicy
+}
+
+// TransferServiceOption configures optional settings of the transfer service.
+type TransferServiceOption func(*transferService)
+
+// WithImportCanonicalizer sets how the original URLs of imported rows are canonicalized.
+// It should match the canonicalizer of the URL service, so imported URLs are reused by later shorten requests.
+//
+// Parameters:
+// - c (Canonicalizer): The canonicalizer. Without this option, tracking parameters are kept.
+//
+// Returns:
+// - TransferServiceOption: The option applying the canonicalizer.
+func WithImportCanonicalizer(c Canonicalizer) TransferServiceOption {
+	return func(s *transferService) {
+		s.canonicalizer = c
+	}
+}
+
+// WithImportDestinationPolicy screens the destinations of imported rows, including those of their redirect rules
+// and variants, with the policy applied to every other write. It should match the policy of the URL service.
+//
+// Parameters:
+// - policy (DestinationPolicy): The policy. Without this option, every destination is accepted.
+//
+// Returns:
+// - TransferServiceOption: The option applying the policy.
+func WithImportDestinationPolicy(policy DestinationPolicy) TransferServiceOption {
+	return func(s *transferService) {
+		s.destinations = policy
+	}
+}
+
+// NewTransferService creates a TransferService.
+//
+// Parameters:
+// - repo (repository.URLRepository): The repository for persistent URL storage.
+// - cache (repository.URLCacheRepository): The cache evicted when an import overwrites a URL.
+// - opts (...TransferServiceOption): Optional settings.
+//
+// Returns:
+// - TransferService: The transfer service.
+func NewTransferService(repo repository.URLRepository, cache repository.URLCacheRepository, opts ...TransferServiceOption) TransferService {
+	s := &transferService{repo: repo, cacheRepo: cache}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Import reads rows one at a time and stores them importBatchSize at a time.
+//
+// Behavior:
+// - Invalid rows are counted and reported; they do not stop the import. So are rows with a destination
+// rejected by the destination policy.
+// - Replaced URLs are evicted from the cache under their previous keys.
+// - Reading or storing errors stop the import; rows stored before that stay stored.
+func (s *transferService) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	overwrite := false
+	switch opts.OnConflict {
+	case "", ConflictSkip:
+	case ConflictOverwrite:
+		overwrite = true
+	default:
+		return nil, ErrInvalidConflictMode
+	}
+	rows, err := newRecordReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{}
+	batch := make([]*entity.URL, 0, importBatchSize)
+	lines := make([]int, 0, importBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, replaced, err := s.repo.ImportMany(ctx, batch, overwrite)
+		if err != nil {
+			return err
+		}
+
+		previous := make(map[string]bool, len(replaced))
+		for _, url := range replaced {
+			previous[url.Key()] = true
+			_ = s.cacheRepo.Delete(ctx, url)
+		}
+		for i, url := range batch {
+			switch {
+			case errs[i] == nil && previous[url.Key()]:
+				report.Overwritten++
+			case errs[i] == nil:
+				report.Imported++
+			case errors.Is(errs[i], repository.ErrDuplicateShortID):
+				report.Skipped++
+			default:
+				report.fail(lines[i], url.ShortID, errs[i])
+			}
+		}
+
+		batch, lines = batch[:0], lines[:0]
+		if opts.Progress != nil {
+			opts.Progress(*report)
+		}
+		return nil
+	}
+
+	for {
+		line, rec, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *recordError
+		if errors.As(err, &rowErr) {
+			report.Processed++
+			report.fail(line, "", rowErr.err)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.Processed++
+		url, err := rec.toURL(ctx, s.destinations)
+		if err != nil {
+			report.fail(line, rec.ShortID, err)
+			continue
+		}
+		url.Canonical = canonicalDestination(s.canonicalizer, url.Original)
+		batch = append(batch, url)
+		lines = append(lines, line)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// Export streams URL mappings from the repository to the writer.
+func (s *transferService) Export(ctx context.Context, w io.Writer, opts ExportOptions) (int64, error) {
+	out, err := newRecordWriter(w, opts.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	err = s.repo.ForEach(ctx, opts.OwnerID, func(url *entity.URL) error {
+		if err := out.write(newTransferRecord(url)); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	return written, out.flush()
+}
+
+// transferRecord is one URL mapping as it is imported and exported.
+type transferRecord struct {
+	ShortID       string                 `json:"short_id"`
+	Domain        string                 `json:"domain,omitempty"`
+	OriginalURL   string                 `json:"original_url"`
+	OwnerID       string                 `json:"owner_id,omitempty"`
+	Alias         bool                   `json:"alias,omitempty"`
+	RedirectType  int                    `json:"redirect_type,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
+	MaxClicks     int64                  `json:"max_clicks,omitempty"`
+	Clicks        int64                  `json:"clicks,omitempty"`
+	CreatedAt     *time.Time             `json:"created_at,omitempty"`
+	Dedupe        string                 `json:"dedupe,omitempty"`
+	PasswordHash  string                 `json:"password_hash,omitempty"`
+	Interstitial  bool                   `json:"interstitial,omitempty"`
+	ForwardQuery  bool                   `json:"forward_query,omitempty"`
+	RedirectRules []transferRedirectRule `json:"redirect_rules,omitempty"`
+	Variants      []transferVariant      `json:"variants,omitempty"`
+}
+
+// transferRedirectRule is a redirect rule as it is imported and exported. CSV rows hold the rules of a URL
+// as a JSON array of these objects.
+type transferRedirectRule struct {
+	Destination string     `json:"destination"`
+	OS          []string   `json:"os,omitempty"`
+	Devices     []string   `json:"devices,omitempty"`
+	Languages   []string   `json:"languages,omitempty"`
+	Countries   []string   `json:"countries,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+// transferVariant is a variant as it is imported and exported. CSV rows hold the variants of a URL
+// as a JSON array of these objects.
+type transferVariant struct {
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// newTransferRecord converts a URL entity to its transfer representation.
+func newTransferRecord(url *entity.URL) transferRecord {
+	rec := transferRecord{
+		ShortID:      url.ShortID,
+		Domain:       url.Domain,
+		OriginalURL:  url.Original,
+		OwnerID:      url.OwnerID,
+		Alias:        url.Alias,
+		RedirectType: url.RedirectType,
+		ExpiresAt:    url.ExpiresAt,
+		MaxClicks:    url.MaxClicks,
+		Clicks:       url.Clicks,
+		Dedupe:       url.Dedupe,
+		PasswordHash: url.PasswordHash,
+		Interstitial: url.Interstitial,
+		ForwardQuery: url.ForwardQuery,
+	}
+	for _, rule := range url.RedirectRules {
+		rec.RedirectRules = append(rec.RedirectRules, transferRedirectRule(rule))
+	}
+	for _, variant := range url.Variants {
+		rec.Variants = append(rec.Variants, transferVariant(variant))
+	}
+	if !url.CreatedAt.IsZero() {
+		createdAt := url.CreatedAt
+		rec.CreatedAt = &createdAt
+	}
+	return rec
+}
+
+// toURL validates an imported record, screens its destinations with a policy, and converts it to a URL entity.
+func (rec transferRecord) toURL(ctx context.Context, policy DestinationPolicy) (*entity.URL, error) {
+	if err := validateImportedShortID(rec.ShortID); err != nil {
+		return nil, err
+	}
+	if rec.Domain != "" {
+		domain, err := normalizeCustomDomain(rec.Domain)
+		if err != nil {
+			return nil, err
+		}
+		rec.Domain = domain
+	}
+	if !isAbsoluteHTTPURL(rec.OriginalURL) {
+		return nil, ErrInvalidImportURL
+	}
+	if err := CheckDestination(ctx, policy, rec.OriginalURL); err != nil {
+		return nil, err
+	}
+	switch rec.RedirectType {
+	case 0, 301, 302, 307, 308:
+	default:
+		return nil, ErrInvalidRedirectType
+	}
+	if rec.MaxClicks < 0 || rec.Clicks < 0 {
+		return nil, ErrNegativeCount
+	}
+	if rec.Dedupe == entity.DedupeGlobal {
+		rec.Dedupe = ""
+	} else if rec.Dedupe != "" {
+		if err := ValidateDedupe(rec.Dedupe); err != nil {
+			return nil, err
+		}
+	}
+	if rec.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(rec.PasswordHash)); err != nil {
+			return nil, ErrInvalidPasswordHash
+		}
+	}
+
+	url := &entity.URL{
+		ShortID:      rec.ShortID,
+		Domain:       rec.Domain,
+		Original:     rec.OriginalURL,
+		OwnerID:      rec.OwnerID,
+		Alias:        rec.Alias,
+		RedirectType: rec.RedirectType,
+		ExpiresAt:    rec.ExpiresAt,
+		MaxClicks:    rec.MaxClicks,
+		Clicks:       rec.Clicks,
+		Dedupe:       rec.Dedupe,
+		PasswordHash: rec.PasswordHash,
+		Interstitial: rec.Interstitial,
+		ForwardQuery: rec.ForwardQuery,
+	}
+	for _, rule := range rec.RedirectRules {
+		url.RedirectRules = append(url.RedirectRules, entity.RedirectRule(rule))
+	}
+	rules, err := normalizeRedirectRules(ctx, policy, url.RedirectRules)
+	if err != nil {
+		return nil, err
+	}
+	url.RedirectRules = rules
+	for _, variant := range rec.Variants {
+		url.Variants = append(url.Variants, entity.Variant(variant))
+	}
+	if url.Variants, err = normalizeVariants(ctx, policy, url.Variants); err != nil {
+		return nil, err
+	}
+	if rec.CreatedAt != nil {
+		url.CreatedAt = *rec.CreatedAt
+	}
+	return url, nil
+}
+
+// validateImportedShortID checks that an imported short ID can be served.
+// Imported IDs keep whatever length the previous shortener used, so only the charset,
+// the maximum length and the reserved words of aliases apply.
+func validateImportedShortID(shortID string) error {
+	if shortID == "" || len(shortID) > MaxAliasLength || !aliasPattern.MatchString(shortID) {
+		return ErrInvalidShortID
+	}
+	if _, ok := reservedAliases[strings.ToLower(shortID)]; ok {
+		return ErrReservedAlias
+	}
+	return nil
+}
+
+// recordError wraps an error that only affects one input row, so the import can go on.
+type recordError struct {
+	err error
+}
+
+func (e *recordError) Error() string {
+	return e.err.Error()
+}
+
+// recordReader reads transfer records one at a time.
+// next returns the line of the record, io.EOF at the end of the input, or a *recordError for a malformed row.
+type recordReader interface {
+	next() (int, transferRecord, error)
+}
+
+// newRecordReader returns a reader for the given format.
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVRecordReader(r)
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineBytes)
+		return &jsonlRecordReader{scanner: scanner}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// csvRecordReader reads CSV rows, locating fields by the column names of the header row.
+type csvRecordReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newCSVRecordReader reads the header row and checks that the required columns are present.
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrMissingImportColumns
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["short_id"]; !ok {
+		return nil, ErrMissingImportColumns
+	}
+	if _, ok := columns["original_url"]; !ok {
+		return nil, ErrMissingImportColumns
+	}
+	return &csvRecordReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvRecordReader) next() (int, transferRecord, error) {
+	fields, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, transferRecord{}, &recordError{err: err}
+		}
+		return 0, transferRecord{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	rec := transferRecord{
+		ShortID:      field("short_id"),
+		Domain:       field("domain"),
+		OriginalURL:  field("original_url"),
+		OwnerID:      field("owner_id"),
+		Dedupe:       field("dedupe"),
+		PasswordHash: field("password_hash"),
+	}
+	if v := field("alias"); v != "" {
+		if rec.Alias, err = strconv.ParseBool(v); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid alias %q", v)}
+		}
+	}
+	if v := field("interstitial"); v != "" {
+		if rec.Interstitial, err = strconv.ParseBool(v); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid interstitial %q", v)}
+		}
+	}
+	if v := field("redirect_rules"); v != "" {
+		if err := json.Unmarshal([]byte(v), &rec.RedirectRules); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid redirect_rules: %v", err)}
+		}
+	}
+	if v := field("variants"); v != "" {
+		if err := json.Unmarshal([]byte(v), &rec.Variants); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid variants: %v", err)}
+		}
+	}
+	if v := field("forward_query"); v != "" {
+		if rec.ForwardQuery, err = strconv.ParseBool(v); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid forward_query %q", v)}
+		}
+	}
+	if v := field("redirect_type"); v != "" {
+		if rec.RedirectType, err = strconv.Atoi(v); err != nil {
+			return line, rec, &recordError{err: ErrInvalidRedirectType}
+		}
+	}
+	if v := field("max_clicks"); v != "" {
+		if rec.MaxClicks, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid max_clicks %q", v)}
+		}
+	}
+	if v := field("clicks"); v != "" {
+		if rec.Clicks, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return line, rec, &recordError{err: fmt.Errorf("invalid clicks %q", v)}
+		}
+	}
+	if rec.ExpiresAt, err = parseOptionalTime(field("expires_at")); err != nil {
+		return line, rec, &recordError{err: fmt.Errorf("invalid expires_at: %w", err)}
+	}
+	if rec.CreatedAt, err = parseOptionalTime(field("created_at")); err != nil {
+		return line, rec, &recordError{err: fmt.Errorf("invalid created_at: %w", err)}
+	}
+	return line, rec, nil
+}
+
+// jsonlRecordReader reads one JSON object per line, skipping blank lines.
+type jsonlRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlRecordReader) next() (int, transferRecord, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := r.scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		var rec transferRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return r.line, rec, &recordError{err: err}
+		}
+		return r.line, rec, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return r.line, transferRecord{}, err
+	}
+	return r.line, transferRecord{}, io.EOF
+}
+
+// recordWriter writes transfer records in one format.
+type recordWriter interface {
+	write(rec transferRecord) error
+	flush() error
+}
+
+// newRecordWriter returns a writer for the given format. CSV writers start with the header row.
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(transferColumns); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{writer: writer, fields: make([]string, len(transferColumns))}, nil
+	case FormatJSONL:
+		return &jsonlRecordWriter{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// csvRecordWriter writes records as CSV rows in the order of transferColumns.
+type csvRecordWriter struct {
+	writer *csv.Writer
+	fields []string
+}
+
+func (w *csvRecordWriter) write(rec transferRecord) error {
+	w.fields[0] = rec.ShortID
+	w.fields[1] = rec.OriginalURL
+	w.fields[2] = rec.OwnerID
+	w.fields[3] = strconv.FormatBool(rec.Alias)
+	w.fields[4] = formatOptionalInt(int64(rec.RedirectType))
+	w.fields[5] = formatOptionalTime(rec.ExpiresAt)
+	w.fields[6] = formatOptionalInt(rec.MaxClicks)
+	w.fields[7] = strconv.FormatInt(rec.Clicks, 10)
+	w.fields[8] = formatOptionalTime(rec.CreatedAt)
+	w.fields[9] = rec.Dedupe
+	w.fields[10] = rec.PasswordHash
+	w.fields[11] = strconv.FormatBool(rec.Interstitial)
+	w.fields[12] = strconv.FormatBool(rec.ForwardQuery)
+	var err error
+	if w.fields[13], err = formatOptionalJSON(rec.RedirectRules, len(rec.RedirectRules) == 0); err != nil {
+		return err
+	}
+	if w.fields[14], err = formatOptionalJSON(rec.Variants, len(rec.Variants) == 0); err != nil {
+		return err
+	}
+	w.fields[15] = rec.Domain
+	return w.writer.Write(w.fields)
+}
+
+func (w *csvRecordWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonlRecordWriter writes records as one JSON object per line.
+type jsonlRecordWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlRecordWriter) write(rec transferRecord) error {
+	return w.encoder.Encode(rec)
+}
+
+func (w *jsonlRecordWriter) flush() error {
+	return nil
+}
+
+// parseOptionalTime parses an RFC 3339 time, returning nil for an empty string.
+func parseOptionalTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// formatOptionalTime formats a time as RFC 3339, returning an empty string for nil.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatOptionalJSON encodes a value as JSON, returning an empty string if it is empty.
+func formatOptionalJSON(v any, empty bool) (string, error) {
+	if empty {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// formatOptionalInt formats an integer, returning an empty string for zero.
+func formatOptionalInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// isAbsoluteHTTPURL reports whether a URL parses as an absolute http or https URL with a host.
+func isAbsoluteHTTPURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport_CSVValidatesAndSkipsConflicts(t *testing.T) {
+	ctx := context.Background()
+	input := strings.Join([]string{
+		"original_url,short_id,max_clicks,created_at",
+		"https://example.com/a,a1,,2023-05-01T10:00:00Z",
+		"ftp://example.com/b,b2,,",
+		"https://example.com/c,api,,",
+		"https://example.com/d,d4,-1,",
+		"https://example.com/e,e5,10,",
+	}, "\n")
+
+	repo := new(MockURLRepository)
+	repo.On("ImportMany", ctx, mock.MatchedBy(func(urls []*entity.URL) bool {
+		return len(urls) == 2 && urls[0].ShortID == "a1" && urls[1].MaxClicks == 10 &&
+			urls[0].CreatedAt.Equal(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+	}), false).Return([]error{nil, repository.ErrDuplicateShortID}, nil, nil)
+
+	var progress []service.ImportReport
+	svc := service.NewTransferService(repo, new(MockURLCacheRepository))
+	report, err := svc.Import(ctx, strings.NewReader(input), service.ImportOptions{
+		Format:   service.FormatCSV,
+		Progress: func(r service.ImportReport) { progress = append(progress, r) },
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), report.Processed)
+	assert.Equal(t, int64(1), report.Imported)
+	assert.Equal(t, int64(1), report.Skipped)
+	assert.Equal(t, int64(3), report.Failed)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, 3, report.Errors[0].Line)
+	assert.ErrorIs(t, report.Errors[0].Err, service.ErrInvalidImportURL)
+	assert.ErrorIs(t, report.Errors[1].Err, service.ErrReservedAlias)
+	assert.ErrorIs(t, report.Errors[2].Err, service.ErrNegativeCount)
+	assert.Len(t, progress, 1)
+}
+
+func TestImport_JSONLOverwriteEvictsCache(t *testing.T) {
+	ctx := context.Background()
+	input := `{"short_id":"a1","original_url":"https://example.com/new"}
+
+{"short_id":"b2","original_url":"https://example.com/b"}
+not json
+`
+	previous := &entity.URL{ShortID: "a1", Original: "https://example.com/old"}
+
+	repo := new(MockURLRepository)
+	cache := new(MockURLCacheRepository)
+	repo.On("ImportMany", ctx, mock.AnythingOfType("[]*entity.URL"), true).
+		Return([]error{nil, nil}, []*entity.URL{previous}, nil)
+	cache.On("Delete", ctx, previous).Return(nil)
+
+	svc := service.NewTransferService(repo, cache)
+	report, err := svc.Import(ctx, strings.NewReader(input), service.ImportOptions{
+		Format:     service.FormatJSONL,
+		OnConflict: service.ConflictOverwrite,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Processed)
+	assert.Equal(t, int64(1), report.Overwritten)
+	assert.Equal(t, int64(1), report.Imported)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, 4, report.Errors[0].Line)
+	cache.AssertExpectations(t)
+}
+
+func TestImport_KeepsUTMFieldsInCanonicalURL(t *testing.T) {
+	ctx := context.Background()
+	input := `{"short_id":"a1","original_url":"https://example.com/docs?utm_campaign=spring&fbclid=abc"}
+`
+
+	repo := new(MockURLRepository)
+	repo.On("ImportMany", ctx, mock.MatchedBy(func(urls []*entity.URL) bool {
+		return len(urls) == 1 && urls[0].Canonical == "https://example.com/docs?utm_campaign=spring"
+	}), false).Return([]error{nil}, nil, nil)
+
+	svc := service.NewTransferService(repo, new(MockURLCacheRepository),
+		service.WithImportCanonicalizer(service.Canonicalizer{DropTrackingParams: true}))
+	report, err := svc.Import(ctx, strings.NewReader(input), service.ImportOptions{Format: service.FormatJSONL})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Imported)
+	repo.AssertExpectations(t)
+}
+
+func TestImport_ScreensDestinations(t *testing.T) {
+	ctx := context.Background()
+	input := strings.Join([]string{
+		`{"short_id":"a1","original_url":"https://example.com/a"}`,
+		`{"short_id":"b2","original_url":"http://10.0.0.1/admin"}`,
+		`{"short_id":"c3","original_url":"https://example.com/c","redirect_rules":[{"destination":"http://10.0.0.1/ios","os":["ios"]}]}`,
+	}, "\n")
+
+	repo := new(MockURLRepository)
+	repo.On("ImportMany", ctx, mock.MatchedBy(func(urls []*entity.URL) bool {
+		return len(urls) == 1 && urls[0].ShortID == "a1"
+	}), false).Return([]error{nil}, nil, nil)
+
+	svc := service.NewTransferService(repo, new(MockURLCacheRepository),
+		service.WithImportDestinationPolicy(service.NewPrivateAddressPolicy(nil)))
+	report, err := svc.Import(ctx, strings.NewReader(input), service.ImportOptions{Format: service.FormatJSONL})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Imported)
+	assert.Equal(t, int64(2), report.Failed)
+	require.Len(t, report.Errors, 2)
+	assert.ErrorIs(t, report.Errors[0].Err, service.ErrDestinationBlocked)
+	assert.ErrorIs(t, report.Errors[1].Err, service.ErrDestinationBlocked)
+	repo.AssertExpectations(t)
+}
+
+func TestImport_InvalidOptions(t *testing.T) {
+	svc := service.NewTransferService(new(MockURLRepository), new(MockURLCacheRepository))
+
+	_, err := svc.Import(context.Background(), strings.NewReader(""), service.ImportOptions{Format: "xml"})
+	assert.ErrorIs(t, err, service.ErrUnsupportedFormat)
+
+	_, err = svc.Import(context.Background(), strings.NewReader(""), service.ImportOptions{Format: service.FormatCSV, OnConflict: "merge"})
+	assert.ErrorIs(t, err, service.ErrInvalidConflictMode)
+
+	_, err = svc.Import(context.Background(), strings.NewReader("url,id\n"), service.ImportOptions{Format: service.FormatCSV})
+	assert.ErrorIs(t, err, service.ErrMissingImportColumns)
+}
+
+func TestExport_CSVAndJSONL(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	urls := []*entity.URL{
+		{
+			ShortID: "a1", Original: "https://example.com/a", OwnerID: "user-1", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Variants: []entity.Variant{{Name: "a", Destination: "https://example.com/a", Weight: 1}, {Name: "b", Destination: "https://example.com/a2", Weight: 3}},
+		},
+		{ShortID: "sale", Original: "https://example.com/b,c", Alias: true, RedirectType: 301, ExpiresAt: &expiresAt},
+	}
+
+	repo := new(MockURLRepository)
+	repo.On("ForEach", ctx, "", mock.Anything).Return(urls, nil)
+	svc := service.NewTransferService(repo, new(MockURLCacheRepository))
+
+	var csvOut bytes.Buffer
+	n, err := svc.Export(ctx, &csvOut, service.ExportOptions{Format: service.FormatCSV})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, "short_id,original_url,owner_id,alias,redirect_type,expires_at,max_clicks,clicks,created_at,dedupe,password_hash,interstitial,forward_query,redirect_rules,variants,domain\n"+
+		"a1,https://example.com/a,user-1,false,,,,0,2024-01-01T00:00:00Z,,,false,false,,"+
+		"\"[{\"\"name\"\":\"\"a\"\",\"\"destination\"\":\"\"https://example.com/a\"\",\"\"weight\"\":1},{\"\"name\"\":\"\"b\"\",\"\"destination\"\":\"\"https://example.com/a2\"\",\"\"weight\"\":3}]\",\n"+
+		"sale,\"https://example.com/b,c\",,true,301,2030-01-02T03:04:05Z,,0,,,,false,false,,,\n", csvOut.String())
+
+	var jsonlOut bytes.Buffer
+	_, err = svc.Export(ctx, &jsonlOut, service.ExportOptions{Format: service.FormatJSONL})
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(jsonlOut.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"short_id":"sale","original_url":"https://example.com/b,c","alias":true,"redirect_type":301,"expires_at":"2030-01-02T03:04:05Z"}`, lines[1])
+
+	// An export can be imported again unchanged.
+	repo.On("ImportMany", ctx, mock.MatchedBy(func(imported []*entity.URL) bool {
+		return len(imported) == 2 && imported[1].Alias && imported[1].ExpiresAt.Equal(expiresAt) && imported[0].OwnerID == "user-1" &&
+			len(imported[0].Variants) == 2 && imported[0].Variants[1].Weight == 3
+	}), false).Return([]error{nil, nil}, nil, nil)
+	report, err := svc.Import(ctx, &csvOut, service.ImportOptions{Format: service.FormatCSV})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Imported)
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the minimum number of bytes in a password.
+	MinPasswordLength = 8
+	// MaxPasswordLength is the maximum number of bytes bcrypt can hash.
+	MaxPasswordLength = 72
+)
+
+var (
+	// ErrUsernameTaken is returned when registering a username that already exists.
+	ErrUsernameTaken = errors.New("username is already taken")
+	// ErrInvalidPassword is returned when a password is too short or too long.
+	ErrInvalidPassword = errors.New("password must be between 8 and 72 bytes")
+	// ErrInvalidCredentials is returned when a username and password do not match an account.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidRole is returned when assigning a role that does not exist.
+	ErrInvalidRole = errors.New("role must be admin, editor or viewer")
+	// ErrUserNotFound is returned when a user ID does not match an account.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrBootstrapUserMismatch is returned when the bootstrap username belongs to an account with another ID,
+	// or the bootstrap ID to an account with another username.
+	ErrBootstrapUserMismatch = errors.New("existing account does not match the bootstrap user")
+)
+
+// dummyPasswordHash is compared against when a username does not exist, so failed logins
+// take the same time whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// UserService defines the interface for managing and authenticating user accounts.
+//
+// Methods:
+// - Register: Creates a new account.
+// - Authenticate: Verifies a username and password.
+// - EnsureUser: Creates a bootstrap account if it does not exist yet.
+// - SetRole: Assigns a role to a user.
+type UserService interface {
+	// Register creates a new account with a hashed password and the default role.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - username (string): The requested username.
+	// - password (string): The plaintext password.
+	//
+	// Returns:
+	// - *entity.User: The created user.
+	// - error: ErrInvalidPassword, ErrUsernameTaken, or an error if the operation fails.
+	Register(ctx context.Context, username, password string) (*entity.User, error)
+
+	// Authenticate verifies a username and password.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - username (string): The username.
+	// - password (string): The plaintext password.
+	//
+	// Returns:
+	// - *entity.User: The authenticated user.
+	// - error: ErrInvalidCredentials if they do not match, or an error if the lookup fails.
+	Authenticate(ctx context.Context, username, password string) (*entity.User, error)
+
+	// EnsureUser creates an account with a fixed ID and role if the username does not exist yet.
+	// An existing account without a role is given the role; one with another role keeps it.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - id (string): The user ID to assign. Empty means one is generated.
+	// - username (string): The username.
+	// - password (string): The plaintext password.
+	// - role (string): The role of the account.
+	//
+	// Returns:
+	// - error: ErrBootstrapUserMismatch if the username or the ID belongs to another account,
+	// or an error if the account cannot be created.
+	EnsureUser(ctx context.Context, id, username, password, role string) error
+
+	// SetRole assigns a role to a user.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context for the operation.
+	// - userID (string): The ID of the user.
+	// - role (string): The role: "admin", "editor" or "viewer".
+	//
+	// Returns:
+	// - *entity.User: The updated user.
+	// - error: ErrInvalidRole, ErrUserNotFound, or an error if the operation fails.
+	SetRole(ctx context.Context, userID, role string) (*entity.User, error)
+}
+
+type userService struct {
+	repo repository.UserRepository
+}
+
+// NewUserService creates a new instance of UserService.
+//
+// Parameters:
+// - repo (repository.UserRepository): The repository for user storage.
+//
+// Returns:
+// - UserService: An instance of the UserService interface.
+func NewUserService(repo repository.UserRepository) UserService {
+	return &userService{repo: repo}
+}
+
+// Register creates a new account with a bcrypt-hashed password.
+func (s *userService) Register(ctx context.Context, username, password string) (*entity.User, error) {
+	return s.create(ctx, "", username, password, auth.DefaultRole)
+}
+
+// Authenticate verifies a username and password against the stored bcrypt hash.
+//
+// Behavior:
+// - Usernames are matched case-insensitively.
+// - Unknown usernames still cost a bcrypt comparison to avoid leaking which accounts exist.
+func (s *userService) Authenticate(ctx context.Context, username, password string) (*entity.User, error) {
+	user, err := s.repo.FindByUsername(ctx, normalizeUsername(username))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// EnsureUser creates an account with a fixed ID and role if the username does not exist yet.
+//
+// Behavior:
+// - An existing account without a role is given the role.
+// - An existing account with another ID is reported rather than accepted, since the bootstrap
+// credentials would otherwise silently log in as a different account than the one configured. So is an ID
+// that already belongs to another username.
+// - An existing account with another role keeps it, since admins may change it, and the difference is logged.
+func (s *userService) EnsureUser(ctx context.Context, id, username, password, role string) error {
+	if !auth.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+	username = normalizeUsername(username)
+	existing, err := s.repo.FindByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		_, err = s.create(ctx, id, username, password, role)
+		if errors.Is(err, repository.ErrDuplicateUserID) {
+			return fmt.Errorf("%w: ID %q belongs to another account than %q", ErrBootstrapUserMismatch, id, username)
+		}
+		if !errors.Is(err, ErrUsernameTaken) {
+			return err
+		}
+		if existing, err = s.repo.FindByUsername(ctx, username); err != nil || existing == nil {
+			return err
+		}
+	}
+
+	if id != "" && existing.ID != id {
+		return fmt.Errorf("%w: %q has ID %q, want %q", ErrBootstrapUserMismatch, username, existing.ID, id)
+	}
+	switch existing.Role {
+	case role:
+		return nil
+	case "":
+		_, err = s.repo.UpdateRole(ctx, existing.ID, role)
+		return err
+	default:
+		log.Printf("bootstrap user %q keeps its role %q instead of %q", username, existing.Role, role)
+		return nil
+	}
+}
+
+// SetRole assigns a role to a user.
+func (s *userService) SetRole(ctx context.Context, userID, role string) (*entity.User, error) {
+	if !auth.IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	found, err := s.repo.UpdateRole(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	return s.repo.FindByID(ctx, userID)
+}
+
+// create hashes the password and stores a new user.
+func (s *userService) create(ctx context.Context, id, username, password, role string) (*entity.User, error) {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		ID:           id,
+		Username:     normalizeUsername(username),
+		PasswordHash: string(hash),
+		Role:         role,
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateUsername) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalizeUsername makes usernames case-insensitive and ignores surrounding whitespace.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/auth"
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/repository"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserRepository is an in-memory UserRepository keyed by username.
+type fakeUserRepository struct {
+	users map[string]*entity.User
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: make(map[string]*entity.User)}
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *entity.User) error {
+	if _, ok := r.users[user.Username]; ok {
+		return repository.ErrDuplicateUsername
+	}
+	for _, u := range r.users {
+		if user.ID != "" && u.ID == user.ID {
+			return repository.ErrDuplicateUserID
+		}
+	}
+	if user.ID == "" {
+		user.ID = "generated-" + user.Username
+	}
+	r.users[user.Username] = user
+	return nil
+}
+
+func (r *fakeUserRepository) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	return r.users[username], nil
+}
+
+func (r *fakeUserRepository) FindByID(ctx context.Context, id string) (*entity.User, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestUserService_RegisterAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewUserService(newFakeUserRepository())
+
+	user, err := svc.Register(ctx, "  Alice ", "correct-horse")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.NotEqual(t, "correct-horse", user.PasswordHash)
+
+	authenticated, err := svc.Authenticate(ctx, "ALICE", "correct-horse")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+
+	_, err = svc.Authenticate(ctx, "alice", "wrong-password")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	_, err = svc.Authenticate(ctx, "bob", "correct-horse")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+}
+
+func TestUserService_RegisterDuplicate(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewUserService(newFakeUserRepository())
+
+	_, err := svc.Register(ctx, "alice", "correct-horse")
+	require.NoError(t, err)
+
+	_, err = svc.Register(ctx, "alice", "another-password")
+	assert.ErrorIs(t, err, service.ErrUsernameTaken)
+}
+
+func TestUserService_RegisterInvalidPassword(t *testing.T) {
+	svc := service.NewUserService(newFakeUserRepository())
+
+	_, err := svc.Register(context.Background(), "alice", "short")
+	assert.ErrorIs(t, err, service.ErrInvalidPassword)
+}
+
+func (r *fakeUserRepository) UpdateRole(ctx context.Context, id, role string) (bool, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			u.Role = role
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestUserService_EnsureUser(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeUserRepository()
+	svc := service.NewUserService(repo)
+
+	require.NoError(t, svc.EnsureUser(ctx, "user-id-123", "admin", "password", auth.RoleAdmin))
+	require.NoError(t, svc.EnsureUser(ctx, "user-id-123", "Admin", "different", auth.RoleAdmin))
+
+	assert.Equal(t, "user-id-123", repo.users["admin"].ID)
+	assert.Equal(t, auth.RoleAdmin, repo.users["admin"].Role)
+
+	user, err := svc.Authenticate(ctx, "admin", "password")
+	require.NoError(t, err)
+	assert.Equal(t, "user-id-123", user.ID)
+}
+
+func TestUserService_EnsureUserAssignsRoleToLegacyAccount(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeUserRepository()
+	repo.users["admin"] = &entity.User{ID: "user-id-123", Username: "admin"}
+	svc := service.NewUserService(repo)
+
+	require.NoError(t, svc.EnsureUser(ctx, "user-id-123", "admin", "password", auth.RoleAdmin))
+
+	assert.Equal(t, auth.RoleAdmin, repo.users["admin"].Role)
+}
+
+func TestUserService_EnsureUserRejectsAccountWithAnotherID(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeUserRepository()
+	repo.users["admin"] = &entity.User{ID: "user-id-123", Username: "admin", Role: auth.RoleViewer}
+	svc := service.NewUserService(repo)
+
+	err := svc.EnsureUser(ctx, "other-id", "admin", "password", auth.RoleViewer)
+	assert.ErrorIs(t, err, service.ErrBootstrapUserMismatch)
+
+	require.NoError(t, svc.EnsureUser(ctx, "user-id-123", "admin", "password", auth.RoleAdmin))
+	assert.Equal(t, auth.RoleViewer, repo.users["admin"].Role, "a role changed by an admin is left alone")
+
+	err = svc.EnsureUser(ctx, "user-id-123", "root", "password", auth.RoleAdmin)
+	assert.ErrorIs(t, err, service.ErrBootstrapUserMismatch)
+	assert.ErrorContains(t, err, "ID \"user-id-123\" belongs to another account")
+}
+
+func TestUserService_RegisterAssignsDefaultRole(t *testing.T) {
+	svc := service.NewUserService(newFakeUserRepository())
+
+	user, err := svc.Register(context.Background(), "alice", "password123")
+	require.NoError(t, err)
+
+	assert.Equal(t, auth.DefaultRole, user.Role)
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewUserService(newFakeUserRepository())
+
+	user, err := svc.Register(ctx, "alice", "password123")
+	require.NoError(t, err)
+
+	updated, err := svc.SetRole(ctx, user.ID, auth.RoleViewer)
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleViewer, updated.Role)
+
+	_, err = svc.SetRole(ctx, user.ID, "superuser")
+	assert.ErrorIs(t, err, service.ErrInvalidRole)
+
+	_, err = svc.SetRole(ctx, "missing", auth.RoleViewer)
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+}
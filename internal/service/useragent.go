@@ -0,0 +1,118 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+// uaFamilies lists user agent tokens in match order. Order matters because most
+// browsers embed the tokens of the engines they derive from (e.g. Edge contains "Chrome").
+var uaFamilies = []struct {
+	token  string
+	family string
+}{
+	{"bot", "Bot"},
+	{"spider", "Bot"},
+	{"crawl", "Bot"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests", "Python"},
+	{"go-http-client", "Go"},
+	{"postmanruntime", "Postman"},
+	{"edg/", "Edge"},
+	{"edge/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"chromium/", "Chrome"},
+	{"safari/", "Safari"},
+	{"msie", "Internet Explorer"},
+	{"trident/", "Internet Explorer"},
+}
+
+// UserAgentFamily returns the browser or client family of a User-Agent header.
+//
+// Parameters:
+// - userAgent (string): The raw User-Agent header.
+//
+// Returns:
+// - string: The family name, "Unknown" for an empty header, or "Other" if no family matches.
+func UserAgentFamily(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown"
+	}
+	ua := strings.ToLower(userAgent)
+	for _, f := range uaFamilies {
+		if strings.Contains(ua, f.token) {
+			return f.family
+		}
+	}
+	return "Other"
+}
+
+// uaOperatingSystems lists user agent tokens of operating systems in match order. iOS and Android come first
+// because their user agents also contain "Mac OS X" and "Linux".
+var uaOperatingSystems = []struct {
+	token string
+	os    string
+}{
+	{"iphone", entity.OSiOS},
+	{"ipad", entity.OSiOS},
+	{"ipod", entity.OSiOS},
+	{"android", entity.OSAndroid},
+	{"cros", entity.OSChromeOS},
+	{"windows", entity.OSWindows},
+	{"macintosh", entity.OSMacOS},
+	{"mac os x", entity.OSMacOS},
+	{"linux", entity.OSLinux},
+}
+
+// UserAgentOS returns the operating system of a User-Agent header.
+// iPads running iPadOS 13 or later request desktop sites and are reported as entity.OSMacOS.
+//
+// Parameters:
+// - userAgent (string): The raw User-Agent header.
+//
+// Returns:
+// - string: One of the entity.OS* constants, or an empty string if no operating system matches.
+func UserAgentOS(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	for _, o := range uaOperatingSystems {
+		if strings.Contains(ua, o.token) {
+			return o.os
+		}
+	}
+	return ""
+}
+
+// UserAgentDevice returns the device type of a User-Agent header.
+//
+// Parameters:
+// - userAgent (string): The raw User-Agent header.
+//
+// Behavior:
+// - Bots are detected like in UserAgentFamily.
+// - iPads, Android devices without "Mobile" and user agents containing "Tablet" are tablets.
+// - iPhones, iPods and user agents containing "Mobi" are mobile devices; everything else is a desktop.
+//
+// Returns:
+// - string: One of the entity.Device* constants.
+func UserAgentDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case UserAgentFamily(userAgent) == "Bot":
+		return entity.DeviceBot
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return entity.DeviceTablet
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		return entity.DeviceMobile
+	default:
+		return entity.DeviceDesktop
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+)
+
+const (
+	// MinVariants and MaxVariants bound the number of variants a URL splitting its traffic may have.
+	MinVariants = 2
+	MaxVariants = 10
+	// MaxVariantWeight is the largest weight of a variant.
+	MaxVariantWeight = 1000
+	// MaxVariantNameLength is the longest name of a variant.
+	MaxVariantNameLength = 32
+)
+
+// ErrInvalidVariant is returned when variants are too few or too many, have an invalid or repeated name,
+// an invalid destination or weight, or are all paused.
+var ErrInvalidVariant = errors.New("invalid variant")
+
+// variantNamePattern matches the allowed variant names, which end up in cookies and click statistics.
+var variantNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ChooseVariant assigns a visitor to one of the variants of a URL.
+//
+// Parameters:
+// - variants ([]entity.Variant): The variants of the URL.
+// - sticky (string): The name of the variant the visitor was assigned to before, e.g. from a cookie.
+// - visitor (string): A key identifying the visitor. Empty picks at random.
+//
+// Behavior:
+// - Keeps the sticky variant while it exists and is not paused.
+// - Otherwise picks a variant with a probability proportional to its weight, using a hash of the visitor
+// so the same visitor keeps getting the same variant.
+//
+// Returns:
+// - *entity.Variant: The assigned variant, or nil if there are none or all are paused.
+func ChooseVariant(variants []entity.Variant, sticky, visitor string) *entity.Variant {
+	total := 0
+	for i := range variants {
+		if variants[i].Weight <= 0 {
+			continue
+		}
+		if sticky != "" && variants[i].Name == sticky {
+			return &variants[i]
+		}
+		total += variants[i].Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var point int
+	if visitor == "" {
+		point = rand.IntN(total)
+	} else {
+		sum := sha256.Sum256([]byte(visitor))
+		point = int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	}
+	for i := range variants {
+		if variants[i].Weight <= 0 {
+			continue
+		}
+		if point < variants[i].Weight {
+			return &variants[i]
+		}
+		point -= variants[i].Weight
+	}
+	return nil
+}
+
+// normalizeVariants validates variants and lowercases their names.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - policy (DestinationPolicy): The policy every variant destination must pass. Nil accepts every URL.
+// - variants ([]entity.Variant): The variants.
+//
+// Returns:
+// - []entity.Variant: The normalized variants, nil if there are none.
+// - error: ErrInvalidVariant, a *DestinationError for a rejected destination, or an error if a check fails.
+func normalizeVariants(ctx context.Context, policy DestinationPolicy, variants []entity.Variant) ([]entity.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < MinVariants || len(variants) > MaxVariants {
+		return nil, fmt.Errorf("%w: between %d and %d variants are allowed", ErrInvalidVariant, MinVariants, MaxVariants)
+	}
+
+	normalized := make([]entity.Variant, len(variants))
+	seen := make(map[string]bool, len(variants))
+	total := 0
+	for i, variant := range variants {
+		invalid := func(reason string, args ...any) error {
+			return fmt.Errorf("%w: variant %d: %s", ErrInvalidVariant, i+1, fmt.Sprintf(reason, args...))
+		}
+
+		variant.Name = strings.ToLower(strings.TrimSpace(variant.Name))
+		if variant.Name == "" || len(variant.Name) > MaxVariantNameLength || !variantNamePattern.MatchString(variant.Name) {
+			return nil, invalid("name must be 1 to %d letters, digits, '-' or '_'", MaxVariantNameLength)
+		}
+		if seen[variant.Name] {
+			return nil, invalid("duplicate name %q", variant.Name)
+		}
+		seen[variant.Name] = true
+
+		variant.Destination = strings.TrimSpace(variant.Destination)
+		if !isAbsoluteHTTPURL(variant.Destination) {
+			return nil, invalid("destination must be an absolute http or https URL")
+		}
+		if err := CheckDestination(ctx, policy, variant.Destination); err != nil {
+			return nil, err
+		}
+
+		if variant.Weight < 0 || variant.Weight > MaxVariantWeight {
+			return nil, invalid("weight must be between 0 and %d", MaxVariantWeight)
+		}
+		total += variant.Weight
+		normalized[i] = variant
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: at least one variant must have a positive weight", ErrInvalidVariant)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/guttosm/url-shortener/internal/entity"
+	"github.com/guttosm/url-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChooseVariant(t *testing.T) {
+	variants := []entity.Variant{
+		{Name: "a", Destination: "https://example.com/a", Weight: 1},
+		{Name: "b", Destination: "https://example.com/b", Weight: 3},
+		{Name: "paused", Destination: "https://example.com/c", Weight: 0},
+	}
+
+	t.Run("keeps the sticky variant", func(t *testing.T) {
+		assert.Equal(t, "a", service.ChooseVariant(variants, "a", "visitor-1").Name)
+	})
+
+	t.Run("reassigns visitors of paused or removed variants", func(t *testing.T) {
+		assert.NotEqual(t, "paused", service.ChooseVariant(variants, "paused", "visitor-1").Name)
+		assert.NotNil(t, service.ChooseVariant(variants, "gone", "visitor-1"))
+	})
+
+	t.Run("assigns the same visitor consistently", func(t *testing.T) {
+		first := service.ChooseVariant(variants, "", "visitor-1")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, service.ChooseVariant(variants, "", "visitor-1"))
+		}
+	})
+
+	t.Run("splits visitors by weight", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := 0; i < 4000; i++ {
+			counts[service.ChooseVariant(variants, "", "visitor-"+strconv.Itoa(i)).Name]++
+		}
+		assert.InDelta(t, 1000, counts["a"], 150)
+		assert.InDelta(t, 3000, counts["b"], 150)
+		assert.Zero(t, counts["paused"])
+	})
+
+	t.Run("random without a visitor", func(t *testing.T) {
+		assert.NotNil(t, service.ChooseVariant(variants, "", ""))
+	})
+
+	t.Run("none when all are paused", func(t *testing.T) {
+		assert.Nil(t, service.ChooseVariant([]entity.Variant{{Name: "a", Weight: 0}}, "a", "visitor-1"))
+		assert.Nil(t, service.ChooseVariant(nil, "", "visitor-1"))
+	})
+}
+
+func TestRedirectURL_Variants(t *testing.T) {
+	url := &entity.URL{
+		ShortID:  "split",
+		Original: "https://example.com/",
+		RedirectRules: []entity.RedirectRule{
+			{Destination: "https://apps.apple.com/app/id1", OS: []string{entity.OSiOS}},
+		},
+		Variants: []entity.Variant{
+			{Name: "a", Destination: "https://example.com/a", Weight: 1},
+			{Name: "b", Destination: "https://example.com/b", Weight: 1},
+		},
+	}
+
+	destination, variant := service.RedirectURL(url, service.Visit{IP: "198.51.100.7", UserAgent: desktopUA, Variant: "b"})
+	assert.Equal(t, "https://example.com/b", destination)
+	assert.Equal(t, "b", variant)
+
+	first, _ := service.RedirectURL(url, service.Visit{IP: "198.51.100.7", UserAgent: desktopUA})
+	again, _ := service.RedirectURL(url, service.Visit{IP: "198.51.100.7", UserAgent: desktopUA})
+	assert.Equal(t, first, again)
+
+	destination, variant = service.RedirectURL(url, service.Visit{IP: "198.51.100.7", UserAgent: iPhoneUA, Variant: "b"})
+	assert.Equal(t, "https://apps.apple.com/app/id1", destination)
+	assert.Empty(t, variant)
+}
+
+func TestShorten_Variants(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("names are lowercased and the link is never reused", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		cache := new(MockURLCacheRepository)
+		repo.On("Save", ctx, mock.AnythingOfType("*entity.URL")).Return(nil)
+		cache.On("SetByShortID", ctx, mock.Anything).Return(nil)
+
+		svc := service.NewURLService(repo, cache)
+		result, err := svc.Shorten(ctx, "https://example.com/", service.ShortenOptions{
+			Variants: []entity.Variant{
+				{Name: " Control ", Destination: "https://example.com/a", Weight: 50},
+				{Name: "new-landing", Destination: "https://example.com/b", Weight: 50},
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "control", result.Variants[0].Name)
+		assert.False(t, result.Reusable())
+		cache.AssertNotCalled(t, "GetByDedupeKey", mock.Anything, mock.Anything)
+	})
+
+	valid := entity.Variant{Name: "a", Destination: "https://example.com/a", Weight: 1}
+	invalid := map[string][]entity.Variant{
+		"single variant":       {valid},
+		"missing name":         {valid, {Destination: "https://example.com/b", Weight: 1}},
+		"invalid name":         {valid, {Name: "b c", Destination: "https://example.com/b", Weight: 1}},
+		"duplicate name":       {valid, {Name: "A", Destination: "https://example.com/b", Weight: 1}},
+		"relative destination": {valid, {Name: "b", Destination: "/b", Weight: 1}},
+		"negative weight":      {valid, {Name: "b", Destination: "https://example.com/b", Weight: -1}},
+		"weight too large":     {valid, {Name: "b", Destination: "https://example.com/b", Weight: service.MaxVariantWeight + 1}},
+		"all paused":           {{Name: "a", Destination: "https://example.com/a"}, {Name: "b", Destination: "https://example.com/b"}},
+	}
+	for name, variants := range invalid {
+		t.Run(name, func(t *testing.T) {
+			svc := service.NewURLService(new(MockURLRepository), new(MockURLCacheRepository))
+			_, err := svc.Shorten(ctx, "https://example.com/", service.ShortenOptions{Variants: variants})
+			assert.ErrorIs(t, err, service.ErrInvalidVariant)
+		})
+	}
+}